@@ -0,0 +1,172 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/huseyinbabal/demory/ds/bitmap"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/structure"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	structure.Register(newBitmapStructure)
+}
+
+// bitmapStructure serves and applies bitmaps.
+type bitmapStructure struct {
+	host    structure.Host
+	bitmaps *bitmap.Bitmaps
+}
+
+func newBitmapStructure(host structure.Host) structure.Structure {
+	return &bitmapStructure{host: host, bitmaps: bitmap.New()}
+}
+
+func (s *bitmapStructure) Kind() string {
+	return "bitmaps"
+}
+
+func (s *bitmapStructure) Commands() []fsm.CommandType {
+	return []fsm.CommandType{fsm.BitmapSetBit, fsm.BitmapOp}
+}
+
+func (s *bitmapStructure) Register(server grpc.ServiceRegistrar) {
+	rpc.RegisterBitmapServer(server, s)
+}
+
+func (s *bitmapStructure) Snapshot() ([]byte, error) {
+	return json.Marshal(s.bitmaps)
+}
+
+func (s *bitmapStructure) Restore(data []byte) (func(), error) {
+	bitmaps := bitmap.New()
+	if err := structure.Unmarshal(data, bitmaps); err != nil {
+		return nil, err
+	}
+	return func() { s.bitmaps = bitmaps }, nil
+}
+
+func (s *bitmapStructure) Stats() structure.Stats {
+	return structure.Stats{Bytes: s.bitmaps.Bytes()}
+}
+
+func (s *bitmapStructure) Destroy(name string) bool {
+	return s.bitmaps.Destroy(name)
+}
+
+// bitmapArgs are the arguments of replicated bitmap commands.
+type bitmapArgs struct {
+	Offset  uint32    `json:"offset,omitempty"`
+	Value   bool      `json:"value,omitempty"`
+	Op      bitmap.Op `json:"op,omitempty"`
+	Sources []string  `json:"sources,omitempty"`
+}
+
+// BitmapSetBit sets or clears a bit of a bitmap and returns its previous value.
+func (s *bitmapStructure) BitmapSetBit(ctx context.Context,
+	req *rpc.BitmapSetBitRequest) (*rpc.BitmapBitResponse, error) {
+	request := fsm.ApplyRequest{Type: fsm.BitmapSetBit, Name: req.Name}
+	data, err := s.host.Propose(request, bitmapArgs{Offset: req.Offset, Value: req.Value})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.BitmapBitResponse{Value: data.(bool)}, nil
+}
+
+// BitmapGetBit returns a bit of a bitmap.
+func (s *bitmapStructure) BitmapGetBit(ctx context.Context,
+	req *rpc.BitmapGetBitRequest) (*rpc.BitmapBitResponse, error) {
+	response := &rpc.BitmapBitResponse{}
+	s.host.Read(func() {
+		response.Value = s.bitmaps.GetBit(req.Name, req.Offset)
+	})
+
+	return response, nil
+}
+
+// BitmapCount returns the number of set bits of a bitmap in a range.
+func (s *bitmapStructure) BitmapCount(ctx context.Context,
+	req *rpc.BitmapCountRequest) (*rpc.BitmapCountResponse, error) {
+	response := &rpc.BitmapCountResponse{}
+	s.host.Read(func() {
+		response.Count = s.bitmaps.Count(req.Name, req.Start, bitmapEnd(req.End))
+	})
+
+	return response, nil
+}
+
+// BitmapPos returns the first offset of a bitmap in a range whose bit is set or clear.
+func (s *bitmapStructure) BitmapPos(ctx context.Context, req *rpc.BitmapPosRequest) (*rpc.BitmapPosResponse, error) {
+	response := &rpc.BitmapPosResponse{}
+	s.host.Read(func() {
+		response.Pos = s.bitmaps.Pos(req.Name, req.Bit, req.Start, bitmapEnd(req.End))
+	})
+
+	return response, nil
+}
+
+// BitmapOp stores the result of a bitwise operation between bitmaps in a destination bitmap and returns
+// its number of set bits.
+func (s *bitmapStructure) BitmapOp(ctx context.Context, req *rpc.BitmapOpRequest) (*rpc.BitmapCountResponse, error) {
+	if err := bitmap.ValidateOp(req.Op, len(req.Sources)); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	request := fsm.ApplyRequest{Type: fsm.BitmapOp, Name: req.Dest}
+	data, err := s.host.Propose(request, bitmapArgs{Op: req.Op, Sources: req.Sources})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.BitmapCountResponse{Count: data.(uint64)}, nil
+}
+
+func (s *bitmapStructure) Apply(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args bitmapArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	switch request.Type {
+	case fsm.BitmapSetBit:
+		return fsm.ApplyResponse{Data: s.bitmaps.SetBit(request.Name, args.Offset, args.Value)}
+	default:
+		count, err := s.bitmaps.Op(args.Op, request.Name, args.Sources)
+		if err != nil {
+			return fsm.ApplyResponse{Error: status.Error(codes.InvalidArgument, err.Error())}
+		}
+		return fsm.ApplyResponse{Data: count}
+	}
+}
+
+// bitmapEnd returns the end of a bit range, which defaults to the last offset of a bitmap.
+func bitmapEnd(offset *uint32) uint32 {
+	if offset == nil {
+		return bitmap.MaxOffset
+	}
+	return *offset
+}
+
+// Growth returns the number of bytes setting a bit adds, or an estimate of the difference in size between the
+// result of a bitwise operation and the bitmap it replaces.
+func (s *bitmapStructure) Growth(request fsm.ApplyRequest) int64 {
+	var args bitmapArgs
+	if json.Unmarshal(request.Args, &args) != nil {
+		return 0
+	}
+
+	switch {
+	case request.Type == fsm.BitmapOp:
+		return s.bitmaps.OpGrowth(args.Op, request.Name, args.Sources)
+	case args.Value:
+		return s.bitmaps.SetBitGrowth(request.Name, args.Offset)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,159 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/huseyinbabal/demory/ds/bloom"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/structure"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	structure.Register(newBloomStructure)
+}
+
+// bloomStructure serves and applies Bloom filters.
+type bloomStructure struct {
+	host    structure.Host
+	filters *bloom.Filters
+}
+
+func newBloomStructure(host structure.Host) structure.Structure {
+	return &bloomStructure{host: host, filters: bloom.New()}
+}
+
+func (s *bloomStructure) Kind() string {
+	return "blooms"
+}
+
+func (s *bloomStructure) Commands() []fsm.CommandType {
+	return []fsm.CommandType{fsm.BloomCreate, fsm.BloomAdd}
+}
+
+func (s *bloomStructure) Register(server grpc.ServiceRegistrar) {
+	rpc.RegisterBloomServer(server, s)
+}
+
+func (s *bloomStructure) Snapshot() ([]byte, error) {
+	return json.Marshal(s.filters)
+}
+
+func (s *bloomStructure) Restore(data []byte) (func(), error) {
+	filters := bloom.New()
+	if err := structure.Unmarshal(data, filters); err != nil {
+		return nil, err
+	}
+	return func() { s.filters = filters }, nil
+}
+
+func (s *bloomStructure) Stats() structure.Stats {
+	return structure.Stats{Bytes: s.filters.Bytes()}
+}
+
+func (s *bloomStructure) Destroy(name string) bool {
+	return s.filters.Destroy(name)
+}
+
+// bloomArgs are the arguments of replicated Bloom filter commands.
+type bloomArgs struct {
+	Capacity  uint64   `json:"capacity,omitempty"`
+	ErrorRate float64  `json:"errorRate,omitempty"`
+	Items     [][]byte `json:"items,omitempty"`
+}
+
+// BloomCreate creates a Bloom filter sized for an expected number of items and false positive rate.
+func (s *bloomStructure) BloomCreate(ctx context.Context, req *rpc.BloomCreateRequest) (*rpc.Empty, error) {
+	if _, _, err := bloom.Dimensions(req.Capacity, req.ErrorRate); err != nil {
+		return nil, bloomError(err)
+	}
+
+	request := fsm.ApplyRequest{Type: fsm.BloomCreate, Name: req.Name}
+	_, err := s.host.Propose(request, bloomArgs{Capacity: req.Capacity, ErrorRate: req.ErrorRate})
+	return new(rpc.Empty), err
+}
+
+// BloomAdd adds items to a Bloom filter, creating it with the default capacity and error rate if needed.
+func (s *bloomStructure) BloomAdd(ctx context.Context, req *rpc.BloomItemsRequest) (*rpc.BloomAddResponse, error) {
+	data, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.BloomAdd, Name: req.Name}, bloomArgs{Items: req.Items})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.BloomAddResponse{Added: data.([]bool)}, nil
+}
+
+// BloomMightContain tells for each item whether it might have been added to a Bloom filter.
+func (s *bloomStructure) BloomMightContain(ctx context.Context,
+	req *rpc.BloomItemsRequest) (*rpc.BloomMightContainResponse, error) {
+	response := &rpc.BloomMightContainResponse{}
+	s.host.Read(func() {
+		response.Found = s.filters.MightContain(req.Name, req.Items)
+	})
+
+	return response, nil
+}
+
+// BloomInfo describes a Bloom filter.
+func (s *bloomStructure) BloomInfo(ctx context.Context, req *rpc.BloomInfoRequest) (*rpc.BloomInfoResponse, error) {
+	response := &rpc.BloomInfoResponse{}
+	var err error
+	s.host.Read(func() {
+		response.Info, err = s.filters.Info(req.Name)
+	})
+	if err != nil {
+		return nil, bloomError(err)
+	}
+
+	return response, nil
+}
+
+func (s *bloomStructure) Apply(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args bloomArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	switch request.Type {
+	case fsm.BloomCreate:
+		if err := s.filters.Create(request.Name, args.Capacity, args.ErrorRate); err != nil {
+			return fsm.ApplyResponse{Error: bloomError(err)}
+		}
+		return fsm.ApplyResponse{}
+	default:
+		return fsm.ApplyResponse{Data: s.filters.Add(request.Name, args.Items)}
+	}
+}
+
+// Growth returns the number of bytes the filter of a Bloom filter command allocates.
+func (s *bloomStructure) Growth(request fsm.ApplyRequest) int64 {
+	if s.filters.Exists(request.Name) {
+		return 0
+	}
+	if request.Type == fsm.BloomAdd {
+		return bloom.Size(bloom.DefaultCapacity, bloom.DefaultErrorRate)
+	}
+
+	var args bloomArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return 0
+	}
+	return bloom.Size(args.Capacity, args.ErrorRate)
+}
+
+// bloomError converts errors of the bloom package to gRPC statuses.
+func bloomError(err error) error {
+	switch {
+	case errors.Is(err, bloom.ErrNoFilter):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, bloom.ErrExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
@@ -0,0 +1,95 @@
+package demory
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/huseyinbabal/demory/cdc"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Changes streams a record of every change made by the commands applied on this node. A subscription resumed
+// after an index that is no longer kept fails with OUT_OF_RANGE, and a subscriber that does not keep up is
+// ended with RESOURCE_EXHAUSTED; both can resume from the index of the last record they received.
+func (d *Demory) Changes(req *rpc.ChangesRequest, stream rpc.ServerStream[cdc.Record]) error {
+	subscriber, err := d.cdc.Subscribe(req.AfterIndex, req.Buffer)
+	switch {
+	case errors.Is(err, cdc.ErrCompacted):
+		return status.Error(codes.OutOfRange, err.Error())
+	case err != nil:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	defer subscriber.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-subscriber.Done():
+			return status.Error(codes.ResourceExhausted, subscriber.Err().Error())
+		case r := <-subscriber.Records():
+			if err := stream.Send(&r); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// capture publishes the changes made by an applied command to the cdc log. Commands that changed no map or
+// cache entry are published as a single record of the structure they work on, unless they failed. Transactions
+// that changed nothing only read entries, so they are not published.
+func (d *Demory) capture(request fsm.ApplyRequest, response fsm.ApplyResponse) {
+	records := d.changes
+	d.changes = nil
+
+	if len(records) == 0 {
+		if response.Error != nil || request.Type == fsm.Transaction {
+			return
+		}
+		records = []cdc.Record{{
+			Structure: d.commandStructure(request),
+			Name:      request.Name,
+			Key:       request.Key,
+			Value:     request.Value,
+		}}
+	}
+
+	timestamp := request.Time
+	if timestamp == 0 && !request.AppendedAt.IsZero() {
+		timestamp = request.AppendedAt.UnixNano()
+	}
+	for i := range records {
+		records[i].Index = request.Index
+		records[i].Term = request.Term
+		records[i].Time = timestamp
+		records[i].Command = request.Type.String()
+	}
+
+	d.cdc.Publish(records)
+}
+
+// commandStructure returns the structure a command works on: the kind of the structure type applying it, or
+// the map or cache of a command of the node itself.
+func (d *Demory) commandStructure(request fsm.ApplyRequest) string {
+	if s, ok := d.structures.Applying(request.Type); ok {
+		return s.Kind()
+	}
+
+	switch request.Type {
+	case fsm.CachePut, fsm.CacheRemove, fsm.CacheClear, fsm.CacheExpire, fsm.CacheSetDefaultTTL:
+		return string(fsm.StructureCache)
+	case fsm.TopicPublish:
+		return "topics"
+	case fsm.RespBatch:
+		return "resp"
+	case fsm.StructureDestroy:
+		var args structureArgs
+		json.Unmarshal(request.Args, &args)
+		return args.Kind
+	default:
+		return string(fsm.StructureMap)
+	}
+}
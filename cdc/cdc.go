@@ -0,0 +1,178 @@
+// Package cdc captures the changes made by applied commands, for change data capture consumers. Recent records
+// are kept, so that consumers can resume from the index they stopped at.
+package cdc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	DefaultHistory = 100000
+	DefaultBuffer  = 1024
+	MaxBuffer      = 1 << 16
+)
+
+var (
+	ErrCompacted      = errors.New("index is compacted")
+	ErrSlowSubscriber = errors.New("subscriber did not keep up with applied commands")
+)
+
+// Record is a change made by an applied command. Commands changing several entries produce a record for each
+// of them, and commands changing no map or cache entry a single record without a key.
+type Record struct {
+	Index uint64 `json:"index"`
+	Term  uint64 `json:"term"`
+	// Time is when the leader proposed the command, in unix nanoseconds.
+	Time    int64  `json:"time"`
+	Command string `json:"command"`
+	// Type is the type of the change of the entry: put, remove, evict or expire.
+	Type      string `json:"type,omitempty"`
+	Structure string `json:"structure,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Key       string `json:"key,omitempty"`
+	Old       []byte `json:"old,omitempty"`
+	Value     []byte `json:"value,omitempty"`
+}
+
+// Subscriber receives the records published after it subscribed.
+type Subscriber struct {
+	log     *Log
+	records chan Record
+	done    chan struct{}
+	err     error
+}
+
+// Records returns the channel records are delivered on.
+func (s *Subscriber) Records() <-chan Record {
+	return s.records
+}
+
+// Done is closed once the subscriber is dropped because it was too slow, see Err.
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns why the subscriber was dropped.
+func (s *Subscriber) Err() error {
+	s.log.mutex.Lock()
+	defer s.log.mutex.Unlock()
+
+	return s.err
+}
+
+// Close stops the subscriber.
+func (s *Subscriber) Close() {
+	s.log.mutex.Lock()
+	defer s.log.mutex.Unlock()
+
+	s.log.remove(s, nil)
+}
+
+// Log keeps the recent records of a node and delivers new ones to subscribers without blocking.
+type Log struct {
+	mutex       sync.Mutex
+	limit       int
+	history     []Record
+	floor       uint64
+	restored    bool
+	subscribers map[*Subscriber]struct{}
+}
+
+// New creates a log keeping up to history records.
+func New(history int) *Log {
+	if history <= 0 {
+		history = DefaultHistory
+	}
+	return &Log{limit: history, subscribers: make(map[*Subscriber]struct{})}
+}
+
+// Restored tells the log that the state was replaced by a snapshot. The records before it are lost.
+func (l *Log) Restored() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.history = nil
+	l.restored = true
+}
+
+// Publish records the records of an applied command and delivers them to subscribers.
+func (l *Log) Publish(records []Record) {
+	if len(records) == 0 {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.restored {
+		l.floor, l.restored = records[0].Index-1, false
+	}
+	l.history = append(l.history, records...)
+	for len(l.history) > l.limit {
+		if l.history[0].Index > l.floor {
+			l.floor = l.history[0].Index
+		}
+		l.history = l.history[1:]
+	}
+
+	// The records of a command are delivered together or not at all, so that subscribers resuming after
+	// the index of the last record they received miss nothing.
+	for s := range l.subscribers {
+		if cap(s.records)-len(s.records) < len(records) {
+			l.remove(s, ErrSlowSubscriber)
+			continue
+		}
+		for _, r := range records {
+			s.records <- r
+		}
+	}
+}
+
+// Subscribe starts delivering records. If after is set, the recorded records after that index are delivered
+// first, unless some of them are no longer kept, in which case ErrCompacted is returned.
+func (l *Log) Subscribe(after *uint64, buffer int) (*Subscriber, error) {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	if buffer > MaxBuffer {
+		buffer = MaxBuffer
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var backlog []Record
+	if after != nil {
+		if *after < l.floor {
+			return nil, fmt.Errorf("%w, the oldest index to resume after is %d", ErrCompacted, l.floor)
+		}
+		for _, r := range l.history {
+			if r.Index > *after {
+				backlog = append(backlog, r)
+			}
+		}
+	}
+
+	s := &Subscriber{
+		log:     l,
+		records: make(chan Record, buffer+len(backlog)),
+		done:    make(chan struct{}),
+	}
+	for _, r := range backlog {
+		s.records <- r
+	}
+	l.subscribers[s] = struct{}{}
+
+	return s, nil
+}
+
+func (l *Log) remove(s *Subscriber, err error) {
+	if _, ok := l.subscribers[s]; !ok {
+		return
+	}
+	delete(l.subscribers, s)
+	s.err = err
+	close(s.done)
+}
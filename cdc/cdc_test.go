@@ -0,0 +1,117 @@
+package cdc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func index(i uint64) *uint64 {
+	return &i
+}
+
+func TestResume(t *testing.T) {
+	l := New(3)
+	l.Publish([]Record{{Index: 1, Key: "a"}, {Index: 1, Key: "b"}})
+	l.Publish([]Record{{Index: 2, Key: "c"}})
+	l.Publish([]Record{{Index: 3, Key: "d"}})
+
+	if _, err := l.Subscribe(index(0), 0); !errors.Is(err, ErrCompacted) {
+		t.Errorf("expected %v, got %v", ErrCompacted, err)
+	}
+
+	s, err := l.Subscribe(index(1), 0)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	l.Publish([]Record{{Index: 4, Key: "e"}})
+
+	for _, key := range []string{"c", "d", "e"} {
+		if r := <-s.Records(); r.Key != key {
+			t.Errorf("expected record %s, got %+v", key, r)
+		}
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	l := New(10)
+	s, _ := l.Subscribe(nil, 2)
+
+	l.Publish([]Record{{Index: 1}})
+	l.Publish([]Record{{Index: 2}, {Index: 2}})
+
+	<-s.Done()
+	if s.Err() != ErrSlowSubscriber {
+		t.Errorf("expected %v, got %v", ErrSlowSubscriber, s.Err())
+	}
+	if len(s.Records()) != 1 {
+		t.Errorf("expected only whole commands to be delivered, got %d records", len(s.Records()))
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cdc.ndjson")
+	l := New(10)
+	l.Publish([]Record{{Index: 1, Key: "a"}})
+
+	sink, err := OpenFileSink(path)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	go sink.Run(l)
+	l.Publish([]Record{{Index: 2, Key: "b"}})
+	time.Sleep(50 * time.Millisecond)
+	sink.Close()
+
+	// A restarted sink skips the records already written.
+	sink, err = OpenFileSink(path)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	go sink.Run(l)
+	l.Publish([]Record{{Index: 3, Key: "c"}})
+	time.Sleep(50 * time.Millisecond)
+	sink.Close()
+
+	file, _ := os.Open(path)
+	defer file.Close()
+	var keys []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid line %s", scanner.Text())
+		}
+		keys = append(keys, r.Key)
+	}
+	if len(keys) != 3 || keys[0] != "a" || keys[2] != "c" {
+		t.Errorf("unexpected records %v", keys)
+	}
+}
+
+func TestFileSinkLargeRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cdc.ndjson")
+	var data []byte
+	for _, r := range []Record{{Index: 1}, {Index: 2, Value: make([]byte, 200<<10)}} {
+		line, _ := json.Marshal(r)
+		data = append(append(data, line...), '\n')
+	}
+	// A record cut short by a crash is not complete.
+	data = append(data, `{"index":3,"value":"`...)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	sink, err := OpenFileSink(path)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer sink.file.Close()
+	if sink.last != 2 {
+		t.Errorf("expected the last complete record to be 2, got %d", sink.last)
+	}
+}
@@ -0,0 +1,157 @@
+package cdc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"time"
+)
+
+// chunk is how much of a sink file is read at once to find the last record written.
+const chunk = 1 << 16
+
+// FileSink appends the records of a Log to a file as newline delimited JSON. It follows the log on its own,
+// so a slow disk never holds up the FSM: when it falls behind it resumes from the last record it wrote.
+type FileSink struct {
+	file *os.File
+	last uint64
+	stop chan struct{}
+	done chan struct{}
+}
+
+// OpenFileSink opens the file at path for appending. Records up to the last index already in the file are
+// skipped, so that commands replayed after a restart are not written twice.
+func OpenFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := lastIndex(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &FileSink{file: file, last: last, stop: make(chan struct{}), done: make(chan struct{})}, nil
+}
+
+// Run writes the records of l until Close is called.
+func (s *FileSink) Run(l *Log) {
+	defer close(s.done)
+
+	writer := bufio.NewWriter(s.file)
+	encoder := json.NewEncoder(writer)
+	flush := time.NewTicker(time.Second)
+	defer flush.Stop()
+
+	for {
+		after := s.last
+		subscriber, err := l.Subscribe(&after, MaxBuffer)
+		if errors.Is(err, ErrCompacted) {
+			log.Printf("cdc sink missed records after index %d %v.\n", after, err)
+			subscriber, err = l.Subscribe(nil, MaxBuffer)
+		}
+		if err != nil {
+			log.Printf("failed to follow cdc log %v.\n", err)
+			return
+		}
+
+		for following := true; following; {
+			select {
+			case <-s.stop:
+				subscriber.Close()
+				if err := writer.Flush(); err != nil {
+					log.Printf("failed to write cdc records %v.\n", err)
+				}
+				return
+			case <-subscriber.Done():
+				// Records delivered before the subscriber was dropped are complete commands.
+				for drained := false; !drained; {
+					select {
+					case r := <-subscriber.Records():
+						s.write(encoder, r)
+					default:
+						drained = true
+					}
+				}
+				following = false
+			case r := <-subscriber.Records():
+				s.write(encoder, r)
+			case <-flush.C:
+				if err := writer.Flush(); err != nil {
+					log.Printf("failed to write cdc records %v.\n", err)
+				}
+			}
+		}
+	}
+}
+
+func (s *FileSink) write(encoder *json.Encoder, r Record) {
+	if err := encoder.Encode(r); err != nil {
+		log.Printf("failed to write cdc record %d %v.\n", r.Index, err)
+		return
+	}
+	s.last = r.Index
+}
+
+// Close stops the sink and closes its file.
+func (s *FileSink) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.file.Close()
+}
+
+// lastIndex returns the index of the last complete record of a sink file, or zero if it has none. Records are
+// written in order, so it reads the file backwards from its end, line by line, whatever their length.
+func lastIndex(file *os.File) (uint64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	// A record after the last newline is incomplete.
+	end, err := lastNewline(file, info.Size())
+	for err == nil && end >= 0 {
+		var start int64
+		if start, err = lastNewline(file, end); err != nil {
+			break
+		}
+
+		line := make([]byte, end-start-1)
+		if _, err = file.ReadAt(line, start+1); err != nil {
+			break
+		}
+		var r Record
+		if json.Unmarshal(line, &r) == nil && r.Index > 0 {
+			return r.Index, nil
+		}
+		end = start
+	}
+
+	return 0, err
+}
+
+// lastNewline returns the offset of the last newline of a file before offset, or -1 if there is none.
+func lastNewline(file *os.File, offset int64) (int64, error) {
+	buffer := make([]byte, chunk)
+	for offset > 0 {
+		n := int64(chunk)
+		if offset < n {
+			n = offset
+		}
+		offset -= n
+
+		if _, err := file.ReadAt(buffer[:n], offset); err != nil {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(buffer[:n], '\n'); i >= 0 {
+			return offset + int64(i), nil
+		}
+	}
+
+	return -1, nil
+}
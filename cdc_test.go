@@ -0,0 +1,37 @@
+package demory
+
+import (
+	"context"
+	"testing"
+
+	proto "github.com/huseyinbabal/demory-proto/golang/demory"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/node"
+	"github.com/huseyinbabal/demory/rpc"
+)
+
+func TestCaptureStructure(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	ctx := context.Background()
+	subscriber, err := d.cdc.Subscribe(nil, 10)
+	if err != nil {
+		t.Fatalf("failed to subscribe %v", err)
+	}
+	defer subscriber.Close()
+
+	d.MapPut(ctx, &proto.MapPutRequest{Name: "users", Key: "a", Value: []byte("1")})
+	d.CacheSetDefaultTTL(ctx, &rpc.CacheSetDefaultTTLRequest{Name: "sessions", TTL: 1000})
+	d.propose(fsm.ApplyRequest{Type: fsm.StreamAdd, Name: "events", Value: []byte("1")}, streamArgs{})
+	d.Execute(ctx, &rpc.TransactionRequest{Operations: []rpc.Operation{
+		{Type: rpc.OperationGet, Structure: "map", Name: "users", Key: "a"},
+	}})
+	d.CacheClear(ctx, &proto.CacheClearRequest{Name: "sessions"})
+
+	expected := []string{"map", "cache", "streams", "cache"}
+	for _, structure := range expected {
+		r := <-subscriber.Records()
+		if r.Structure != structure {
+			t.Errorf("expected a record of %s for %s, got %s", structure, r.Command, r.Structure)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/huseyinbabal/demory/ds/cms"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/structure"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	structure.Register(newCMSStructure)
+}
+
+// cmsStructure serves and applies count-min sketches.
+type cmsStructure struct {
+	host     structure.Host
+	sketches *cms.Sketches
+}
+
+func newCMSStructure(host structure.Host) structure.Structure {
+	return &cmsStructure{host: host, sketches: cms.New()}
+}
+
+func (s *cmsStructure) Kind() string {
+	return "cms"
+}
+
+func (s *cmsStructure) Commands() []fsm.CommandType {
+	return []fsm.CommandType{fsm.CMSCreate, fsm.CMSIncrement, fsm.CMSMerge}
+}
+
+func (s *cmsStructure) Register(server grpc.ServiceRegistrar) {
+	rpc.RegisterCountMinSketchServer(server, s)
+}
+
+func (s *cmsStructure) Snapshot() ([]byte, error) {
+	return json.Marshal(s.sketches)
+}
+
+func (s *cmsStructure) Restore(data []byte) (func(), error) {
+	sketches := cms.New()
+	if err := structure.Unmarshal(data, sketches); err != nil {
+		return nil, err
+	}
+	return func() { s.sketches = sketches }, nil
+}
+
+func (s *cmsStructure) Stats() structure.Stats {
+	return structure.Stats{Bytes: s.sketches.Bytes()}
+}
+
+func (s *cmsStructure) Destroy(name string) bool {
+	return s.sketches.Destroy(name)
+}
+
+// cmsArgs are the arguments of replicated count-min sketch commands.
+type cmsArgs struct {
+	Width      uint32          `json:"width,omitempty"`
+	Depth      uint32          `json:"depth,omitempty"`
+	Increments []cms.Increment `json:"increments,omitempty"`
+	Sources    []string        `json:"sources,omitempty"`
+	Weights    []uint64        `json:"weights,omitempty"`
+}
+
+// CMSCreate creates a count-min sketch.
+func (s *cmsStructure) CMSCreate(ctx context.Context, req *rpc.CMSCreateRequest) (*rpc.Empty, error) {
+	if err := cms.Validate(req.Width, req.Depth); err != nil {
+		return nil, cmsError(err)
+	}
+
+	request := fsm.ApplyRequest{Type: fsm.CMSCreate, Name: req.Name}
+	_, err := s.host.Propose(request, cmsArgs{Width: req.Width, Depth: req.Depth})
+	return new(rpc.Empty), err
+}
+
+// CMSIncrement counts items in a count-min sketch and returns their new estimates.
+func (s *cmsStructure) CMSIncrement(ctx context.Context, req *rpc.CMSIncrementRequest) (*rpc.CMSCountsResponse, error) {
+	request := fsm.ApplyRequest{Type: fsm.CMSIncrement, Name: req.Name}
+	data, err := s.host.Propose(request, cmsArgs{Increments: req.Increments})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.CMSCountsResponse{Counts: data.([]uint64)}, nil
+}
+
+// CMSQuery returns the estimated counts of items in a count-min sketch.
+func (s *cmsStructure) CMSQuery(ctx context.Context, req *rpc.CMSQueryRequest) (*rpc.CMSCountsResponse, error) {
+	response := &rpc.CMSCountsResponse{}
+	var err error
+	s.host.Read(func() {
+		response.Counts, err = s.sketches.Query(req.Name, req.Items)
+	})
+	if err != nil {
+		return nil, cmsError(err)
+	}
+
+	return response, nil
+}
+
+// CMSMerge adds the weighted counts of count-min sketches to a destination sketch of the same dimensions.
+func (s *cmsStructure) CMSMerge(ctx context.Context, req *rpc.CMSMergeRequest) (*rpc.Empty, error) {
+	request := fsm.ApplyRequest{Type: fsm.CMSMerge, Name: req.Dest}
+	_, err := s.host.Propose(request, cmsArgs{Sources: req.Sources, Weights: req.Weights})
+	return new(rpc.Empty), err
+}
+
+func (s *cmsStructure) Apply(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args cmsArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	var data interface{}
+	var err error
+	switch request.Type {
+	case fsm.CMSCreate:
+		err = s.sketches.Create(request.Name, args.Width, args.Depth)
+	case fsm.CMSIncrement:
+		data, err = s.sketches.Increment(request.Name, args.Increments)
+	case fsm.CMSMerge:
+		err = s.sketches.Merge(request.Name, args.Sources, args.Weights)
+	}
+	if err != nil {
+		return fsm.ApplyResponse{Error: cmsError(err)}
+	}
+
+	return fsm.ApplyResponse{Data: data}
+}
+
+// Growth returns the number of bytes a count-min sketch command allocates.
+func (s *cmsStructure) Growth(request fsm.ApplyRequest) int64 {
+	var args cmsArgs
+	if request.Type != fsm.CMSCreate || s.sketches.Exists(request.Name) ||
+		json.Unmarshal(request.Args, &args) != nil || cms.Validate(args.Width, args.Depth) != nil {
+		return 0
+	}
+	return int64(args.Width) * int64(args.Depth) * 8
+}
+
+// cmsError converts errors of the cms package to gRPC statuses.
+func cmsError(err error) error {
+	switch {
+	case errors.Is(err, cms.ErrNoSketch):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, cms.ErrExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, cms.ErrMismatch):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
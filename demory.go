@@ -2,11 +2,14 @@ package demory
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	proto "github.com/huseyinbabal/demory-proto/golang/demory"
@@ -15,10 +18,19 @@ import (
 	"github.com/Jille/raft-grpc-leader-rpc/leaderhealth"
 	"github.com/Jille/raftadmin"
 	"github.com/hashicorp/raft"
+	"github.com/huseyinbabal/demory/cdc"
 	"github.com/huseyinbabal/demory/discovery"
 	"github.com/huseyinbabal/demory/ds/hashmap"
 	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/index"
+	"github.com/huseyinbabal/demory/mapstore"
+	"github.com/huseyinbabal/demory/metrics"
 	"github.com/huseyinbabal/demory/node"
+	"github.com/huseyinbabal/demory/pubsub"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/structure"
+	"github.com/huseyinbabal/demory/txn"
+	"github.com/huseyinbabal/demory/watch"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/protobuf/types/known/emptypb"
@@ -27,10 +39,20 @@ import (
 // Demory is for representing data structure storage
 // It also has basic api interface for data operations.
 type Demory struct {
-	hashMap *hashmap.HashMap
-	cache   *cache.Cache
-	fsm     *fsm.Fsm
-	config  *node.Config
+	hashMap    *hashmap.HashMap
+	cache      *cache.Cache
+	resp       *respState
+	fsm        *fsm.Fsm
+	config     *node.Config
+	persister  *mapstore.Persister
+	pending    *mapstore.Pending
+	indexes    *index.Indexes
+	txns       *txn.Manager
+	broker     *pubsub.Broker
+	structures *structure.Registry
+	watches    *watch.Hub
+	cdc        *cdc.Log
+	changes    []cdc.Record
 	proto.UnimplementedDemoryServer
 }
 
@@ -41,114 +63,154 @@ func New() *Demory {
 		log.Fatalf("node config error %v", nodeConfigErr)
 	}
 
-	return &Demory{
-		hashMap: hashmap.New(),
-		cache:   cache.New(),
-		fsm:     fsm.New(*nodeConfig),
-		config:  nodeConfig,
+	return newDemory(nodeConfig)
+}
+
+func newDemory(nodeConfig *node.Config) *Demory {
+	persister, persisterErr := newPersister(nodeConfig)
+	if persisterErr != nil {
+		log.Fatalf("map store error %v", persisterErr)
+	}
+
+	d := &Demory{
+		hashMap:   hashmap.New(),
+		cache:     cache.New(),
+		resp:      newRespState(),
+		config:    nodeConfig,
+		persister: persister,
+		pending:   mapstore.NewPending(),
+		indexes:   index.NewIndexes(),
+		broker:    pubsub.New(),
+		watches:   watch.New(nodeConfig.WatchHistory),
+		cdc:       cdc.New(nodeConfig.CDCHistory),
+		txns: txn.New(txn.Config{
+			Limit:   nodeConfig.MaxTransactions,
+			Timeout: time.Duration(nodeConfig.TransactionTimeout) * time.Millisecond,
+		}),
+	}
+	structures, structuresErr := structure.New(host{d: d})
+	if structuresErr != nil {
+		log.Fatalf("structure error %v", structuresErr)
 	}
+	for _, kind := range coreKinds {
+		if _, ok := structures.Kind(kind); ok {
+			log.Fatalf("structure error %s is reserved for the node itself", kind)
+		}
+	}
+	d.structures = structures
+	d.listen()
+	d.fsm = fsm.New(*nodeConfig, fsm.State{Apply: d.apply, Snapshot: d.snapshot, Restore: d.restore})
+
+	return d
 }
 
 // MapPut saves data into store.
 func (d *Demory) MapPut(ctx context.Context, req *proto.MapPutRequest) (*emptypb.Empty, error) {
-	bytes, bytesErr := json.Marshal(fsm.ApplyRequest{
-		Command: func() interface{} {
-			return d.hashMap.Put(req.GetName(), req.GetKey(), req.GetValue())
-		},
-	})
-	if bytesErr != nil {
-		return new(emptypb.Empty), bytesErr
+	request := fsm.ApplyRequest{
+		Type:        fsm.MapPut,
+		Name:        req.GetName(),
+		Key:         req.GetKey(),
+		Value:       req.GetValue(),
+		WriteBehind: d.writesBehind(req.GetName()),
 	}
-
-	apply := d.fsm.Raft.Apply(bytes, time.Second)
-
-	if err := apply.Error(); err != nil {
+	if _, err := d.propose(request, nil); err != nil {
 		return new(emptypb.Empty), err
 	}
 
-	return new(emptypb.Empty), nil
+	return new(emptypb.Empty), d.persist(req.GetName(), func(p *mapstore.Persister) error {
+		return p.Put(req.GetName(), req.GetKey(), req.GetValue())
+	})
 }
 
 // MapGet retrieves data from store.
 func (d *Demory) MapGet(ctx context.Context, req *proto.MapGetRequest) (*proto.MapGetResponse, error) {
-	return &proto.MapGetResponse{Value: d.hashMap.Get(req.GetName(), req.GetKey())}, nil
+	var value []byte
+	var found bool
+	d.fsm.Read(func() {
+		value = d.hashMap.Get(req.GetName(), req.GetKey())
+		_, found = d.hashMap.EntrySize(req.GetName(), req.GetKey())
+	})
+
+	if !found {
+		loaded, ok, err := d.load(req.GetName(), req.GetKey())
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			d.cacheLoaded(req.GetName(), req.GetKey(), loaded)
+			value = loaded
+		}
+	}
+
+	return &proto.MapGetResponse{Value: value}, nil
 }
 
 // MapPutIfAbsent inserts value at specified key if there is no value
 func (d *Demory) MapPutIfAbsent(ctx context.Context, req *proto.MapPutIfAbsentRequest) (*emptypb.Empty, error) {
-	bytes, bytesErr := json.Marshal(fsm.ApplyRequest{
-		Command: func() interface{} {
-			return d.hashMap.PutIfAbsent(req.GetName(), req.GetKey(), req.GetValue())
-		},
-	})
-	if bytesErr != nil {
-		return new(emptypb.Empty), bytesErr
+	request := fsm.ApplyRequest{
+		Type:        fsm.MapPutIfAbsent,
+		Name:        req.GetName(),
+		Key:         req.GetKey(),
+		Value:       req.GetValue(),
+		WriteBehind: d.writesBehind(req.GetName()),
 	}
-
-	apply := d.fsm.Raft.Apply(bytes, time.Second)
-
-	if err := apply.Error(); err != nil {
+	data, err := d.propose(request, nil)
+	if err != nil || data != 1 {
 		return new(emptypb.Empty), err
 	}
 
-	return new(emptypb.Empty), nil
+	return new(emptypb.Empty), d.persist(req.GetName(), func(p *mapstore.Persister) error {
+		return p.Put(req.GetName(), req.GetKey(), req.GetValue())
+	})
 }
 
 // MapRemove removes the value at specified key
 func (d *Demory) MapRemove(ctx context.Context, req *proto.MapRemoveRequest) (*emptypb.Empty, error) {
-	bytes, bytesErr := json.Marshal(fsm.ApplyRequest{
-		Command: func() interface{} {
-			return d.hashMap.Remove(req.GetName(), req.GetKey())
-		},
-	})
-	if bytesErr != nil {
-		return new(emptypb.Empty), bytesErr
+	request := fsm.ApplyRequest{
+		Type:        fsm.MapRemove,
+		Name:        req.GetName(),
+		Key:         req.GetKey(),
+		WriteBehind: d.writesBehind(req.GetName()),
 	}
-
-	apply := d.fsm.Raft.Apply(bytes, time.Second)
-
-	if err := apply.Error(); err != nil {
+	if _, err := d.propose(request, nil); err != nil {
 		return new(emptypb.Empty), err
 	}
 
-	return new(emptypb.Empty), nil
+	return new(emptypb.Empty), d.persist(req.GetName(), func(p *mapstore.Persister) error {
+		return p.Delete(req.GetName(), req.GetKey())
+	})
 }
 
 // MapClear clears all the entries in map specified with name
 func (d *Demory) MapClear(ctx context.Context, req *proto.MapClearRequest) (*emptypb.Empty, error) {
-	bytes, bytesErr := json.Marshal(fsm.ApplyRequest{
-		Command: func() interface{} {
-			return d.hashMap.Clear(req.GetName())
-		},
-	})
-	if bytesErr != nil {
-		return new(emptypb.Empty), bytesErr
+	request := fsm.ApplyRequest{
+		Type:        fsm.MapClear,
+		Name:        req.GetName(),
+		WriteBehind: d.writesBehind(req.GetName()),
 	}
+	var keys []string
+	d.fsm.Read(func() {
+		keys = d.hashMap.Keys(req.GetName())
+	})
 
-	apply := d.fsm.Raft.Apply(bytes, time.Second)
-
-	if err := apply.Error(); err != nil {
+	if _, err := d.propose(request, nil); err != nil {
 		return new(emptypb.Empty), err
 	}
 
-	return new(emptypb.Empty), nil
+	return new(emptypb.Empty), d.persist(req.GetName(), func(p *mapstore.Persister) error {
+		return p.Delete(req.GetName(), keys...)
+	})
 }
 
 // CachePut saves data into store.
 func (d *Demory) CachePut(ctx context.Context, req *proto.CachePutRequest) (*emptypb.Empty, error) {
-	bytes, bytesErr := json.Marshal(fsm.ApplyRequest{
-		Command: func() interface{} {
-			d.cache.Put(req.GetName(), req.GetKey(), req.GetValue())
-			return nil
-		},
-	})
-	if bytesErr != nil {
-		return new(emptypb.Empty), bytesErr
+	request := fsm.ApplyRequest{
+		Type:  fsm.CachePut,
+		Name:  req.GetName(),
+		Key:   req.GetKey(),
+		Value: req.GetValue(),
 	}
-
-	apply := d.fsm.Raft.Apply(bytes, time.Second)
-
-	if err := apply.Error(); err != nil {
+	if _, err := d.propose(request, nil); err != nil {
 		return new(emptypb.Empty), err
 	}
 
@@ -157,24 +219,22 @@ func (d *Demory) CachePut(ctx context.Context, req *proto.CachePutRequest) (*emp
 
 // CacheGet retrieves data from store.
 func (d *Demory) CacheGet(ctx context.Context, req *proto.CacheGetRequest) (*proto.CacheGetResponse, error) {
-	return &proto.CacheGetResponse{Value: d.cache.Get(req.GetName(), req.GetKey())}, nil
+	var value []byte
+	d.fsm.Read(func() {
+		value = d.cache.Get(req.GetName(), req.GetKey(), time.Now())
+	})
+
+	return &proto.CacheGetResponse{Value: value}, nil
 }
 
 // Remove removes the value at specified key
 func (d *Demory) CacheRemove(ctx context.Context, req *proto.CacheRemoveRequest) (*emptypb.Empty, error) {
-	bytes, bytesErr := json.Marshal(fsm.ApplyRequest{
-		Command: func() interface{} {
-			d.cache.Remove(req.GetName(), req.GetKey())
-			return nil
-		},
-	})
-	if bytesErr != nil {
-		return new(emptypb.Empty), bytesErr
+	request := fsm.ApplyRequest{
+		Type: fsm.CacheRemove,
+		Name: req.GetName(),
+		Key:  req.GetKey(),
 	}
-
-	apply := d.fsm.Raft.Apply(bytes, time.Second)
-
-	if err := apply.Error(); err != nil {
+	if _, err := d.propose(request, nil); err != nil {
 		return new(emptypb.Empty), err
 	}
 
@@ -183,19 +243,11 @@ func (d *Demory) CacheRemove(ctx context.Context, req *proto.CacheRemoveRequest)
 
 // CacheClear clears all the entries in cache specified with name
 func (d *Demory) CacheClear(ctx context.Context, req *proto.CacheClearRequest) (*emptypb.Empty, error) {
-	bytes, bytesErr := json.Marshal(fsm.ApplyRequest{
-		Command: func() interface{} {
-			d.cache.Clear(req.GetName())
-			return nil
-		},
-	})
-	if bytesErr != nil {
-		return new(emptypb.Empty), bytesErr
+	request := fsm.ApplyRequest{
+		Type: fsm.CacheClear,
+		Name: req.GetName(),
 	}
-
-	apply := d.fsm.Raft.Apply(bytes, time.Second)
-
-	if err := apply.Error(); err != nil {
+	if _, err := d.propose(request, nil); err != nil {
 		return new(emptypb.Empty), err
 	}
 
@@ -217,6 +269,77 @@ func (d *Demory) JoinToCluster(ctx context.Context, request *proto.JoinToCluster
 	}, errors.New("not a leader")
 }
 
+// apply applies a command replicated through the raft log to the data structures of this node.
+func (d *Demory) apply(request fsm.ApplyRequest) fsm.ApplyResponse {
+	d.hashMap.SetVersion(request.Index)
+	d.cache.SetVersion(request.Index)
+	d.watches.Applied(request.Index)
+
+	response := d.applyCommand(request)
+	d.markPending(request)
+	d.capture(request, response)
+
+	return response
+}
+
+func (d *Demory) applyCommand(request fsm.ApplyRequest) fsm.ApplyResponse {
+	if err := d.reserve(request); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	switch request.Type {
+	case fsm.MapPut:
+		return fsm.ApplyResponse{Data: d.hashMap.Put(request.Name, request.Key, request.Value)}
+	case fsm.MapPutIfAbsent:
+		return fsm.ApplyResponse{Data: d.hashMap.PutIfAbsent(request.Name, request.Key, request.Value)}
+	case fsm.MapRemove:
+		return fsm.ApplyResponse{Data: d.hashMap.Remove(request.Name, request.Key)}
+	case fsm.MapClear:
+		return fsm.ApplyResponse{Data: d.hashMap.Clear(request.Name)}
+	case fsm.CachePut:
+		evicted := d.cache.Put(request.Name, request.Key, request.Value, request.TTL, time.Unix(0, request.Time))
+		return fsm.ApplyResponse{Data: evicted}
+	case fsm.CacheRemove:
+		d.cache.Remove(request.Name, request.Key)
+		return fsm.ApplyResponse{}
+	case fsm.CacheClear:
+		d.cache.Clear(request.Name)
+		return fsm.ApplyResponse{}
+	case fsm.CacheExpire:
+		expired := 0
+		for _, key := range request.Keys {
+			if d.cache.Expire(request.Name, key, time.Unix(0, request.Time)) {
+				expired++
+			}
+		}
+		return fsm.ApplyResponse{Data: expired}
+	case fsm.CacheSetDefaultTTL:
+		d.cache.SetDefaultTTL(request.Name, request.TTL)
+		return fsm.ApplyResponse{}
+	case fsm.MapExecute:
+		return d.execute(request)
+	case fsm.IndexCreate:
+		return d.createIndex(request)
+	case fsm.IndexDrop:
+		return d.dropIndex(request)
+	case fsm.Transaction:
+		return d.transaction(request)
+	case fsm.TopicPublish:
+		return d.publish(request)
+	case fsm.StructureDestroy:
+		return d.destroyStructure(request)
+	case fsm.MapStoreFlush:
+		return d.flushed(request)
+	case fsm.RespBatch:
+		return d.respBatch(request)
+	default:
+		if s, ok := d.structures.Applying(request.Type); ok {
+			return s.Apply(request)
+		}
+		return fsm.ApplyResponse{Error: fmt.Errorf("unknown command type %d", request.Type)}
+	}
+}
+
 func (d *Demory) Run() {
 	nodeConfig := d.config
 
@@ -231,15 +354,71 @@ func (d *Demory) Run() {
 		log.Fatalf("socket error %v", socketErr)
 	}
 
+	go d.expireEntries()
+	for _, s := range d.structures.Structures() {
+		if starter, ok := s.(structure.Starter); ok {
+			starter.Start()
+		}
+	}
+
+	if nodeConfig.CDCFile != "" {
+		sink, sinkErr := cdc.OpenFileSink(nodeConfig.CDCFile)
+		if sinkErr != nil {
+			log.Fatalf("cdc sink error %v", sinkErr)
+		}
+		go sink.Run(d.cdc)
+	}
+
+	if d.persister != nil && d.persister.Mode() == mapstore.WriteBehind {
+		go d.writeBehind()
+	}
+
+	if nodeConfig.MetricsPort > 0 {
+		go d.serveMetrics(nodeConfig.MetricsPort)
+	}
+
+	if nodeConfig.RespPort > 0 {
+		go d.serveResp(nodeConfig.RespPort)
+	}
+
 	server := grpc.NewServer()
 	proto.RegisterDemoryServer(server, d)
+	rpc.RegisterCacheServer(server, d)
+	rpc.RegisterMapServer(server, d)
+	rpc.RegisterIndexServer(server, d)
+	rpc.RegisterAdminServer(server, d)
+	rpc.RegisterTransactionServer(server, d)
+	rpc.RegisterTopicServer(server, d)
+	rpc.RegisterWatchServer(server, d)
+	rpc.RegisterCDCServer(server, d)
+	for _, s := range d.structures.Structures() {
+		s.Register(server)
+	}
 	d.fsm.Manager.Register(server)
 	leaderhealth.Setup(d.fsm.Raft, server, []string{"Leader"})
 	raftadmin.Register(server, d.fsm.Raft)
 	reflection.Register(server)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-stop
+		server.GracefulStop()
+	}()
+
 	serveErr := server.Serve(socket)
+	d.closeMapStore()
 
 	if serveErr != nil {
 		log.Fatalf("serve error %v", serveErr)
 	}
 }
+
+func (d *Demory) serveMetrics(port int) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler(d.collectCacheMetrics, d.collectStructureMetrics))
+
+	if err := http.ListenAndServe(fmt.Sprintf(":%d", port), mux); err != nil {
+		log.Fatalf("metrics server error %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package demory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/node"
+)
+
+// newTestNode starts a single node cluster with config and waits until the node leads it.
+func newTestNode(t *testing.T, config node.Config) *Demory {
+	t.Helper()
+
+	config.NodeID = fmt.Sprintf("demory-test-%d", time.Now().UnixNano())
+	config.NodeAddress = "127.0.0.1:0"
+	d := newDemory(&config)
+	t.Cleanup(func() {
+		d.fsm.Raft.Shutdown().Error()
+		os.RemoveAll(filepath.Join("/tmp", config.NodeID))
+	})
+
+	server := raft.Server{ID: raft.ServerID(config.NodeID), Address: raft.ServerAddress(config.NodeAddress)}
+	if err := d.fsm.Raft.BootstrapCluster(raft.Configuration{Servers: []raft.Server{server}}).Error(); err != nil {
+		t.Fatalf("failed to bootstrap %v", err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for d.fsm.Raft.State() != raft.Leader {
+		if time.Now().After(deadline) {
+			t.Fatal("node did not become leader")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return d
+}
+
+// apply replicates request as it is, without the stamps propose adds, and returns its response.
+func apply(t *testing.T, d *Demory, request fsm.ApplyRequest) fsm.ApplyResponse {
+	t.Helper()
+
+	bytes, err := json.Marshal(request)
+	if err != nil {
+		t.Fatalf("failed to encode %v", err)
+	}
+	future := d.fsm.Raft.Apply(bytes, time.Second)
+	if err := future.Error(); err != nil {
+		t.Fatalf("failed to apply %v", err)
+	}
+	return future.Response().(fsm.ApplyResponse)
+}
@@ -0,0 +1,303 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/huseyinbabal/demory/ds/document"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/jsonpath"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/structure"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	structure.Register(newDocumentStructure)
+}
+
+// documentStructure serves and applies JSON documents.
+type documentStructure struct {
+	host      structure.Host
+	documents *document.Documents
+}
+
+func newDocumentStructure(host structure.Host) structure.Structure {
+	return &documentStructure{host: host, documents: document.New()}
+}
+
+func (s *documentStructure) Kind() string {
+	return "documents"
+}
+
+func (s *documentStructure) Commands() []fsm.CommandType {
+	return []fsm.CommandType{
+		fsm.DocumentSet, fsm.DocumentDelete, fsm.DocumentIncrement, fsm.DocumentArrayAppend,
+		fsm.DocumentArrayInsert, fsm.DocumentArrayPop,
+	}
+}
+
+func (s *documentStructure) Register(server grpc.ServiceRegistrar) {
+	rpc.RegisterDocumentServer(server, s)
+}
+
+func (s *documentStructure) Snapshot() ([]byte, error) {
+	return json.Marshal(s.documents)
+}
+
+func (s *documentStructure) Restore(data []byte) (func(), error) {
+	documents := document.New()
+	if err := structure.Unmarshal(data, documents); err != nil {
+		return nil, err
+	}
+	return func() { s.documents = documents }, nil
+}
+
+func (s *documentStructure) Stats() structure.Stats {
+	return structure.Stats{Bytes: s.documents.Bytes()}
+}
+
+func (s *documentStructure) Destroy(name string) bool {
+	return s.documents.Destroy(name)
+}
+
+// documentArgs are the arguments of replicated document commands. Set values travel as the request value.
+type documentArgs struct {
+	Path      string             `json:"path,omitempty"`
+	Condition document.Condition `json:"condition,omitempty"`
+	Delta     json.Number        `json:"delta,omitempty"`
+	Index     int                `json:"index,omitempty"`
+	Values    []json.RawMessage  `json:"values,omitempty"`
+}
+
+// DocumentGet returns the value at a path within a document.
+func (s *documentStructure) DocumentGet(ctx context.Context,
+	req *rpc.DocumentGetRequest) (*rpc.DocumentGetResponse, error) {
+	response := &rpc.DocumentGetResponse{}
+	var err error
+	s.host.Read(func() {
+		response.Value, response.Found, err = s.documents.Get(req.Name, req.Path)
+	})
+	if err != nil {
+		return nil, documentError(err)
+	}
+
+	return response, nil
+}
+
+// DocumentType returns the type of the value at a path within a document.
+func (s *documentStructure) DocumentType(ctx context.Context,
+	req *rpc.DocumentGetRequest) (*rpc.DocumentTypeResponse, error) {
+	response := &rpc.DocumentTypeResponse{}
+	var err error
+	s.host.Read(func() {
+		response.Type, response.Found, err = s.documents.Type(req.Name, req.Path)
+	})
+	if err != nil {
+		return nil, documentError(err)
+	}
+
+	return response, nil
+}
+
+// DocumentSet stores a value at a path within a document. Setting the whole document creates it.
+func (s *documentStructure) DocumentSet(ctx context.Context,
+	req *rpc.DocumentSetRequest) (*rpc.DocumentSetResponse, error) {
+	if err := validateDocumentPath(req.Path); err != nil {
+		return nil, err
+	}
+	if !json.Valid(req.Value) {
+		return nil, documentError(document.ErrInvalid)
+	}
+	switch req.Condition {
+	case document.Always, document.IfAbsent, document.IfExists:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown condition %q", req.Condition)
+	}
+
+	request := fsm.ApplyRequest{Type: fsm.DocumentSet, Name: req.Name, Value: req.Value}
+	data, err := s.host.Propose(request, documentArgs{Path: req.Path, Condition: req.Condition})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.DocumentSetResponse{Set: data.(bool)}, nil
+}
+
+// DocumentDelete removes the value at a path within a document. Deleting the whole document removes it.
+func (s *documentStructure) DocumentDelete(ctx context.Context,
+	req *rpc.DocumentDeleteRequest) (*rpc.DocumentDeleteResponse, error) {
+	if err := validateDocumentPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	data, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.DocumentDelete, Name: req.Name}, documentArgs{Path: req.Path})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.DocumentDeleteResponse{Deleted: data.(bool)}, nil
+}
+
+// DocumentIncrement adds a delta to the number at a path within a document and returns the result.
+func (s *documentStructure) DocumentIncrement(ctx context.Context,
+	req *rpc.DocumentIncrementRequest) (*rpc.DocumentIncrementResponse, error) {
+	if err := validateDocumentPath(req.Path); err != nil {
+		return nil, err
+	}
+	if _, err := req.Delta.Float64(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "delta %q is not a number", req.Delta)
+	}
+
+	request := fsm.ApplyRequest{Type: fsm.DocumentIncrement, Name: req.Name}
+	data, err := s.host.Propose(request, documentArgs{Path: req.Path, Delta: req.Delta})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.DocumentIncrementResponse{Value: data.(json.Number)}, nil
+}
+
+// DocumentArrayAppend adds values at the end of the array at a path within a document.
+func (s *documentStructure) DocumentArrayAppend(ctx context.Context,
+	req *rpc.DocumentArrayAppendRequest) (*rpc.DocumentArrayResponse, error) {
+	if err := validateDocumentValues(req.Path, req.Values); err != nil {
+		return nil, err
+	}
+
+	request := fsm.ApplyRequest{Type: fsm.DocumentArrayAppend, Name: req.Name}
+	data, err := s.host.Propose(request, documentArgs{Path: req.Path, Values: req.Values})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.DocumentArrayResponse{Length: data.(int)}, nil
+}
+
+// DocumentArrayInsert adds values to the array at a path within a document before an index.
+func (s *documentStructure) DocumentArrayInsert(ctx context.Context,
+	req *rpc.DocumentArrayInsertRequest) (*rpc.DocumentArrayResponse, error) {
+	if err := validateDocumentValues(req.Path, req.Values); err != nil {
+		return nil, err
+	}
+
+	request := fsm.ApplyRequest{Type: fsm.DocumentArrayInsert, Name: req.Name}
+	data, err := s.host.Propose(request, documentArgs{Path: req.Path, Index: req.Index, Values: req.Values})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.DocumentArrayResponse{Length: data.(int)}, nil
+}
+
+// DocumentArrayPop removes an element from the array at a path within a document and returns it.
+func (s *documentStructure) DocumentArrayPop(ctx context.Context,
+	req *rpc.DocumentArrayPopRequest) (*rpc.DocumentArrayPopResponse, error) {
+	if err := validateDocumentPath(req.Path); err != nil {
+		return nil, err
+	}
+
+	index := -1
+	if req.Index != nil {
+		index = *req.Index
+	}
+
+	request := fsm.ApplyRequest{Type: fsm.DocumentArrayPop, Name: req.Name}
+	data, err := s.host.Propose(request, documentArgs{Path: req.Path, Index: index})
+	if err != nil {
+		return nil, err
+	}
+
+	return data.(*rpc.DocumentArrayPopResponse), nil
+}
+
+func validateDocumentPath(path string) error {
+	if _, err := jsonpath.Parse(path); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+func validateDocumentValues(path string, values []json.RawMessage) error {
+	if len(values) == 0 {
+		return status.Error(codes.InvalidArgument, "values are required")
+	}
+	for _, value := range values {
+		if !json.Valid(value) {
+			return documentError(document.ErrInvalid)
+		}
+	}
+	return validateDocumentPath(path)
+}
+
+func (s *documentStructure) Apply(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args documentArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	var data interface{}
+	var err error
+	switch request.Type {
+	case fsm.DocumentSet:
+		data, err = s.documents.Set(request.Name, args.Path, request.Value, args.Condition)
+	case fsm.DocumentDelete:
+		data, err = s.documents.Delete(request.Name, args.Path)
+	case fsm.DocumentIncrement:
+		data, err = s.documents.Increment(request.Name, args.Path, args.Delta)
+	case fsm.DocumentArrayAppend:
+		data, err = s.documents.Append(request.Name, args.Path, args.Values)
+	case fsm.DocumentArrayInsert:
+		data, err = s.documents.Insert(request.Name, args.Path, args.Index, args.Values)
+	case fsm.DocumentArrayPop:
+		response := &rpc.DocumentArrayPopResponse{}
+		response.Value, response.Found, err = s.documents.Pop(request.Name, args.Path, args.Index)
+		data = response
+	}
+	if err != nil {
+		return fsm.ApplyResponse{Error: documentError(err)}
+	}
+
+	return fsm.ApplyResponse{Data: data}
+}
+
+// Growth returns about the number of bytes a document command adds.
+func (s *documentStructure) Growth(request fsm.ApplyRequest) int64 {
+	var args documentArgs
+	if json.Unmarshal(request.Args, &args) != nil {
+		return 0
+	}
+
+	switch request.Type {
+	case fsm.DocumentSet:
+		if segments, err := jsonpath.Parse(args.Path); err == nil && len(segments) == 0 {
+			return int64(len(request.Name)+len(request.Value)) - s.documents.Size(request.Name)
+		}
+		return int64(len(request.Value))
+	case fsm.DocumentArrayAppend, fsm.DocumentArrayInsert:
+		var growth int64
+		for _, value := range args.Values {
+			growth += int64(len(value)) + 1
+		}
+		return growth
+	default:
+		return 0
+	}
+}
+
+// documentError converts errors of the document and jsonpath packages to gRPC statuses.
+func documentError(err error) error {
+	switch {
+	case errors.Is(err, document.ErrNoDocument), errors.Is(err, document.ErrNoPath):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, document.ErrNotNumber), errors.Is(err, document.ErrNotArray):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, document.ErrIndex):
+		return status.Error(codes.OutOfRange, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
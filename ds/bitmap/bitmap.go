@@ -0,0 +1,442 @@
+// Package bitmap implements named bitmaps of up to 2^32 bits in a compressed representation similar to
+// roaring bitmaps: offsets are grouped by their high 16 bits into containers, which keep the low 16 bits in
+// a sorted array while they are sparse, and in a plain bitmap once they are dense.
+package bitmap
+
+import (
+	"errors"
+	"sort"
+)
+
+// Op is a bitwise operation between bitmaps.
+type Op string
+
+const (
+	And Op = "and"
+	Or  Op = "or"
+	Xor Op = "xor"
+	// Not flips the bits of a single bitmap up to the end of the byte holding its highest set bit.
+	Not Op = "not"
+)
+
+var (
+	ErrUnknownOp = errors.New("unknown bitwise operation")
+	ErrSources   = errors.New("not takes exactly one source, other operations at least one")
+)
+
+// MaxOffset is the highest offset of a bitmap.
+const MaxOffset = 1<<32 - 1
+
+type bitmap struct {
+	keys       []uint16
+	containers []*container
+}
+
+// Bitmaps holds all bitmaps of a node.
+type Bitmaps struct {
+	bitmaps map[string]*bitmap
+	bytes   int64
+}
+
+// New creates an empty set of bitmaps.
+func New() *Bitmaps {
+	return &Bitmaps{bitmaps: make(map[string]*bitmap)}
+}
+
+// ValidateOp checks that op can be applied to a number of sources.
+func ValidateOp(op Op, sources int) error {
+	switch op {
+	case And, Or, Xor:
+		if sources == 0 {
+			return ErrSources
+		}
+	case Not:
+		if sources != 1 {
+			return ErrSources
+		}
+	default:
+		return ErrUnknownOp
+	}
+	return nil
+}
+
+// SetBit sets or clears the bit at offset of the bitmap name, creating it if needed, and returns the
+// previous value of the bit. A bitmap whose last bit is cleared is removed.
+func (b *Bitmaps) SetBit(name string, offset uint32, value bool) bool {
+	bm, ok := b.bitmaps[name]
+	if !ok {
+		if !value {
+			return false
+		}
+		bm = &bitmap{}
+		b.bitmaps[name] = bm
+	}
+
+	i, found := bm.find(uint16(offset >> 16))
+	if !value {
+		if !found {
+			return false
+		}
+		c := bm.containers[i]
+		before := c.size()
+		changed := c.remove(uint16(offset))
+		b.bytes += c.size() - before
+		if c.len() == 0 {
+			bm.keys = append(bm.keys[:i], bm.keys[i+1:]...)
+			bm.containers = append(bm.containers[:i], bm.containers[i+1:]...)
+		}
+		if len(bm.keys) == 0 {
+			delete(b.bitmaps, name)
+		}
+		return changed
+	}
+
+	if !found {
+		bm.insert(uint16(offset>>16), &container{})
+	}
+	c := bm.containers[i]
+	before := c.size()
+	changed := c.add(uint16(offset))
+	b.bytes += c.size() - before
+	return !changed
+}
+
+// GetBit returns the bit at offset of the bitmap name.
+func (b *Bitmaps) GetBit(name string, offset uint32) bool {
+	bm, ok := b.bitmaps[name]
+	if !ok {
+		return false
+	}
+	i, found := bm.find(uint16(offset >> 16))
+	return found && bm.containers[i].contains(uint16(offset))
+}
+
+// Count returns the number of set bits of the bitmap name between start and end, both included.
+func (b *Bitmaps) Count(name string, start, end uint32) uint64 {
+	bm, ok := b.bitmaps[name]
+	if !ok || start > end {
+		return 0
+	}
+
+	var n uint64
+	bm.each(start, end, func(c *container, lo, hi uint16, _ uint32) bool {
+		n += uint64(c.count(lo, hi))
+		return true
+	})
+	return n
+}
+
+// Pos returns the first offset between start and end, both included, whose bit is value, or -1 if there is
+// none.
+func (b *Bitmaps) Pos(name string, value bool, start, end uint32) int64 {
+	if start > end {
+		return -1
+	}
+	bm := b.bitmaps[name]
+	if bm == nil {
+		bm = &bitmap{}
+	}
+
+	if value {
+		pos := int64(-1)
+		bm.each(start, end, func(c *container, lo, hi uint16, high uint32) bool {
+			if x, ok := c.next(lo, true); ok && x <= hi {
+				pos = int64(high | uint32(x))
+				return false
+			}
+			return true
+		})
+		return pos
+	}
+
+	for offset := uint64(start); offset <= uint64(end); offset = offset>>16<<16 + 1<<16 {
+		i, found := bm.find(uint16(offset >> 16))
+		if !found {
+			return int64(offset)
+		}
+		if x, ok := bm.containers[i].next(uint16(offset), false); ok {
+			if pos := offset>>16<<16 | uint64(x); pos <= uint64(end) {
+				return int64(pos)
+			}
+			return -1
+		}
+	}
+	return -1
+}
+
+// Op stores the result of a bitwise operation between the source bitmaps in dest, replacing it, and returns
+// the number of set bits of the result. Missing sources are empty, and an empty result removes dest.
+func (b *Bitmaps) Op(op Op, dest string, sources []string) (uint64, error) {
+	if err := ValidateOp(op, len(sources)); err != nil {
+		return 0, err
+	}
+
+	result := b.result(op, sources)
+	if previous, ok := b.bitmaps[dest]; ok {
+		b.bytes -= previous.size()
+		delete(b.bitmaps, dest)
+	}
+	if len(result.keys) == 0 {
+		return 0, nil
+	}
+	b.bitmaps[dest] = result
+	b.bytes += result.size()
+
+	var n uint64
+	for _, c := range result.containers {
+		n += uint64(c.len())
+	}
+	return n, nil
+}
+
+// result returns the result of a bitwise operation between the source bitmaps.
+func (b *Bitmaps) result(op Op, sources []string) *bitmap {
+	inputs := make([]*bitmap, len(sources))
+	for i, source := range sources {
+		inputs[i] = b.bitmaps[source]
+		if inputs[i] == nil {
+			inputs[i] = &bitmap{}
+		}
+	}
+
+	if op == Not {
+		return not(inputs[0])
+	}
+	return combine(op, inputs)
+}
+
+// SetBitGrowth returns the number of bytes setting the bit at offset of the bitmap name adds.
+func (b *Bitmaps) SetBitGrowth(name string, offset uint32) int64 {
+	bm, ok := b.bitmaps[name]
+	if !ok {
+		return 2
+	}
+	i, found := bm.find(uint16(offset >> 16))
+	if !found {
+		return 2
+	}
+
+	c := bm.containers[i]
+	switch {
+	case c.bits != nil || c.contains(uint16(offset)):
+		return 0
+	case len(c.array) >= arrayMax:
+		return words*8 - c.size()
+	default:
+		return 2
+	}
+}
+
+// OpGrowth estimates the number of bytes storing the result of a bitwise operation in dest adds, which is
+// negative if the result is smaller than dest. It does not compute the operation, but bounds the number of
+// set bits of every container of the result by those of the sources, so it never estimates too little.
+func (b *Bitmaps) OpGrowth(op Op, dest string, sources []string) int64 {
+	if ValidateOp(op, len(sources)) != nil {
+		return 0
+	}
+
+	var growth int64
+	if op == Not {
+		growth = notSize(b.bitmaps[sources[0]])
+	} else {
+		growth = b.combineSize(op, sources)
+	}
+	if previous, ok := b.bitmaps[dest]; ok {
+		growth -= previous.size()
+	}
+	return growth
+}
+
+// combineSize returns the most bytes the result of a bitwise and, or or xor between the source bitmaps holds.
+func (b *Bitmaps) combineSize(op Op, sources []string) int64 {
+	lens := make(map[uint16][]int)
+	for _, source := range sources {
+		if bm, ok := b.bitmaps[source]; ok {
+			for i, key := range bm.keys {
+				lens[key] = append(lens[key], bm.containers[i].len())
+			}
+		}
+	}
+
+	var size int64
+	for _, n := range lens {
+		if op == And && len(n) < len(sources) {
+			continue
+		}
+		bound := n[0]
+		for _, m := range n[1:] {
+			if op != And {
+				bound += m
+			} else if m < bound {
+				bound = m
+			}
+		}
+		size += containerSize(bound)
+	}
+	return size
+}
+
+// notSize returns the number of bytes the result of a bitwise not of input holds.
+func notSize(input *bitmap) int64 {
+	if input == nil || len(input.keys) == 0 {
+		return 0
+	}
+
+	last := len(input.keys) - 1
+	limit := (uint32(input.keys[last])<<16 | uint32(input.containers[last].max())) | 7
+	var size int64
+	for key := 0; key <= int(limit>>16); key++ {
+		n := 1 << 16
+		if key == int(limit>>16) {
+			n = int(limit&0xffff) + 1
+		}
+		if i, found := input.find(uint16(key)); found {
+			n -= input.containers[i].len()
+		}
+		size += containerSize(n)
+	}
+	return size
+}
+
+// Destroy removes the bitmap name, and reports whether it had set bits.
+func (b *Bitmaps) Destroy(name string) bool {
+	bm, ok := b.bitmaps[name]
+	if !ok {
+		return false
+	}
+	for _, c := range bm.containers {
+		b.bytes -= c.size()
+	}
+	delete(b.bitmaps, name)
+	return true
+}
+
+// Exists reports whether the bitmap name has set bits.
+func (b *Bitmaps) Exists(name string) bool {
+	_, ok := b.bitmaps[name]
+	return ok
+}
+
+// Bytes returns the number of bytes held by the containers of all bitmaps.
+func (b *Bitmaps) Bytes() int64 {
+	return b.bytes
+}
+
+func combine(op Op, inputs []*bitmap) *bitmap {
+	keys := make(map[uint16]int)
+	for _, input := range inputs {
+		for _, key := range input.keys {
+			keys[key]++
+		}
+	}
+
+	result := &bitmap{}
+	for key, n := range keys {
+		if op == And && n < len(inputs) {
+			continue
+		}
+
+		var w []uint64
+		for _, input := range inputs {
+			i, found := input.find(key)
+			if !found {
+				continue
+			}
+			if w == nil {
+				w = input.containers[i].words()
+				continue
+			}
+			for j, word := range input.containers[i].words() {
+				switch op {
+				case And:
+					w[j] &= word
+				case Or:
+					w[j] |= word
+				case Xor:
+					w[j] ^= word
+				}
+			}
+		}
+		result.insert(key, fromWords(w))
+	}
+	return result
+}
+
+func not(input *bitmap) *bitmap {
+	result := &bitmap{}
+	if len(input.keys) == 0 {
+		return result
+	}
+
+	last := len(input.keys) - 1
+	max := uint32(input.keys[last])<<16 | uint32(input.containers[last].max())
+	limit := max | 7
+	for key := 0; key <= int(limit>>16); key++ {
+		w := make([]uint64, words)
+		if i, found := input.find(uint16(key)); found {
+			w = input.containers[i].words()
+		}
+		for j := range w {
+			w[j] = ^w[j]
+		}
+		if key == int(limit>>16) {
+			low := limit & 0xffff
+			for j := range w {
+				switch {
+				case uint32(j*64) > low:
+					w[j] = 0
+				case uint32(j*64+63) > low:
+					w[j] &= ^uint64(0) >> (63 - low%64)
+				}
+			}
+		}
+		result.insert(uint16(key), fromWords(w))
+	}
+	return result
+}
+
+func (bm *bitmap) find(key uint16) (int, bool) {
+	i := sort.Search(len(bm.keys), func(i int) bool { return bm.keys[i] >= key })
+	return i, i < len(bm.keys) && bm.keys[i] == key
+}
+
+// insert adds a container with a key that is not in the bitmap yet. Nil containers are skipped.
+func (bm *bitmap) insert(key uint16, c *container) {
+	if c == nil {
+		return
+	}
+	i, _ := bm.find(key)
+	bm.keys = append(bm.keys, 0)
+	copy(bm.keys[i+1:], bm.keys[i:])
+	bm.keys[i] = key
+	bm.containers = append(bm.containers, nil)
+	copy(bm.containers[i+1:], bm.containers[i:])
+	bm.containers[i] = c
+}
+
+// each calls fn with the containers holding offsets between start and end, the low bits of the range within
+// each container and its high bits, until fn returns false.
+func (bm *bitmap) each(start, end uint32, fn func(c *container, lo, hi uint16, high uint32) bool) {
+	i, _ := bm.find(uint16(start >> 16))
+	for ; i < len(bm.keys) && uint32(bm.keys[i]) <= end>>16; i++ {
+		key := uint32(bm.keys[i])
+		lo, hi := uint16(0), uint16(1<<16-1)
+		if key == start>>16 {
+			lo = uint16(start)
+		}
+		if key == end>>16 {
+			hi = uint16(end)
+		}
+		if !fn(bm.containers[i], lo, hi, key<<16) {
+			return
+		}
+	}
+}
+
+func (bm *bitmap) size() int64 {
+	var size int64
+	for _, c := range bm.containers {
+		size += c.size()
+	}
+	return size
+}
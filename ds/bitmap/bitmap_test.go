@@ -0,0 +1,142 @@
+package bitmap
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSetBit(t *testing.T) {
+	b := New()
+	if b.SetBit("active", 7, true) {
+		t.Errorf("expected a new bit to be clear before")
+	}
+	if !b.SetBit("active", 7, true) {
+		t.Errorf("expected a set bit to be set before")
+	}
+	b.SetBit("active", 1<<20, true)
+	b.SetBit("active", MaxOffset, true)
+
+	if !b.GetBit("active", 1<<20) || b.GetBit("active", 8) || !b.GetBit("active", MaxOffset) {
+		t.Errorf("unexpected bits")
+	}
+	if n := b.Count("active", 0, MaxOffset); n != 3 {
+		t.Errorf("expected 3 set bits, got %d", n)
+	}
+	if n := b.Count("active", 8, MaxOffset-1); n != 1 {
+		t.Errorf("expected 1 set bit in range, got %d", n)
+	}
+
+	b.SetBit("active", 7, false)
+	b.SetBit("active", 1<<20, false)
+	b.SetBit("active", MaxOffset, false)
+	if b.Exists("active") || b.Bytes() != 0 {
+		t.Errorf("expected a bitmap without set bits to be removed, %d bytes left", b.Bytes())
+	}
+}
+
+func TestDenseContainer(t *testing.T) {
+	b := New()
+	for i := uint32(0); i < 10000; i++ {
+		before, growth := b.Bytes(), b.SetBitGrowth("dense", i)
+		b.SetBit("dense", i, true)
+		if b.Bytes()-before != growth {
+			t.Fatalf("expected setting bit %d to add %d bytes, got %d", i, growth, b.Bytes()-before)
+		}
+	}
+	if b.SetBitGrowth("dense", 0) != 0 {
+		t.Error("expected setting a set bit to add no bytes")
+	}
+	if b.Bytes() != words*8 {
+		t.Errorf("expected a dense container to be a bitmap, got %d bytes", b.Bytes())
+	}
+	if n := b.Count("dense", 100, 199); n != 100 {
+		t.Errorf("expected 100 set bits in range, got %d", n)
+	}
+
+	if pos := b.Pos("dense", false, 0, MaxOffset); pos != 10000 {
+		t.Errorf("expected the first clear bit at 10000, got %d", pos)
+	}
+	if pos := b.Pos("dense", true, 5000, MaxOffset); pos != 5000 {
+		t.Errorf("expected the first set bit from 5000 at 5000, got %d", pos)
+	}
+	if pos := b.Pos("dense", false, 0, 9999); pos != -1 {
+		t.Errorf("expected no clear bit in range, got %d", pos)
+	}
+
+	for i := uint32(0); i < 9000; i++ {
+		b.SetBit("dense", i, false)
+	}
+	if b.Bytes() != 2000 {
+		t.Errorf("expected a sparse container to be an array, got %d bytes", b.Bytes())
+	}
+}
+
+func TestOp(t *testing.T) {
+	b := New()
+	for _, offset := range []uint32{1, 2, 3, 1 << 17} {
+		b.SetBit("a", offset, true)
+	}
+	for _, offset := range []uint32{2, 3, 4} {
+		b.SetBit("b", offset, true)
+	}
+
+	tests := []struct {
+		op       Op
+		sources  []string
+		expected []uint32
+	}{
+		{And, []string{"a", "b"}, []uint32{2, 3}},
+		{Or, []string{"a", "b"}, []uint32{1, 2, 3, 4, 1 << 17}},
+		{Xor, []string{"a", "b"}, []uint32{1, 4, 1 << 17}},
+		{Not, []string{"b"}, []uint32{0, 1, 5, 6, 7}},
+		{And, []string{"a", "missing"}, nil},
+	}
+	for _, test := range tests {
+		before, growth := b.Bytes(), b.OpGrowth(test.op, "dest", test.sources)
+		n, err := b.Op(test.op, "dest", test.sources)
+		if b.Bytes()-before > growth {
+			t.Errorf("%s: expected the result to add at most %d bytes, got %d", test.op, growth, b.Bytes()-before)
+		}
+		if err != nil || n != uint64(len(test.expected)) {
+			t.Errorf("%s: expected %d bits, got %d, error %v", test.op, len(test.expected), n, err)
+		}
+		for _, offset := range test.expected {
+			if !b.GetBit("dest", offset) {
+				t.Errorf("%s: expected bit %d to be set", test.op, offset)
+			}
+		}
+	}
+
+	// The result of not is known from the number of set bits alone.
+	before, growth := b.Bytes(), b.OpGrowth(Not, "flipped", []string{"a"})
+	b.Op(Not, "flipped", []string{"a"})
+	if b.Bytes()-before != growth || growth != 2*words*8+14 {
+		t.Errorf("expected the result to add %d bytes, got %d", growth, b.Bytes()-before)
+	}
+
+	if _, err := b.Op(Not, "dest", []string{"a", "b"}); err != ErrSources {
+		t.Errorf("expected %v, got %v", ErrSources, err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	b := New()
+	for i := uint32(0); i < 5000; i++ {
+		b.SetBit("dense", i*2, true)
+	}
+	b.SetBit("sparse", 1<<30, true)
+
+	encoded, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := New()
+	if err := json.Unmarshal(encoded, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.Bytes() != b.Bytes() || restored.Count("dense", 0, MaxOffset) != 5000 ||
+		!restored.GetBit("sparse", 1<<30) {
+		t.Errorf("expected the snapshot to keep all bitmaps")
+	}
+}
@@ -0,0 +1,209 @@
+package bitmap
+
+import (
+	"math/bits"
+	"sort"
+)
+
+const (
+	// arrayMax is the cardinality up to which a container keeps a sorted array, which is smaller than a
+	// bitmap of 8KB below it.
+	arrayMax = 4096
+	words    = 1 << 16 / 64
+)
+
+// container holds the low 16 bits of the offsets sharing the same high 16 bits, either as a sorted array or
+// as a bitmap with its cardinality.
+type container struct {
+	array []uint16
+	bits  []uint64
+	n     int
+}
+
+// fromWords creates the container of a bitmap, or returns nil if it is empty.
+func fromWords(w []uint64) *container {
+	n := 0
+	for _, word := range w {
+		n += bits.OnesCount64(word)
+	}
+	if n == 0 {
+		return nil
+	}
+	if n > arrayMax {
+		return &container{bits: w, n: n}
+	}
+
+	array := make([]uint16, 0, n)
+	for i, word := range w {
+		for word != 0 {
+			array = append(array, uint16(i*64+bits.TrailingZeros64(word)))
+			word &= word - 1
+		}
+	}
+	return &container{array: array}
+}
+
+func (c *container) len() int {
+	if c.bits != nil {
+		return c.n
+	}
+	return len(c.array)
+}
+
+// size returns the number of bytes held by the container.
+func (c *container) size() int64 {
+	if c.bits != nil {
+		return words * 8
+	}
+	return int64(len(c.array)) * 2
+}
+
+// containerSize returns the number of bytes held by a container of n set bits.
+func containerSize(n int) int64 {
+	if n > arrayMax {
+		return words * 8
+	}
+	return int64(n) * 2
+}
+
+func (c *container) search(x uint16) int {
+	return sort.Search(len(c.array), func(i int) bool { return c.array[i] >= x })
+}
+
+func (c *container) contains(x uint16) bool {
+	if c.bits != nil {
+		return c.bits[x/64]&(1<<(x%64)) != 0
+	}
+	i := c.search(x)
+	return i < len(c.array) && c.array[i] == x
+}
+
+// add sets x and reports whether it was clear.
+func (c *container) add(x uint16) bool {
+	if c.bits != nil {
+		if c.contains(x) {
+			return false
+		}
+		c.bits[x/64] |= 1 << (x % 64)
+		c.n++
+		return true
+	}
+
+	i := c.search(x)
+	if i < len(c.array) && c.array[i] == x {
+		return false
+	}
+	if len(c.array) >= arrayMax {
+		c.bits, c.array, c.n = c.words(), nil, len(c.array)
+		return c.add(x)
+	}
+	c.array = append(c.array, 0)
+	copy(c.array[i+1:], c.array[i:])
+	c.array[i] = x
+	return true
+}
+
+// remove clears x and reports whether it was set.
+func (c *container) remove(x uint16) bool {
+	if c.bits != nil {
+		if !c.contains(x) {
+			return false
+		}
+		c.bits[x/64] &^= 1 << (x % 64)
+		c.n--
+		if c.n <= arrayMax {
+			*c = *fromWords(c.bits)
+		}
+		return true
+	}
+
+	i := c.search(x)
+	if i == len(c.array) || c.array[i] != x {
+		return false
+	}
+	c.array = append(c.array[:i], c.array[i+1:]...)
+	return true
+}
+
+// count returns the number of set bits between lo and hi, both included.
+func (c *container) count(lo, hi uint16) int {
+	if c.bits == nil {
+		end := len(c.array)
+		if hi < 1<<16-1 {
+			end = c.search(hi + 1)
+		}
+		return end - c.search(lo)
+	}
+
+	n := 0
+	for w := int(lo / 64); w <= int(hi/64); w++ {
+		mask := ^uint64(0)
+		if w == int(lo/64) {
+			mask &= ^uint64(0) << (lo % 64)
+		}
+		if w == int(hi/64) {
+			mask &= ^uint64(0) >> (63 - hi%64)
+		}
+		n += bits.OnesCount64(c.bits[w] & mask)
+	}
+	return n
+}
+
+// next returns the first offset from lo on whose bit is value.
+func (c *container) next(lo uint16, value bool) (uint16, bool) {
+	if c.bits != nil {
+		for w := int(lo / 64); w < words; w++ {
+			word := c.bits[w]
+			if !value {
+				word = ^word
+			}
+			if w == int(lo/64) {
+				word &= ^uint64(0) << (lo % 64)
+			}
+			if word != 0 {
+				return uint16(w*64 + bits.TrailingZeros64(word)), true
+			}
+		}
+		return 0, false
+	}
+
+	i := c.search(lo)
+	if value {
+		if i < len(c.array) {
+			return c.array[i], true
+		}
+		return 0, false
+	}
+	for x := int(lo); x < 1<<16; x++ {
+		if i == len(c.array) || int(c.array[i]) != x {
+			return uint16(x), true
+		}
+		i++
+	}
+	return 0, false
+}
+
+// max returns the highest set offset of a container that is not empty.
+func (c *container) max() uint16 {
+	if c.bits == nil {
+		return c.array[len(c.array)-1]
+	}
+	for w := words - 1; ; w-- {
+		if c.bits[w] != 0 {
+			return uint16(w*64 + 63 - bits.LeadingZeros64(c.bits[w]))
+		}
+	}
+}
+
+// words returns a copy of the container as a bitmap.
+func (c *container) words() []uint64 {
+	w := make([]uint64, words)
+	if c.bits != nil {
+		copy(w, c.bits)
+		return w
+	}
+	for _, x := range c.array {
+		w[x/64] |= 1 << (x % 64)
+	}
+	return w
+}
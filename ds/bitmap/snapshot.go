@@ -0,0 +1,59 @@
+package bitmap
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type snapshotContainer struct {
+	Key   uint16   `json:"key"`
+	Array []uint16 `json:"array,omitempty"`
+	Bits  []uint64 `json:"bits,omitempty"`
+}
+
+// MarshalJSON encodes the containers of all bitmaps for a snapshot.
+func (b *Bitmaps) MarshalJSON() ([]byte, error) {
+	bitmaps := make(map[string][]snapshotContainer, len(b.bitmaps))
+	for name, bm := range b.bitmaps {
+		containers := make([]snapshotContainer, len(bm.keys))
+		for i, c := range bm.containers {
+			containers[i] = snapshotContainer{Key: bm.keys[i], Array: c.array, Bits: c.bits}
+		}
+		bitmaps[name] = containers
+	}
+
+	return json.Marshal(bitmaps)
+}
+
+// UnmarshalJSON replaces all bitmaps with a snapshot.
+func (b *Bitmaps) UnmarshalJSON(data []byte) error {
+	var bitmaps map[string][]snapshotContainer
+	if err := json.Unmarshal(data, &bitmaps); err != nil {
+		return err
+	}
+
+	b.bitmaps = make(map[string]*bitmap, len(bitmaps))
+	b.bytes = 0
+	for name, containers := range bitmaps {
+		bm := &bitmap{}
+		for _, sc := range containers {
+			if sc.Bits != nil && len(sc.Bits) != words {
+				return fmt.Errorf("bitmap %s has a container of %d words", name, len(sc.Bits))
+			}
+			c := &container{array: sc.Array}
+			if sc.Bits != nil {
+				c = fromWords(sc.Bits)
+			}
+			if c == nil || c.len() == 0 {
+				continue
+			}
+			bm.insert(sc.Key, c)
+		}
+		if len(bm.keys) > 0 {
+			b.bitmaps[name] = bm
+			b.bytes += bm.size()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+// Package bloom implements Bloom filters, which tell whether an item might have been added to them or
+// definitely was not, using a fixed number of bits sized for an expected number of items and error rate.
+package bloom
+
+import (
+	"errors"
+	"math"
+
+	"github.com/huseyinbabal/demory/ds/hash"
+)
+
+const (
+	// DefaultCapacity and DefaultErrorRate size the filters created by adding to a filter that does not exist.
+	DefaultCapacity  = 1000
+	DefaultErrorRate = 0.01
+	// MaxBits limits the size of a single filter to 512MB.
+	MaxBits = 1 << 32
+)
+
+var (
+	ErrExists      = errors.New("filter already exists")
+	ErrNoFilter    = errors.New("filter does not exist")
+	ErrInvalidRate = errors.New("error rate must be between 0 and 1")
+	ErrCapacity    = errors.New("capacity must be positive")
+	ErrTooLarge    = errors.New("filter would be too large")
+)
+
+// Info describes a filter.
+type Info struct {
+	Capacity  uint64  `json:"capacity"`
+	ErrorRate float64 `json:"errorRate"`
+	Bits      uint64  `json:"bits"`
+	Hashes    uint64  `json:"hashes"`
+	// Items is the number of added items that were not reported as already present.
+	Items uint64 `json:"items"`
+}
+
+type filter struct {
+	Info
+	words []uint64
+}
+
+// Filters holds all Bloom filters of a node.
+type Filters struct {
+	filters map[string]*filter
+	bytes   int64
+}
+
+// New creates an empty set of filters.
+func New() *Filters {
+	return &Filters{filters: make(map[string]*filter)}
+}
+
+// Dimensions returns the number of bits and hash functions of a filter expecting capacity items with a false
+// positive rate of errorRate.
+func Dimensions(capacity uint64, errorRate float64) (bits, hashes uint64, err error) {
+	if capacity == 0 {
+		return 0, 0, ErrCapacity
+	}
+	if errorRate <= 0 || errorRate >= 1 {
+		return 0, 0, ErrInvalidRate
+	}
+
+	m := math.Ceil(-float64(capacity) * math.Log(errorRate) / (math.Ln2 * math.Ln2))
+	if m > MaxBits {
+		return 0, 0, ErrTooLarge
+	}
+	bits = (uint64(m) + 63) &^ 63
+	hashes = uint64(math.Max(1, math.Round(float64(bits)/float64(capacity)*math.Ln2)))
+
+	return bits, hashes, nil
+}
+
+// Size returns the number of bytes of a filter expecting capacity items with a false positive rate of
+// errorRate, or zero if the parameters are invalid.
+func Size(capacity uint64, errorRate float64) int64 {
+	bits, _, err := Dimensions(capacity, errorRate)
+	if err != nil {
+		return 0
+	}
+	return int64(bits / 8)
+}
+
+// Create creates the filter name, expecting capacity items with a false positive rate of errorRate.
+func (f *Filters) Create(name string, capacity uint64, errorRate float64) error {
+	if _, ok := f.filters[name]; ok {
+		return ErrExists
+	}
+
+	bits, hashes, err := Dimensions(capacity, errorRate)
+	if err != nil {
+		return err
+	}
+
+	f.filters[name] = &filter{
+		Info:  Info{Capacity: capacity, ErrorRate: errorRate, Bits: bits, Hashes: hashes},
+		words: make([]uint64, bits/64),
+	}
+	f.bytes += int64(bits / 8)
+
+	return nil
+}
+
+// Add adds items to the filter name, creating it with the default capacity and error rate if needed. For
+// each item, it reports whether it was definitely not in the filter before.
+func (f *Filters) Add(name string, items [][]byte) []bool {
+	if _, ok := f.filters[name]; !ok {
+		_ = f.Create(name, DefaultCapacity, DefaultErrorRate)
+	}
+	filter := f.filters[name]
+
+	added := make([]bool, len(items))
+	for i, item := range items {
+		h1, h2 := hash.Pair(item)
+		for j := uint64(0); j < filter.Hashes; j++ {
+			bit := (h1 + j*h2) % filter.Bits
+			if filter.words[bit/64]&(1<<(bit%64)) == 0 {
+				filter.words[bit/64] |= 1 << (bit % 64)
+				added[i] = true
+			}
+		}
+		if added[i] {
+			filter.Items++
+		}
+	}
+
+	return added
+}
+
+// MightContain reports for each item whether it might have been added to the filter name. It is false for
+// all items if the filter does not exist.
+func (f *Filters) MightContain(name string, items [][]byte) []bool {
+	result := make([]bool, len(items))
+	filter, ok := f.filters[name]
+	if !ok {
+		return result
+	}
+
+	for i, item := range items {
+		h1, h2 := hash.Pair(item)
+		result[i] = true
+		for j := uint64(0); j < filter.Hashes; j++ {
+			bit := (h1 + j*h2) % filter.Bits
+			if filter.words[bit/64]&(1<<(bit%64)) == 0 {
+				result[i] = false
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// Info returns the description of the filter name.
+func (f *Filters) Info(name string) (Info, error) {
+	filter, ok := f.filters[name]
+	if !ok {
+		return Info{}, ErrNoFilter
+	}
+	return filter.Info, nil
+}
+
+// Destroy removes the filter name, and reports whether it existed.
+func (f *Filters) Destroy(name string) bool {
+	filter, ok := f.filters[name]
+	if !ok {
+		return false
+	}
+	f.bytes -= int64(len(filter.words)) * 8
+	delete(f.filters, name)
+	return true
+}
+
+// Exists reports whether the filter name exists.
+func (f *Filters) Exists(name string) bool {
+	_, ok := f.filters[name]
+	return ok
+}
+
+// Bytes returns the number of bytes held by the bits of all filters.
+func (f *Filters) Bytes() int64 {
+	return f.bytes
+}
@@ -0,0 +1,74 @@
+package bloom
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func items(prefix string, n int) [][]byte {
+	result := make([][]byte, n)
+	for i := range result {
+		result[i] = []byte(fmt.Sprintf("%s-%d", prefix, i))
+	}
+	return result
+}
+
+func TestFilter(t *testing.T) {
+	f := New()
+	if err := f.Create("seen", 10000, 0.01); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Create("seen", 10000, 0.01); err != ErrExists {
+		t.Errorf("expected %v, got %v", ErrExists, err)
+	}
+	if err := f.Create("invalid", 10000, 1); err != ErrInvalidRate {
+		t.Errorf("expected %v, got %v", ErrInvalidRate, err)
+	}
+
+	added := f.Add("seen", items("a", 10000))
+	for i, ok := range f.MightContain("seen", items("a", 10000)) {
+		if !ok {
+			t.Fatalf("expected added item %d to be found", i)
+		}
+	}
+	if again := f.Add("seen", items("a", 1)); again[0] || !added[0] {
+		t.Errorf("expected an item to be reported as added only once")
+	}
+
+	falsePositives := 0
+	for _, ok := range f.MightContain("seen", items("b", 10000)) {
+		if ok {
+			falsePositives++
+		}
+	}
+	if falsePositives > 200 {
+		t.Errorf("expected a false positive rate of about 1%%, got %d in 10000", falsePositives)
+	}
+
+	if f.MightContain("missing", items("a", 1))[0] {
+		t.Errorf("expected a missing filter to contain nothing")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	f := New()
+	f.Add("seen", items("a", 10))
+
+	encoded, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := New()
+	if err := json.Unmarshal(encoded, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := restored.Info("seen")
+	if err != nil || info.Capacity != DefaultCapacity || info.Items != 10 || restored.Bytes() != f.Bytes() {
+		t.Errorf("unexpected restored filter %+v, error %v", info, err)
+	}
+	if !restored.MightContain("seen", items("a", 10))[9] {
+		t.Errorf("expected the snapshot to keep the bits of the filter")
+	}
+}
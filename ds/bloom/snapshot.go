@@ -0,0 +1,41 @@
+package bloom
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type snapshotFilter struct {
+	Info
+	Words []uint64 `json:"words"`
+}
+
+// MarshalJSON encodes all filters with their bits for a snapshot.
+func (f *Filters) MarshalJSON() ([]byte, error) {
+	filters := make(map[string]snapshotFilter, len(f.filters))
+	for name, filter := range f.filters {
+		filters[name] = snapshotFilter{Info: filter.Info, Words: filter.words}
+	}
+
+	return json.Marshal(filters)
+}
+
+// UnmarshalJSON replaces all filters with a snapshot.
+func (f *Filters) UnmarshalJSON(data []byte) error {
+	var filters map[string]snapshotFilter
+	if err := json.Unmarshal(data, &filters); err != nil {
+		return err
+	}
+
+	f.filters = make(map[string]*filter, len(filters))
+	f.bytes = 0
+	for name, sf := range filters {
+		if uint64(len(sf.Words))*64 != sf.Bits {
+			return fmt.Errorf("filter %s has %d words for %d bits", name, len(sf.Words), sf.Bits)
+		}
+		f.filters[name] = &filter{Info: sf.Info, words: sf.Words}
+		f.bytes += int64(sf.Bits / 8)
+	}
+
+	return nil
+}
@@ -1,45 +1,226 @@
 package cache
 
-import "github.com/huseyinbabal/lru-cache"
+import (
+	"container/list"
+	"sort"
+	"time"
+)
 
 const DefaultCacheCapacity = 1000
 
 type Cache struct {
-	store map[string]*lru.Cache
+	store     map[string]*lru
+	expiry    *expiryQueue
+	seq       uint64
+	version   uint64
+	bytes     int64
+	listeners []Listener
+}
+
+// Kind is the kind of a change of an entry.
+type Kind string
+
+const (
+	Put    Kind = "put"
+	Remove Kind = "remove"
+	// Evict is the removal of an entry to stay within the capacity of its cache or to free memory.
+	Evict Kind = "evict"
+	// Expire is the removal of an entry after its TTL.
+	Expire Kind = "expire"
+)
+
+// Event describes a change of an entry. Old is the value before the change, and Value the value after it,
+// which is nil for removals.
+type Event struct {
+	Kind  Kind
+	Name  string
+	Key   string
+	Old   []byte
+	Value []byte
+}
+
+// Listener is notified after an entry of a cache changes.
+type Listener func(e Event)
+
+// Key identifies an entry within a named cache.
+type Key struct {
+	Name string
+	Key  string
 }
 
 func New() *Cache {
 	return &Cache{
-		store: make(map[string]*lru.Cache),
+		store:  make(map[string]*lru),
+		expiry: &expiryQueue{},
 	}
 }
 
 // Put Puts value at a key location under a specified cache. It initializes an empty cache if name does not exist.
-// It returns zero if there is already a value at specified key, returns 1 otherwise.
-func (c *Cache) Put(name, key string, value []byte) {
-	if !c.exists(name) {
-		c.store[name] = lru.New(DefaultCacheCapacity)
+// The entry expires ttl after now, or after the default TTL of the cache if ttl is zero. Entries never expire if
+// neither is set. It returns the keys evicted from the cache to stay within its capacity.
+func (c *Cache) Put(name, key string, value []byte, ttl time.Duration, now time.Time) (evicted []string) {
+	l := c.getOrCreate(name)
+
+	if ttl == 0 {
+		ttl = time.Duration(l.defaultTTL)
+	}
+	var expireAt int64
+	if ttl > 0 {
+		expireAt = now.Add(ttl).UnixNano()
+	}
+
+	var old []byte
+	if e, ok := l.get(key); ok {
+		old = e.value
+	}
+
+	c.seq++
+	before := l.bytes
+	evictedEntries := l.put(key, value, c.seq, c.version, expireAt)
+	c.bytes += l.bytes - before
+	l.counters.puts++
+	l.counters.evictions += uint64(len(evictedEntries))
+
+	for _, e := range evictedEntries {
+		evicted = append(evicted, e.key)
+		c.notify(Event{Kind: Evict, Name: name, Key: e.key, Old: e.value})
 	}
+	c.notify(Event{Kind: Put, Name: name, Key: key, Old: old, Value: value})
 
-	c.store[name].Put([]byte(key), value)
+	return evicted
 }
 
-// Get returns the value associated with key within specific cache.
-func (c *Cache) Get(name, key string) []byte {
+// Get returns the value associated with key within specific cache. Entries expired at now are not returned,
+// even if they have not been removed yet.
+func (c *Cache) Get(name, key string, now time.Time) []byte {
 	if !c.exists(name) {
 		return nil
 	}
 
-	return c.store[name].Get([]byte(key))
+	e, ok := c.store[name].get(key)
+	if ok && e.expired(now.UnixNano()) {
+		ok = false
+	}
+	c.store[name].counters.hit(ok)
+
+	if !ok {
+		return nil
+	}
+	return e.value
+}
+
+// Peek returns the value at key and whether it exists, like Get but without counting a hit or a miss.
+func (c *Cache) Peek(name, key string, now time.Time) ([]byte, bool) {
+	if !c.exists(name) {
+		return nil, false
+	}
+
+	e, ok := c.store[name].get(key)
+	if !ok || e.expired(now.UnixNano()) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// TTL returns the time left before the entry at key expires, which is zero for entries that never expire, and
+// whether the entry exists at now.
+func (c *Cache) TTL(name, key string, now time.Time) (time.Duration, bool) {
+	if !c.exists(name) {
+		return 0, false
+	}
+
+	e, ok := c.store[name].get(key)
+	if !ok || e.expired(now.UnixNano()) {
+		return 0, false
+	}
+	if e.expireAt == 0 {
+		return 0, true
+	}
+	return time.Duration(e.expireAt - now.UnixNano()), true
+}
+
+// Changed returns the version of the last write or removal that may have changed the entry at key: the version
+// of its last write if it exists, otherwise the version of the last removal of any entry of the cache.
+func (c *Cache) Changed(name, key string) uint64 {
+	if !c.exists(name) {
+		return 0
+	}
+
+	l := c.store[name]
+	if e, ok := l.get(key); ok {
+		return e.version
+	}
+	return l.removed
+}
+
+// SetVersion sets the version of the entries written and removed from now on. Like the versions of map
+// entries, they are the raft log indexes of the commands.
+func (c *Cache) SetVersion(version uint64) {
+	c.version = version
+}
+
+// SetDefaultTTL sets the TTL of entries put into a cache without a TTL of their own. It initializes an empty
+// cache if name does not exist. A zero TTL disables the default.
+func (c *Cache) SetDefaultTTL(name string, ttl time.Duration) {
+	c.getOrCreate(name).defaultTTL = int64(ttl)
+}
+
+// SetCapacity sets the number of entries the cache name keeps before evicting the least recently written ones,
+// creating it if it does not exist. Caches with a capacity of zero keep any number of entries.
+func (c *Cache) SetCapacity(name string, capacity int) {
+	c.getOrCreate(name).capacity = capacity
+}
+
+// Expire removes the entry at key if it is expired at now. It returns true if the entry is removed.
+func (c *Cache) Expire(name, key string, now time.Time) bool {
+	if !c.exists(name) {
+		return false
+	}
+
+	e, ok := c.store[name].get(key)
+	if !ok || !e.expired(now.UnixNano()) {
+		return false
+	}
+
+	c.remove(name, key, Expire)
+	c.store[name].counters.expirations++
+
+	return true
+}
+
+// Expired returns up to limit entries across all caches that are expired at now.
+func (c *Cache) Expired(now time.Time, limit int) []Key {
+	var keys []Key
+	c.collectExpired(0, now.UnixNano(), limit, &keys)
+
+	return keys
+}
+
+// collectExpired walks the expiry queue from index i. Children of an entry never expire before it,
+// so the walk stops at the first entry that is not expired on each branch.
+func (c *Cache) collectExpired(i int, now int64, limit int, keys *[]Key) {
+	q := *c.expiry
+	if i >= len(q) || len(*keys) >= limit || !q[i].expired(now) {
+		return
+	}
+
+	*keys = append(*keys, Key{Name: q[i].name, Key: q[i].key})
+	c.collectExpired(2*i+1, now, limit, keys)
+	c.collectExpired(2*i+2, now, limit, keys)
 }
 
 // Remove removes value specified by key from a cache. It ignores if key is not in the cache.
 func (c *Cache) Remove(name, key string) {
-	if c.exists(name) {
-		return
+	if c.remove(name, key, Remove) {
+		c.store[name].counters.removals++
 	}
+}
 
-	c.store[name].Remove([]byte(key))
+// Evict removes value specified by key from a cache to free memory. It ignores if key is not in the cache.
+func (c *Cache) Evict(name, key string) {
+	if c.remove(name, key, Evict) {
+		c.store[name].counters.evictions++
+	}
 }
 
 // Clear removes all the element within cache.
@@ -47,7 +228,139 @@ func (c *Cache) Clear(name string) {
 	if !c.exists(name) {
 		return
 	}
-	c.store[name].Clear()
+
+	l := c.store[name]
+	var removed []*entry
+	if len(c.listeners) > 0 {
+		for elem := l.order.Back(); elem != nil; elem = elem.Prev() {
+			removed = append(removed, elem.Value.(*entry))
+		}
+	}
+
+	c.bytes -= l.bytes
+	l.counters.removals += uint64(l.clear(c.version))
+
+	for _, e := range removed {
+		c.notify(Event{Kind: Remove, Name: name, Key: e.key, Old: e.value})
+	}
+}
+
+// Names returns the names of all caches in lexical order.
+func (c *Cache) Names() []string {
+	names := make([]string, 0, len(c.store))
+	for name := range c.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// EntrySize returns the number of bytes accounted for the entry at key, and whether the entry exists.
+func (c *Cache) EntrySize(name, key string) (int64, bool) {
+	if !c.exists(name) {
+		return 0, false
+	}
+
+	e, ok := c.store[name].get(key)
+	if !ok {
+		return 0, false
+	}
+	return size(key, e.value), true
+}
+
+// Size returns the number of bytes held by the keys and values of a cache.
+func (c *Cache) Size(name string) int64 {
+	if !c.exists(name) {
+		return 0
+	}
+	return c.store[name].bytes
+}
+
+// Bytes returns the number of bytes held by the keys and values of all caches.
+func (c *Cache) Bytes() int64 {
+	return c.bytes
+}
+
+// Oldest returns the least recently written entries across all caches, until their sizes add up to
+// at least bytes. Entries for which skip returns true are left out. It also returns the bytes they hold.
+func (c *Cache) Oldest(bytes int64, skip func(name, key string) bool) (keys []Key, freed int64) {
+	return c.oldest(bytes, false, skip)
+}
+
+// OldestVolatile is like Oldest, but only returns entries that expire.
+func (c *Cache) OldestVolatile(bytes int64, skip func(name, key string) bool) (keys []Key, freed int64) {
+	return c.oldest(bytes, true, skip)
+}
+
+func (c *Cache) oldest(bytes int64, volatile bool, skip func(name, key string) bool) (keys []Key, freed int64) {
+	cursors := make(map[string]*list.Element, len(c.store))
+	for name, l := range c.store {
+		if back := l.order.Back(); back != nil {
+			cursors[name] = back
+		}
+	}
+
+	for freed < bytes && len(cursors) > 0 {
+		var oldestName string
+		var oldest *entry
+		for name, elem := range cursors {
+			e := elem.Value.(*entry)
+			if oldest == nil || e.seq < oldest.seq {
+				oldestName, oldest = name, e
+			}
+		}
+
+		if prev := cursors[oldestName].Prev(); prev != nil {
+			cursors[oldestName] = prev
+		} else {
+			delete(cursors, oldestName)
+		}
+
+		if volatile && oldest.expireAt == 0 || skip != nil && skip(oldestName, oldest.key) {
+			continue
+		}
+		keys = append(keys, Key{Name: oldestName, Key: oldest.key})
+		freed += size(oldest.key, oldest.value)
+	}
+
+	return keys, freed
+}
+
+func (c *Cache) getOrCreate(name string) *lru {
+	if !c.exists(name) {
+		c.store[name] = newLRU(name, DefaultCacheCapacity, c.expiry)
+	}
+	return c.store[name]
+}
+
+func (c *Cache) remove(name, key string, kind Kind) bool {
+	if !c.exists(name) {
+		return false
+	}
+
+	l := c.store[name]
+	e, ok := l.get(key)
+	if !ok {
+		return false
+	}
+	before := l.bytes
+	l.remove(key, c.version)
+	c.bytes += l.bytes - before
+	c.notify(Event{Kind: kind, Name: name, Key: key, Old: e.value})
+
+	return true
+}
+
+// Listen registers l to be notified of every change of every cache.
+func (c *Cache) Listen(l Listener) {
+	c.listeners = append(c.listeners, l)
+}
+
+func (c *Cache) notify(e Event) {
+	for _, l := range c.listeners {
+		l(e)
+	}
 }
 
 func (c *Cache) exists(key string) bool {
@@ -0,0 +1,246 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var now = time.Unix(1700000000, 0)
+
+func TestCacheBytes(t *testing.T) {
+	c := New()
+	c.Put("users", "a", []byte("123"), 0, now)
+	c.Put("users", "b", []byte("45"), 0, now)
+	c.Put("sessions", "c", []byte("6"), 0, now)
+
+	if got := c.Size("users"); got != 7 {
+		t.Errorf("expected users size 7, got %d", got)
+	}
+
+	c.Put("users", "a", []byte("1"), 0, now)
+	c.Remove("sessions", "c")
+
+	if got := c.Bytes(); got != 5 {
+		t.Errorf("expected total size 5, got %d", got)
+	}
+
+	c.Clear("users")
+
+	if got := c.Bytes(); got != 0 {
+		t.Errorf("expected total size 0, got %d", got)
+	}
+}
+
+func TestCacheOldest(t *testing.T) {
+	c := New()
+	c.Put("users", "a", []byte("1"), 0, now)
+	c.Put("sessions", "b", []byte("2"), 0, now)
+	c.Put("users", "c", []byte("3"), 0, now)
+	c.Put("users", "a", []byte("4"), 0, now)
+
+	keys, freed := c.Oldest(4, func(name, key string) bool {
+		return key == "c"
+	})
+
+	if freed != 4 {
+		t.Errorf("expected 4 bytes to be freed, got %d", freed)
+	}
+	if len(keys) != 2 || keys[0] != (Key{Name: "sessions", Key: "b"}) || keys[1] != (Key{Name: "users", Key: "a"}) {
+		t.Errorf("unexpected eviction order %v", keys)
+	}
+}
+
+func TestCacheOldestVolatile(t *testing.T) {
+	c := New()
+	c.Put("users", "a", []byte("1"), 0, now)
+	c.Put("users", "b", []byte("2"), time.Minute, now)
+	c.Put("sessions", "c", []byte("3"), 0, now)
+	c.SetDefaultTTL("sessions", time.Minute)
+	c.Put("sessions", "d", []byte("4"), 0, now)
+
+	keys, freed := c.OldestVolatile(10, nil)
+
+	if freed != 4 {
+		t.Errorf("expected 4 bytes to be freed, got %d", freed)
+	}
+	if len(keys) != 2 || keys[0] != (Key{Name: "users", Key: "b"}) || keys[1] != (Key{Name: "sessions", Key: "d"}) {
+		t.Errorf("expected only expiring entries, got %v", keys)
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	c := New()
+	c.Put("users", "a", []byte("1"), 0, now)
+	c.Put("users", "b", []byte("2"), 0, now)
+	c.Get("users", "a", now)
+	c.Get("users", "c", now)
+	c.Remove("users", "b")
+	c.Evict("users", "a")
+
+	stats, ok := c.Stats("users")
+	if !ok {
+		t.Fatal("expected stats for users")
+	}
+	expected := Stats{Hits: 1, Misses: 1, Puts: 2, Removals: 1, Evictions: 1}
+	if stats != expected {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+	if stats.HitRatio() != 0.5 {
+		t.Errorf("expected hit ratio 0.5, got %v", stats.HitRatio())
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	c := New()
+	c.SetDefaultTTL("sessions", time.Minute)
+	c.Put("sessions", "a", []byte("1"), 0, now)
+	c.Put("sessions", "b", []byte("2"), time.Second, now)
+	c.Put("sessions", "c", []byte("3"), 2*time.Hour, now)
+	c.Put("users", "d", []byte("4"), 0, now)
+
+	later := now.Add(time.Hour)
+	if c.Get("sessions", "a", later) != nil {
+		t.Error("expected expired entry to be hidden")
+	}
+
+	expired := c.Expired(later, 10)
+	if len(expired) != 2 {
+		t.Fatalf("expected 2 expired entries, got %v", expired)
+	}
+	for _, k := range expired {
+		if !c.Expire(k.Name, k.Key, later) {
+			t.Errorf("expected %v to expire", k)
+		}
+	}
+
+	if ttl, ok := c.TTL("sessions", "c", later); !ok || ttl != time.Hour {
+		t.Errorf("expected an hour left, got %v %v", ttl, ok)
+	}
+	if ttl, ok := c.TTL("users", "d", later); !ok || ttl != 0 {
+		t.Errorf("expected no expiry, got %v %v", ttl, ok)
+	}
+	if _, ok := c.TTL("sessions", "b", later); ok {
+		t.Error("expected no ttl for an expired entry")
+	}
+
+	if c.Expire("sessions", "c", later) {
+		t.Error("expected entry with a longer ttl to stay")
+	}
+	if c.Get("users", "d", later.Add(time.Hour*24*365)) == nil {
+		t.Error("expected entry without ttl to stay")
+	}
+	if stats, _ := c.Stats("sessions"); stats.Expirations != 2 || stats.Entries != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCacheChanged(t *testing.T) {
+	c := New()
+	c.SetVersion(3)
+	c.Put("sessions", "a", []byte("1"), 0, now)
+	c.Put("sessions", "b", []byte("2"), time.Second, now)
+	if c.Changed("sessions", "a") != 3 || c.Changed("sessions", "c") != 0 {
+		t.Errorf("unexpected versions %d and %d", c.Changed("sessions", "a"), c.Changed("sessions", "c"))
+	}
+
+	// Writing the same value again still changes the entry.
+	c.SetVersion(5)
+	c.Put("sessions", "a", []byte("1"), 0, now)
+	if c.Changed("sessions", "a") != 5 {
+		t.Errorf("expected a to change at 5, got %d", c.Changed("sessions", "a"))
+	}
+
+	c.SetVersion(7)
+	c.Expire("sessions", "b", now.Add(time.Second))
+	if c.Changed("sessions", "b") != 7 || c.Changed("sessions", "c") != 7 {
+		t.Error("expected removals to change every absent entry of the cache")
+	}
+
+	data, _ := json.Marshal(c)
+	restored := New()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if restored.Changed("sessions", "a") != 5 || restored.Changed("sessions", "b") != 7 {
+		t.Error("expected versions to be kept in snapshots")
+	}
+}
+
+func TestCacheCapacity(t *testing.T) {
+	c := New()
+	c.SetCapacity("bounded", 2)
+	c.SetCapacity("unbounded", 0)
+	for i := 0; i < DefaultCacheCapacity+1; i++ {
+		c.Put("bounded", fmt.Sprint(i), []byte("v"), 0, now)
+		c.Put("unbounded", fmt.Sprint(i), []byte("v"), 0, now)
+	}
+
+	if _, ok := c.Peek("bounded", "0", now); ok {
+		t.Error("expected the oldest entry to be evicted")
+	}
+	data, _ := json.Marshal(c)
+	restored := New()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	restored.Put("unbounded", "last", []byte("v"), 0, now)
+	if _, ok := restored.Peek("unbounded", "0", now); !ok {
+		t.Error("expected a cache without capacity to keep every entry")
+	}
+}
+
+func TestCacheSnapshot(t *testing.T) {
+	c := New()
+	c.SetDefaultTTL("sessions", time.Minute)
+	c.Put("sessions", "a", []byte("1"), 0, now)
+	c.Put("sessions", "b", []byte("2"), 0, now)
+	c.Put("users", "c", []byte("3"), 0, now)
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	restored := New()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if restored.Bytes() != c.Bytes() {
+		t.Errorf("expected %d bytes, got %d", c.Bytes(), restored.Bytes())
+	}
+	keys, _ := restored.Oldest(1, nil)
+	if len(keys) != 1 || keys[0] != (Key{Name: "sessions", Key: "a"}) {
+		t.Errorf("expected the write order to be kept, got %v", keys)
+	}
+	if expired := restored.Expired(now.Add(time.Hour), 10); len(expired) != 2 {
+		t.Errorf("expected the expiry to be kept, got %v", expired)
+	}
+}
+
+func TestCacheEvents(t *testing.T) {
+	c := New()
+	var events []Event
+	c.Listen(func(e Event) {
+		events = append(events, e)
+	})
+
+	c.Put("sessions", "a", []byte("1"), time.Second, now)
+	c.Put("sessions", "a", []byte("2"), time.Second, now)
+	c.Expire("sessions", "a", now.Add(time.Second))
+	c.Put("sessions", "b", []byte("3"), 0, now)
+	c.Evict("sessions", "b")
+
+	expected := []Event{
+		{Kind: Put, Name: "sessions", Key: "a", Value: []byte("1")},
+		{Kind: Put, Name: "sessions", Key: "a", Old: []byte("1"), Value: []byte("2")},
+		{Kind: Expire, Name: "sessions", Key: "a", Old: []byte("2")},
+		{Kind: Put, Name: "sessions", Key: "b", Value: []byte("3")},
+		{Kind: Evict, Name: "sessions", Key: "b", Old: []byte("3")},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %+v, got %+v", expected, events)
+	}
+}
@@ -0,0 +1,58 @@
+package cache
+
+import "container/heap"
+
+// expiryQueue orders the entries of all caches that have an expiry by their expiry time.
+type expiryQueue []*entry
+
+var _ heap.Interface = &expiryQueue{}
+
+func (q expiryQueue) Len() int {
+	return len(q)
+}
+
+func (q expiryQueue) Less(i, j int) bool {
+	if q[i].expireAt == q[j].expireAt {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].expireAt < q[j].expireAt
+}
+
+func (q expiryQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *expiryQueue) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *expiryQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	e.index = -1
+	*q = old[:len(old)-1]
+	return e
+}
+
+// schedule adds, moves or removes e in the queue according to its expiry time.
+func (q *expiryQueue) schedule(e *entry) {
+	switch {
+	case e.index >= 0 && e.expireAt == 0:
+		heap.Remove(q, e.index)
+	case e.index >= 0:
+		heap.Fix(q, e.index)
+	case e.expireAt != 0:
+		heap.Push(q, e)
+	}
+}
+
+func (q *expiryQueue) unschedule(e *entry) {
+	if e.index >= 0 {
+		heap.Remove(q, e.index)
+	}
+}
@@ -0,0 +1,115 @@
+package cache
+
+import "container/list"
+
+type entry struct {
+	name     string
+	key      string
+	value    []byte
+	seq      uint64
+	version  uint64
+	expireAt int64
+	index    int
+}
+
+// expired reports whether e has an expiry at or before now, given in unix nanoseconds.
+func (e *entry) expired(now int64) bool {
+	return e.expireAt != 0 && e.expireAt <= now
+}
+
+// lru keeps the entries of a single cache ordered by their last write.
+// Reads do not change the order, so that every replica evicts the same entries.
+type lru struct {
+	name       string
+	capacity   int
+	defaultTTL int64
+	// removed is the version of the last removal of an entry.
+	removed  uint64
+	order    *list.List
+	entries  map[string]*list.Element
+	expiry   *expiryQueue
+	bytes    int64
+	counters counters
+}
+
+func newLRU(name string, capacity int, expiry *expiryQueue) *lru {
+	return &lru{
+		name:     name,
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		expiry:   expiry,
+	}
+}
+
+func (l *lru) get(key string) (*entry, bool) {
+	if elem, ok := l.entries[key]; ok {
+		return elem.Value.(*entry), true
+	}
+	return nil, false
+}
+
+// put stores value at key and returns the entries evicted to stay within capacity, which is unbounded if zero.
+func (l *lru) put(key string, value []byte, seq, version uint64, expireAt int64) (evicted []*entry) {
+	if elem, ok := l.entries[key]; ok {
+		e := elem.Value.(*entry)
+		l.bytes += size(key, value) - size(key, e.value)
+		e.value = value
+		e.seq = seq
+		e.version = version
+		e.expireAt = expireAt
+		l.expiry.schedule(e)
+		l.order.MoveToFront(elem)
+		return nil
+	}
+
+	for l.capacity > 0 && l.order.Len() >= l.capacity {
+		oldest := l.order.Back().Value.(*entry)
+		l.remove(oldest.key, version)
+		evicted = append(evicted, oldest)
+	}
+
+	e := &entry{name: l.name, key: key, value: value, seq: seq, version: version, expireAt: expireAt, index: -1}
+	l.entries[key] = l.order.PushFront(e)
+	l.expiry.schedule(e)
+	l.bytes += size(key, value)
+
+	return evicted
+}
+
+func (l *lru) remove(key string, version uint64) bool {
+	elem, ok := l.entries[key]
+	if !ok {
+		return false
+	}
+
+	e := elem.Value.(*entry)
+	l.bytes -= size(key, e.value)
+	l.expiry.unschedule(e)
+	l.order.Remove(elem)
+	delete(l.entries, key)
+	l.removed = version
+
+	return true
+}
+
+func (l *lru) clear(version uint64) int {
+	cleared := l.order.Len()
+	for elem := l.order.Front(); elem != nil; elem = elem.Next() {
+		l.expiry.unschedule(elem.Value.(*entry))
+	}
+	l.order.Init()
+	l.entries = make(map[string]*list.Element)
+	l.bytes = 0
+	l.removed = version
+
+	return cleared
+}
+
+func (l *lru) len() int {
+	return l.order.Len()
+}
+
+func size(key string, value []byte) int64 {
+	return int64(len(key) + len(value))
+}
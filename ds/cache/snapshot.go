@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type snapshotEntry struct {
+	Key      string `json:"key"`
+	Value    []byte `json:"value"`
+	Seq      uint64 `json:"seq"`
+	Version  uint64 `json:"version,omitempty"`
+	ExpireAt int64  `json:"expireAt,omitempty"`
+}
+
+type snapshotCache struct {
+	Capacity   int             `json:"capacity"`
+	DefaultTTL time.Duration   `json:"defaultTTL,omitempty"`
+	Removed    uint64          `json:"removed,omitempty"`
+	Entries    []snapshotEntry `json:"entries"`
+}
+
+type snapshot struct {
+	Seq    uint64                   `json:"seq"`
+	Caches map[string]snapshotCache `json:"caches"`
+}
+
+// MarshalJSON encodes the entries of all caches for a snapshot, keeping their order and expiry.
+// Statistics are local to a node and are not part of it.
+func (c *Cache) MarshalJSON() ([]byte, error) {
+	s := snapshot{Seq: c.seq, Caches: make(map[string]snapshotCache, len(c.store))}
+	for name, l := range c.store {
+		entries := make([]snapshotEntry, 0, l.len())
+		for elem := l.order.Back(); elem != nil; elem = elem.Prev() {
+			e := elem.Value.(*entry)
+			entries = append(entries, snapshotEntry{
+				Key:      e.key,
+				Value:    e.value,
+				Seq:      e.seq,
+				Version:  e.version,
+				ExpireAt: e.expireAt,
+			})
+		}
+		s.Caches[name] = snapshotCache{
+			Capacity:   l.capacity,
+			DefaultTTL: time.Duration(l.defaultTTL),
+			Removed:    l.removed,
+			Entries:    entries,
+		}
+	}
+
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON replaces all caches with a snapshot.
+func (c *Cache) UnmarshalJSON(data []byte) error {
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	c.store = make(map[string]*lru, len(s.Caches))
+	c.expiry = &expiryQueue{}
+	c.bytes = 0
+	for name, sc := range s.Caches {
+		l := newLRU(name, sc.Capacity, c.expiry)
+		l.defaultTTL = int64(sc.DefaultTTL)
+		for _, e := range sc.Entries {
+			l.put(e.Key, e.Value, e.Seq, e.Version, e.ExpireAt)
+		}
+		l.removed = sc.Removed
+		c.store[name] = l
+		c.bytes += l.bytes
+	}
+	c.seq = s.Seq
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package cache
+
+import "sync/atomic"
+
+// Stats describes the activity and the footprint of a single cache on this node.
+type Stats struct {
+	Hits        uint64
+	Misses      uint64
+	Puts        uint64
+	Removals    uint64
+	Evictions   uint64
+	Expirations uint64
+	Entries     int
+	Bytes       int64
+}
+
+// HitRatio returns the share of reads that found a value, or zero if there were no reads.
+func (s Stats) HitRatio() float64 {
+	reads := s.Hits + s.Misses
+	if reads == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(reads)
+}
+
+// counters are kept per cache. Hits and misses are updated by concurrent readers, so they are
+// only accessed atomically.
+type counters struct {
+	hits        uint64
+	misses      uint64
+	puts        uint64
+	removals    uint64
+	evictions   uint64
+	expirations uint64
+}
+
+func (c *counters) hit(found bool) {
+	if found {
+		atomic.AddUint64(&c.hits, 1)
+	} else {
+		atomic.AddUint64(&c.misses, 1)
+	}
+}
+
+// Stats returns the statistics of a cache, and whether the cache exists.
+func (c *Cache) Stats(name string) (Stats, bool) {
+	if !c.exists(name) {
+		return Stats{}, false
+	}
+
+	l := c.store[name]
+	return Stats{
+		Hits:        atomic.LoadUint64(&l.counters.hits),
+		Misses:      atomic.LoadUint64(&l.counters.misses),
+		Puts:        l.counters.puts,
+		Removals:    l.counters.removals,
+		Evictions:   l.counters.evictions,
+		Expirations: l.counters.expirations,
+		Entries:     l.len(),
+		Bytes:       l.bytes,
+	}, true
+}
@@ -0,0 +1,214 @@
+// Package cms implements count-min sketches, which estimate how often items were counted in a fixed amount
+// of memory. Estimates are never below the real count, and above it by at most 2/width of the total count
+// with a probability of 1-(1/2)^depth.
+package cms
+
+import (
+	"errors"
+
+	"github.com/huseyinbabal/demory/ds/hash"
+)
+
+// MaxCounters limits the size of a single sketch to 128MB.
+const MaxCounters = 1 << 24
+
+var (
+	ErrExists     = errors.New("sketch already exists")
+	ErrNoSketch   = errors.New("sketch does not exist")
+	ErrDimensions = errors.New("width and depth must be positive")
+	ErrTooLarge   = errors.New("sketch would be too large")
+	ErrMismatch   = errors.New("sketches have different dimensions")
+)
+
+// Increment counts an item a number of times.
+type Increment struct {
+	Item  string `json:"item"`
+	Count uint64 `json:"count"`
+}
+
+// Sketch is a single count-min sketch.
+type Sketch struct {
+	Width  uint32   `json:"width"`
+	Depth  uint32   `json:"depth"`
+	Counts []uint64 `json:"counts"`
+}
+
+// Validate checks the dimensions of a sketch before it is created.
+func Validate(width, depth uint32) error {
+	if width == 0 || depth == 0 {
+		return ErrDimensions
+	}
+	if uint64(width)*uint64(depth) > MaxCounters {
+		return ErrTooLarge
+	}
+	return nil
+}
+
+// NewSketch creates an empty sketch with depth rows of width counters.
+func NewSketch(width, depth uint32) *Sketch {
+	return &Sketch{Width: width, Depth: depth, Counts: make([]uint64, uint64(width)*uint64(depth))}
+}
+
+// Add counts item count times and returns its new estimate.
+func (s *Sketch) Add(item string, count uint64) uint64 {
+	estimate := ^uint64(0)
+	s.cells(item, func(cell int) {
+		s.Counts[cell] += count
+		if s.Counts[cell] < estimate {
+			estimate = s.Counts[cell]
+		}
+	})
+	return estimate
+}
+
+// Estimate returns how often item was counted, possibly more but never less.
+func (s *Sketch) Estimate(item string) uint64 {
+	estimate := ^uint64(0)
+	s.cells(item, func(cell int) {
+		if s.Counts[cell] < estimate {
+			estimate = s.Counts[cell]
+		}
+	})
+	return estimate
+}
+
+// Merge adds the counts of other, multiplied by weight, to the sketch.
+func (s *Sketch) Merge(other *Sketch, weight uint64) error {
+	if other.Width != s.Width || other.Depth != s.Depth {
+		return ErrMismatch
+	}
+
+	for i, count := range other.Counts {
+		s.Counts[i] += count * weight
+	}
+	return nil
+}
+
+// Bytes returns the size of the counters of the sketch.
+func (s *Sketch) Bytes() int64 {
+	return int64(len(s.Counts)) * 8
+}
+
+func (s *Sketch) cells(item string, fn func(cell int)) {
+	h1, h2 := hash.Pair([]byte(item))
+	for row := uint64(0); row < uint64(s.Depth); row++ {
+		fn(int(row*uint64(s.Width) + (h1+row*h2)%uint64(s.Width)))
+	}
+}
+
+// Sketches holds all count-min sketches of a node.
+type Sketches struct {
+	sketches map[string]*Sketch
+	bytes    int64
+}
+
+// New creates an empty set of sketches.
+func New() *Sketches {
+	return &Sketches{sketches: make(map[string]*Sketch)}
+}
+
+// Create creates the sketch name with depth rows of width counters.
+func (s *Sketches) Create(name string, width, depth uint32) error {
+	if _, ok := s.sketches[name]; ok {
+		return ErrExists
+	}
+	if err := Validate(width, depth); err != nil {
+		return err
+	}
+
+	sketch := NewSketch(width, depth)
+	s.sketches[name] = sketch
+	s.bytes += sketch.Bytes()
+
+	return nil
+}
+
+// Increment counts items in the sketch name and returns their new estimates.
+func (s *Sketches) Increment(name string, increments []Increment) ([]uint64, error) {
+	sketch, ok := s.sketches[name]
+	if !ok {
+		return nil, ErrNoSketch
+	}
+
+	estimates := make([]uint64, len(increments))
+	for i, increment := range increments {
+		estimates[i] = sketch.Add(increment.Item, increment.Count)
+	}
+	return estimates, nil
+}
+
+// Query returns the estimates of items in the sketch name.
+func (s *Sketches) Query(name string, items []string) ([]uint64, error) {
+	sketch, ok := s.sketches[name]
+	if !ok {
+		return nil, ErrNoSketch
+	}
+
+	estimates := make([]uint64, len(items))
+	for i, item := range items {
+		estimates[i] = sketch.Estimate(item)
+	}
+	return estimates, nil
+}
+
+// Merge adds the counts of the sources, multiplied by their weights, to the sketch dest. Weights default
+// to 1. All sketches must exist and have the same dimensions; dest is left unchanged otherwise.
+func (s *Sketches) Merge(dest string, sources []string, weights []uint64) error {
+	sketch, ok := s.sketches[dest]
+	if !ok {
+		return ErrNoSketch
+	}
+	for _, source := range sources {
+		other, ok := s.sketches[source]
+		if !ok {
+			return ErrNoSketch
+		}
+		if other.Width != sketch.Width || other.Depth != sketch.Depth {
+			return ErrMismatch
+		}
+	}
+
+	merged := NewSketch(sketch.Width, sketch.Depth)
+	copy(merged.Counts, sketch.Counts)
+	for i, source := range sources {
+		weight := uint64(1)
+		if i < len(weights) {
+			weight = weights[i]
+		}
+		_ = merged.Merge(s.sketches[source], weight)
+	}
+	sketch.Counts = merged.Counts
+
+	return nil
+}
+
+// Dimensions returns the width and depth of the sketch name.
+func (s *Sketches) Dimensions(name string) (uint32, uint32, error) {
+	sketch, ok := s.sketches[name]
+	if !ok {
+		return 0, 0, ErrNoSketch
+	}
+	return sketch.Width, sketch.Depth, nil
+}
+
+// Destroy removes the sketch name, and reports whether it existed.
+func (s *Sketches) Destroy(name string) bool {
+	sketch, ok := s.sketches[name]
+	if !ok {
+		return false
+	}
+	s.bytes -= sketch.Bytes()
+	delete(s.sketches, name)
+	return true
+}
+
+// Exists reports whether the sketch name exists.
+func (s *Sketches) Exists(name string) bool {
+	_, ok := s.sketches[name]
+	return ok
+}
+
+// Bytes returns the number of bytes held by the counters of all sketches.
+func (s *Sketches) Bytes() int64 {
+	return s.bytes
+}
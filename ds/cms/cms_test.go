@@ -0,0 +1,61 @@
+package cms
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSketch(t *testing.T) {
+	s := New()
+	if err := s.Create("hits", 0, 4); err != ErrDimensions {
+		t.Errorf("expected %v, got %v", ErrDimensions, err)
+	}
+	if _, err := s.Increment("hits", []Increment{{Item: "a", Count: 1}}); err != ErrNoSketch {
+		t.Errorf("expected %v, got %v", ErrNoSketch, err)
+	}
+	if err := s.Create("hits", 1000, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	estimates, err := s.Increment("hits", []Increment{{Item: "/home", Count: 10}, {Item: "/about", Count: 3},
+		{Item: "/home", Count: 5}})
+	if err != nil || !reflect.DeepEqual(estimates, []uint64{10, 3, 15}) {
+		t.Errorf("unexpected estimates %v, error %v", estimates, err)
+	}
+	if estimates, _ := s.Query("hits", []string{"/home", "/missing"}); !reflect.DeepEqual(estimates, []uint64{15, 0}) {
+		t.Errorf("unexpected estimates %v", estimates)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	s := New()
+	s.Create("monday", 100, 4)
+	s.Create("tuesday", 100, 4)
+	s.Create("week", 100, 4)
+	s.Create("small", 10, 4)
+	s.Increment("monday", []Increment{{Item: "a", Count: 2}})
+	s.Increment("tuesday", []Increment{{Item: "a", Count: 3}})
+
+	if err := s.Merge("week", []string{"monday", "small"}, nil); err != ErrMismatch {
+		t.Errorf("expected %v, got %v", ErrMismatch, err)
+	}
+	if err := s.Merge("week", []string{"monday", "tuesday"}, []uint64{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if estimates, _ := s.Query("week", []string{"a"}); estimates[0] != 8 {
+		t.Errorf("expected a weighted estimate of 8, got %d", estimates[0])
+	}
+
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := New()
+	if err := json.Unmarshal(encoded, restored); err != nil {
+		t.Fatal(err)
+	}
+	if estimates, _ := restored.Query("week", []string{"a"}); estimates[0] != 8 || restored.Bytes() != s.Bytes() {
+		t.Errorf("expected the snapshot to keep all sketches")
+	}
+}
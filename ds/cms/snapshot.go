@@ -0,0 +1,32 @@
+package cms
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalJSON encodes all sketches with their counters for a snapshot.
+func (s *Sketches) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.sketches)
+}
+
+// UnmarshalJSON replaces all sketches with a snapshot.
+func (s *Sketches) UnmarshalJSON(data []byte) error {
+	var sketches map[string]*Sketch
+	if err := json.Unmarshal(data, &sketches); err != nil {
+		return err
+	}
+
+	s.sketches = make(map[string]*Sketch, len(sketches))
+	s.bytes = 0
+	for name, sketch := range sketches {
+		if uint64(len(sketch.Counts)) != uint64(sketch.Width)*uint64(sketch.Depth) {
+			return fmt.Errorf("sketch %s has %d counters for %dx%d", name, len(sketch.Counts), sketch.Width,
+				sketch.Depth)
+		}
+		s.sketches[name] = sketch
+		s.bytes += sketch.Bytes()
+	}
+
+	return nil
+}
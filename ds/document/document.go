@@ -0,0 +1,375 @@
+// Package document stores JSON documents and changes them in place at JSON paths, so that clients do not
+// need to read, modify and write back a whole document to change a part of it.
+package document
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/huseyinbabal/demory/jsonpath"
+)
+
+var (
+	ErrNoDocument = errors.New("document does not exist")
+	ErrNoPath     = errors.New("path does not exist in document")
+	ErrNotNumber  = errors.New("value at path is not a number")
+	ErrNotArray   = errors.New("value at path is not an array")
+	ErrIndex      = errors.New("array index out of range")
+	ErrInvalid    = errors.New("invalid json value")
+)
+
+// Condition restricts when Set writes a value.
+type Condition string
+
+const (
+	// Always writes the value whether the path exists or not.
+	Always Condition = ""
+	// IfAbsent only writes the value if nothing exists at the path.
+	IfAbsent Condition = "if-absent"
+	// IfExists only replaces an existing value.
+	IfExists Condition = "if-exists"
+)
+
+type document struct {
+	value interface{}
+	size  int64
+}
+
+// Documents holds all documents of a node.
+type Documents struct {
+	documents map[string]*document
+	bytes     int64
+}
+
+// New creates an empty set of documents.
+func New() *Documents {
+	return &Documents{documents: make(map[string]*document)}
+}
+
+// Decode decodes a JSON value, keeping numbers as they were written.
+func Decode(value []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, ErrInvalid
+	}
+	if decoder.More() {
+		return nil, ErrInvalid
+	}
+	return decoded, nil
+}
+
+// Get returns the encoded value at path within document name, and whether it exists.
+func (d *Documents) Get(name, path string) (json.RawMessage, bool, error) {
+	value, ok, err := d.lookup(name, path)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	encoded, err := json.Marshal(value)
+	return encoded, err == nil, err
+}
+
+// Type returns the type of the value at path within document name: object, array, string, integer, number,
+// boolean or null.
+func (d *Documents) Type(name, path string) (string, bool, error) {
+	value, ok, err := d.lookup(name, path)
+	if err != nil || !ok {
+		return "", false, err
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return "object", true, nil
+	case []interface{}:
+		return "array", true, nil
+	case string:
+		return "string", true, nil
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer", true, nil
+		}
+		return "number", true, nil
+	case bool:
+		return "boolean", true, nil
+	default:
+		return "null", true, nil
+	}
+}
+
+// Set stores a JSON value at path within document name, and reports whether it was written. Setting the root
+// path creates the document; other paths require it to exist. Missing objects along the path are created.
+func (d *Documents) Set(name, path string, value []byte, condition Condition) (bool, error) {
+	segments, err := jsonpath.Parse(path)
+	if err != nil {
+		return false, err
+	}
+	decoded, err := Decode(value)
+	if err != nil {
+		return false, err
+	}
+
+	doc, ok := d.documents[name]
+	if !ok && len(segments) > 0 {
+		return false, ErrNoDocument
+	}
+
+	var exists bool
+	if ok {
+		_, exists = jsonpath.Lookup(doc.value, segments)
+	}
+	if condition == IfAbsent && exists || condition == IfExists && !exists {
+		return false, nil
+	}
+
+	if !ok {
+		doc = &document{}
+		d.documents[name] = doc
+	}
+	updated, err := jsonpath.SetSegments(doc.value, segments, decoded)
+	if err != nil {
+		return false, err
+	}
+
+	return true, d.store(name, doc, updated)
+}
+
+// Delete removes the value at path within document name, and reports whether it existed. Deleting the root
+// path removes the document.
+func (d *Documents) Delete(name, path string) (bool, error) {
+	segments, err := jsonpath.Parse(path)
+	if err != nil {
+		return false, err
+	}
+
+	doc, ok := d.documents[name]
+	if !ok {
+		return false, nil
+	}
+	if len(segments) == 0 {
+		d.Destroy(name)
+		return true, nil
+	}
+
+	updated, deleted := jsonpath.Delete(doc.value, segments)
+	if !deleted {
+		return false, nil
+	}
+
+	return true, d.store(name, doc, updated)
+}
+
+// Destroy removes document name, and reports whether it existed.
+func (d *Documents) Destroy(name string) bool {
+	doc, ok := d.documents[name]
+	if !ok {
+		return false
+	}
+	d.bytes -= doc.size
+	delete(d.documents, name)
+	return true
+}
+
+// Increment adds delta to the number at path within document name and returns the result. Integers stay
+// integers as long as delta is one too, whatever their size.
+func (d *Documents) Increment(name, path string, delta json.Number) (json.Number, error) {
+	doc, segments, err := d.resolve(name, path)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := jsonpath.Lookup(doc.value, segments)
+	if !ok {
+		return "", ErrNoPath
+	}
+	current, ok := value.(json.Number)
+	if !ok {
+		return "", ErrNotNumber
+	}
+
+	result, err := add(current, delta)
+	if err != nil {
+		return "", err
+	}
+	updated, err := jsonpath.SetSegments(doc.value, segments, result)
+	if err != nil {
+		return "", err
+	}
+
+	return result, d.store(name, doc, updated)
+}
+
+func add(current, delta json.Number) (json.Number, error) {
+	a, aInt := new(big.Int).SetString(string(current), 10)
+	b, bInt := new(big.Int).SetString(string(delta), 10)
+	if aInt && bInt {
+		return json.Number(a.Add(a, b).String()), nil
+	}
+
+	x, err := current.Float64()
+	if err != nil {
+		return "", ErrNotNumber
+	}
+	y, err := delta.Float64()
+	if err != nil {
+		return "", ErrNotNumber
+	}
+	sum := x + y
+	if math.IsInf(sum, 0) || math.IsNaN(sum) {
+		return "", ErrNotNumber
+	}
+
+	return json.Number(strconv.FormatFloat(sum, 'g', -1, 64)), nil
+}
+
+// Append adds JSON values at the end of the array at path within document name and returns its new length.
+func (d *Documents) Append(name, path string, values []json.RawMessage) (int, error) {
+	return d.Insert(name, path, math.MaxInt, values)
+}
+
+// Insert adds JSON values to the array at path within document name before index, and returns its new length.
+// Negative indexes count from the end of the array, and math.MaxInt appends.
+func (d *Documents) Insert(name, path string, index int, values []json.RawMessage) (int, error) {
+	decoded := make([]interface{}, len(values))
+	for i, value := range values {
+		var err error
+		if decoded[i], err = Decode(value); err != nil {
+			return 0, err
+		}
+	}
+
+	doc, segments, array, err := d.array(name, path)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case index == math.MaxInt:
+		index = len(array)
+	case index < 0:
+		index += len(array)
+	}
+	if index < 0 || index > len(array) {
+		return 0, ErrIndex
+	}
+
+	inserted := make([]interface{}, 0, len(array)+len(decoded))
+	inserted = append(append(append(inserted, array[:index]...), decoded...), array[index:]...)
+	updated, err := jsonpath.SetSegments(doc.value, segments, inserted)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(inserted), d.store(name, doc, updated)
+}
+
+// Pop removes the element at index from the array at path within document name and returns it, or false if
+// the array is empty. Negative indexes count from the end of the array, and indexes out of range are clamped.
+func (d *Documents) Pop(name, path string, index int) (json.RawMessage, bool, error) {
+	doc, segments, array, err := d.array(name, path)
+	if err != nil || len(array) == 0 {
+		return nil, false, err
+	}
+
+	if index < 0 {
+		index += len(array)
+	}
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(array) {
+		index = len(array) - 1
+	}
+
+	popped, err := json.Marshal(array[index])
+	if err != nil {
+		return nil, false, err
+	}
+	updated, err := jsonpath.SetSegments(doc.value, segments, append(array[:index:index], array[index+1:]...))
+	if err != nil {
+		return nil, false, err
+	}
+
+	return popped, true, d.store(name, doc, updated)
+}
+
+// Exists reports whether document name exists.
+func (d *Documents) Exists(name string) bool {
+	_, ok := d.documents[name]
+	return ok
+}
+
+// Size returns the number of bytes of document name, or zero if it does not exist.
+func (d *Documents) Size(name string) int64 {
+	if doc, ok := d.documents[name]; ok {
+		return doc.size
+	}
+	return 0
+}
+
+// Bytes returns the number of bytes held by the names and the encoded values of all documents.
+func (d *Documents) Bytes() int64 {
+	return d.bytes
+}
+
+func (d *Documents) lookup(name, path string) (interface{}, bool, error) {
+	doc, segments, err := d.resolve(name, path)
+	if errors.Is(err, ErrNoDocument) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, ok := jsonpath.Lookup(doc.value, segments)
+	return value, ok, nil
+}
+
+func (d *Documents) resolve(name, path string) (*document, []jsonpath.Segment, error) {
+	segments, err := jsonpath.Parse(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc, ok := d.documents[name]
+	if !ok {
+		return nil, nil, ErrNoDocument
+	}
+	return doc, segments, nil
+}
+
+func (d *Documents) array(name, path string) (*document, []jsonpath.Segment, []interface{}, error) {
+	doc, segments, err := d.resolve(name, path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	value, ok := jsonpath.Lookup(doc.value, segments)
+	if !ok {
+		return nil, nil, nil, ErrNoPath
+	}
+	array, ok := value.([]interface{})
+	if !ok {
+		return nil, nil, nil, ErrNotArray
+	}
+	return doc, segments, array, nil
+}
+
+// store replaces the value of a document and accounts for its new size.
+func (d *Documents) store(name string, doc *document, value interface{}) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	size := int64(len(name) + len(encoded))
+	d.bytes += size - doc.size
+	doc.value, doc.size = value, size
+	return nil
+}
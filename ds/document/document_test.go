@@ -0,0 +1,99 @@
+package document
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPaths(t *testing.T) {
+	d := New()
+	if _, err := d.Set("user", "name", []byte(`"ada"`), Always); err != ErrNoDocument {
+		t.Errorf("expected %v, got %v", ErrNoDocument, err)
+	}
+	if set, err := d.Set("user", "$", []byte(`{"name":"ada","tags":["a"],"visits":1}`), Always); !set || err != nil {
+		t.Fatalf("unexpected set %t, error %v", set, err)
+	}
+
+	if set, _ := d.Set("user", "name", []byte(`"grace"`), IfAbsent); set {
+		t.Errorf("expected an existing value not to be set if absent")
+	}
+	if set, _ := d.Set("user", "address.city", []byte(`"london"`), Always); !set {
+		t.Errorf("expected a nested value to be set")
+	}
+	if value, ok, _ := d.Get("user", "address.city"); !ok || string(value) != `"london"` {
+		t.Errorf("unexpected value %s", value)
+	}
+
+	if value, err := d.Increment("user", "visits", "41"); err != nil || value != "42" {
+		t.Errorf("unexpected increment %s, error %v", value, err)
+	}
+	if value, _ := d.Increment("user", "visits", "0.5"); value != "42.5" {
+		t.Errorf("unexpected fractional increment %s", value)
+	}
+	if _, err := d.Increment("user", "name", "1"); err != ErrNotNumber {
+		t.Errorf("expected %v, got %v", ErrNotNumber, err)
+	}
+
+	if kind, _, _ := d.Type("user", "tags"); kind != "array" {
+		t.Errorf("unexpected type %s", kind)
+	}
+	if deleted, _ := d.Delete("user", "address"); !deleted {
+		t.Errorf("expected address to be deleted")
+	}
+	if _, ok, _ := d.Get("user", "address.city"); ok {
+		t.Errorf("expected a deleted value not to be found")
+	}
+
+	if deleted, _ := d.Delete("user", "$"); !deleted || d.Exists("user") || d.Bytes() != 0 {
+		t.Errorf("expected the document to be removed with its bytes")
+	}
+}
+
+func TestArrays(t *testing.T) {
+	d := New()
+	if _, err := d.Set("list", "$", []byte(`{"items":[1,2]}`), Always); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := d.Append("list", "items", []json.RawMessage{[]byte(`3`), []byte(`"four"`)}); err != nil || n != 4 {
+		t.Errorf("unexpected length %d, error %v", n, err)
+	}
+	if n, _ := d.Insert("list", "items", 0, []json.RawMessage{[]byte(`0`)}); n != 5 {
+		t.Errorf("unexpected length %d", n)
+	}
+	if _, err := d.Insert("list", "items", 9, []json.RawMessage{[]byte(`0`)}); err != ErrIndex {
+		t.Errorf("expected %v, got %v", ErrIndex, err)
+	}
+	if value, ok, _ := d.Pop("list", "items", -1); !ok || string(value) != `"four"` {
+		t.Errorf("unexpected popped value %s", value)
+	}
+	if value, _, _ := d.Get("list", "items"); string(value) != `[0,1,2,3]` {
+		t.Errorf("unexpected array %s", value)
+	}
+	if _, err := d.Append("list", "items[0]", []json.RawMessage{[]byte(`1`)}); err != ErrNotArray {
+		t.Errorf("expected %v, got %v", ErrNotArray, err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	d := New()
+	if _, err := d.Set("doc", "$", []byte(`{"big":12345678901234567890}`), Always); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := New()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _, _ := restored.Get("doc", "big"); string(value) != "12345678901234567890" {
+		t.Errorf("expected large integers to survive a snapshot, got %s", value)
+	}
+	if restored.Bytes() != d.Bytes() {
+		t.Errorf("expected %d bytes, got %d", d.Bytes(), restored.Bytes())
+	}
+}
@@ -0,0 +1,31 @@
+package document
+
+import "encoding/json"
+
+// MarshalJSON encodes all documents for a snapshot.
+func (d *Documents) MarshalJSON() ([]byte, error) {
+	documents := make(map[string]interface{}, len(d.documents))
+	for name, doc := range d.documents {
+		documents[name] = doc.value
+	}
+
+	return json.Marshal(documents)
+}
+
+// UnmarshalJSON replaces all documents with a snapshot.
+func (d *Documents) UnmarshalJSON(data []byte) error {
+	var documents map[string]json.RawMessage
+	if err := json.Unmarshal(data, &documents); err != nil {
+		return err
+	}
+
+	d.documents = make(map[string]*document, len(documents))
+	d.bytes = 0
+	for name, encoded := range documents {
+		if _, err := d.Set(name, "$", encoded, Always); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
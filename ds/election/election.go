@@ -0,0 +1,327 @@
+// Package election implements leader elections for client applications. Candidates campaign with a session,
+// which they keep alive; the first candidate becomes the leader and the others queue up behind it. When the
+// leader resigns or its session expires, the next candidate takes over with a new, greater term, which
+// serves as a fencing token.
+package election
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// MinTTL and MaxTTL bound the time to live of sessions.
+	MinTTL = time.Second
+	MaxTTL = time.Hour
+	// SessionSize is the number of bytes accounted for a session.
+	SessionSize = 24
+)
+
+var (
+	ErrNoSession = errors.New("session does not exist or expired")
+	ErrTTL       = errors.New("session ttl must be between 1s and 1h")
+	ErrNotLeader = errors.New("session is not the leader of the election")
+)
+
+// Leader is the leader of an election.
+type Leader struct {
+	Session uint64 `json:"session"`
+	Value   []byte `json:"value,omitempty"`
+	// Term is the raft log index of the command that made the session leader. It increases with every new
+	// leader of an election.
+	Term uint64 `json:"term"`
+}
+
+type candidate struct {
+	Session uint64 `json:"session"`
+	Value   []byte `json:"value,omitempty"`
+}
+
+type session struct {
+	TTL     int64 `json:"ttl"`
+	Expires int64 `json:"expires"`
+}
+
+type election struct {
+	Leader     *Leader     `json:"leader,omitempty"`
+	Candidates []candidate `json:"candidates,omitempty"`
+}
+
+// Elections holds all sessions and elections of a node.
+type Elections struct {
+	sessions  map[uint64]*session
+	elections map[string]*election
+	bytes     int64
+	// signals are taken by waiters concurrently, so they are guarded separately.
+	mutex   sync.Mutex
+	signals map[string]chan struct{}
+}
+
+// New creates an empty set of elections.
+func New() *Elections {
+	return &Elections{
+		sessions:  make(map[uint64]*session),
+		elections: make(map[string]*election),
+		signals:   make(map[string]chan struct{}),
+	}
+}
+
+// ValidateTTL checks the time to live of a session.
+func ValidateTTL(ttl time.Duration) error {
+	if ttl < MinTTL || ttl > MaxTTL {
+		return ErrTTL
+	}
+	return nil
+}
+
+// Grant creates the session id, which expires ttl after now unless it is kept alive. Times are in unix
+// nanoseconds.
+func (e *Elections) Grant(id uint64, ttl time.Duration, now int64) error {
+	if err := ValidateTTL(ttl); err != nil {
+		return err
+	}
+
+	if _, ok := e.sessions[id]; !ok {
+		e.bytes += SessionSize
+	}
+	e.sessions[id] = &session{TTL: int64(ttl), Expires: now + int64(ttl)}
+	return nil
+}
+
+// KeepAlive extends the session id by its ttl from now and returns when it expires.
+func (e *Elections) KeepAlive(id uint64, now int64) (int64, error) {
+	s, ok := e.sessions[id]
+	if !ok || s.Expires < now {
+		return 0, ErrNoSession
+	}
+
+	s.Expires = now + s.TTL
+	return s.Expires, nil
+}
+
+// Revoke removes the session id, which resigns it from all elections. The next candidates that take over
+// get term as their term.
+func (e *Elections) Revoke(id uint64, term uint64) bool {
+	if _, ok := e.sessions[id]; !ok {
+		return false
+	}
+	delete(e.sessions, id)
+	e.bytes -= SessionSize
+
+	names := make([]string, 0, len(e.elections))
+	for name := range e.elections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		e.withdraw(name, id, term)
+	}
+	return true
+}
+
+// Expire revokes the sessions that expired before now and returns them.
+func (e *Elections) Expire(ids []uint64, now int64, term uint64) []uint64 {
+	var expired []uint64
+	for _, id := range ids {
+		if s, ok := e.sessions[id]; ok && s.Expires < now {
+			e.Revoke(id, term)
+			expired = append(expired, id)
+		}
+	}
+	return expired
+}
+
+// Expired returns up to max sessions that expired before now.
+func (e *Elections) Expired(now int64, max int) []uint64 {
+	var expired []uint64
+	for id, s := range e.sessions {
+		if s.Expires < now {
+			expired = append(expired, id)
+		}
+		if len(expired) == max {
+			break
+		}
+	}
+	return expired
+}
+
+// Campaign makes the session a candidate of the election name with value, and returns the leader of the
+// election afterwards. The session becomes the leader with term if there is none.
+func (e *Elections) Campaign(name string, id uint64, value []byte, term uint64) (Leader, error) {
+	if _, ok := e.sessions[id]; !ok {
+		return Leader{}, ErrNoSession
+	}
+
+	el, ok := e.elections[name]
+	if !ok {
+		el = &election{}
+		e.elections[name] = el
+		e.bytes += int64(len(name))
+	}
+
+	switch {
+	case el.Leader == nil:
+		el.Leader = &Leader{Session: id, Value: value, Term: term}
+		e.bytes += CandidateSize(value)
+		e.signal(name)
+	case el.Leader.Session == id:
+	case el.find(id) < 0:
+		el.Candidates = append(el.Candidates, candidate{Session: id, Value: value})
+		e.bytes += CandidateSize(value)
+	}
+	return *el.Leader, nil
+}
+
+// Resign withdraws the session from the election name, as its leader or as a candidate. A leader only
+// resigns if term is zero or its own term, so that a stale request cannot end a later leadership.
+func (e *Elections) Resign(name string, id uint64, leaderTerm, term uint64) error {
+	el, ok := e.elections[name]
+	if !ok {
+		return ErrNotLeader
+	}
+	isLeader := el.Leader != nil && el.Leader.Session == id
+	if isLeader && leaderTerm != 0 && el.Leader.Term != leaderTerm || !isLeader && el.find(id) < 0 {
+		return ErrNotLeader
+	}
+
+	e.withdraw(name, id, term)
+	return nil
+}
+
+// Leader returns the leader of the election name.
+func (e *Elections) Leader(name string) (Leader, bool) {
+	el, ok := e.elections[name]
+	if !ok || el.Leader == nil {
+		return Leader{}, false
+	}
+	return *el.Leader, true
+}
+
+// CampaignGrowth returns the number of bytes the session campaigning in the election name with value adds.
+func (e *Elections) CampaignGrowth(name string, id uint64, value []byte) int64 {
+	el, ok := e.elections[name]
+	switch {
+	case !ok:
+		return int64(len(name)) + CandidateSize(value)
+	case el.Leader != nil && el.Leader.Session == id || el.find(id) >= 0:
+		return 0
+	default:
+		return CandidateSize(value)
+	}
+}
+
+// CandidateSize returns the number of bytes accounted for a leader or a candidate with value.
+func CandidateSize(value []byte) int64 {
+	return int64(8 + len(value))
+}
+
+// Bytes returns the number of bytes held by all sessions and elections.
+func (e *Elections) Bytes() int64 {
+	return e.bytes
+}
+
+// HasSession reports whether the session id exists.
+func (e *Elections) HasSession(id uint64) bool {
+	_, ok := e.sessions[id]
+	return ok
+}
+
+// Signal returns a channel that is closed when the leader of the election name changes.
+func (e *Elections) Signal(name string) <-chan struct{} {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	signal, ok := e.signals[name]
+	if !ok {
+		signal = make(chan struct{})
+		e.signals[name] = signal
+	}
+	return signal
+}
+
+// Wake closes the channels returned by Signal, for waiters to look at the elections again after they were
+// replaced by a snapshot.
+func (e *Elections) Wake() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for name, signal := range e.signals {
+		close(signal)
+		delete(e.signals, name)
+	}
+}
+
+// Destroy removes the election name, without a leader nor candidates left, and reports whether it existed.
+// Sessions are kept.
+func (e *Elections) Destroy(name string) bool {
+	el, ok := e.elections[name]
+	if !ok {
+		return false
+	}
+	e.bytes -= el.size(name)
+	delete(e.elections, name)
+	e.signal(name)
+	return true
+}
+
+// withdraw removes a session from an election, handing the leadership to the next candidate if it led it.
+func (e *Elections) withdraw(name string, id uint64, term uint64) {
+	el, ok := e.elections[name]
+	if !ok {
+		return
+	}
+
+	if i := el.find(id); i >= 0 {
+		e.bytes -= CandidateSize(el.Candidates[i].Value)
+		el.Candidates = append(el.Candidates[:i], el.Candidates[i+1:]...)
+	}
+	if el.Leader == nil || el.Leader.Session != id {
+		return
+	}
+
+	e.bytes -= CandidateSize(el.Leader.Value)
+	el.Leader = nil
+	if len(el.Candidates) > 0 {
+		// The next candidate keeps its bytes as the leader.
+		next := el.Candidates[0]
+		el.Candidates = el.Candidates[1:]
+		el.Leader = &Leader{Session: next.Session, Value: next.Value, Term: term}
+	} else {
+		e.bytes -= int64(len(name))
+		delete(e.elections, name)
+	}
+	e.signal(name)
+}
+
+func (e *Elections) signal(name string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if signal, ok := e.signals[name]; ok {
+		close(signal)
+		delete(e.signals, name)
+	}
+}
+
+// size returns the number of bytes accounted for the election name.
+func (el *election) size(name string) int64 {
+	size := int64(len(name))
+	if el.Leader != nil {
+		size += CandidateSize(el.Leader.Value)
+	}
+	for _, c := range el.Candidates {
+		size += CandidateSize(c.Value)
+	}
+	return size
+}
+
+func (el *election) find(id uint64) int {
+	for i, c := range el.Candidates {
+		if c.Session == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,128 @@
+package election
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+const now = int64(1700000000) * int64(time.Second)
+
+func TestCampaign(t *testing.T) {
+	e := New()
+	e.Grant(1, time.Second, now)
+	e.Grant(2, time.Second, now)
+	e.Grant(3, time.Second, now)
+
+	if _, err := e.Campaign("scheduler", 4, nil, 10); err != ErrNoSession {
+		t.Errorf("expected %v, got %v", ErrNoSession, err)
+	}
+
+	leader, _ := e.Campaign("scheduler", 1, []byte("a"), 10)
+	if leader.Session != 1 || leader.Term != 10 {
+		t.Errorf("expected the first candidate to lead with term 10, got %+v", leader)
+	}
+	signal := e.Signal("scheduler")
+	if leader, _ := e.Campaign("scheduler", 2, []byte("b"), 11); leader.Session != 1 {
+		t.Errorf("expected the leader to stay, got %+v", leader)
+	}
+	e.Campaign("scheduler", 3, []byte("c"), 12)
+
+	if err := e.Resign("scheduler", 1, 9, 13); err != ErrNotLeader {
+		t.Errorf("expected a stale term not to resign, got %v", err)
+	}
+	if err := e.Resign("scheduler", 1, 10, 13); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-signal:
+	default:
+		t.Errorf("expected a change of leader to be signaled")
+	}
+	if leader, _ := e.Leader("scheduler"); leader.Session != 2 || leader.Term != 13 || string(leader.Value) != "b" {
+		t.Errorf("expected the next candidate to lead with a new term, got %+v", leader)
+	}
+
+	if expired := e.Expire([]uint64{2, 3}, now+int64(2*time.Second), 14); len(expired) != 2 {
+		t.Errorf("expected 2 expired sessions, got %v", expired)
+	}
+	if _, ok := e.Leader("scheduler"); ok {
+		t.Errorf("expected no leader once all sessions expired")
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	e := New()
+	if err := e.Grant(1, time.Millisecond, now); err != ErrTTL {
+		t.Errorf("expected %v, got %v", ErrTTL, err)
+	}
+	e.Grant(1, time.Second, now)
+
+	expires, err := e.KeepAlive(1, now+int64(900*time.Millisecond))
+	if err != nil || expires != now+int64(1900*time.Millisecond) {
+		t.Errorf("unexpected expiry %d, error %v", expires, err)
+	}
+	if expired := e.Expired(now+int64(1500*time.Millisecond), 10); len(expired) != 0 {
+		t.Errorf("expected a session kept alive not to expire, got %v", expired)
+	}
+	if _, err := e.KeepAlive(1, now+int64(2*time.Second)); err != ErrNoSession {
+		t.Errorf("expected an expired session not to be kept alive, got %v", err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	e := New()
+	e.Grant(1, time.Second, now)
+	e.Grant(2, time.Second, now)
+	e.Campaign("scheduler", 1, nil, 10)
+	e.Campaign("scheduler", 2, nil, 11)
+
+	encoded, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := New()
+	if err := json.Unmarshal(encoded, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	restored.Revoke(1, 12)
+	if leader, _ := restored.Leader("scheduler"); leader.Session != 2 || leader.Term != 12 {
+		t.Errorf("expected the snapshot to keep the candidates, got %+v", leader)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	e := New()
+	e.Grant(1, time.Second, now)
+	e.Grant(2, time.Second, now)
+
+	growth := e.CampaignGrowth("scheduler", 1, []byte("a"))
+	e.Campaign("scheduler", 1, []byte("a"), 10)
+	growth += e.CampaignGrowth("scheduler", 2, []byte("bb"))
+	e.Campaign("scheduler", 2, []byte("bb"), 11)
+	if e.CampaignGrowth("scheduler", 2, []byte("bb")) != 0 {
+		t.Error("expected campaigning again to take no bytes")
+	}
+
+	if growth != int64(len("scheduler"))+CandidateSize([]byte("a"))+CandidateSize([]byte("bb")) {
+		t.Errorf("unexpected growth %d", growth)
+	}
+	if expected := 2*SessionSize + growth; e.Bytes() != expected {
+		t.Errorf("expected %d bytes, got %d", expected, e.Bytes())
+	}
+	data, _ := json.Marshal(e)
+	restored := New()
+	if err := json.Unmarshal(data, restored); err != nil || restored.Bytes() != e.Bytes() {
+		t.Errorf("expected the snapshot to keep %d bytes, got %d", e.Bytes(), restored.Bytes())
+	}
+
+	e.Revoke(1, 12)
+	if e.Bytes() != SessionSize+int64(len("scheduler"))+CandidateSize([]byte("bb")) {
+		t.Errorf("unexpected bytes %d after the leader left", e.Bytes())
+	}
+	e.Revoke(2, 13)
+	if e.Bytes() != 0 {
+		t.Errorf("expected no bytes left, got %d", e.Bytes())
+	}
+}
@@ -0,0 +1,35 @@
+package election
+
+import "encoding/json"
+
+type snapshot struct {
+	Sessions  map[uint64]*session  `json:"sessions"`
+	Elections map[string]*election `json:"elections"`
+}
+
+// MarshalJSON encodes all sessions and elections for a snapshot.
+func (e *Elections) MarshalJSON() ([]byte, error) {
+	return json.Marshal(snapshot{Sessions: e.sessions, Elections: e.elections})
+}
+
+// UnmarshalJSON replaces all sessions and elections with a snapshot.
+func (e *Elections) UnmarshalJSON(data []byte) error {
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	e.sessions, e.elections = s.Sessions, s.Elections
+	if e.sessions == nil {
+		e.sessions = make(map[uint64]*session)
+	}
+	if e.elections == nil {
+		e.elections = make(map[string]*election)
+	}
+
+	e.bytes = int64(len(e.sessions)) * SessionSize
+	for name, el := range e.elections {
+		e.bytes += el.size(name)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+// Package flake generates unique, roughly time ordered 64 bit ids without coordination per id. The cluster
+// only leases a node id to every server through the raft log; every node then builds ids from its node id,
+// the time in milliseconds and a sequence within the millisecond.
+//
+// An id has 41 bits of milliseconds since Epoch, 6 bits of sequence and 16 bits of node id, which leaves the
+// sign bit clear. A node id can produce 64 ids per millisecond; faster callers borrow milliseconds from the
+// future, up to MaxAhead.
+package flake
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	timestampBits = 41
+	sequenceBits  = 6
+	nodeBits      = 16
+
+	// MaxNodes is the number of node ids a generator can hand out.
+	MaxNodes = 1 << nodeBits
+	// MaxBatch is the number of ids that can be asked for at once.
+	MaxBatch = 100000
+	// MaxAhead is how far generators may run ahead of the clock before they refuse to generate ids.
+	MaxAhead = 15 * time.Second
+	// LeaseDuration is how long a server keeps its node id without the lease being renewed.
+	LeaseDuration = time.Minute
+)
+
+// Epoch is the time ids count milliseconds from.
+var Epoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	ErrNodesExhausted = errors.New("all node ids are allocated")
+	ErrTooFarAhead    = errors.New("generator is too far ahead of the clock, retry later")
+	ErrBatch          = errors.New("count must be between 1 and 100000")
+)
+
+// Allocator leases node ids to the servers of a cluster. It is part of the replicated state. A server only
+// generates ids while its lease runs, and the leader renews the leases of the members of the cluster before
+// they expire, so a server keeps its node id across restarts, and uses it for all generators. Node ids that
+// were never handed out are handed out first; once all were, the node id of an expired lease is handed out
+// again, so the node ids of removed servers are reclaimed.
+type Allocator struct {
+	leases map[string]lease
+	next   uint32
+	bytes  int64
+}
+
+// lease is a node id handed out to a server until a time, in unix nanoseconds.
+type lease struct {
+	Node    uint32 `json:"node"`
+	Expires int64  `json:"expires"`
+}
+
+// NewAllocator creates an allocator which has not handed out any node id.
+func NewAllocator() *Allocator {
+	return &Allocator{leases: make(map[string]lease)}
+}
+
+// Allocate leases a node id to server for LeaseDuration from now. A server with a lease, even an expired one
+// that was not reclaimed yet, keeps its node id.
+func (a *Allocator) Allocate(server string, now time.Time) (uint32, error) {
+	expires := now.Add(LeaseDuration).UnixNano()
+	if l, ok := a.leases[server]; ok {
+		a.leases[server] = lease{Node: l.Node, Expires: expires}
+		return l.Node, nil
+	}
+
+	var node uint32
+	if a.next < MaxNodes {
+		node = a.next
+		a.next++
+	} else {
+		reclaimed, ok := a.reclaimable(now)
+		if !ok {
+			return 0, ErrNodesExhausted
+		}
+		node = a.leases[reclaimed].Node
+		delete(a.leases, reclaimed)
+		a.bytes -= nodeSize(reclaimed)
+	}
+
+	a.leases[server] = lease{Node: node, Expires: expires}
+	a.bytes += nodeSize(server)
+	return node, nil
+}
+
+// reclaimable returns the server holding the lowest node id that can be handed out again at now. A node id can
+// once its lease expired longer than MaxAhead and LeaseDuration ago: no id its server generated, even borrowed
+// from the future, can be generated again, even if the clock of the server runs behind by up to LeaseDuration.
+func (a *Allocator) reclaimable(now time.Time) (string, bool) {
+	var reclaimed string
+	var found bool
+	for server, l := range a.leases {
+		if time.Unix(0, l.Expires).Add(MaxAhead + LeaseDuration).After(now) {
+			continue
+		}
+		if !found || l.Node < a.leases[reclaimed].Node {
+			reclaimed, found = server, true
+		}
+	}
+	return reclaimed, found
+}
+
+// Renewing returns the servers whose lease must be renewed at now, as they have none or it runs out within
+// half of LeaseDuration.
+func (a *Allocator) Renewing(servers []string, now time.Time) []string {
+	var renewing []string
+	for _, server := range servers {
+		l, ok := a.leases[server]
+		if !ok || time.Unix(0, l.Expires).Sub(now) < LeaseDuration/2 {
+			renewing = append(renewing, server)
+		}
+	}
+	return renewing
+}
+
+// Growth returns the number of bytes leasing node ids to the servers without a lease takes.
+func (a *Allocator) Growth(servers []string) int64 {
+	var growth int64
+	seen := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		if _, ok := a.leases[server]; !ok && !seen[server] {
+			growth += nodeSize(server)
+		}
+		seen[server] = true
+	}
+	return growth
+}
+
+// Bytes returns the number of bytes held by the leases.
+func (a *Allocator) Bytes() int64 {
+	return a.bytes
+}
+
+func nodeSize(server string) int64 {
+	return int64(len(server) + 12)
+}
+
+// Node returns the node id of server, and whether its lease runs at now.
+func (a *Allocator) Node(server string, now time.Time) (uint32, bool) {
+	l, ok := a.leases[server]
+	if !ok || now.UnixNano() >= l.Expires {
+		return 0, false
+	}
+	return l.Node, true
+}
+
+// Len returns the number of leases, expired or not.
+func (a *Allocator) Len() int {
+	return len(a.leases)
+}
+
+// Generator generates the ids of a node for one generator name. It is local to the node.
+type Generator struct {
+	mutex    sync.Mutex
+	node     uint32
+	last     int64
+	sequence int64
+}
+
+// NewGenerator creates a generator for a node id handed out by the Allocator, which generates ids from after
+// on. Ids generated before by the same node id are at most MaxAhead ahead of their time, so generators created
+// after a restart start MaxAhead ahead of the clock, to never generate the same ids again.
+func NewGenerator(node uint32, after time.Time) *Generator {
+	return &Generator{node: node, last: after.Sub(Epoch).Milliseconds() - 1, sequence: 1<<sequenceBits - 1}
+}
+
+// Next returns count new ids, generated at now. Ids are increasing across calls, even if the clock goes back.
+func (g *Generator) Next(count int, now time.Time) ([]int64, error) {
+	if count <= 0 || count > MaxBatch {
+		return nil, ErrBatch
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	timestamp, sequence := now.Sub(Epoch).Milliseconds(), int64(0)
+	if timestamp <= g.last {
+		timestamp, sequence = g.last, g.sequence+1
+	}
+
+	// The ids of a batch take consecutive sequences, overflowing into the following milliseconds.
+	end := timestamp + (sequence+int64(count)-1)>>sequenceBits
+	if time.Duration(end-now.Sub(Epoch).Milliseconds())*time.Millisecond > MaxAhead {
+		return nil, ErrTooFarAhead
+	}
+
+	ids := make([]int64, count)
+	for i := range ids {
+		if sequence == 1<<sequenceBits {
+			timestamp, sequence = timestamp+1, 0
+		}
+		ids[i] = timestamp<<(sequenceBits+nodeBits) | sequence<<nodeBits | int64(g.node)
+		g.last, g.sequence = timestamp, sequence
+		sequence++
+	}
+
+	return ids, nil
+}
+
+// Generators holds the generators of a node for every generator name.
+type Generators struct {
+	mutex      sync.Mutex
+	after      time.Time
+	generators map[string]*Generator
+}
+
+// NewGenerators creates an empty set of generators for a node started at started.
+func NewGenerators(started time.Time) *Generators {
+	return &Generators{after: started.Add(MaxAhead), generators: make(map[string]*Generator)}
+}
+
+// Get returns the generator name, creating it for node id node if it does not exist yet.
+func (g *Generators) Get(name string, node uint32) *Generator {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	generator, ok := g.generators[name]
+	if !ok {
+		generator = NewGenerator(node, g.after)
+		g.generators[name] = generator
+	}
+	return generator
+}
+
+// Timestamp returns the time an id was generated at.
+func Timestamp(id int64) time.Time {
+	return Epoch.Add(time.Duration(id>>(sequenceBits+nodeBits)) * time.Millisecond)
+}
+
+// Node returns the node id an id was generated by.
+func Node(id int64) uint32 {
+	return uint32(id & (1<<nodeBits - 1))
+}
@@ -0,0 +1,146 @@
+package flake
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAllocate(t *testing.T) {
+	now := Epoch.Add(time.Hour)
+	a := NewAllocator()
+	for i, server := range []string{"a", "b", "c"} {
+		if node, err := a.Allocate(server, now); err != nil || node != uint32(i) {
+			t.Errorf("expected node id %d, got %d, error %v", i, node, err)
+		}
+	}
+	if node, _ := a.Allocate("b", now); node != 1 {
+		t.Errorf("expected servers to keep their node ids, got %d", node)
+	}
+
+	encoded, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := NewAllocator()
+	if err := json.Unmarshal(encoded, restored); err != nil {
+		t.Fatal(err)
+	}
+	if node, ok := restored.Node("c", now); !ok || node != 2 {
+		t.Errorf("expected the snapshot to keep the leases, got %d", node)
+	}
+	if growth := restored.Growth([]string{"c", "d", "d"}); growth != nodeSize("d") {
+		t.Errorf("expected only the new server to grow, got %d bytes", growth)
+	}
+	if node, _ := restored.Allocate("d", now); node != 3 {
+		t.Errorf("expected a new node id, got %d", node)
+	}
+	if restored.Bytes() != a.Bytes()+nodeSize("d") {
+		t.Errorf("expected the snapshot to keep the bytes, got %d", restored.Bytes())
+	}
+
+	// Leases run out unless they are renewed.
+	renewing := a.Renewing([]string{"a", "e"}, now.Add(LeaseDuration/2+time.Millisecond))
+	if !reflect.DeepEqual(renewing, []string{"a", "e"}) {
+		t.Errorf("expected leases running out and missing ones to be renewed, got %v", renewing)
+	}
+	if _, ok := a.Node("a", now.Add(LeaseDuration)); ok {
+		t.Error("expected the lease to expire")
+	}
+}
+
+func TestReclaim(t *testing.T) {
+	now := Epoch.Add(time.Hour)
+	a := NewAllocator()
+	for i := 0; i < MaxNodes; i++ {
+		a.Allocate(fmt.Sprintf("server-%d", i), now)
+	}
+	if _, err := a.Allocate("new", now); err != ErrNodesExhausted {
+		t.Fatalf("expected %v, got %v", ErrNodesExhausted, err)
+	}
+
+	// Every server but two removed ones keeps its lease.
+	renewed := now.Add(LeaseDuration / 2)
+	for i := 0; i < MaxNodes; i++ {
+		if i != 5 && i != 9 {
+			a.Allocate(fmt.Sprintf("server-%d", i), renewed)
+		}
+	}
+	reclaimable := now.Add(2*LeaseDuration + MaxAhead)
+	if _, err := a.Allocate("new", reclaimable.Add(-time.Millisecond)); err != ErrNodesExhausted {
+		t.Errorf("expected node ids to be reclaimed only after their ids can no longer be generated, got %v", err)
+	}
+	if node, err := a.Allocate("new", reclaimable); err != nil || node != 5 {
+		t.Errorf("expected the lowest expired node id, got %d, error %v", node, err)
+	}
+	if node, err := a.Allocate("other", reclaimable); err != nil || node != 9 {
+		t.Errorf("expected the other expired node id, got %d, error %v", node, err)
+	}
+	if _, ok := a.Node("server-5", reclaimable); ok || a.Len() != MaxNodes {
+		t.Errorf("expected the lease of the removed server to be replaced, %d leases", a.Len())
+	}
+	if _, err := a.Allocate("last", reclaimable); err != ErrNodesExhausted {
+		t.Errorf("expected %v, got %v", ErrNodesExhausted, err)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	now := Epoch.Add(time.Hour)
+	before, _ := NewGenerator(7, now).Next(MaxBatch, now)
+
+	// A generator started right after has the same node id, but must not repeat the ids borrowed from the future.
+	restarted := NewGenerators(now).Get("orders", 7)
+	after, err := restarted.Next(1, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after[0] <= before[len(before)-1] {
+		t.Errorf("expected %d to be greater than the ids generated before the restart", after[0])
+	}
+	if _, err := restarted.Next(MaxBatch, now); err != ErrTooFarAhead {
+		t.Errorf("expected %v, got %v", ErrTooFarAhead, err)
+	}
+}
+
+func TestNext(t *testing.T) {
+	now := Epoch.Add(time.Hour)
+	g := NewGenerator(7, Epoch)
+
+	ids, err := g.Next(100, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The clock going back must not produce smaller ids.
+	more, err := g.Next(10, now.Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids = append(ids, more...)
+
+	for i, id := range ids {
+		if Node(id) != 7 {
+			t.Fatalf("expected node id 7 in %d, got %d", id, Node(id))
+		}
+		if i > 0 && id <= ids[i-1] {
+			t.Fatalf("expected increasing ids, got %d after %d", id, ids[i-1])
+		}
+	}
+	if got := Timestamp(ids[0]); !got.Equal(now) {
+		t.Errorf("expected the first id to be generated at %v, got %v", now, got)
+	}
+	if got := Timestamp(ids[len(ids)-1]); got.Sub(now) != time.Millisecond {
+		t.Errorf("expected 110 ids to borrow one millisecond, got %v", got.Sub(now))
+	}
+
+	if _, err := g.Next(MaxBatch, now); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		_, err = g.Next(MaxBatch, now)
+	}
+	if err != ErrTooFarAhead {
+		t.Errorf("expected %v, got %v", ErrTooFarAhead, err)
+	}
+}
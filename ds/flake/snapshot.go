@@ -0,0 +1,29 @@
+package flake
+
+import "encoding/json"
+
+// snapshot is the state of an Allocator in a snapshot.
+type snapshot struct {
+	Leases map[string]lease `json:"leases"`
+	Next   uint32           `json:"next"`
+}
+
+// MarshalJSON encodes the leases and the next node id never handed out for a snapshot.
+func (a *Allocator) MarshalJSON() ([]byte, error) {
+	return json.Marshal(snapshot{Leases: a.leases, Next: a.next})
+}
+
+// UnmarshalJSON replaces the leases with a snapshot.
+func (a *Allocator) UnmarshalJSON(data []byte) error {
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	a.leases, a.next, a.bytes = make(map[string]lease, len(s.Leases)), s.Next, 0
+	for server, l := range s.Leases {
+		a.leases[server] = l
+		a.bytes += nodeSize(server)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+// Package geo implements geo sets, which keep members with a position on earth and find them by distance.
+// Members are ordered by latitude, so that searches only look at the band of latitudes they can match.
+package geo
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// EarthRadius is the mean radius of the earth in meters, as used for distances.
+const EarthRadius = 6372797.560856
+
+var ErrInvalidPoint = errors.New("latitude must be between -90 and 90, longitude between -180 and 180")
+
+// Point is a position on earth in degrees.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Member is a named position of a geo set.
+type Member struct {
+	Member string `json:"member"`
+	Point
+}
+
+// Result is a member found by a search, with its distance from the center in meters.
+type Result struct {
+	Member
+	Distance float64 `json:"distance"`
+}
+
+// Validate checks that p is a position on earth.
+func (p Point) Validate() error {
+	if math.IsNaN(p.Lat) || math.IsNaN(p.Lon) || p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 {
+		return ErrInvalidPoint
+	}
+	return nil
+}
+
+// Distance returns the great-circle distance between two points in meters.
+func Distance(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	u := math.Sin((lat2 - lat1) / 2)
+	v := math.Sin(radians(b.Lon-a.Lon) / 2)
+	return 2 * EarthRadius * math.Asin(math.Sqrt(u*u+math.Cos(lat1)*math.Cos(lat2)*v*v))
+}
+
+type set struct {
+	members map[string]Point
+	// byLat holds the members ordered by latitude, then by name.
+	byLat []Member
+}
+
+// Sets holds all geo sets of a node.
+type Sets struct {
+	sets  map[string]*set
+	bytes int64
+}
+
+// New creates an empty collection of geo sets.
+func New() *Sets {
+	return &Sets{sets: make(map[string]*set)}
+}
+
+// EntrySize returns the number of bytes a member takes.
+func EntrySize(member string) int64 {
+	return int64(len(member)) + 16
+}
+
+// Add adds members to the set name or moves them, creating the set if needed. It returns the number of
+// members that were not in the set before.
+func (s *Sets) Add(name string, members []Member) (int, error) {
+	for _, m := range members {
+		if err := m.Validate(); err != nil {
+			return 0, err
+		}
+	}
+
+	st, ok := s.sets[name]
+	if !ok {
+		st = &set{members: make(map[string]Point)}
+		s.sets[name] = st
+	}
+
+	added := 0
+	for _, m := range members {
+		if previous, ok := st.members[m.Member]; ok {
+			st.remove(Member{Member: m.Member, Point: previous})
+		} else {
+			added++
+			s.bytes += EntrySize(m.Member)
+		}
+		st.members[m.Member] = m.Point
+		i := st.search(m.Lat, m.Member)
+		st.byLat = append(st.byLat, Member{})
+		copy(st.byLat[i+1:], st.byLat[i:])
+		st.byLat[i] = m
+	}
+	return added, nil
+}
+
+// Remove removes members from the set name and returns how many were in it. An empty set is removed.
+func (s *Sets) Remove(name string, members []string) int {
+	st, ok := s.sets[name]
+	if !ok {
+		return 0
+	}
+
+	removed := 0
+	for _, member := range members {
+		if p, ok := st.members[member]; ok {
+			st.remove(Member{Member: member, Point: p})
+			delete(st.members, member)
+			s.bytes -= EntrySize(member)
+			removed++
+		}
+	}
+	if len(st.members) == 0 {
+		delete(s.sets, name)
+	}
+	return removed
+}
+
+// Destroy removes the set name with all its members, and reports whether it existed.
+func (s *Sets) Destroy(name string) bool {
+	st, ok := s.sets[name]
+	if !ok {
+		return false
+	}
+	for member := range st.members {
+		s.bytes -= EntrySize(member)
+	}
+	delete(s.sets, name)
+	return true
+}
+
+// Position returns the position of a member of the set name.
+func (s *Sets) Position(name, member string) (Point, bool) {
+	st, ok := s.sets[name]
+	if !ok {
+		return Point{}, false
+	}
+	p, ok := st.members[member]
+	return p, ok
+}
+
+// Contains reports whether member is in the set name.
+func (s *Sets) Contains(name, member string) bool {
+	_, ok := s.Position(name, member)
+	return ok
+}
+
+// Len returns the number of members of the set name.
+func (s *Sets) Len(name string) int {
+	if st, ok := s.sets[name]; ok {
+		return len(st.members)
+	}
+	return 0
+}
+
+// Radius returns the members of the set name within radius meters of center, nearest first. A positive
+// limit returns only the nearest limit members.
+func (s *Sets) Radius(name string, center Point, radius float64, limit int) []Result {
+	return s.search(name, center, radius, limit, func(m Member, distance float64) bool {
+		return distance <= radius
+	})
+}
+
+// Box returns the members of the set name within a box of width by height meters centered on center,
+// nearest to the center first. A positive limit returns only the nearest limit members.
+func (s *Sets) Box(name string, center Point, width, height float64, limit int) []Result {
+	return s.search(name, center, height/2, limit, func(m Member, _ float64) bool {
+		latDistance := Distance(center, Point{Lat: m.Lat, Lon: center.Lon})
+		lonDistance := Distance(Point{Lat: m.Lat, Lon: center.Lon}, m.Point)
+		return latDistance <= height/2 && lonDistance <= width/2
+	})
+}
+
+// Bytes returns the number of bytes held by the members of all sets.
+func (s *Sets) Bytes() int64 {
+	return s.bytes
+}
+
+// search returns the members within reach meters north or south of center that match, nearest first.
+func (s *Sets) search(name string, center Point, reach float64, limit int,
+	match func(m Member, distance float64) bool) []Result {
+	st, ok := s.sets[name]
+	if !ok {
+		return nil
+	}
+
+	band := degrees(reach / EarthRadius)
+	var results []Result
+	for i := st.search(center.Lat-band, ""); i < len(st.byLat) && st.byLat[i].Lat <= center.Lat+band; i++ {
+		m := st.byLat[i]
+		if distance := Distance(center, m.Point); match(m, distance) {
+			results = append(results, Result{Member: m, Distance: distance})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Distance != results[j].Distance {
+			return results[i].Distance < results[j].Distance
+		}
+		return results[i].Member.Member < results[j].Member.Member
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// search returns the position of the first member at or after lat and member in latitude order.
+func (st *set) search(lat float64, member string) int {
+	return sort.Search(len(st.byLat), func(i int) bool {
+		m := st.byLat[i]
+		return m.Lat > lat || m.Lat == lat && m.Member >= member
+	})
+}
+
+func (st *set) remove(m Member) {
+	i := st.search(m.Lat, m.Member)
+	st.byLat = append(st.byLat[:i], st.byLat[i+1:]...)
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func degrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
@@ -0,0 +1,82 @@
+package geo
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+var (
+	palermo = Member{Member: "palermo", Point: Point{Lat: 38.115556, Lon: 13.361389}}
+	catania = Member{Member: "catania", Point: Point{Lat: 37.502669, Lon: 15.087269}}
+	rome    = Member{Member: "rome", Point: Point{Lat: 41.902782, Lon: 12.496366}}
+)
+
+func names(results []Result) []string {
+	var result []string
+	for _, r := range results {
+		result = append(result, r.Member.Member)
+	}
+	return result
+}
+
+func TestDistance(t *testing.T) {
+	if d := Distance(palermo.Point, catania.Point); math.Abs(d-166274.15) > 1 {
+		t.Errorf("expected about 166274m between palermo and catania, got %f", d)
+	}
+	if err := (Point{Lat: 91}).Validate(); err != ErrInvalidPoint {
+		t.Errorf("expected %v, got %v", ErrInvalidPoint, err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	s := New()
+	if added, err := s.Add("cities", []Member{palermo, catania, rome}); err != nil || added != 3 {
+		t.Fatalf("unexpected added %d, error %v", added, err)
+	}
+
+	center := Point{Lat: 38, Lon: 15}
+	if got := names(s.Radius("cities", center, 200000, 0)); !reflect.DeepEqual(got, []string{"catania", "palermo"}) {
+		t.Errorf("unexpected radius result %v", got)
+	}
+	if got := names(s.Radius("cities", center, 600000, 1)); !reflect.DeepEqual(got, []string{"catania"}) {
+		t.Errorf("unexpected limited radius result %v", got)
+	}
+	if got := names(s.Box("cities", center, 400000, 400000, 0)); !reflect.DeepEqual(got, []string{"catania", "palermo"}) {
+		t.Errorf("unexpected box result %v", got)
+	}
+	if got := names(s.Box("cities", center, 400000, 20000, 0)); got != nil {
+		t.Errorf("expected a flat box to find nothing, got %v", got)
+	}
+
+	moved := Member{Member: "rome", Point: Point{Lat: 38.1, Lon: 15.1}}
+	if added, _ := s.Add("cities", []Member{moved}); added != 0 || s.Len("cities") != 3 {
+		t.Errorf("expected a moved member not to be added")
+	}
+	if got := names(s.Radius("cities", center, 200000, 0)); len(got) != 3 {
+		t.Errorf("expected the moved member to be found at its new position, got %v", got)
+	}
+
+	if removed := s.Remove("cities", []string{"rome", "missing"}); removed != 1 {
+		t.Errorf("expected 1 removed member, got %d", removed)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	s := New()
+	s.Add("cities", []Member{palermo, catania})
+
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := New()
+	if err := json.Unmarshal(encoded, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if p, ok := restored.Position("cities", "palermo"); !ok || p != palermo.Point || restored.Bytes() != s.Bytes() {
+		t.Errorf("expected the snapshot to keep all members, got %v", p)
+	}
+}
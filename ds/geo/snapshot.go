@@ -0,0 +1,35 @@
+package geo
+
+import "encoding/json"
+
+// MarshalJSON encodes the members of all sets with their positions for a snapshot.
+func (s *Sets) MarshalJSON() ([]byte, error) {
+	sets := make(map[string]map[string]Point, len(s.sets))
+	for name, st := range s.sets {
+		sets[name] = st.members
+	}
+
+	return json.Marshal(sets)
+}
+
+// UnmarshalJSON replaces all sets with a snapshot.
+func (s *Sets) UnmarshalJSON(data []byte) error {
+	var sets map[string]map[string]Point
+	if err := json.Unmarshal(data, &sets); err != nil {
+		return err
+	}
+
+	s.sets = make(map[string]*set, len(sets))
+	s.bytes = 0
+	for name, members := range sets {
+		list := make([]Member, 0, len(members))
+		for member, p := range members {
+			list = append(list, Member{Member: member, Point: p})
+		}
+		if _, err := s.Add(name, list); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
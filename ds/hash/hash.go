@@ -0,0 +1,29 @@
+// Package hash provides the hash functions used by the probabilistic data structures. They must never
+// change, because sketches and filters restored from snapshots rely on them.
+package hash
+
+import "hash/fnv"
+
+// Sum64 returns a 64 bit hash of data. FNV-1a is finalized with the mixer of MurmurHash3, so that every
+// bit of the result depends on every bit of data.
+func Sum64(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+	return mix(h.Sum64())
+}
+
+// Pair returns two independent 64 bit hashes of data, to derive any number of hashes from as h1 + i*h2.
+func Pair(data []byte) (uint64, uint64) {
+	h1 := Sum64(data)
+	h2 := mix(h1 ^ 0x9e3779b97f4a7c15)
+	return h1, h2 | 1
+}
+
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
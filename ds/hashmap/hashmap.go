@@ -1,13 +1,47 @@
 package hashmap
 
+import "sort"
+
 type HashMap struct {
-	data map[string]map[string][]byte
+	data      map[string]map[string][]byte
+	versions  map[string]map[string]uint64
+	version   uint64
+	removed   map[string]uint64
+	sizes     map[string]int64
+	bytes     int64
+	listeners []Listener
 }
 
+// Kind is the kind of a change of an entry.
+type Kind string
+
+const (
+	Put    Kind = "put"
+	Remove Kind = "remove"
+	// Evict is the removal of an entry to free memory.
+	Evict Kind = "evict"
+)
+
+// Event describes a change of an entry. Old is the value before the change, and Value the value after it,
+// which is nil for removals.
+type Event struct {
+	Kind  Kind
+	Name  string
+	Key   string
+	Old   []byte
+	Value []byte
+}
+
+// Listener is notified after an entry of a map changes.
+type Listener func(e Event)
+
 // New creates a new hashmap.
 func New() *HashMap {
 	return &HashMap{
-		data: make(map[string]map[string][]byte),
+		data:     make(map[string]map[string][]byte),
+		versions: make(map[string]map[string]uint64),
+		removed:  make(map[string]uint64),
+		sizes:    make(map[string]int64),
 	}
 }
 
@@ -15,14 +49,20 @@ func New() *HashMap {
 // It returns zero if there is already a value at specified key, returns 1 otherwise.
 func (h *HashMap) Put(name, key string, value []byte) (result int) {
 	if !h.exists(name) {
-		h.data[name] = make(map[string][]byte)
+		h.create(name)
 	}
 
-	if _, ok := h.data[name][key]; !ok {
+	old, ok := h.data[name][key]
+	if !ok {
 		result = 1
+	} else {
+		h.account(name, -size(key, old))
 	}
 
 	h.data[name][key] = value
+	h.versions[name][key] = h.version
+	h.account(name, size(key, value))
+	h.notify(Event{Kind: Put, Name: name, Key: key, Old: old, Value: value})
 
 	return result
 }
@@ -40,11 +80,14 @@ func (h *HashMap) Get(name, key string) []byte {
 // It returns zero if there is already a value at specified key, returns 1 otherwise.
 func (h *HashMap) PutIfAbsent(name, key string, value []byte) (result int) {
 	if !h.exists(name) {
-		h.data[name] = make(map[string][]byte)
+		h.create(name)
 	}
 
 	if _, ok := h.data[name][key]; !ok {
 		h.data[name][key] = value
+		h.versions[name][key] = h.version
+		h.account(name, size(key, value))
+		h.notify(Event{Kind: Put, Name: name, Key: key, Value: value})
 		result = 1
 	}
 
@@ -54,14 +97,26 @@ func (h *HashMap) PutIfAbsent(name, key string, value []byte) (result int) {
 // Remove removes value specified by key from a map. It ignores if key is not in the map.
 // It returns 1 if removal is successful, returns 0 otherwise.
 func (h *HashMap) Remove(name, key string) int {
+	return h.remove(name, key, Remove)
+}
+
+// Evict removes the entry at key to free memory. It returns 1 if the entry existed, returns 0 otherwise.
+func (h *HashMap) Evict(name, key string) int {
+	return h.remove(name, key, Evict)
+}
+
+func (h *HashMap) remove(name, key string, kind Kind) int {
 	if !h.exists(name) {
 		return 0
 	}
 
-	if _, ok := h.data[name][key]; ok {
+	if value, ok := h.data[name][key]; ok {
 		delete(h.data[name], key)
+		delete(h.versions[name], key)
+		h.removed[name] = h.version
+		h.account(name, -size(key, value))
+		h.notify(Event{Kind: kind, Name: name, Key: key, Old: value})
 		return 1
-
 	}
 
 	return 0
@@ -73,14 +128,134 @@ func (h *HashMap) Clear(name string) int {
 	if !h.exists(name) {
 		return 0
 	}
+	entries := h.data[name]
 	delete(h.data, name)
+	delete(h.versions, name)
+	h.removed[name] = h.version
+	h.bytes -= h.sizes[name]
+	delete(h.sizes, name)
+
+	if len(h.listeners) > 0 {
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			h.notify(Event{Kind: Remove, Name: name, Key: key, Old: entries[key]})
+		}
+	}
 
 	return 1
 }
 
+// Range calls fn for every entry of a map until fn returns false.
+func (h *HashMap) Range(name string, fn func(key string, value []byte) bool) {
+	for key, value := range h.data[name] {
+		if !fn(key, value) {
+			return
+		}
+	}
+}
+
+// Keys returns the keys of a map in lexical order.
+func (h *HashMap) Keys(name string) []string {
+	keys := make([]string, 0, len(h.data[name]))
+	for key := range h.data[name] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Version returns the version of the entry at key, or zero if it does not exist.
+func (h *HashMap) Version(name, key string) uint64 {
+	return h.versions[name][key]
+}
+
+// Changed returns the version of the last write or removal that may have changed the entry at key: its version
+// if it exists, otherwise the version of the last removal of any entry of the map.
+func (h *HashMap) Changed(name, key string) uint64 {
+	if version, ok := h.versions[name][key]; ok {
+		return version
+	}
+	return h.removed[name]
+}
+
+// SetVersion sets the version of the entries written from now on. Versions are the raft log indexes
+// of the commands writing the entries, so they only grow and are equal on every replica.
+func (h *HashMap) SetVersion(version uint64) {
+	h.version = version
+}
+
+// EntrySize returns the number of bytes accounted for the entry at key, and whether the entry exists.
+func (h *HashMap) EntrySize(name, key string) (int64, bool) {
+	if !h.exists(name) {
+		return 0, false
+	}
+
+	value, ok := h.data[name][key]
+	if !ok {
+		return 0, false
+	}
+	return size(key, value), true
+}
+
+// Size returns the number of bytes held by the keys and values of a map.
+func (h *HashMap) Size(name string) int64 {
+	return h.sizes[name]
+}
+
+// Len returns the number of entries of a map.
+func (h *HashMap) Len(name string) int {
+	return len(h.data[name])
+}
+
+// Bytes returns the number of bytes held by the keys and values of all maps.
+func (h *HashMap) Bytes() int64 {
+	return h.bytes
+}
+
+// Listen registers l to be notified of every change of every map.
+func (h *HashMap) Listen(l Listener) {
+	h.listeners = append(h.listeners, l)
+}
+
+// Names returns the names of all maps in lexical order.
+func (h *HashMap) Names() []string {
+	names := make([]string, 0, len(h.data))
+	for name := range h.data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (h *HashMap) create(name string) {
+	h.data[name] = make(map[string][]byte)
+	h.versions[name] = make(map[string]uint64)
+}
+
+func (h *HashMap) notify(e Event) {
+	for _, l := range h.listeners {
+		l(e)
+	}
+}
+
+func (h *HashMap) account(name string, delta int64) {
+	h.sizes[name] += delta
+	h.bytes += delta
+}
+
 func (h *HashMap) exists(key string) bool {
 	if _, ok := h.data[key]; ok {
 		return true
 	}
 	return false
 }
+
+func size(key string, value []byte) int64 {
+	return int64(len(key) + len(value))
+}
@@ -0,0 +1,94 @@
+package hashmap
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSizes(t *testing.T) {
+	h := New()
+	h.Put("users", "a", []byte("1234"))
+	h.Put("users", "a", []byte("12"))
+	h.PutIfAbsent("users", "b", []byte("123"))
+	h.PutIfAbsent("users", "b", []byte("123456"))
+	h.Put("sessions", "c", []byte("1"))
+
+	if size, ok := h.EntrySize("users", "a"); !ok || size != 3 {
+		t.Errorf("expected entry a to hold 3 bytes, got %d", size)
+	}
+	if h.Size("users") != 7 || h.Bytes() != 9 {
+		t.Errorf("expected 7 bytes in users and 9 in total, got %d and %d", h.Size("users"), h.Bytes())
+	}
+	if h.Len("users") != 2 || h.Len("missing") != 0 {
+		t.Errorf("expected 2 entries in users, got %d", h.Len("users"))
+	}
+
+	h.Remove("users", "a")
+	h.Evict("users", "b")
+	h.Clear("sessions")
+	if h.Size("users") != 0 || h.Bytes() != 0 {
+		t.Errorf("expected no bytes to be left, got %d and %d", h.Size("users"), h.Bytes())
+	}
+	if _, ok := h.EntrySize("users", "a"); ok {
+		t.Error("expected removed entries to have no size")
+	}
+}
+
+func TestVersions(t *testing.T) {
+	h := New()
+	h.SetVersion(3)
+	h.Put("users", "a", []byte("1"))
+	h.SetVersion(5)
+	h.PutIfAbsent("users", "a", []byte("2"))
+	h.PutIfAbsent("users", "b", []byte("2"))
+
+	if h.Version("users", "a") != 3 || h.Version("users", "b") != 5 {
+		t.Errorf("unexpected versions %d and %d", h.Version("users", "a"), h.Version("users", "b"))
+	}
+
+	h.SetVersion(7)
+	h.Put("users", "a", []byte("3"))
+	h.Remove("users", "b")
+	if h.Version("users", "a") != 7 || h.Version("users", "b") != 0 {
+		t.Errorf("unexpected versions %d and %d", h.Version("users", "a"), h.Version("users", "b"))
+	}
+	if h.Changed("users", "a") != 7 || h.Changed("users", "b") != 7 || h.Changed("users", "c") != 7 {
+		t.Error("expected removals to change every absent entry of the map")
+	}
+
+	h.SetVersion(9)
+	h.Clear("users")
+	restored := New()
+	data, _ := h.MarshalJSON()
+	if err := restored.UnmarshalJSON(data); err != nil {
+		t.Fatalf("failed to restore %v", err)
+	}
+	if restored.Changed("users", "a") != 9 {
+		t.Errorf("expected the clear to be kept in snapshots, got %d", restored.Changed("users", "a"))
+	}
+}
+
+func TestEvents(t *testing.T) {
+	h := New()
+	var events []Event
+	h.Listen(func(e Event) {
+		events = append(events, e)
+	})
+
+	h.Put("users", "a", []byte("1"))
+	h.Put("users", "a", []byte("2"))
+	h.Evict("users", "a")
+	h.Put("users", "b", []byte("3"))
+	h.Clear("users")
+
+	expected := []Event{
+		{Kind: Put, Name: "users", Key: "a", Value: []byte("1")},
+		{Kind: Put, Name: "users", Key: "a", Old: []byte("1"), Value: []byte("2")},
+		{Kind: Evict, Name: "users", Key: "a", Old: []byte("2")},
+		{Kind: Put, Name: "users", Key: "b", Value: []byte("3")},
+		{Kind: Remove, Name: "users", Key: "b", Old: []byte("3")},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+}
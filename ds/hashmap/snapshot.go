@@ -0,0 +1,41 @@
+package hashmap
+
+import "encoding/json"
+
+type snapshot struct {
+	Data     map[string]map[string][]byte `json:"data"`
+	Versions map[string]map[string]uint64 `json:"versions"`
+	Removed  map[string]uint64            `json:"removed,omitempty"`
+}
+
+// MarshalJSON encodes the entries of all maps, their versions and the versions of the last removals for a snapshot.
+func (h *HashMap) MarshalJSON() ([]byte, error) {
+	return json.Marshal(snapshot{Data: h.data, Versions: h.versions, Removed: h.removed})
+}
+
+// UnmarshalJSON replaces the entries of all maps with a snapshot. Listeners are kept but not notified.
+func (h *HashMap) UnmarshalJSON(data []byte) error {
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	h.data = make(map[string]map[string][]byte, len(s.Data))
+	h.versions = make(map[string]map[string]uint64, len(s.Data))
+	h.removed = make(map[string]uint64, len(s.Removed))
+	h.sizes = make(map[string]int64)
+	h.bytes = 0
+	for name, values := range s.Data {
+		h.create(name)
+		for key, value := range values {
+			h.data[name][key] = value
+			h.versions[name][key] = s.Versions[name][key]
+			h.account(name, size(key, value))
+		}
+	}
+	for name, version := range s.Removed {
+		h.removed[name] = version
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+// Package hll implements HyperLogLog sketches, which estimate the number of distinct elements added to them
+// in a fixed amount of memory, with a standard error of about 0.8%.
+package hll
+
+import (
+	"math"
+	"math/bits"
+
+	"github.com/huseyinbabal/demory/ds/hash"
+)
+
+const (
+	// Precision is the number of hash bits that select a register.
+	Precision = 14
+	// Registers is the number of registers of a sketch, which is also its size in bytes.
+	Registers = 1 << Precision
+)
+
+type registers []uint8
+
+// Sketches holds all HyperLogLog sketches of a node.
+type Sketches struct {
+	sketches map[string]registers
+}
+
+// New creates an empty set of sketches.
+func New() *Sketches {
+	return &Sketches{sketches: make(map[string]registers)}
+}
+
+// Add adds elements to the sketch name, creating it if needed. It reports whether the estimate may have
+// changed, which is false if all elements were seen before.
+func (s *Sketches) Add(name string, elements [][]byte) bool {
+	r, ok := s.sketches[name]
+	if !ok {
+		r = make(registers, Registers)
+		s.sketches[name] = r
+	}
+
+	changed := !ok
+	for _, element := range elements {
+		h := hash.Sum64(element)
+		index := h >> (64 - Precision)
+		rank := uint8(bits.LeadingZeros64(h<<Precision|1<<(Precision-1))) + 1
+		if rank > r[index] {
+			r[index] = rank
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// Count returns the estimated number of distinct elements added to the union of the named sketches.
+// Sketches that do not exist count as empty.
+func (s *Sketches) Count(names ...string) uint64 {
+	union := make(registers, Registers)
+	for _, name := range names {
+		union.merge(s.sketches[name])
+	}
+
+	return union.estimate()
+}
+
+// Merge sets the sketch dest to the union of itself and the sources, creating it if needed. Sources that do
+// not exist are ignored.
+func (s *Sketches) Merge(dest string, sources []string) {
+	r, ok := s.sketches[dest]
+	if !ok {
+		r = make(registers, Registers)
+		s.sketches[dest] = r
+	}
+
+	for _, source := range sources {
+		r.merge(s.sketches[source])
+	}
+}
+
+// Destroy removes the sketch name, and reports whether it existed.
+func (s *Sketches) Destroy(name string) bool {
+	if _, ok := s.sketches[name]; !ok {
+		return false
+	}
+	delete(s.sketches, name)
+	return true
+}
+
+// Exists reports whether the sketch name exists.
+func (s *Sketches) Exists(name string) bool {
+	_, ok := s.sketches[name]
+	return ok
+}
+
+// Bytes returns the number of bytes held by the registers of all sketches.
+func (s *Sketches) Bytes() int64 {
+	return int64(len(s.sketches)) * Registers
+}
+
+func (r registers) merge(other registers) {
+	for i, rank := range other {
+		if rank > r[i] {
+			r[i] = rank
+		}
+	}
+}
+
+func (r registers) estimate() uint64 {
+	m := float64(Registers)
+	var sum float64
+	zeros := 0
+	for _, rank := range r {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// Linear counting is more accurate while many registers are still empty.
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(estimate + 0.5)
+}
@@ -0,0 +1,69 @@
+package hll
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func elements(prefix string, n int) [][]byte {
+	result := make([][]byte, n)
+	for i := range result {
+		result[i] = []byte(fmt.Sprintf("%s-%d", prefix, i))
+	}
+	return result
+}
+
+func within(estimate uint64, expected int, tolerance float64) bool {
+	return math.Abs(float64(estimate)-float64(expected)) <= tolerance*float64(expected)
+}
+
+func TestCount(t *testing.T) {
+	s := New()
+	if s.Count("visitors") != 0 {
+		t.Errorf("expected an empty sketch to count 0")
+	}
+
+	if !s.Add("visitors", elements("user", 100)) {
+		t.Errorf("expected new elements to change the sketch")
+	}
+	if s.Add("visitors", elements("user", 100)) {
+		t.Errorf("expected seen elements not to change the sketch")
+	}
+	if got := s.Count("visitors"); !within(got, 100, 0.02) {
+		t.Errorf("expected about 100, got %d", got)
+	}
+
+	s.Add("visitors", elements("user", 100000))
+	if got := s.Count("visitors"); !within(got, 100000, 0.03) {
+		t.Errorf("expected about 100000, got %d", got)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	s := New()
+	s.Add("monday", elements("user", 6000))
+	s.Add("tuesday", elements("user", 10000)[4000:])
+
+	if got := s.Count("monday", "tuesday"); !within(got, 10000, 0.03) {
+		t.Errorf("expected the union to count about 10000, got %d", got)
+	}
+
+	s.Merge("week", []string{"monday", "tuesday", "missing"})
+	if got, union := s.Count("week"), s.Count("monday", "tuesday"); got != union {
+		t.Errorf("expected the merged sketch to count %d, got %d", union, got)
+	}
+
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := New()
+	if err := json.Unmarshal(encoded, restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Count("week") != s.Count("week") || restored.Bytes() != 3*Registers {
+		t.Errorf("expected the snapshot to keep all sketches")
+	}
+}
@@ -0,0 +1,34 @@
+package hll
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalJSON encodes the registers of all sketches for a snapshot.
+func (s *Sketches) MarshalJSON() ([]byte, error) {
+	sketches := make(map[string][]byte, len(s.sketches))
+	for name, r := range s.sketches {
+		sketches[name] = r
+	}
+
+	return json.Marshal(sketches)
+}
+
+// UnmarshalJSON replaces all sketches with a snapshot.
+func (s *Sketches) UnmarshalJSON(data []byte) error {
+	var sketches map[string][]byte
+	if err := json.Unmarshal(data, &sketches); err != nil {
+		return err
+	}
+
+	s.sketches = make(map[string]registers, len(sketches))
+	for name, r := range sketches {
+		if len(r) != Registers {
+			return fmt.Errorf("sketch %s has %d registers, expected %d", name, len(r), Registers)
+		}
+		s.sketches[name] = r
+	}
+
+	return nil
+}
@@ -1 +1,174 @@
+// Package list implements named lists of values, which are double ended queues: values are pushed and popped
+// at both ends in constant time, and read by their position.
 package list
+
+// list keeps the values before its first pushed one in front, in reverse order, and the others in back, so
+// that pushing at either end appends to a slice.
+type list struct {
+	front [][]byte
+	back  [][]byte
+}
+
+// Lists holds all lists of a node.
+type Lists struct {
+	lists map[string]*list
+	bytes int64
+}
+
+// New creates an empty set of lists.
+func New() *Lists {
+	return &Lists{lists: make(map[string]*list)}
+}
+
+// Push adds values to the head or the tail of the list name, one after the other, and returns its length.
+// Values pushed to the head end up in reverse order.
+func (l *Lists) Push(name string, values [][]byte, head bool) int {
+	li, ok := l.lists[name]
+	if !ok {
+		li = &list{}
+		l.lists[name] = li
+		l.bytes += int64(len(name))
+	}
+
+	for _, value := range values {
+		if head {
+			li.front = append(li.front, value)
+		} else {
+			li.back = append(li.back, value)
+		}
+		l.bytes += int64(len(value))
+	}
+	return li.len()
+}
+
+// Pop removes up to count values from the head or the tail of the list name and returns them in the order
+// they were removed. A list left without values is removed.
+func (l *Lists) Pop(name string, count int, head bool) [][]byte {
+	li, ok := l.lists[name]
+	if !ok {
+		return nil
+	}
+
+	if n := li.len(); count > n {
+		count = n
+	}
+	popped := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		var value []byte
+		if head {
+			value = li.popFront()
+		} else {
+			value = li.popBack()
+		}
+		popped = append(popped, value)
+		l.bytes -= int64(len(value))
+	}
+
+	if li.len() == 0 {
+		delete(l.lists, name)
+		l.bytes -= int64(len(name))
+	}
+	return popped
+}
+
+// Len returns the number of values of the list name.
+func (l *Lists) Len(name string) int {
+	if li, ok := l.lists[name]; ok {
+		return li.len()
+	}
+	return 0
+}
+
+// Index returns the value at position i of the list name, and whether there is one.
+func (l *Lists) Index(name string, i int) ([]byte, bool) {
+	li, ok := l.lists[name]
+	if !ok || i < 0 || i >= li.len() {
+		return nil, false
+	}
+	return li.at(i), true
+}
+
+// Range returns the values of the list name from position start to position stop, both included.
+func (l *Lists) Range(name string, start, stop int) [][]byte {
+	li, ok := l.lists[name]
+	if !ok {
+		return nil
+	}
+	if start < 0 {
+		start = 0
+	}
+	if n := li.len(); stop >= n {
+		stop = n - 1
+	}
+
+	var values [][]byte
+	for i := start; i <= stop; i++ {
+		values = append(values, li.at(i))
+	}
+	return values
+}
+
+// Destroy removes the list name with all its values, and reports whether it existed.
+func (l *Lists) Destroy(name string) bool {
+	li, ok := l.lists[name]
+	if !ok {
+		return false
+	}
+	l.bytes -= int64(len(name))
+	for _, value := range li.values() {
+		l.bytes -= int64(len(value))
+	}
+	delete(l.lists, name)
+	return true
+}
+
+// Bytes returns the number of bytes held by the names and the values of all lists.
+func (l *Lists) Bytes() int64 {
+	return l.bytes
+}
+
+func (li *list) len() int {
+	return len(li.front) + len(li.back)
+}
+
+func (li *list) at(i int) []byte {
+	if i < len(li.front) {
+		return li.front[len(li.front)-1-i]
+	}
+	return li.back[i-len(li.front)]
+}
+
+func (li *list) popFront() []byte {
+	if n := len(li.front); n > 0 {
+		value := li.front[n-1]
+		li.front[n-1] = nil
+		li.front = li.front[:n-1]
+		return value
+	}
+	value := li.back[0]
+	li.back[0] = nil
+	li.back = li.back[1:]
+	return value
+}
+
+func (li *list) popBack() []byte {
+	if n := len(li.back); n > 0 {
+		value := li.back[n-1]
+		li.back[n-1] = nil
+		li.back = li.back[:n-1]
+		return value
+	}
+	value := li.front[0]
+	li.front[0] = nil
+	li.front = li.front[1:]
+	return value
+}
+
+// values returns the values of the list in order.
+func (li *list) values() [][]byte {
+	values := make([][]byte, 0, li.len())
+	for i := 0; i < li.len(); i++ {
+		values = append(values, li.at(i))
+	}
+	return values
+}
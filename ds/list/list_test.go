@@ -0,0 +1,72 @@
+package list
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func texts(values [][]byte) []string {
+	var result []string
+	for _, v := range values {
+		result = append(result, string(v))
+	}
+	return result
+}
+
+func raw(values ...string) [][]byte {
+	var result [][]byte
+	for _, v := range values {
+		result = append(result, []byte(v))
+	}
+	return result
+}
+
+func TestPushPop(t *testing.T) {
+	l := New()
+	l.Push("jobs", raw("c", "d"), false)
+	if n := l.Push("jobs", raw("b", "a"), true); n != 4 {
+		t.Fatalf("expected 4 values, got %d", n)
+	}
+	if values := l.Range("jobs", 0, 10); !reflect.DeepEqual(texts(values), []string{"a", "b", "c", "d"}) {
+		t.Errorf("unexpected values %v", texts(values))
+	}
+	if value, ok := l.Index("jobs", 2); !ok || string(value) != "c" {
+		t.Errorf("unexpected value %q at 2", value)
+	}
+
+	// Popping past the end of one side takes the values of the other.
+	if popped := l.Pop("jobs", 3, false); !reflect.DeepEqual(texts(popped), []string{"d", "c", "b"}) {
+		t.Errorf("unexpected popped values %v", texts(popped))
+	}
+	if popped := l.Pop("jobs", 5, true); !reflect.DeepEqual(texts(popped), []string{"a"}) {
+		t.Errorf("unexpected popped values %v", texts(popped))
+	}
+	if l.Len("jobs") != 0 || l.Bytes() != 0 {
+		t.Errorf("expected the empty list to be removed, %d bytes left", l.Bytes())
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	l := New()
+	l.Push("jobs", raw("b", "c"), false)
+	l.Push("jobs", raw("a"), true)
+	l.Push("done", raw("x"), false)
+	l.Destroy("done")
+
+	data, err := json.Marshal(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := New()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if values := restored.Range("jobs", 0, 2); !reflect.DeepEqual(texts(values), []string{"a", "b", "c"}) {
+		t.Errorf("unexpected values %v", texts(values))
+	}
+	if restored.Bytes() != l.Bytes() || l.Bytes() != int64(len("jobs")+3) {
+		t.Errorf("expected %d bytes, got %d", l.Bytes(), restored.Bytes())
+	}
+}
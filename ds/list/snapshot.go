@@ -0,0 +1,29 @@
+package list
+
+import "encoding/json"
+
+// MarshalJSON encodes the values of all lists in order for a snapshot.
+func (l *Lists) MarshalJSON() ([]byte, error) {
+	lists := make(map[string][][]byte, len(l.lists))
+	for name, li := range l.lists {
+		lists[name] = li.values()
+	}
+	return json.Marshal(lists)
+}
+
+// UnmarshalJSON replaces all lists with a snapshot.
+func (l *Lists) UnmarshalJSON(data []byte) error {
+	var lists map[string][][]byte
+	if err := json.Unmarshal(data, &lists); err != nil {
+		return err
+	}
+
+	l.lists = make(map[string]*list, len(lists))
+	l.bytes = 0
+	for name, values := range lists {
+		if len(values) > 0 {
+			l.Push(name, values, false)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// Package multimap implements named maps holding many values per key. The values of a key either form a set,
+// where adding a value twice keeps one copy, or a list, where values are kept in the order they were added
+// with their duplicates.
+package multimap
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	ErrMode        = errors.New("key already holds values with another mode")
+	ErrUnknownMode = errors.New("unknown multimap mode")
+)
+
+// Mode is the semantics of the values of a key.
+type Mode string
+
+const (
+	// Set keeps distinct values. It is the default.
+	Set Mode = "set"
+	// List keeps every value added, duplicates included, in order.
+	List Mode = "list"
+)
+
+// ValidateMode returns an error if mode is not a known mode. The empty mode is Set.
+func ValidateMode(mode Mode) error {
+	if mode != "" && mode != Set && mode != List {
+		return ErrUnknownMode
+	}
+	return nil
+}
+
+type values struct {
+	mode   Mode
+	values [][]byte
+	// members counts the values of a set, to find duplicates without scanning.
+	members map[string]struct{}
+}
+
+// MultiMap holds all multimaps of a node.
+type MultiMap struct {
+	maps  map[string]map[string]*values
+	bytes int64
+}
+
+// New creates an empty set of multimaps.
+func New() *MultiMap {
+	return &MultiMap{maps: make(map[string]map[string]*values)}
+}
+
+// Put adds values to key of the multimap name and returns the number of values added. The mode of a key is
+// set by its first put, and later puts must use the same one. An empty mode is Set for new keys and the
+// current mode of existing ones.
+func (m *MultiMap) Put(name, key string, added [][]byte, mode Mode) (int, error) {
+	if err := ValidateMode(mode); err != nil {
+		return 0, err
+	}
+
+	v, ok := m.maps[name][key]
+	if ok && mode != "" && mode != v.mode {
+		return 0, ErrMode
+	}
+	if !ok {
+		if mode == "" {
+			mode = Set
+		}
+		v = &values{mode: mode}
+		if mode == Set {
+			v.members = make(map[string]struct{})
+		}
+		if m.maps[name] == nil {
+			m.maps[name] = make(map[string]*values)
+		}
+		m.maps[name][key] = v
+		m.bytes += int64(len(key))
+	}
+
+	count := 0
+	for _, value := range added {
+		if v.mode == Set {
+			if _, ok := v.members[string(value)]; ok {
+				continue
+			}
+			v.members[string(value)] = struct{}{}
+		}
+		v.values = append(v.values, value)
+		m.bytes += int64(len(value))
+		count++
+	}
+
+	return count, nil
+}
+
+// Get returns the values of key in the multimap name, in the order they were added, and their mode.
+func (m *MultiMap) Get(name, key string) ([][]byte, Mode) {
+	v, ok := m.maps[name][key]
+	if !ok {
+		return nil, ""
+	}
+	return append([][]byte(nil), v.values...), v.mode
+}
+
+// Contains reports whether key of the multimap name holds value.
+func (m *MultiMap) Contains(name, key string, value []byte) bool {
+	v, ok := m.maps[name][key]
+	if !ok {
+		return false
+	}
+	return v.index(value) >= 0
+}
+
+// Remove removes the first occurrence of value from key of the multimap name, and reports whether it was
+// found. A key left without values is removed.
+func (m *MultiMap) Remove(name, key string, value []byte) bool {
+	v, ok := m.maps[name][key]
+	if !ok {
+		return false
+	}
+
+	i := v.index(value)
+	if i < 0 {
+		return false
+	}
+	v.values = append(v.values[:i], v.values[i+1:]...)
+	if v.mode == Set {
+		delete(v.members, string(value))
+	}
+	m.bytes -= int64(len(value))
+
+	if len(v.values) == 0 {
+		m.RemoveAll(name, key)
+	}
+	return true
+}
+
+// RemoveAll removes key of the multimap name with all its values, and returns the number of values removed.
+func (m *MultiMap) RemoveAll(name, key string) int {
+	v, ok := m.maps[name][key]
+	if !ok {
+		return 0
+	}
+
+	m.bytes -= int64(len(key))
+	for _, value := range v.values {
+		m.bytes -= int64(len(value))
+	}
+	delete(m.maps[name], key)
+	if len(m.maps[name]) == 0 {
+		delete(m.maps, name)
+	}
+	return len(v.values)
+}
+
+// Count returns the number of values of key in the multimap name.
+func (m *MultiMap) Count(name, key string) int {
+	if v, ok := m.maps[name][key]; ok {
+		return len(v.values)
+	}
+	return 0
+}
+
+// Keys returns the keys of the multimap name, sorted.
+func (m *MultiMap) Keys(name string) []string {
+	keys := make([]string, 0, len(m.maps[name]))
+	for key := range m.maps[name] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Destroy removes the multimap name with all its keys, and reports whether it existed.
+func (m *MultiMap) Destroy(name string) bool {
+	keys, ok := m.maps[name]
+	if !ok {
+		return false
+	}
+	for key := range keys {
+		m.RemoveAll(name, key)
+	}
+	return true
+}
+
+// Exists reports whether key of the multimap name holds values.
+func (m *MultiMap) Exists(name, key string) bool {
+	_, ok := m.maps[name][key]
+	return ok
+}
+
+// Bytes returns the number of bytes held by the keys and the values of all multimaps.
+func (m *MultiMap) Bytes() int64 {
+	return m.bytes
+}
+
+func (v *values) index(value []byte) int {
+	if v.mode == Set {
+		if _, ok := v.members[string(value)]; !ok {
+			return -1
+		}
+	}
+	for i, current := range v.values {
+		if string(current) == string(value) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,81 @@
+package multimap
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func texts(values [][]byte) []string {
+	var result []string
+	for _, v := range values {
+		result = append(result, string(v))
+	}
+	return result
+}
+
+func raw(values ...string) [][]byte {
+	var result [][]byte
+	for _, v := range values {
+		result = append(result, []byte(v))
+	}
+	return result
+}
+
+func TestModes(t *testing.T) {
+	m := New()
+	if added, err := m.Put("tags", "post", raw("go", "db", "go"), ""); err != nil || added != 2 {
+		t.Fatalf("unexpected added %d, error %v", added, err)
+	}
+	if added, _ := m.Put("history", "user", raw("a", "b", "a"), List); added != 3 {
+		t.Errorf("expected lists to keep duplicates, added %d", added)
+	}
+	if _, err := m.Put("tags", "post", raw("x"), List); err != ErrMode {
+		t.Errorf("expected %v, got %v", ErrMode, err)
+	}
+
+	if values, mode := m.Get("tags", "post"); mode != Set || !reflect.DeepEqual(texts(values), []string{"go", "db"}) {
+		t.Errorf("unexpected values %v in mode %s", texts(values), mode)
+	}
+	if !m.Remove("history", "user", []byte("a")) {
+		t.Errorf("expected a value to be removed")
+	}
+	if values, _ := m.Get("history", "user"); !reflect.DeepEqual(texts(values), []string{"b", "a"}) {
+		t.Errorf("expected only the first occurrence to be removed, got %v", texts(values))
+	}
+	if m.Count("history", "user") != 2 || !m.Contains("history", "user", []byte("a")) {
+		t.Errorf("unexpected count %d", m.Count("history", "user"))
+	}
+
+	if removed := m.RemoveAll("tags", "post"); removed != 2 || m.Exists("tags", "post") {
+		t.Errorf("unexpected removed %d", removed)
+	}
+	m.Remove("history", "user", []byte("a"))
+	m.Remove("history", "user", []byte("b"))
+	if m.Exists("history", "user") || m.Bytes() != 0 {
+		t.Errorf("expected an emptied key to be removed with its bytes, %d left", m.Bytes())
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	m := New()
+	if _, err := m.Put("history", "user", raw("a", "a"), List); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := New()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if values, mode := restored.Get("history", "user"); mode != List || len(values) != 2 {
+		t.Errorf("unexpected values %v in mode %s", texts(values), mode)
+	}
+	if restored.Bytes() != m.Bytes() {
+		t.Errorf("expected %d bytes, got %d", m.Bytes(), restored.Bytes())
+	}
+}
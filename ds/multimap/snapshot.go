@@ -0,0 +1,41 @@
+package multimap
+
+import "encoding/json"
+
+type snapshotValues struct {
+	Mode   Mode     `json:"mode"`
+	Values [][]byte `json:"values"`
+}
+
+// MarshalJSON encodes the values of all multimaps with their modes for a snapshot.
+func (m *MultiMap) MarshalJSON() ([]byte, error) {
+	maps := make(map[string]map[string]snapshotValues, len(m.maps))
+	for name, keys := range m.maps {
+		maps[name] = make(map[string]snapshotValues, len(keys))
+		for key, v := range keys {
+			maps[name][key] = snapshotValues{Mode: v.mode, Values: v.values}
+		}
+	}
+
+	return json.Marshal(maps)
+}
+
+// UnmarshalJSON replaces all multimaps with a snapshot.
+func (m *MultiMap) UnmarshalJSON(data []byte) error {
+	var maps map[string]map[string]snapshotValues
+	if err := json.Unmarshal(data, &maps); err != nil {
+		return err
+	}
+
+	m.maps = make(map[string]map[string]*values, len(maps))
+	m.bytes = 0
+	for name, keys := range maps {
+		for key, v := range keys {
+			if _, err := m.Put(name, key, v.Values, v.Mode); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
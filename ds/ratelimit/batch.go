@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+)
+
+// Flush applies a batch of acquisitions, returning a result or an error for each of them, or an error for
+// the whole batch.
+type Flush func(batch []Acquire) ([]Result, []error, error)
+
+type call struct {
+	acquire Acquire
+	result  Result
+	err     error
+	done    chan struct{}
+}
+
+// Batcher groups concurrent acquisitions, so that they are replicated together. While a batch is applied,
+// the acquisitions arriving meanwhile are collected into the next one.
+type Batcher struct {
+	mutex   sync.Mutex
+	flush   Flush
+	max     int
+	pending []*call
+	running bool
+}
+
+// NewBatcher creates a batcher applying batches of up to max acquisitions with flush.
+func NewBatcher(max int, flush Flush) *Batcher {
+	return &Batcher{flush: flush, max: max}
+}
+
+// TryAcquire applies an acquisition as part of the next batch and waits for its result. It returns the error
+// of ctx once ctx is done; the acquisition is left out if its batch was not applied yet, and may still take
+// permits otherwise.
+func (b *Batcher) TryAcquire(ctx context.Context, a Acquire) (Result, error) {
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	c := &call{acquire: a, done: make(chan struct{})}
+
+	b.mutex.Lock()
+	b.pending = append(b.pending, c)
+	if !b.running {
+		b.running = true
+		go b.run()
+	}
+	b.mutex.Unlock()
+
+	select {
+	case <-c.done:
+		return c.result, c.err
+	case <-ctx.Done():
+		b.mutex.Lock()
+		for i, pending := range b.pending {
+			if pending == c {
+				b.pending = append(b.pending[:i:i], b.pending[i+1:]...)
+				break
+			}
+		}
+		b.mutex.Unlock()
+		return Result{}, ctx.Err()
+	}
+}
+
+func (b *Batcher) run() {
+	for {
+		b.mutex.Lock()
+		if len(b.pending) == 0 {
+			b.running = false
+			b.mutex.Unlock()
+			return
+		}
+		n := len(b.pending)
+		if n > b.max {
+			n = b.max
+		}
+		calls := b.pending[:n:n]
+		b.pending = b.pending[n:]
+		b.mutex.Unlock()
+
+		batch := make([]Acquire, len(calls))
+		for i, c := range calls {
+			batch[i] = c.acquire
+		}
+		results, errs, err := b.flush(batch)
+		for i, c := range calls {
+			if err != nil {
+				c.err = err
+			} else {
+				c.result, c.err = results[i], errs[i]
+			}
+			close(c.done)
+		}
+	}
+}
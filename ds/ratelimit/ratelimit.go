@@ -0,0 +1,307 @@
+// Package ratelimit implements named rate limiters with a state per key. Time is always passed in by the
+// caller, and all arithmetic is done on integers, so that replicas applying the same acquisitions reach the
+// same decisions.
+package ratelimit
+
+import (
+	"errors"
+	"time"
+)
+
+// Algorithm decides how a limiter counts permits.
+type Algorithm string
+
+const (
+	// TokenBucket refills Limit permits per Interval into a bucket holding up to Burst permits.
+	TokenBucket Algorithm = "token-bucket"
+	// SlidingWindow allows Limit permits in any window of Interval, estimated from the counts of the current
+	// and the previous fixed window.
+	SlidingWindow Algorithm = "sliding-window"
+)
+
+const (
+	// MaxLimit is the highest limit and burst of a limiter.
+	MaxLimit = 1000000
+	// MaxInterval is the longest interval of a limiter.
+	MaxInterval = 24 * time.Hour
+	// pruneEvery is the number of acquisitions of a limiter after which idle keys are dropped.
+	pruneEvery = 1024
+)
+
+var (
+	ErrNoLimiter  = errors.New("rate limiter does not exist")
+	ErrAlgorithm  = errors.New("unknown rate limiter algorithm")
+	ErrDefinition = errors.New("limit and burst must be between 1 and 1000000, interval between 1ms and 24h")
+	ErrPermits    = errors.New("permits must be positive and not exceed the burst of the limiter")
+)
+
+// Definition is the configuration of a limiter.
+type Definition struct {
+	Algorithm Algorithm `json:"algorithm"`
+	// Limit is the number of permits per Interval, in milliseconds.
+	Limit    int64 `json:"limit"`
+	Interval int64 `json:"interval"`
+	// Burst is the size of a token bucket, Limit if it is not set.
+	Burst int64 `json:"burst,omitempty"`
+}
+
+// Validate checks a definition and fills in its defaults.
+func (def *Definition) Validate() error {
+	if def.Algorithm != TokenBucket && def.Algorithm != SlidingWindow {
+		return ErrAlgorithm
+	}
+	if def.Burst == 0 || def.Algorithm == SlidingWindow {
+		def.Burst = def.Limit
+	}
+	if def.Limit < 1 || def.Limit > MaxLimit || def.Burst < 1 || def.Burst > MaxLimit || def.Interval < 1 ||
+		time.Duration(def.Interval)*time.Millisecond > MaxInterval {
+		return ErrDefinition
+	}
+	return nil
+}
+
+// Acquire asks for permits of a key of a limiter.
+type Acquire struct {
+	Name    string `json:"name"`
+	Key     string `json:"key"`
+	Permits int64  `json:"permits"`
+}
+
+// Result is the decision on an acquisition.
+type Result struct {
+	Allowed bool `json:"allowed"`
+	// Remaining is the number of permits left for the key.
+	Remaining int64 `json:"remaining"`
+	// RetryAfter is how long to wait in milliseconds before the permits can be acquired, if they were denied.
+	RetryAfter int64 `json:"retryAfter,omitempty"`
+}
+
+// state is the state of a key. Tokens are scaled by the interval in microseconds, so that refills are exact.
+type state struct {
+	Tokens   int64 `json:"tokens,omitempty"`
+	Last     int64 `json:"last,omitempty"`
+	Start    int64 `json:"start,omitempty"`
+	Current  int64 `json:"current,omitempty"`
+	Previous int64 `json:"previous,omitempty"`
+}
+
+type limiter struct {
+	Definition
+	states   map[string]*state
+	acquired int
+}
+
+// Limiters holds all rate limiters of a node.
+type Limiters struct {
+	limiters map[string]*limiter
+	bytes    int64
+}
+
+// New creates an empty set of limiters.
+func New() *Limiters {
+	return &Limiters{limiters: make(map[string]*limiter)}
+}
+
+// Define creates the limiter name, or changes its definition. The state of its keys is reset on changes.
+func (l *Limiters) Define(name string, def Definition) error {
+	if err := def.Validate(); err != nil {
+		return err
+	}
+
+	if li, ok := l.limiters[name]; ok {
+		if li.Definition == def {
+			return nil
+		}
+		l.Delete(name)
+	}
+	l.limiters[name] = &limiter{Definition: def, states: make(map[string]*state)}
+	return nil
+}
+
+// Delete removes the limiter name with the state of its keys.
+func (l *Limiters) Delete(name string) bool {
+	li, ok := l.limiters[name]
+	if !ok {
+		return false
+	}
+	for key := range li.states {
+		l.bytes -= stateSize(key)
+	}
+	delete(l.limiters, name)
+	return true
+}
+
+// Get returns the definition of the limiter name.
+func (l *Limiters) Get(name string) (Definition, bool) {
+	li, ok := l.limiters[name]
+	if !ok {
+		return Definition{}, false
+	}
+	return li.Definition, true
+}
+
+// TryAcquire takes permits for a key of the limiter name at now, in unix nanoseconds, if they are available.
+func (l *Limiters) TryAcquire(a Acquire, now int64) (Result, error) {
+	li, ok := l.limiters[a.Name]
+	if !ok {
+		return Result{}, ErrNoLimiter
+	}
+	if a.Permits < 1 || a.Permits > li.Burst {
+		return Result{}, ErrPermits
+	}
+
+	micros := now / int64(time.Microsecond)
+	li.acquired++
+	if li.acquired%pruneEvery == 0 {
+		l.prune(li, micros)
+	}
+
+	st, ok := li.states[a.Key]
+	if !ok {
+		st = li.fresh(micros)
+		li.states[a.Key] = st
+		l.bytes += stateSize(a.Key)
+	}
+
+	if li.Algorithm == TokenBucket {
+		return li.takeTokens(st, a.Permits, micros), nil
+	}
+	return li.countWindow(st, a.Permits, micros), nil
+}
+
+// Growth returns the number of bytes the state of keys acquired from for the first time takes.
+func (l *Limiters) Growth(acquisitions []Acquire) int64 {
+	var growth int64
+	seen := make(map[Acquire]bool, len(acquisitions))
+	for _, a := range acquisitions {
+		a.Permits = 0
+		li, ok := l.limiters[a.Name]
+		if !ok || seen[a] {
+			continue
+		}
+		seen[a] = true
+		if _, ok := li.states[a.Key]; !ok {
+			growth += stateSize(a.Key)
+		}
+	}
+	return growth
+}
+
+// Bytes returns the number of bytes held by the state of all keys.
+func (l *Limiters) Bytes() int64 {
+	return l.bytes
+}
+
+func (li *limiter) interval() int64 {
+	return li.Interval * int64(time.Millisecond/time.Microsecond)
+}
+
+// fresh returns the state of a key that was never seen, or not for long enough to be dropped.
+func (li *limiter) fresh(now int64) *state {
+	if li.Algorithm == TokenBucket {
+		return &state{Tokens: li.Burst * li.interval(), Last: now}
+	}
+	return &state{Start: now - now%li.interval()}
+}
+
+// idle reports whether the state of a key is the same as a fresh one at now.
+func (li *limiter) idle(st *state, now int64) bool {
+	if li.Algorithm == TokenBucket {
+		return st.Tokens+li.refill(st, now) >= li.Burst*li.interval()
+	}
+	return now-st.Start >= 2*li.interval()
+}
+
+func (l *Limiters) prune(li *limiter, now int64) {
+	for key, st := range li.states {
+		if li.idle(st, now) {
+			delete(li.states, key)
+			l.bytes -= stateSize(key)
+		}
+	}
+}
+
+func (li *limiter) takeTokens(st *state, permits, now int64) Result {
+	interval, capacity := li.interval(), li.Burst*li.interval()
+	if now > st.Last {
+		st.Tokens += li.refill(st, now)
+		if st.Tokens > capacity {
+			st.Tokens = capacity
+		}
+		st.Last = now
+	}
+
+	need := permits * interval
+	if st.Tokens >= need {
+		st.Tokens -= need
+		return Result{Allowed: true, Remaining: st.Tokens / interval}
+	}
+
+	wait := ceil(need-st.Tokens, li.Limit)
+	return Result{Remaining: st.Tokens / interval, RetryAfter: millis(wait)}
+}
+
+// refill returns the scaled tokens refilled into a bucket since it was last used, without overflowing for
+// buckets unused for long.
+func (li *limiter) refill(st *state, now int64) int64 {
+	elapsed := now - st.Last
+	if full := ceil(li.Burst*li.interval(), li.Limit); elapsed > full {
+		elapsed = full
+	}
+	if elapsed < 0 {
+		return 0
+	}
+	return elapsed * li.Limit
+}
+
+func (li *limiter) countWindow(st *state, permits, now int64) Result {
+	interval := li.interval()
+	if start := now - now%interval; start > st.Start {
+		if start-st.Start == interval {
+			st.Previous = st.Current
+		} else {
+			st.Previous = 0
+		}
+		st.Start, st.Current = start, 0
+	}
+
+	// The previous window counts for the part of it that is still within the sliding window.
+	elapsed := now - st.Start
+	weighted := st.Previous*(interval-elapsed) + st.Current*interval
+	if weighted+permits*interval <= li.Limit*interval {
+		st.Current += permits
+		weighted += permits * interval
+		return Result{Allowed: true, Remaining: (li.Limit*interval - weighted) / interval}
+	}
+
+	remaining := (li.Limit*interval - weighted) / interval
+	if free := li.Limit - st.Current - permits; free >= 0 {
+		// The permits fit once enough of the previous window slid out.
+		return Result{Remaining: remaining, RetryAfter: millis(interval - free*interval/st.Previous - elapsed)}
+	}
+
+	// The permits only fit in the next window, once enough of the current one slid out.
+	wait := interval - elapsed
+	if st.Current > 0 {
+		if slide := interval - (li.Limit-permits)*interval/st.Current; slide > 0 {
+			wait += slide
+		}
+	}
+	return Result{Remaining: remaining, RetryAfter: millis(wait)}
+}
+
+func stateSize(key string) int64 {
+	return int64(len(key)) + 40
+}
+
+func ceil(a, b int64) int64 {
+	return (a + b - 1) / b
+}
+
+// millis converts microseconds to milliseconds, rounding up so that retrying after them succeeds.
+func millis(micros int64) int64 {
+	if micros < 1 {
+		micros = 1
+	}
+	return ceil(micros, int64(time.Millisecond/time.Microsecond))
+}
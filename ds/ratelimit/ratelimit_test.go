@@ -0,0 +1,193 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+const start = int64(1700000000) * int64(time.Second)
+
+func at(offset time.Duration) int64 {
+	return start + int64(offset)
+}
+
+func TestTokenBucket(t *testing.T) {
+	l := New()
+	if err := l.Define("api", Definition{Algorithm: TokenBucket, Limit: 10, Interval: 1000, Burst: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	acquire := Acquire{Name: "api", Key: "client", Permits: 1}
+	for i := 0; i < 5; i++ {
+		if result, _ := l.TryAcquire(acquire, at(0)); !result.Allowed || result.Remaining != int64(4-i) {
+			t.Fatalf("expected acquisition %d within the burst to be allowed, got %+v", i, result)
+		}
+	}
+
+	result, _ := l.TryAcquire(acquire, at(0))
+	if result.Allowed || result.RetryAfter != 100 {
+		t.Errorf("expected a denial with a retry after 100ms, got %+v", result)
+	}
+	if result, _ := l.TryAcquire(acquire, at(100*time.Millisecond)); !result.Allowed {
+		t.Errorf("expected an acquisition after the retry to be allowed, got %+v", result)
+	}
+	if result, _ := l.TryAcquire(acquire, at(time.Hour)); !result.Allowed || result.Remaining != 4 {
+		t.Errorf("expected the bucket to be refilled up to its burst, got %+v", result)
+	}
+
+	if _, err := l.TryAcquire(Acquire{Name: "api", Key: "client", Permits: 6}, at(0)); err != ErrPermits {
+		t.Errorf("expected %v, got %v", ErrPermits, err)
+	}
+	if _, err := l.TryAcquire(Acquire{Name: "missing", Permits: 1}, at(0)); err != ErrNoLimiter {
+		t.Errorf("expected %v, got %v", ErrNoLimiter, err)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	l := New()
+	if err := l.Define("login", Definition{Algorithm: SlidingWindow, Limit: 10, Interval: 1000}); err != nil {
+		t.Fatal(err)
+	}
+
+	acquire := Acquire{Name: "login", Key: "user", Permits: 10}
+	if result, _ := l.TryAcquire(acquire, at(0)); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected the whole limit to be allowed, got %+v", result)
+	}
+
+	acquire.Permits = 5
+	result, _ := l.TryAcquire(acquire, at(0))
+	if result.Allowed || result.RetryAfter != 1500 {
+		t.Errorf("expected a denial until half of the window slid out, got %+v", result)
+	}
+	if result, _ := l.TryAcquire(acquire, at(1400*time.Millisecond)); result.Allowed {
+		t.Errorf("expected an acquisition before the retry to be denied, got %+v", result)
+	}
+	if result, _ := l.TryAcquire(acquire, at(1500*time.Millisecond)); !result.Allowed {
+		t.Errorf("expected an acquisition after the retry to be allowed, got %+v", result)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	l := New()
+	l.Define("api", Definition{Algorithm: TokenBucket, Limit: 2, Interval: 1000})
+	l.TryAcquire(Acquire{Name: "api", Key: "client", Permits: 2}, at(0))
+
+	encoded, err := json.Marshal(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := New()
+	if err := json.Unmarshal(encoded, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, _ := restored.TryAcquire(Acquire{Name: "api", Key: "client", Permits: 1}, at(0)); result.Allowed {
+		t.Errorf("expected the snapshot to keep the state of the keys, got %+v", result)
+	}
+	if restored.Bytes() != l.Bytes() {
+		t.Errorf("expected %d bytes, got %d", l.Bytes(), restored.Bytes())
+	}
+}
+
+func TestGrowth(t *testing.T) {
+	l := New()
+	l.Define("api", Definition{Algorithm: TokenBucket, Limit: 5, Interval: 1000})
+	l.TryAcquire(Acquire{Name: "api", Key: "known", Permits: 1}, at(0))
+
+	growth := l.Growth([]Acquire{
+		{Name: "api", Key: "known", Permits: 1},
+		{Name: "api", Key: "new", Permits: 1},
+		{Name: "api", Key: "new", Permits: 2},
+		{Name: "missing", Key: "other", Permits: 1},
+	})
+	if growth != stateSize("new") {
+		t.Errorf("expected only the new key to grow, got %d bytes", growth)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	l := New()
+	l.Define("api", Definition{Algorithm: TokenBucket, Limit: 1, Interval: 1000})
+	for i := 0; i < pruneEvery-1; i++ {
+		l.TryAcquire(Acquire{Name: "api", Key: fmt.Sprint(i), Permits: 1}, at(0))
+	}
+	l.TryAcquire(Acquire{Name: "api", Key: "last", Permits: 1}, at(time.Minute))
+
+	if n := len(l.limiters["api"].states); n != 1 {
+		t.Errorf("expected idle keys to be dropped, %d left", n)
+	}
+}
+
+func TestBatcher(t *testing.T) {
+	var mutex sync.Mutex
+	var batches int
+	b := NewBatcher(100, func(batch []Acquire) ([]Result, []error, error) {
+		mutex.Lock()
+		batches++
+		mutex.Unlock()
+		time.Sleep(10 * time.Millisecond)
+
+		results := make([]Result, len(batch))
+		for i, a := range batch {
+			results[i] = Result{Allowed: a.Permits%2 == 0}
+		}
+		return results, make([]error, len(batch)), nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(permits int64) {
+			defer wg.Done()
+			result, err := b.TryAcquire(context.Background(), Acquire{Permits: permits})
+			if err != nil || result.Allowed != (permits%2 == 0) {
+				t.Errorf("unexpected result %+v for %d permits, error %v", result, permits, err)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+
+	if batches >= 50 {
+		t.Errorf("expected concurrent acquisitions to be batched, got %d batches", batches)
+	}
+}
+
+func TestBatcherCancel(t *testing.T) {
+	release := make(chan struct{})
+	var applied []Acquire
+	b := NewBatcher(100, func(batch []Acquire) ([]Result, []error, error) {
+		<-release
+		applied = append(applied, batch...)
+		return make([]Result, len(batch)), make([]error, len(batch)), nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := b.TryAcquire(ctx, Acquire{Key: "cancelled"}); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+
+	// The first acquisition holds up the batch, so the second one is still queued when it times out.
+	go b.TryAcquire(context.Background(), Acquire{Key: "first"})
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := b.TryAcquire(ctx, Acquire{Key: "queued"}); err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	close(release)
+	b.TryAcquire(context.Background(), Acquire{Key: "last"})
+	for _, a := range applied {
+		if a.Key == "cancelled" || a.Key == "queued" {
+			t.Errorf("expected %s not to be applied", a.Key)
+		}
+	}
+	if len(applied) != 2 {
+		t.Errorf("expected 2 acquisitions to be applied, got %v", applied)
+	}
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import "encoding/json"
+
+type snapshotLimiter struct {
+	Definition
+	States   map[string]*state `json:"states,omitempty"`
+	Acquired int               `json:"acquired,omitempty"`
+}
+
+// MarshalJSON encodes all limiters with the state of their keys for a snapshot.
+func (l *Limiters) MarshalJSON() ([]byte, error) {
+	limiters := make(map[string]snapshotLimiter, len(l.limiters))
+	for name, li := range l.limiters {
+		limiters[name] = snapshotLimiter{Definition: li.Definition, States: li.states, Acquired: li.acquired}
+	}
+
+	return json.Marshal(limiters)
+}
+
+// UnmarshalJSON replaces all limiters with a snapshot.
+func (l *Limiters) UnmarshalJSON(data []byte) error {
+	var limiters map[string]snapshotLimiter
+	if err := json.Unmarshal(data, &limiters); err != nil {
+		return err
+	}
+
+	l.limiters = make(map[string]*limiter, len(limiters))
+	l.bytes = 0
+	for name, sl := range limiters {
+		li := &limiter{Definition: sl.Definition, states: sl.States, acquired: sl.Acquired}
+		if li.states == nil {
+			li.states = make(map[string]*state)
+		}
+		for key := range li.states {
+			l.bytes += stateSize(key)
+		}
+		l.limiters[name] = li
+	}
+
+	return nil
+}
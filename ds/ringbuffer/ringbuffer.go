@@ -0,0 +1,263 @@
+// Package ringbuffer implements fixed-capacity buffers of items numbered by sequence. Consumers read from a
+// sequence of their own, so a buffer keeps a bounded history that any number of them can replay, and those
+// falling further behind than the capacity learn that they missed items. Time is always passed in by the
+// caller, so that replicas applying the same commands reach the same state.
+package ringbuffer
+
+import (
+	"errors"
+	"sync"
+)
+
+// MaxCapacity limits the number of items a single buffer holds.
+const MaxCapacity = 1 << 20
+
+var (
+	ErrExists   = errors.New("ring buffer already exists")
+	ErrNoBuffer = errors.New("ring buffer does not exist")
+	ErrInvalid  = errors.New("capacity must be between 1 and 1048576")
+	ErrPolicy   = errors.New("unknown overflow policy")
+	ErrFull     = errors.New("ring buffer is full")
+	ErrStale    = errors.New("sequence is no longer in the ring buffer")
+	ErrSequence = errors.New("sequence is past the end of the ring buffer")
+)
+
+// Policy decides what happens when items are added to a full buffer.
+type Policy string
+
+const (
+	// Overwrite drops the oldest items to make room.
+	Overwrite Policy = "overwrite"
+	// Fail rejects the items, unless the oldest ones expired.
+	Fail Policy = "fail"
+)
+
+// Item is an item of a buffer.
+type Item struct {
+	Sequence uint64 `json:"sequence"`
+	Value    []byte `json:"value"`
+	// Added is when the item was added, in unix nanoseconds.
+	Added int64 `json:"added"`
+}
+
+// Info describes a buffer.
+type Info struct {
+	Capacity int    `json:"capacity"`
+	Policy   Policy `json:"policy"`
+	// TTL is how long items are kept, in nanoseconds, or zero to keep them until they are overwritten.
+	TTL int64 `json:"ttl,omitempty"`
+	// Head is the sequence of the oldest item still in the buffer, Tail the sequence the next item gets.
+	Head uint64 `json:"head"`
+	Tail uint64 `json:"tail"`
+}
+
+type buffer struct {
+	Info
+	slots []Item
+}
+
+// Buffers holds all ring buffers of a node.
+type Buffers struct {
+	buffers map[string]*buffer
+	bytes   int64
+	// signals are taken by readers concurrently, so they are guarded separately.
+	mutex   sync.Mutex
+	signals map[string]chan struct{}
+}
+
+// New creates an empty set of buffers.
+func New() *Buffers {
+	return &Buffers{
+		buffers: make(map[string]*buffer),
+		signals: make(map[string]chan struct{}),
+	}
+}
+
+// Validate returns an error if a buffer cannot be created with a capacity and a policy. An empty policy is
+// Overwrite.
+func Validate(capacity int, policy Policy) error {
+	if capacity < 1 || capacity > MaxCapacity {
+		return ErrInvalid
+	}
+	if policy != "" && policy != Overwrite && policy != Fail {
+		return ErrPolicy
+	}
+	return nil
+}
+
+// Create creates the buffer name holding up to capacity items. Items expire after ttl nanoseconds if it is
+// positive.
+func (b *Buffers) Create(name string, capacity int, policy Policy, ttl int64) error {
+	if _, ok := b.buffers[name]; ok {
+		return ErrExists
+	}
+	if err := Validate(capacity, policy); err != nil {
+		return err
+	}
+	if policy == "" {
+		policy = Overwrite
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	b.buffers[name] = &buffer{Info: Info{Capacity: capacity, Policy: policy, TTL: ttl}}
+	b.bytes += int64(len(name))
+	return nil
+}
+
+// Destroy removes the buffer name with its items, and reports whether it existed.
+func (b *Buffers) Destroy(name string) bool {
+	buf, ok := b.buffers[name]
+	if !ok {
+		return false
+	}
+
+	b.expire(buf, buf.Tail)
+	b.bytes -= int64(len(name))
+	delete(b.buffers, name)
+	b.signal(name)
+	return true
+}
+
+// Add appends values to the buffer name at now, and returns the sequence of the first one. Expired items are
+// dropped first. A buffer with the Fail policy takes either all values or none.
+func (b *Buffers) Add(name string, values [][]byte, now int64) (uint64, error) {
+	buf, ok := b.buffers[name]
+	if !ok {
+		return 0, ErrNoBuffer
+	}
+
+	b.expire(buf, buf.head(now))
+	free := buf.Capacity - int(buf.Tail-buf.Head)
+	if buf.Policy == Fail && len(values) > free {
+		return 0, ErrFull
+	}
+
+	first := buf.Tail
+	for _, value := range values {
+		if int(buf.Tail-buf.Head) == buf.Capacity {
+			b.expire(buf, buf.Head+1)
+		}
+
+		item := Item{Sequence: buf.Tail, Value: value, Added: now}
+		if len(buf.slots) < buf.Capacity {
+			buf.slots = append(buf.slots, item)
+		} else {
+			buf.slots[buf.Tail%uint64(buf.Capacity)] = item
+		}
+		buf.Tail++
+		b.bytes += int64(len(value))
+	}
+	if len(values) > 0 {
+		b.signal(name)
+	}
+
+	return first, nil
+}
+
+// Read returns up to max items of the buffer name from sequence from on, or all of them if max is not positive.
+// It returns ErrStale if the item at from was overwritten or expired at now.
+func (b *Buffers) Read(name string, from uint64, max int, now int64) ([]Item, error) {
+	buf, ok := b.buffers[name]
+	if !ok {
+		return nil, ErrNoBuffer
+	}
+
+	if from < buf.head(now) {
+		return nil, ErrStale
+	}
+	if from > buf.Tail {
+		return nil, ErrSequence
+	}
+
+	end := buf.Tail
+	if max > 0 && end-from > uint64(max) {
+		end = from + uint64(max)
+	}
+
+	items := make([]Item, 0, end-from)
+	for seq := from; seq < end; seq++ {
+		items = append(items, buf.slots[seq%uint64(buf.Capacity)])
+	}
+	return items, nil
+}
+
+// Info describes the buffer name at now.
+func (b *Buffers) Info(name string, now int64) (Info, error) {
+	buf, ok := b.buffers[name]
+	if !ok {
+		return Info{}, ErrNoBuffer
+	}
+
+	info := buf.Info
+	info.Head = buf.head(now)
+	return info, nil
+}
+
+// Exists reports whether buffer name exists.
+func (b *Buffers) Exists(name string) bool {
+	_, ok := b.buffers[name]
+	return ok
+}
+
+// Signal returns a channel that is closed once items are added to a buffer, or it is destroyed.
+func (b *Buffers) Signal(name string) <-chan struct{} {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	signal, ok := b.signals[name]
+	if !ok {
+		signal = make(chan struct{})
+		b.signals[name] = signal
+	}
+	return signal
+}
+
+// Wake closes the channels returned by Signal, for waiters to look at the buffers again after they were
+// replaced by a snapshot.
+func (b *Buffers) Wake() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for name, signal := range b.signals {
+		close(signal)
+		delete(b.signals, name)
+	}
+}
+
+// Bytes returns the number of bytes held by the names and the items of all buffers.
+func (b *Buffers) Bytes() int64 {
+	return b.bytes
+}
+
+func (b *Buffers) signal(name string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if signal, ok := b.signals[name]; ok {
+		close(signal)
+		delete(b.signals, name)
+	}
+}
+
+// expire drops the items of buf before sequence head.
+func (b *Buffers) expire(buf *buffer, head uint64) {
+	for ; buf.Head < head; buf.Head++ {
+		slot := &buf.slots[buf.Head%uint64(buf.Capacity)]
+		b.bytes -= int64(len(slot.Value))
+		slot.Value = nil
+	}
+}
+
+// head returns the sequence of the oldest item of buf that did not expire at now.
+func (buf *buffer) head(now int64) uint64 {
+	head := buf.Head
+	if buf.TTL <= 0 {
+		return head
+	}
+	for head < buf.Tail && buf.slots[head%uint64(buf.Capacity)].Added+buf.TTL <= now {
+		head++
+	}
+	return head
+}
@@ -0,0 +1,103 @@
+package ringbuffer
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func values(items []Item) []string {
+	var result []string
+	for _, item := range items {
+		result = append(result, string(item.Value))
+	}
+	return result
+}
+
+func TestOverwrite(t *testing.T) {
+	b := New()
+	if err := b.Create("events", 3, Overwrite, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if first, err := b.Add("events", [][]byte{[]byte("a"), []byte("b")}, 0); err != nil || first != 0 {
+		t.Fatalf("unexpected first sequence %d, error %v", first, err)
+	}
+	if items, _ := b.Read("events", 1, 0, 0); len(items) != 1 || items[0].Sequence != 1 {
+		t.Errorf("unexpected items %v", values(items))
+	}
+
+	if first, _ := b.Add("events", [][]byte{[]byte("c"), []byte("d")}, 0); first != 2 {
+		t.Errorf("unexpected first sequence %d", first)
+	}
+	if _, err := b.Read("events", 0, 0, 0); err != ErrStale {
+		t.Errorf("expected %v, got %v", ErrStale, err)
+	}
+	if items, _ := b.Read("events", 1, 2, 0); len(items) != 2 || values(items)[1] != "c" {
+		t.Errorf("unexpected items %v", values(items))
+	}
+	if items, err := b.Read("events", 4, 0, 0); err != nil || len(items) != 0 {
+		t.Errorf("expected no items at the tail, got %v, error %v", values(items), err)
+	}
+	if _, err := b.Read("events", 5, 0, 0); err != ErrSequence {
+		t.Errorf("expected %v, got %v", ErrSequence, err)
+	}
+	if b.Bytes() != int64(len("events")+3) {
+		t.Errorf("unexpected bytes %d", b.Bytes())
+	}
+}
+
+func TestFail(t *testing.T) {
+	b := New()
+	if err := b.Create("jobs", 2, Fail, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.Add("jobs", [][]byte{[]byte("a"), []byte("b"), []byte("c")}, 0); err != ErrFull {
+		t.Errorf("expected %v, got %v", ErrFull, err)
+	}
+	if _, err := b.Add("jobs", [][]byte{[]byte("a"), []byte("b")}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Add("jobs", [][]byte{[]byte("c")}, 5); err != ErrFull {
+		t.Errorf("expected %v, got %v", ErrFull, err)
+	}
+
+	if first, err := b.Add("jobs", [][]byte{[]byte("c")}, 10); err != nil || first != 2 {
+		t.Errorf("expected expired items to make room, got %d, error %v", first, err)
+	}
+	if info, _ := b.Info("jobs", 10); info.Head != 2 || info.Tail != 3 {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	b := New()
+	if err := b.Create("events", 2, Overwrite, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Add("events", [][]byte{[]byte("a"), []byte("b"), []byte("c")}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := New()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if items, err := restored.Read("events", 1, 0, 0); err != nil || len(items) != 2 || values(items)[1] != "c" {
+		t.Errorf("unexpected items %v, error %v", values(items), err)
+	}
+	if restored.Bytes() != b.Bytes() {
+		t.Errorf("expected %d bytes, got %d", b.Bytes(), restored.Bytes())
+	}
+	if _, err := restored.Add("events", [][]byte{[]byte("d")}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := restored.Read("events", 2, 0, 0); values(items)[1] != "d" {
+		t.Errorf("unexpected items %v", values(items))
+	}
+}
@@ -0,0 +1,52 @@
+package ringbuffer
+
+import "encoding/json"
+
+type snapshotBuffer struct {
+	Info
+	Items []Item `json:"items"`
+}
+
+// MarshalJSON encodes all buffers with the items still in them for a snapshot.
+func (b *Buffers) MarshalJSON() ([]byte, error) {
+	buffers := make(map[string]snapshotBuffer, len(b.buffers))
+	for name, buf := range b.buffers {
+		items := make([]Item, 0, buf.Tail-buf.Head)
+		for seq := buf.Head; seq < buf.Tail; seq++ {
+			items = append(items, buf.slots[seq%uint64(buf.Capacity)])
+		}
+		buffers[name] = snapshotBuffer{Info: buf.Info, Items: items}
+	}
+
+	return json.Marshal(buffers)
+}
+
+// UnmarshalJSON replaces all buffers with a snapshot. Waiters are not woken.
+func (b *Buffers) UnmarshalJSON(data []byte) error {
+	var buffers map[string]snapshotBuffer
+	if err := json.Unmarshal(data, &buffers); err != nil {
+		return err
+	}
+
+	b.buffers = make(map[string]*buffer, len(buffers))
+	b.bytes = 0
+	for name, s := range buffers {
+		if err := Validate(s.Capacity, s.Policy); err != nil {
+			return err
+		}
+
+		slots := uint64(s.Capacity)
+		if s.Tail < slots {
+			slots = s.Tail
+		}
+		buf := &buffer{Info: s.Info, slots: make([]Item, slots)}
+		for _, item := range s.Items {
+			buf.slots[item.Sequence%uint64(s.Capacity)] = item
+			b.bytes += int64(len(item.Value))
+		}
+		b.buffers[name] = buf
+		b.bytes += int64(len(name))
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidID = errors.New("invalid stream id")
+
+// ID identifies an entry of a stream. IDs are the time an entry was added at, in unix milliseconds,
+// and a sequence number telling apart entries added within the same millisecond.
+type ID struct {
+	Ms  uint64
+	Seq uint64
+}
+
+// ParseID parses an ID written as "ms-seq", or "ms" for a zero sequence number.
+func ParseID(s string) (ID, error) {
+	ms, seq, found := strings.Cut(s, "-")
+
+	var id ID
+	var err error
+	if id.Ms, err = strconv.ParseUint(ms, 10, 64); err != nil {
+		return ID{}, fmt.Errorf("%w %q", ErrInvalidID, s)
+	}
+	if found {
+		if id.Seq, err = strconv.ParseUint(seq, 10, 64); err != nil {
+			return ID{}, fmt.Errorf("%w %q", ErrInvalidID, s)
+		}
+	}
+
+	return id, nil
+}
+
+func (id ID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+// Compare returns -1, 0 or 1 depending on whether id is before, equal to or after other.
+func (id ID) Compare(other ID) int {
+	switch {
+	case id.Ms < other.Ms || (id.Ms == other.Ms && id.Seq < other.Seq):
+		return -1
+	case id == other:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// Next returns the smallest ID after id.
+func (id ID) Next() ID {
+	if id.Seq == ^uint64(0) {
+		return ID{Ms: id.Ms + 1}
+	}
+	return ID{Ms: id.Ms, Seq: id.Seq + 1}
+}
+
+func (id ID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id *ID) UnmarshalText(text []byte) error {
+	parsed, err := ParseID(string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
@@ -0,0 +1,71 @@
+package stream
+
+import "encoding/json"
+
+type snapshotGroup struct {
+	Last    ID        `json:"last"`
+	Pending []Pending `json:"pending,omitempty"`
+}
+
+type snapshotStream struct {
+	Entries []Entry                  `json:"entries"`
+	Last    ID                       `json:"last"`
+	Groups  map[string]snapshotGroup `json:"groups,omitempty"`
+}
+
+// MarshalJSON encodes all streams with their consumer groups for a snapshot.
+func (s *Streams) MarshalJSON() ([]byte, error) {
+	streams := make(map[string]snapshotStream, len(s.streams))
+	for name, st := range s.streams {
+		groups := make(map[string]snapshotGroup, len(st.groups))
+		for groupName, g := range st.groups {
+			sg := snapshotGroup{Last: g.last}
+			for _, p := range g.sortedPending() {
+				sg.Pending = append(sg.Pending, *p)
+			}
+			groups[groupName] = sg
+		}
+		streams[name] = snapshotStream{Entries: st.entries, Last: st.last, Groups: groups}
+	}
+
+	return json.Marshal(streams)
+}
+
+// UnmarshalJSON replaces all streams with a snapshot.
+func (s *Streams) UnmarshalJSON(data []byte) error {
+	var streams map[string]snapshotStream
+	if err := json.Unmarshal(data, &streams); err != nil {
+		return err
+	}
+
+	s.streams = make(map[string]*stream, len(streams))
+	s.bytes = 0
+	for name, ss := range streams {
+		st := &stream{entries: ss.Entries, last: ss.Last, groups: make(map[string]*group, len(ss.Groups))}
+		for _, e := range st.entries {
+			s.bytes += size(e.Value)
+		}
+		for groupName, sg := range ss.Groups {
+			g := &group{last: sg.Last, pending: make(map[ID]*Pending, len(sg.Pending))}
+			for i := range sg.Pending {
+				g.pending[sg.Pending[i].ID] = &sg.Pending[i]
+			}
+			st.groups[groupName] = g
+		}
+		s.streams[name] = st
+	}
+
+	return nil
+}
+
+// Wake closes the channels returned by Signal, for waiters to look at the streams again after they
+// were replaced by a snapshot.
+func (s *Streams) Wake() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for name, signal := range s.signals {
+		close(signal)
+		delete(s.signals, name)
+	}
+}
@@ -0,0 +1,405 @@
+// Package stream implements append-only streams with consumer groups, similar to Redis streams. Time is
+// always passed in by the caller, so that replicas applying the same commands reach the same state.
+package stream
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrIDTooSmall   = errors.New("id must be greater than the last id of the stream")
+	ErrNoStream     = errors.New("stream does not exist")
+	ErrNoGroup      = errors.New("consumer group does not exist")
+	ErrGroupExists  = errors.New("consumer group already exists")
+	ErrInvalidStart = errors.New("invalid group start id")
+)
+
+// Entry is an entry of a stream.
+type Entry struct {
+	ID    ID     `json:"id"`
+	Value []byte `json:"value"`
+}
+
+// Pending is an entry delivered to a consumer of a group and not acknowledged yet.
+type Pending struct {
+	ID       ID     `json:"id"`
+	Consumer string `json:"consumer"`
+	// Delivered is when the entry was last delivered, in unix milliseconds.
+	Delivered  int64 `json:"delivered"`
+	Deliveries int   `json:"deliveries"`
+}
+
+type group struct {
+	last    ID
+	pending map[ID]*Pending
+}
+
+type stream struct {
+	entries []Entry
+	last    ID
+	groups  map[string]*group
+}
+
+// Streams holds all streams of a node.
+type Streams struct {
+	streams map[string]*stream
+	bytes   int64
+	// signals are taken by readers concurrently, so they are guarded separately.
+	mutex   sync.Mutex
+	signals map[string]chan struct{}
+}
+
+// New creates an empty set of streams.
+func New() *Streams {
+	return &Streams{
+		streams: make(map[string]*stream),
+		signals: make(map[string]chan struct{}),
+	}
+}
+
+// Add appends value to the stream name, creating it if needed. A nil id generates one from now, in unix
+// milliseconds, which is greater than the last id even if the clock went back. A positive maxLen trims
+// the stream to its last maxLen entries afterwards.
+func (s *Streams) Add(name string, id *ID, value []byte, maxLen int, now int64) (ID, error) {
+	st := s.getOrCreate(name)
+
+	var added ID
+	switch {
+	case id != nil:
+		if id.Compare(st.last) <= 0 {
+			return ID{}, ErrIDTooSmall
+		}
+		added = *id
+	case uint64(now) > st.last.Ms:
+		added = ID{Ms: uint64(now)}
+	default:
+		added = st.last.Next()
+	}
+
+	st.entries = append(st.entries, Entry{ID: added, Value: value})
+	st.last = added
+	s.bytes += size(value)
+
+	if maxLen > 0 {
+		s.Trim(name, maxLen)
+	}
+
+	s.mutex.Lock()
+	if signal, ok := s.signals[name]; ok {
+		close(signal)
+		delete(s.signals, name)
+	}
+	s.mutex.Unlock()
+
+	return added, nil
+}
+
+// Trim removes the oldest entries of a stream until at most maxLen are left. It returns how many it removed.
+// Removed values are released at once, and the entries left are copied to a new array once the array holds
+// twice as many, so that trimming on every Add stays cheap.
+func (s *Streams) Trim(name string, maxLen int) int {
+	st, ok := s.streams[name]
+	if !ok || maxLen < 0 || len(st.entries) <= maxLen {
+		return 0
+	}
+
+	removed := len(st.entries) - maxLen
+	for i, e := range st.entries[:removed] {
+		s.bytes -= size(e.Value)
+		st.entries[i] = Entry{}
+	}
+	st.entries = st.entries[removed:]
+	if 2*len(st.entries) <= cap(st.entries) {
+		st.entries = append([]Entry(nil), st.entries...)
+	}
+
+	return removed
+}
+
+// Range returns up to count entries with ids from from to to, both included. A non positive count
+// returns all of them.
+func (s *Streams) Range(name string, from, to ID, count int) []Entry {
+	st, ok := s.streams[name]
+	if !ok {
+		return nil
+	}
+
+	var entries []Entry
+	for i := st.search(from); i < len(st.entries) && st.entries[i].ID.Compare(to) <= 0; i++ {
+		if count > 0 && len(entries) >= count {
+			break
+		}
+		entries = append(entries, st.entries[i])
+	}
+
+	return entries
+}
+
+// After returns up to count entries with ids greater than after.
+func (s *Streams) After(name string, after ID, count int) []Entry {
+	return s.Range(name, after.Next(), ID{Ms: ^uint64(0), Seq: ^uint64(0)}, count)
+}
+
+// Last returns the id of the last entry added to a stream, even if it was trimmed since.
+func (s *Streams) Last(name string) ID {
+	if st, ok := s.streams[name]; ok {
+		return st.last
+	}
+	return ID{}
+}
+
+// Len returns the number of entries of a stream.
+func (s *Streams) Len(name string) int {
+	if st, ok := s.streams[name]; ok {
+		return len(st.entries)
+	}
+	return 0
+}
+
+// Destroy removes a stream with its consumer groups, and reports whether it existed.
+func (s *Streams) Destroy(name string) bool {
+	st, ok := s.streams[name]
+	if !ok {
+		return false
+	}
+	for _, e := range st.entries {
+		s.bytes -= size(e.Value)
+	}
+	delete(s.streams, name)
+	return true
+}
+
+// Exists reports whether a stream exists.
+func (s *Streams) Exists(name string) bool {
+	_, ok := s.streams[name]
+	return ok
+}
+
+// Signal returns a channel that is closed once an entry is added to a stream.
+func (s *Streams) Signal(name string) <-chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	signal, ok := s.signals[name]
+	if !ok {
+		signal = make(chan struct{})
+		s.signals[name] = signal
+	}
+	return signal
+}
+
+// Bytes returns the number of bytes held by the values of all streams.
+func (s *Streams) Bytes() int64 {
+	return s.bytes
+}
+
+// EntrySize returns the number of bytes accounted for an entry holding value.
+func (s *Streams) EntrySize(value []byte) int64 {
+	return size(value)
+}
+
+// CreateGroup creates a consumer group of a stream, creating the stream if needed. The group delivers
+// the entries after start, which is an id, or "$" for the entries added from now on.
+func (s *Streams) CreateGroup(name, groupName, start string) error {
+	var last ID
+	if start == "$" {
+		last = s.Last(name)
+	} else {
+		var err error
+		if last, err = ParseID(start); err != nil {
+			return ErrInvalidStart
+		}
+	}
+
+	st := s.getOrCreate(name)
+	if _, ok := st.groups[groupName]; ok {
+		return ErrGroupExists
+	}
+	st.groups[groupName] = &group{last: last, pending: make(map[ID]*Pending)}
+
+	return nil
+}
+
+// DestroyGroup removes a consumer group and its pending entries.
+func (s *Streams) DestroyGroup(name, groupName string) bool {
+	st, ok := s.streams[name]
+	if !ok {
+		return false
+	}
+	if _, ok := st.groups[groupName]; !ok {
+		return false
+	}
+
+	delete(st.groups, groupName)
+	return true
+}
+
+// Groups returns the names of the consumer groups of a stream in lexical order.
+func (s *Streams) Groups(name string) []string {
+	st, ok := s.streams[name]
+	if !ok {
+		return nil
+	}
+
+	names := make([]string, 0, len(st.groups))
+	for groupName := range st.groups {
+		names = append(names, groupName)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Undelivered reports whether the stream has entries the group has not delivered yet.
+func (s *Streams) Undelivered(name, groupName string) (bool, error) {
+	st, g, err := s.group(name, groupName)
+	if err != nil {
+		return false, err
+	}
+	return len(st.entries) > 0 && st.entries[len(st.entries)-1].ID.Compare(g.last) > 0, nil
+}
+
+// ReadGroup delivers up to count entries the group has not delivered yet to consumer, and adds them to the
+// pending entries of the group.
+func (s *Streams) ReadGroup(name, groupName, consumer string, count int, now int64) ([]Entry, error) {
+	_, g, err := s.group(name, groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := s.After(name, g.last, count)
+	for _, e := range entries {
+		g.pending[e.ID] = &Pending{ID: e.ID, Consumer: consumer, Delivered: now, Deliveries: 1}
+	}
+	if len(entries) > 0 {
+		g.last = entries[len(entries)-1].ID
+	}
+
+	return entries, nil
+}
+
+// Pending returns the pending entries of a group ordered by id, only those of consumer if it is not empty.
+func (s *Streams) Pending(name, groupName, consumer string) ([]Pending, error) {
+	_, g, err := s.group(name, groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]Pending, 0, len(g.pending))
+	for _, p := range g.sortedPending() {
+		if consumer == "" || p.Consumer == consumer {
+			pending = append(pending, *p)
+		}
+	}
+
+	return pending, nil
+}
+
+// Ack removes ids from the pending entries of a group. It returns how many of them were pending.
+func (s *Streams) Ack(name, groupName string, ids []ID) (int, error) {
+	_, g, err := s.group(name, groupName)
+	if err != nil {
+		return 0, err
+	}
+
+	acked := 0
+	for _, id := range ids {
+		if _, ok := g.pending[id]; ok {
+			delete(g.pending, id)
+			acked++
+		}
+	}
+
+	return acked, nil
+}
+
+// Claim transfers pending entries idle for at least minIdle milliseconds to consumer and delivers them again.
+// If ids is empty, it claims up to count of the idle entries with the lowest ids. Pending entries that were
+// trimmed from the stream are dropped instead of being claimed.
+func (s *Streams) Claim(name, groupName, consumer string, minIdle int64, ids []ID, count int,
+	now int64) ([]Entry, error) {
+	st, g, err := s.group(name, groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*Pending
+	if len(ids) == 0 {
+		candidates = g.sortedPending()
+	} else {
+		for _, id := range ids {
+			if p, ok := g.pending[id]; ok {
+				candidates = append(candidates, p)
+			}
+		}
+	}
+
+	var entries []Entry
+	for _, p := range candidates {
+		if len(ids) == 0 && count > 0 && len(entries) >= count {
+			break
+		}
+		if now-p.Delivered < minIdle {
+			continue
+		}
+
+		i := st.search(p.ID)
+		if i >= len(st.entries) || st.entries[i].ID != p.ID {
+			delete(g.pending, p.ID)
+			continue
+		}
+
+		p.Consumer = consumer
+		p.Delivered = now
+		p.Deliveries++
+		entries = append(entries, st.entries[i])
+	}
+
+	return entries, nil
+}
+
+func (s *Streams) group(name, groupName string) (*stream, *group, error) {
+	st, ok := s.streams[name]
+	if !ok {
+		return nil, nil, ErrNoStream
+	}
+	g, ok := st.groups[groupName]
+	if !ok {
+		return nil, nil, ErrNoGroup
+	}
+	return st, g, nil
+}
+
+func (s *Streams) getOrCreate(name string) *stream {
+	st, ok := s.streams[name]
+	if !ok {
+		st = &stream{groups: make(map[string]*group)}
+		s.streams[name] = st
+	}
+	return st
+}
+
+// search returns the index of the first entry with an id of at least id.
+func (st *stream) search(id ID) int {
+	return sort.Search(len(st.entries), func(i int) bool {
+		return st.entries[i].ID.Compare(id) >= 0
+	})
+}
+
+func (g *group) sortedPending() []*Pending {
+	pending := make([]*Pending, 0, len(g.pending))
+	for _, p := range g.pending {
+		pending = append(pending, p)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].ID.Compare(pending[j].ID) < 0
+	})
+	return pending
+}
+
+func size(value []byte) int64 {
+	// An id takes 16 bytes.
+	return int64(len(value)) + 16
+}
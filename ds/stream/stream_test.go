@@ -0,0 +1,157 @@
+package stream
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const now = 1700000000000
+
+func ids(entries []Entry) []string {
+	var result []string
+	for _, e := range entries {
+		result = append(result, e.ID.String())
+	}
+	return result
+}
+
+func TestAdd(t *testing.T) {
+	s := New()
+	s.Add("events", nil, []byte("a"), 0, now)
+	s.Add("events", nil, []byte("b"), 0, now)
+	s.Add("events", nil, []byte("c"), 0, now-5)
+
+	expected := []string{"1700000000000-0", "1700000000000-1", "1700000000000-2"}
+	if got := ids(s.Range("events", ID{}, ID{Ms: now + 1}, 0)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected monotonic ids %v, got %v", expected, got)
+	}
+
+	if _, err := s.Add("events", &ID{Ms: now}, nil, 0, now); err != ErrIDTooSmall {
+		t.Errorf("expected %v, got %v", ErrIDTooSmall, err)
+	}
+	if id, err := s.Add("events", &ID{Ms: now + 10}, nil, 2, now); err != nil || id.Ms != uint64(now+10) {
+		t.Errorf("unexpected id %v, error %v", id, err)
+	}
+	if s.Len("events") != 2 {
+		t.Errorf("expected the stream to be trimmed to 2 entries, got %d", s.Len("events"))
+	}
+	if got := ids(s.After("events", ID{Ms: now, Seq: 2}, 0)); len(got) != 1 {
+		t.Errorf("unexpected entries after the third %v", got)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	s := New()
+	for i := 0; i < 100; i++ {
+		s.Add("events", nil, []byte("value"), 10, now)
+	}
+
+	st := s.streams["events"]
+	if s.Len("events") != 10 || cap(st.entries) > 20 {
+		t.Errorf("expected trimmed entries to be released, got %d entries in an array of %d", s.Len("events"),
+			cap(st.entries))
+	}
+	if s.Bytes() != 10*s.EntrySize([]byte("value")) {
+		t.Errorf("unexpected bytes %d", s.Bytes())
+	}
+
+	kept := st.entries
+	if s.Trim("events", 1) != 9 || len(kept) > 0 && kept[0].Value != nil {
+		t.Error("expected the values of trimmed entries to be released")
+	}
+}
+
+func TestSignal(t *testing.T) {
+	s := New()
+	signal := s.Signal("events")
+	s.Add("events", nil, []byte("a"), 0, now)
+
+	select {
+	case <-signal:
+	default:
+		t.Error("expected signal to be closed by an add")
+	}
+}
+
+func TestGroups(t *testing.T) {
+	s := New()
+	s.Add("events", nil, []byte("a"), 0, now)
+	if err := s.CreateGroup("events", "workers", "$"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := s.CreateGroup("events", "workers", "0"); err != ErrGroupExists {
+		t.Errorf("expected %v, got %v", ErrGroupExists, err)
+	}
+
+	if undelivered, _ := s.Undelivered("events", "workers"); undelivered {
+		t.Error("expected entries before the group to be delivered")
+	}
+	first, _ := s.Add("events", nil, []byte("b"), 0, now+1)
+	second, _ := s.Add("events", nil, []byte("c"), 0, now+2)
+	if undelivered, _ := s.Undelivered("events", "workers"); !undelivered {
+		t.Error("expected new entries to be undelivered")
+	}
+
+	entries, err := s.ReadGroup("events", "workers", "alice", 10, now+10)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected 2 new entries, got %v, error %v", entries, err)
+	}
+	if entries, _ := s.ReadGroup("events", "workers", "bob", 10, now+10); len(entries) != 0 {
+		t.Errorf("expected no entries left to deliver, got %v", entries)
+	}
+	if undelivered, _ := s.Undelivered("events", "workers"); undelivered {
+		t.Error("expected no entries left to deliver")
+	}
+	if _, err := s.Undelivered("events", "others"); err != ErrNoGroup {
+		t.Errorf("expected %v, got %v", ErrNoGroup, err)
+	}
+
+	if acked, _ := s.Ack("events", "workers", []ID{first, first}); acked != 1 {
+		t.Errorf("expected 1 ack, got %d", acked)
+	}
+
+	if claimed, _ := s.Claim("events", "workers", "bob", 100, nil, 10, now+50); len(claimed) != 0 {
+		t.Errorf("expected entries idle for less than min idle to stay, got %v", claimed)
+	}
+	claimed, _ := s.Claim("events", "workers", "bob", 100, nil, 10, now+200)
+	if len(claimed) != 1 || claimed[0].ID != second {
+		t.Fatalf("expected second entry to be claimed, got %v", claimed)
+	}
+
+	pending, _ := s.Pending("events", "workers", "bob")
+	if len(pending) != 1 || pending[0].Deliveries != 2 || pending[0].Delivered != now+200 {
+		t.Errorf("unexpected pending entries %+v", pending)
+	}
+
+	s.Trim("events", 0)
+	if claimed, _ := s.Claim("events", "workers", "alice", 0, []ID{second}, 0, now+300); len(claimed) != 0 {
+		t.Errorf("expected trimmed entry not to be claimed, got %v", claimed)
+	}
+	if pending, _ := s.Pending("events", "workers", ""); len(pending) != 0 {
+		t.Errorf("expected trimmed entry to leave the pending list, got %v", pending)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	s := New()
+	s.Add("events", nil, []byte("a"), 0, now)
+	s.CreateGroup("events", "workers", "0")
+	s.ReadGroup("events", "workers", "alice", 1, now)
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	restored := New()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if restored.Bytes() != s.Bytes() || restored.Last("events") != s.Last("events") {
+		t.Errorf("expected restored stream to match")
+	}
+	if pending, _ := restored.Pending("events", "workers", "alice"); len(pending) != 1 {
+		t.Errorf("expected pending entries to be restored, got %v", pending)
+	}
+}
@@ -0,0 +1,31 @@
+package topk
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalJSON encodes all lists with their sketches and tracked items for a snapshot.
+func (l *Lists) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.lists)
+}
+
+// UnmarshalJSON replaces all lists with a snapshot.
+func (l *Lists) UnmarshalJSON(data []byte) error {
+	var lists map[string]*list
+	if err := json.Unmarshal(data, &lists); err != nil {
+		return err
+	}
+
+	l.lists = make(map[string]*list, len(lists))
+	l.bytes = 0
+	for name, li := range lists {
+		if li.Sketch == nil {
+			return fmt.Errorf("top-k list %s has no sketch", name)
+		}
+		l.lists[name] = li
+		l.bytes += li.Sketch.Bytes()
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+// Package topk tracks the k most frequent items of a stream of counts. Counts are estimated by a count-min
+// sketch, so that the items below the top k do not need to be kept.
+package topk
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/huseyinbabal/demory/ds/cms"
+)
+
+const (
+	// DefaultWidth and DefaultDepth size the sketch of a list if they are not set.
+	DefaultWidth = 2048
+	DefaultDepth = 5
+	// MaxK limits the number of items a single list tracks.
+	MaxK = 10000
+)
+
+var (
+	ErrExists  = errors.New("top-k list already exists")
+	ErrNoList  = errors.New("top-k list does not exist")
+	ErrInvalid = errors.New("k must be between 1 and 10000")
+)
+
+// Item is a tracked item with its estimated count.
+type Item struct {
+	Item  string `json:"item"`
+	Count uint64 `json:"count"`
+}
+
+type list struct {
+	K      uint32      `json:"k"`
+	Sketch *cms.Sketch `json:"sketch"`
+	Items  []Item      `json:"items"`
+}
+
+// Lists holds all top-k lists of a node.
+type Lists struct {
+	lists map[string]*list
+	bytes int64
+}
+
+// New creates an empty set of lists.
+func New() *Lists {
+	return &Lists{lists: make(map[string]*list)}
+}
+
+// Dimensions returns the sketch dimensions of a list, applying the defaults, or an error if they are invalid.
+func Dimensions(k, width, depth uint32) (uint32, uint32, error) {
+	if k == 0 || k > MaxK {
+		return 0, 0, ErrInvalid
+	}
+	if width == 0 {
+		width = DefaultWidth
+	}
+	if depth == 0 {
+		depth = DefaultDepth
+	}
+	return width, depth, cms.Validate(width, depth)
+}
+
+// Size returns the number of bytes of a list, or zero if its dimensions are invalid.
+func Size(k, width, depth uint32) int64 {
+	width, depth, err := Dimensions(k, width, depth)
+	if err != nil {
+		return 0
+	}
+	return int64(width) * int64(depth) * 8
+}
+
+// Create creates the list name tracking the k most frequent items, with a sketch of depth rows of width
+// counters. Zero dimensions are replaced by the defaults.
+func (l *Lists) Create(name string, k, width, depth uint32) error {
+	if _, ok := l.lists[name]; ok {
+		return ErrExists
+	}
+	width, depth, err := Dimensions(k, width, depth)
+	if err != nil {
+		return err
+	}
+
+	sketch := cms.NewSketch(width, depth)
+	l.lists[name] = &list{K: k, Sketch: sketch}
+	l.bytes += sketch.Bytes()
+
+	return nil
+}
+
+// Add counts items in the list name. For each increment, it returns the item that was expelled from the
+// top k to make room for it, or an empty string.
+func (l *Lists) Add(name string, increments []cms.Increment) ([]string, error) {
+	li, ok := l.lists[name]
+	if !ok {
+		return nil, ErrNoList
+	}
+
+	expelled := make([]string, len(increments))
+	for i, increment := range increments {
+		count := li.Sketch.Add(increment.Item, increment.Count)
+		expelled[i] = li.add(increment.Item, count)
+	}
+	return expelled, nil
+}
+
+// List returns the tracked items of the list name, most frequent first.
+func (l *Lists) List(name string) ([]Item, error) {
+	li, ok := l.lists[name]
+	if !ok {
+		return nil, ErrNoList
+	}
+
+	items := append([]Item(nil), li.Items...)
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Item < items[j].Item
+	})
+	return items, nil
+}
+
+// Query reports for each item whether it is in the top k of the list name.
+func (l *Lists) Query(name string, items []string) ([]bool, error) {
+	li, ok := l.lists[name]
+	if !ok {
+		return nil, ErrNoList
+	}
+
+	result := make([]bool, len(items))
+	for i, item := range items {
+		result[i] = li.find(item) >= 0
+	}
+	return result, nil
+}
+
+// Count returns the estimated counts of items in the list name, whether they are in the top k or not.
+func (l *Lists) Count(name string, items []string) ([]uint64, error) {
+	li, ok := l.lists[name]
+	if !ok {
+		return nil, ErrNoList
+	}
+
+	counts := make([]uint64, len(items))
+	for i, item := range items {
+		counts[i] = li.Sketch.Estimate(item)
+	}
+	return counts, nil
+}
+
+// Destroy removes the list name, and reports whether it existed.
+func (l *Lists) Destroy(name string) bool {
+	li, ok := l.lists[name]
+	if !ok {
+		return false
+	}
+	l.bytes -= li.Sketch.Bytes()
+	delete(l.lists, name)
+	return true
+}
+
+// Exists reports whether the list name exists.
+func (l *Lists) Exists(name string) bool {
+	_, ok := l.lists[name]
+	return ok
+}
+
+// Bytes returns the number of bytes held by the sketches of all lists.
+func (l *Lists) Bytes() int64 {
+	return l.bytes
+}
+
+// add updates the count of a tracked item, or tracks it in place of the least frequent item if its count is
+// higher. Ties are broken by the item, so that every replica expels the same one.
+func (li *list) add(item string, count uint64) string {
+	if i := li.find(item); i >= 0 {
+		li.Items[i].Count = count
+		return ""
+	}
+	if len(li.Items) < int(li.K) {
+		li.Items = append(li.Items, Item{Item: item, Count: count})
+		return ""
+	}
+
+	least := 0
+	for i, tracked := range li.Items {
+		if tracked.Count < li.Items[least].Count ||
+			tracked.Count == li.Items[least].Count && tracked.Item > li.Items[least].Item {
+			least = i
+		}
+	}
+	if count <= li.Items[least].Count {
+		return ""
+	}
+
+	expelled := li.Items[least].Item
+	li.Items[least] = Item{Item: item, Count: count}
+	return expelled
+}
+
+func (li *list) find(item string) int {
+	for i, tracked := range li.Items {
+		if tracked.Item == item {
+			return i
+		}
+	}
+	return -1
+}
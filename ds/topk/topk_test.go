@@ -0,0 +1,48 @@
+package topk
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/huseyinbabal/demory/ds/cms"
+)
+
+func TestTopK(t *testing.T) {
+	l := New()
+	if err := l.Create("pages", 0, 0, 0); err != ErrInvalid {
+		t.Errorf("expected %v, got %v", ErrInvalid, err)
+	}
+	if err := l.Create("pages", 2, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	expelled, err := l.Add("pages", []cms.Increment{{Item: "a", Count: 5}, {Item: "b", Count: 3},
+		{Item: "c", Count: 1}, {Item: "c", Count: 3}})
+	if err != nil || !reflect.DeepEqual(expelled, []string{"", "", "", "b"}) {
+		t.Errorf("unexpected expelled items %v, error %v", expelled, err)
+	}
+
+	items, _ := l.List("pages")
+	if expected := []Item{{Item: "a", Count: 5}, {Item: "c", Count: 4}}; !reflect.DeepEqual(items, expected) {
+		t.Errorf("expected %v, got %v", expected, items)
+	}
+	if found, _ := l.Query("pages", []string{"a", "b"}); !reflect.DeepEqual(found, []bool{true, false}) {
+		t.Errorf("unexpected query result %v", found)
+	}
+	if counts, _ := l.Count("pages", []string{"b"}); counts[0] != 3 {
+		t.Errorf("expected the count of an expelled item to be kept, got %d", counts[0])
+	}
+
+	encoded, err := json.Marshal(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := New()
+	if err := json.Unmarshal(encoded, restored); err != nil {
+		t.Fatal(err)
+	}
+	if restoredItems, _ := restored.List("pages"); !reflect.DeepEqual(restoredItems, items) {
+		t.Errorf("expected the snapshot to keep the tracked items, got %v", restoredItems)
+	}
+}
@@ -0,0 +1,296 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/huseyinbabal/demory/ds/election"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/structure"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ structure.Waker = &election.Elections{}
+
+func init() {
+	structure.Register(newElectionStructure)
+}
+
+// electionStructure serves and applies sessions and leader elections.
+type electionStructure struct {
+	host      structure.Host
+	elections *election.Elections
+}
+
+func newElectionStructure(host structure.Host) structure.Structure {
+	return &electionStructure{host: host, elections: election.New()}
+}
+
+func (s *electionStructure) Kind() string {
+	return "elections"
+}
+
+func (s *electionStructure) Commands() []fsm.CommandType {
+	return []fsm.CommandType{
+		fsm.SessionGrant, fsm.SessionKeepAlive, fsm.SessionRevoke, fsm.SessionExpire, fsm.ElectionCampaign,
+		fsm.ElectionResign,
+	}
+}
+
+func (s *electionStructure) Register(server grpc.ServiceRegistrar) {
+	rpc.RegisterElectionServer(server, s)
+}
+
+func (s *electionStructure) Snapshot() ([]byte, error) {
+	return json.Marshal(s.elections)
+}
+
+func (s *electionStructure) Restore(data []byte) (func(), error) {
+	elections := election.New()
+	if err := structure.Unmarshal(data, elections); err != nil {
+		return nil, err
+	}
+	return func() {
+		previous := s.elections
+		s.elections = elections
+		previous.Wake()
+	}, nil
+}
+
+func (s *electionStructure) Stats() structure.Stats {
+	return structure.Stats{Bytes: s.elections.Bytes()}
+}
+
+func (s *electionStructure) Destroy(name string) bool {
+	return s.elections.Destroy(name)
+}
+
+// sessionExpirationBatch is the maximum number of sessions expired per sweep.
+const sessionExpirationBatch = 1000
+
+// electionArgs are the arguments of replicated session and election commands.
+type electionArgs struct {
+	Session uint64   `json:"session,omitempty"`
+	TTL     int64    `json:"ttl,omitempty"`
+	Term    uint64   `json:"term,omitempty"`
+	Expired []uint64 `json:"expired,omitempty"`
+}
+
+// GrantSession creates a session to campaign with. It expires after its ttl unless it is kept alive.
+func (s *electionStructure) GrantSession(ctx context.Context,
+	req *rpc.SessionGrantRequest) (*rpc.SessionResponse, error) {
+	if err := election.ValidateTTL(time.Duration(req.TTL) * time.Millisecond); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	data, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.SessionGrant}, electionArgs{TTL: req.TTL})
+	if err != nil {
+		return nil, err
+	}
+
+	return data.(*rpc.SessionResponse), nil
+}
+
+// KeepAliveSession extends a session by its ttl.
+func (s *electionStructure) KeepAliveSession(ctx context.Context,
+	req *rpc.SessionRequest) (*rpc.SessionResponse, error) {
+	data, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.SessionKeepAlive}, electionArgs{Session: req.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.SessionResponse{ID: req.ID, Expires: data.(int64)}, nil
+}
+
+// RevokeSession removes a session, resigning it from all elections.
+func (s *electionStructure) RevokeSession(ctx context.Context, req *rpc.SessionRequest) (*rpc.Empty, error) {
+	_, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.SessionRevoke}, electionArgs{Session: req.ID})
+	return new(rpc.Empty), err
+}
+
+// Campaign makes a session a candidate of an election and waits until it leads it. If the call is canceled
+// before, the session withdraws its candidacy.
+func (s *electionStructure) Campaign(ctx context.Context, req *rpc.CampaignRequest) (*rpc.LeaderResponse, error) {
+	request := fsm.ApplyRequest{Type: fsm.ElectionCampaign, Name: req.Name, Value: req.Value}
+	if _, err := s.host.Propose(request, electionArgs{Session: req.Session}); err != nil {
+		return nil, err
+	}
+
+	for {
+		// The signal is taken before reading, so that no change after the read is missed.
+		var signal <-chan struct{}
+		var leader election.Leader
+		var leads, alive bool
+		s.host.Read(func() {
+			signal = s.elections.Signal(req.Name)
+			leader, leads = s.elections.Leader(req.Name)
+			alive = s.elections.HasSession(req.Session)
+		})
+
+		if leads && leader.Session == req.Session {
+			return &rpc.LeaderResponse{Leader: &leader}, nil
+		}
+		if !alive {
+			return nil, electionError(election.ErrNoSession)
+		}
+
+		select {
+		case <-signal:
+		case <-ctx.Done():
+			withdraw := fsm.ApplyRequest{Type: fsm.ElectionResign, Name: req.Name}
+			if _, err := s.host.Propose(withdraw, electionArgs{Session: req.Session}); err != nil {
+				log.Printf("failed to withdraw session %d from election %s %v.\n", req.Session, req.Name, err)
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Resign withdraws a session from an election, handing the leadership to the next candidate if it led it.
+func (s *electionStructure) Resign(ctx context.Context, req *rpc.ResignRequest) (*rpc.Empty, error) {
+	request := fsm.ApplyRequest{Type: fsm.ElectionResign, Name: req.Name}
+	_, err := s.host.Propose(request, electionArgs{Session: req.Session, Term: req.Term})
+	return new(rpc.Empty), err
+}
+
+// ElectionLeader returns the leader of an election.
+func (s *electionStructure) ElectionLeader(ctx context.Context, req *rpc.ElectionRequest) (*rpc.LeaderResponse, error) {
+	response := &rpc.LeaderResponse{}
+	s.host.Read(func() {
+		if leader, ok := s.elections.Leader(req.Name); ok {
+			response.Leader = &leader
+		}
+	})
+
+	return response, nil
+}
+
+// ObserveElection streams the leader of an election, first as it is and then every time it changes.
+func (s *electionStructure) ObserveElection(req *rpc.ElectionRequest,
+	stream rpc.ServerStream[rpc.LeaderResponse]) error {
+	var sent *election.Leader
+	first := true
+	for {
+		var signal <-chan struct{}
+		response := &rpc.LeaderResponse{}
+		s.host.Read(func() {
+			signal = s.elections.Signal(req.Name)
+			if leader, ok := s.elections.Leader(req.Name); ok {
+				response.Leader = &leader
+			}
+		})
+
+		changed := (sent == nil) != (response.Leader == nil) ||
+			sent != nil && (sent.Session != response.Leader.Session || sent.Term != response.Leader.Term)
+		if first || changed {
+			if err := stream.Send(response); err != nil {
+				return err
+			}
+			sent, first = response.Leader, false
+		}
+
+		select {
+		case <-signal:
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// Start runs the sweep expiring sessions.
+func (s *electionStructure) Start() {
+	go s.expireSessions()
+}
+
+// expireSessions periodically proposes the removal of expired sessions. Only the leader sweeps, and the
+// sessions are removed through the raft log, so every replica hands over the same leaderships.
+func (s *electionStructure) expireSessions() {
+	ticker := time.NewTicker(expirationInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !s.host.Leader() {
+			continue
+		}
+
+		var expired []uint64
+		s.host.Read(func() {
+			expired = s.elections.Expired(time.Now().UnixNano(), sessionExpirationBatch)
+		})
+		if len(expired) == 0 {
+			continue
+		}
+
+		if _, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.SessionExpire}, electionArgs{Expired: expired}); err != nil {
+			log.Printf("failed to expire sessions %v.\n", err)
+		}
+	}
+}
+
+func (s *electionStructure) Apply(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args electionArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	var data interface{}
+	var err error
+	switch request.Type {
+	case fsm.SessionGrant:
+		ttl := time.Duration(args.TTL) * time.Millisecond
+		if err = s.elections.Grant(request.Index, ttl, request.Time); err == nil {
+			data = &rpc.SessionResponse{ID: request.Index, Expires: request.Time + int64(ttl)}
+		}
+	case fsm.SessionKeepAlive:
+		data, err = s.elections.KeepAlive(args.Session, request.Time)
+	case fsm.SessionRevoke:
+		if !s.elections.Revoke(args.Session, request.Index) {
+			err = election.ErrNoSession
+		}
+	case fsm.SessionExpire:
+		data = s.elections.Expire(args.Expired, request.Time, request.Index)
+	case fsm.ElectionCampaign:
+		data, err = s.elections.Campaign(request.Name, args.Session, request.Value, request.Index)
+	case fsm.ElectionResign:
+		err = s.elections.Resign(request.Name, args.Session, args.Term, request.Index)
+	}
+	if err != nil {
+		return fsm.ApplyResponse{Error: electionError(err)}
+	}
+
+	return fsm.ApplyResponse{Data: data}
+}
+
+// electionError converts errors of the election package to gRPC statuses.
+func electionError(err error) error {
+	switch {
+	case errors.Is(err, election.ErrNoSession):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, election.ErrNotLeader):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
+
+// Growth returns the number of bytes of a granted session, or of a new candidate of an election.
+func (s *electionStructure) Growth(request fsm.ApplyRequest) int64 {
+	switch request.Type {
+	case fsm.SessionGrant:
+		return election.SessionSize
+	case fsm.ElectionCampaign:
+		var args electionArgs
+		if json.Unmarshal(request.Args, &args) != nil || !s.elections.HasSession(args.Session) {
+			return 0
+		}
+		return s.elections.CampaignGrowth(request.Name, args.Session, request.Value)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,156 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/mapstore"
+	"github.com/huseyinbabal/demory/predicate"
+	"github.com/huseyinbabal/demory/processor"
+	"github.com/huseyinbabal/demory/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// executeArgs are the arguments of a replicated MapExecute command.
+type executeArgs struct {
+	Processor string               `json:"processor"`
+	Arguments json.RawMessage      `json:"arguments,omitempty"`
+	Predicate *predicate.Predicate `json:"predicate,omitempty"`
+}
+
+// MapExecute runs an entry processor atomically on one key, a set of keys, or every entry matching a predicate.
+func (d *Demory) MapExecute(ctx context.Context, req *rpc.MapExecuteRequest) (*rpc.MapExecuteResponse, error) {
+	if _, ok := processor.Get(req.Processor); !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown processor %s", req.Processor)
+	}
+
+	selectors := 0
+	for _, selected := range []bool{req.Key != "", len(req.Keys) > 0, req.Predicate != nil} {
+		if selected {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		return nil, status.Error(codes.InvalidArgument, "exactly one of key, keys and predicate must be set")
+	}
+
+	request := fsm.ApplyRequest{
+		Type:        fsm.MapExecute,
+		Name:        req.Name,
+		Key:         req.Key,
+		Keys:        req.Keys,
+		WriteBehind: d.writesBehind(req.Name),
+	}
+	data, err := d.propose(request, executeArgs{
+		Processor: req.Processor,
+		Arguments: req.Arguments,
+		Predicate: req.Predicate,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := data.([]processor.Entry)
+	results := make([]rpc.EntryResult, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, rpc.EntryResult{Key: entry.Key, Value: entry.Value, Removed: !entry.Exists})
+	}
+
+	persistErr := d.persist(req.Name, func(p *mapstore.Persister) error {
+		for _, entry := range entries {
+			var err error
+			if entry.Exists {
+				err = p.Put(req.Name, entry.Key, entry.Value)
+			} else {
+				err = p.Delete(req.Name, entry.Key)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return &rpc.MapExecuteResponse{Results: results}, persistErr
+}
+
+// execute applies a MapExecute command. All entries are processed before any of them is written,
+// so a failing processor leaves the map untouched.
+func (d *Demory) execute(request fsm.ApplyRequest) fsm.ApplyResponse {
+	entries, _, err := d.process(request)
+	if err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	for _, entry := range entries {
+		if entry.Exists {
+			d.hashMap.Put(request.Name, entry.Key, entry.Value)
+		} else {
+			d.hashMap.Remove(request.Name, entry.Key)
+		}
+	}
+
+	return fsm.ApplyResponse{Data: entries}
+}
+
+// process runs the processor of a MapExecute command on the entries it selects, without writing them. It
+// returns the processed entries and the number of bytes the map would grow by once they are written.
+func (d *Demory) process(request fsm.ApplyRequest) ([]processor.Entry, int64, error) {
+	var args executeArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return nil, 0, err
+	}
+
+	process, ok := processor.Get(args.Processor)
+	if !ok {
+		return nil, 0, status.Errorf(codes.InvalidArgument, "unknown processor %s", args.Processor)
+	}
+
+	keys := request.Keys
+	if request.Key != "" {
+		keys = []string{request.Key}
+	}
+	if args.Predicate != nil {
+		keys = nil
+		for _, key := range d.hashMap.Keys(request.Name) {
+			matches, err := args.Predicate.Match(d.hashMap.Get(request.Name, key))
+			if err != nil {
+				return nil, 0, status.Error(codes.InvalidArgument, err.Error())
+			}
+			if matches {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	var growth int64
+	seen := make(map[string]bool, len(keys))
+	entries := make([]processor.Entry, 0, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		current := processor.Entry{Key: key, Value: d.hashMap.Get(request.Name, key)}
+		currentSize, exists := d.hashMap.EntrySize(request.Name, key)
+		current.Exists = exists
+
+		entry, err := process(current, args.Arguments)
+		if err != nil {
+			return nil, 0, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		entry.Key = key
+
+		if entry.Exists {
+			growth += int64(len(key)+len(entry.Value)) - currentSize
+		} else {
+			growth -= currentSize
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, growth, nil
+}
@@ -0,0 +1,65 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	proto "github.com/huseyinbabal/demory-proto/golang/demory"
+	"github.com/huseyinbabal/demory/node"
+	"github.com/huseyinbabal/demory/processor"
+	"github.com/huseyinbabal/demory/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMapExecute(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	ctx := context.Background()
+	d.MapPut(ctx, &proto.MapPutRequest{Name: "counters", Key: "a", Value: []byte("1")})
+
+	response, err := d.MapExecute(ctx, &rpc.MapExecuteRequest{
+		Name:      "counters",
+		Keys:      []string{"a", "b"},
+		Processor: processor.Increment,
+		Arguments: json.RawMessage(`{"delta": 2}`),
+	})
+	if err != nil {
+		t.Fatalf("failed to execute %v", err)
+	}
+	if len(response.Results) != 2 || string(response.Results[0].Value) != "3" || string(response.Results[1].Value) != "2" {
+		t.Errorf("unexpected results %v", response.Results)
+	}
+
+	// A failing processor leaves every entry untouched.
+	d.MapPut(ctx, &proto.MapPutRequest{Name: "counters", Key: "c", Value: []byte("text")})
+	_, err = d.MapExecute(ctx, &rpc.MapExecuteRequest{
+		Name:      "counters",
+		Keys:      []string{"a", "c"},
+		Processor: processor.Increment,
+		Arguments: json.RawMessage(`{"delta": 1}`),
+	})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected the processor to fail, got %v", err)
+	}
+	d.fsm.Read(func() {
+		if got := string(d.hashMap.Get("counters", "a")); got != "3" {
+			t.Errorf("expected a to be untouched, got %s", got)
+		}
+	})
+}
+
+func TestMapExecuteOutOfMemory(t *testing.T) {
+	d := newTestNode(t, node.Config{MaxMemory: 4})
+	ctx := context.Background()
+
+	_, err := d.MapExecute(ctx, &rpc.MapExecuteRequest{
+		Name:      "logs",
+		Key:       "a",
+		Processor: processor.Append,
+		Arguments: json.RawMessage(`{"value": "MTIzNA=="}`),
+	})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected the write to be rejected, got %v", err)
+	}
+}
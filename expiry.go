@@ -0,0 +1,94 @@
+package demory
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/huseyinbabal/demory/ds/cache"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// expirationInterval is how often the leader looks for expired cache entries.
+	expirationInterval = time.Second
+	// expirationBatch is the maximum number of entries expired per sweep.
+	expirationBatch = 1000
+)
+
+// CachePutWithTTL saves data into store with an expiry.
+func (d *Demory) CachePutWithTTL(ctx context.Context, req *rpc.CachePutWithTTLRequest) (*rpc.Empty, error) {
+	if req.TTL < 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl must not be negative")
+	}
+
+	request := fsm.ApplyRequest{
+		Type:  fsm.CachePut,
+		Name:  req.Name,
+		Key:   req.Key,
+		Value: req.Value,
+		TTL:   time.Duration(req.TTL) * time.Millisecond,
+	}
+	if _, err := d.propose(request, nil); err != nil {
+		return nil, err
+	}
+
+	return &rpc.Empty{}, nil
+}
+
+// CacheSetDefaultTTL sets the TTL of entries put into a cache without a TTL of their own.
+func (d *Demory) CacheSetDefaultTTL(ctx context.Context, req *rpc.CacheSetDefaultTTLRequest) (*rpc.Empty, error) {
+	if req.TTL < 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl must not be negative")
+	}
+
+	request := fsm.ApplyRequest{
+		Type: fsm.CacheSetDefaultTTL,
+		Name: req.Name,
+		TTL:  time.Duration(req.TTL) * time.Millisecond,
+	}
+	if _, err := d.propose(request, nil); err != nil {
+		return nil, err
+	}
+
+	return &rpc.Empty{}, nil
+}
+
+// expireEntries periodically proposes the removal of expired cache entries. Only the leader sweeps,
+// and entries are removed through the raft log, so every replica expires the same entries.
+func (d *Demory) expireEntries() {
+	ticker := time.NewTicker(expirationInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if d.fsm.Raft.State() != raft.Leader {
+			continue
+		}
+
+		now := time.Now()
+		var expired []cache.Key
+		d.fsm.Read(func() {
+			expired = d.cache.Expired(now, expirationBatch)
+		})
+
+		keys := make(map[string][]string)
+		for _, k := range expired {
+			keys[k.Name] = append(keys[k.Name], k.Key)
+		}
+
+		for name, cacheKeys := range keys {
+			request := fsm.ApplyRequest{
+				Type: fsm.CacheExpire,
+				Name: name,
+				Keys: cacheKeys,
+			}
+			if _, err := d.propose(request, nil); err != nil {
+				log.Printf("failed to expire entries of cache %s %v.\n", name, err)
+			}
+		}
+	}
+}
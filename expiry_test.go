@@ -0,0 +1,63 @@
+package demory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	proto "github.com/huseyinbabal/demory-proto/golang/demory"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/node"
+	"github.com/huseyinbabal/demory/rpc"
+)
+
+func TestCacheExpire(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	ctx := context.Background()
+
+	d.CachePutWithTTL(ctx, &rpc.CachePutWithTTLRequest{Name: "sessions", Key: "a", Value: []byte("1"), TTL: 1000})
+	d.CachePutWithTTL(ctx, &rpc.CachePutWithTTLRequest{Name: "sessions", Key: "b", Value: []byte("2"), TTL: 60000})
+	d.CachePut(ctx, &proto.CachePutRequest{Name: "sessions", Key: "c", Value: []byte("3")})
+
+	// Expiry is decided by the time the leader proposed it, not the clock of the replica.
+	response := apply(t, d, fsm.ApplyRequest{
+		Type: fsm.CacheExpire,
+		Name: "sessions",
+		Keys: []string{"a", "b", "c"},
+		Time: time.Now().Add(2 * time.Second).UnixNano(),
+	})
+	if response.Data != 1 {
+		t.Errorf("expected one entry to expire, got %v", response.Data)
+	}
+
+	now := time.Now()
+	d.fsm.Read(func() {
+		if _, ok := d.cache.EntrySize("sessions", "a"); ok {
+			t.Error("expected a to be removed")
+		}
+		for _, key := range []string{"b", "c"} {
+			if _, ok := d.cache.Peek("sessions", key, now); !ok {
+				t.Errorf("expected %s to be kept", key)
+			}
+		}
+	})
+}
+
+func TestCacheDefaultTTL(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	ctx := context.Background()
+
+	d.CachePut(ctx, &proto.CachePutRequest{Name: "sessions", Key: "a", Value: []byte("1")})
+	d.CacheSetDefaultTTL(ctx, &rpc.CacheSetDefaultTTLRequest{Name: "sessions", TTL: 1000})
+	d.CachePut(ctx, &proto.CachePutRequest{Name: "sessions", Key: "b", Value: []byte("2")})
+
+	now := time.Now()
+	d.fsm.Read(func() {
+		if ttl, ok := d.cache.TTL("sessions", "a", now); !ok || ttl != 0 {
+			t.Errorf("expected a to keep not expiring, got %v", ttl)
+		}
+		if ttl, ok := d.cache.TTL("sessions", "b", now); !ok || ttl <= 0 || ttl > time.Second {
+			t.Errorf("expected b to expire after the default TTL, got %v", ttl)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/huseyinbabal/demory/ds/flake"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/structure"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	structure.Register(newFlakeStructure)
+}
+
+// flakeStructure serves and applies flake id generators.
+type flakeStructure struct {
+	host       structure.Host
+	allocator  *flake.Allocator
+	generators *flake.Generators
+}
+
+func newFlakeStructure(host structure.Host) structure.Structure {
+	return &flakeStructure{
+		host:       host,
+		allocator:  flake.NewAllocator(),
+		generators: flake.NewGenerators(time.Now()),
+	}
+}
+
+func (s *flakeStructure) Kind() string {
+	return "flakes"
+}
+
+func (s *flakeStructure) Commands() []fsm.CommandType {
+	return []fsm.CommandType{fsm.FlakeAllocate}
+}
+
+func (s *flakeStructure) Register(server grpc.ServiceRegistrar) {
+	rpc.RegisterFlakeIDServer(server, s)
+}
+
+func (s *flakeStructure) Snapshot() ([]byte, error) {
+	return json.Marshal(s.allocator)
+}
+
+func (s *flakeStructure) Restore(data []byte) (func(), error) {
+	allocator := flake.NewAllocator()
+	if err := structure.Unmarshal(data, allocator); err != nil {
+		return nil, err
+	}
+	return func() { s.allocator = allocator }, nil
+}
+
+func (s *flakeStructure) Stats() structure.Stats {
+	return structure.Stats{Bytes: s.allocator.Bytes()}
+}
+
+// Destroy keeps leases, as handing out a node id twice could generate duplicate ids.
+func (s *flakeStructure) Destroy(name string) bool {
+	return false
+}
+
+// flakeArgs are the arguments of replicated FlakeAllocate commands.
+type flakeArgs struct {
+	Servers []string `json:"servers"`
+}
+
+// nodeAllocationInterval is how often the leader looks for leases to renew.
+const nodeAllocationInterval = time.Second
+
+// Start runs the leasing of node ids.
+func (s *flakeStructure) Start() {
+	go s.allocateNodes()
+}
+
+// allocateNodes leases a node id to every member of the cluster that has none yet, including the leader itself
+// and servers that joined since, and renews the leases of the others before they expire. Only the leader leases,
+// through the raft log, so followers never need to propose anything to generate ids. Servers removed from the
+// cluster are no longer renewed, and their node ids are reclaimed once all others are handed out.
+func (s *flakeStructure) allocateNodes() {
+	ticker := time.NewTicker(nodeAllocationInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !s.host.Leader() {
+			continue
+		}
+
+		servers, err := s.host.Servers()
+		if err != nil {
+			log.Printf("failed to read the servers of the cluster %v.\n", err)
+			continue
+		}
+		var renewing []string
+		s.host.Read(func() {
+			renewing = s.allocator.Renewing(servers, time.Now())
+		})
+		if len(renewing) == 0 {
+			continue
+		}
+
+		if _, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.FlakeAllocate}, flakeArgs{Servers: renewing}); err != nil {
+			log.Printf("failed to allocate flake node ids %v.\n", err)
+		}
+	}
+}
+
+// NewIDs generates unique ids locally, from the node id the leader leased to this node.
+func (s *flakeStructure) NewIDs(ctx context.Context, req *rpc.FlakeIDRequest) (*rpc.FlakeIDResponse, error) {
+	count := req.Count
+	if count == 0 {
+		count = 1
+	}
+	if count < 0 || count > flake.MaxBatch {
+		return nil, status.Error(codes.InvalidArgument, flake.ErrBatch.Error())
+	}
+
+	var node uint32
+	var allocated bool
+	s.host.Read(func() {
+		node, allocated = s.allocator.Node(s.host.Server(), time.Now())
+	})
+	if !allocated {
+		return nil, status.Error(codes.Unavailable, "node id is not leased, retry later")
+	}
+
+	ids, err := s.generators.Get(req.Name, node).Next(count, time.Now())
+	if errors.Is(err, flake.ErrTooFarAhead) {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &rpc.FlakeIDResponse{IDs: ids}, nil
+}
+
+func (s *flakeStructure) Apply(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args flakeArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	// Servers left without a node id do not keep the others from being leased or renewed.
+	now := time.Unix(0, request.Time)
+	var exhausted error
+	for _, server := range args.Servers {
+		if _, err := s.allocator.Allocate(server, now); err != nil {
+			exhausted = status.Error(codes.ResourceExhausted, err.Error())
+		}
+	}
+	return fsm.ApplyResponse{Error: exhausted}
+}
+
+// Growth returns the number of bytes the leases of servers without one take.
+func (s *flakeStructure) Growth(request fsm.ApplyRequest) int64 {
+	var args flakeArgs
+	if json.Unmarshal(request.Args, &args) != nil {
+		return 0
+	}
+	return s.allocator.Growth(args.Servers)
+}
@@ -0,0 +1,52 @@
+package demory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/demory/ds/flake"
+	"github.com/huseyinbabal/demory/node"
+	"github.com/huseyinbabal/demory/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFlakeNodeAllocation(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	ctx := context.Background()
+	kind, _ := d.structures.Kind("flakes")
+	s := kind.(*flakeStructure)
+
+	if _, err := s.NewIDs(ctx, &rpc.FlakeIDRequest{Name: "orders"}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected ids to wait for a node id, got %v", err)
+	}
+
+	s.Start()
+	deadline := time.Now().Add(10 * time.Second)
+	var response *rpc.FlakeIDResponse
+	var err error
+	for response == nil && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+		response, err = s.NewIDs(ctx, &rpc.FlakeIDRequest{Name: "orders", Count: 2})
+	}
+	if err != nil {
+		t.Fatalf("failed to generate ids %v", err)
+	}
+	if len(response.IDs) != 2 || flake.Node(response.IDs[0]) != 0 || response.IDs[1] <= response.IDs[0] {
+		t.Errorf("unexpected ids %v", response.IDs)
+	}
+
+	// Restarting keeps the node id handed out to the server.
+	data, _ := s.Snapshot()
+	restarted := newFlakeStructure(s.host).(*flakeStructure)
+	swap, err := restarted.Restore(data)
+	if err != nil {
+		t.Fatalf("failed to restore %v", err)
+	}
+	swap()
+	response, err = restarted.NewIDs(ctx, &rpc.FlakeIDRequest{Name: "orders"})
+	if err != nil || flake.Node(response.IDs[0]) != 0 {
+		t.Errorf("expected the node id to be kept, got %v %v", response, err)
+	}
+}
@@ -1,10 +1,208 @@
 package fsm
 
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// CommandType identifies the operation carried by a raft log entry.
+type CommandType uint16
+
+const (
+	MapPut CommandType = iota + 1
+	MapPutIfAbsent
+	MapRemove
+	MapClear
+	CachePut
+	CacheRemove
+	CacheClear
+	CacheExpire
+	CacheSetDefaultTTL
+	MapExecute
+	IndexCreate
+	IndexDrop
+	Transaction
+	TopicPublish
+	StreamAdd
+	StreamTrim
+	StreamCreateGroup
+	StreamDestroyGroup
+	StreamReadGroup
+	StreamAck
+	StreamClaim
+	HLLAdd
+	HLLMerge
+	BloomCreate
+	BloomAdd
+	CMSCreate
+	CMSIncrement
+	CMSMerge
+	TopKCreate
+	TopKAdd
+	BitmapSetBit
+	BitmapOp
+	GeoAdd
+	GeoRemove
+	FlakeAllocate
+	RateLimitDefine
+	RateLimitDelete
+	RateLimitAcquire
+	SessionGrant
+	SessionKeepAlive
+	SessionRevoke
+	SessionExpire
+	ElectionCampaign
+	ElectionResign
+	DocumentSet
+	DocumentDelete
+	DocumentIncrement
+	DocumentArrayAppend
+	DocumentArrayInsert
+	DocumentArrayPop
+	RingCreate
+	RingDestroy
+	RingAdd
+	MultiMapPut
+	MultiMapRemove
+	MultiMapRemoveAll
+	StructureDestroy
+	MapStoreFlush
+	RespBatch
+)
+
+// FirstCustomCommand is the first command type left to structure types defined outside of this module.
+const FirstCustomCommand CommandType = 1 << 15
+
+var commandNames = map[CommandType]string{
+	MapPut:              "map-put",
+	MapPutIfAbsent:      "map-put-if-absent",
+	MapRemove:           "map-remove",
+	MapClear:            "map-clear",
+	CachePut:            "cache-put",
+	CacheRemove:         "cache-remove",
+	CacheClear:          "cache-clear",
+	CacheExpire:         "cache-expire",
+	CacheSetDefaultTTL:  "cache-set-default-ttl",
+	MapExecute:          "map-execute",
+	IndexCreate:         "index-create",
+	IndexDrop:           "index-drop",
+	Transaction:         "transaction",
+	TopicPublish:        "topic-publish",
+	StreamAdd:           "stream-add",
+	StreamTrim:          "stream-trim",
+	StreamCreateGroup:   "stream-create-group",
+	StreamDestroyGroup:  "stream-destroy-group",
+	StreamReadGroup:     "stream-read-group",
+	StreamAck:           "stream-ack",
+	StreamClaim:         "stream-claim",
+	HLLAdd:              "hll-add",
+	HLLMerge:            "hll-merge",
+	BloomCreate:         "bloom-create",
+	BloomAdd:            "bloom-add",
+	CMSCreate:           "cms-create",
+	CMSIncrement:        "cms-increment",
+	CMSMerge:            "cms-merge",
+	TopKCreate:          "topk-create",
+	TopKAdd:             "topk-add",
+	BitmapSetBit:        "bitmap-set-bit",
+	BitmapOp:            "bitmap-op",
+	GeoAdd:              "geo-add",
+	GeoRemove:           "geo-remove",
+	FlakeAllocate:       "flake-allocate",
+	RateLimitDefine:     "rate-limit-define",
+	RateLimitDelete:     "rate-limit-delete",
+	RateLimitAcquire:    "rate-limit-acquire",
+	SessionGrant:        "session-grant",
+	SessionKeepAlive:    "session-keep-alive",
+	SessionRevoke:       "session-revoke",
+	SessionExpire:       "session-expire",
+	ElectionCampaign:    "election-campaign",
+	ElectionResign:      "election-resign",
+	DocumentSet:         "document-set",
+	DocumentDelete:      "document-delete",
+	DocumentIncrement:   "document-increment",
+	DocumentArrayAppend: "document-array-append",
+	DocumentArrayInsert: "document-array-insert",
+	DocumentArrayPop:    "document-array-pop",
+	RingCreate:          "ring-create",
+	RingDestroy:         "ring-destroy",
+	RingAdd:             "ring-add",
+	MultiMapPut:         "multimap-put",
+	MultiMapRemove:      "multimap-remove",
+	MultiMapRemoveAll:   "multimap-remove-all",
+	StructureDestroy:    "structure-destroy",
+	MapStoreFlush:       "map-store-flush",
+	RespBatch:           "resp-batch",
+}
+
+// String returns the name of a command type, e.g. "map-put".
+func (t CommandType) String() string {
+	if name, ok := commandNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("command-%d", uint16(t))
+}
+
+// Structure identifies the kind of data structure an entry belongs to.
+type Structure string
+
+const (
+	StructureMap   Structure = "map"
+	StructureCache Structure = "cache"
+)
+
+// ApplyRequest is the command replicated through the raft log.
 type ApplyRequest struct {
-	Command func() interface{}
+	Type  CommandType   `json:"type"`
+	Name  string        `json:"name"`
+	Key   string        `json:"key,omitempty"`
+	Keys  []string      `json:"keys,omitempty"`
+	Value []byte        `json:"value,omitempty"`
+	TTL   time.Duration `json:"ttl,omitempty"`
+	// Args holds the arguments of commands that need more than a key and a value.
+	Args json.RawMessage `json:"args,omitempty"`
+	// Time is the wall clock of the leader when the command was proposed, in unix nanoseconds.
+	// Commands depending on time use it instead of the local clock, so that replicas agree.
+	Time int64 `json:"time,omitempty"`
+	// MaxMemory is the maxmemory limit of the leader when the command was proposed. Replicas check the
+	// growth of the command against it instead of their own configuration, so that they agree.
+	MaxMemory int64 `json:"max_memory,omitempty"`
+	// MaxMemoryPolicy is the maxmemory policy of the leader when the command was proposed. Replicas evict
+	// the entries it allows to make room for the command, oldest first.
+	MaxMemoryPolicy string `json:"max_memory_policy,omitempty"`
+	// WriteBehind is set by leaders persisting the maps the command writes in write-behind mode. Replicas keep
+	// the map entries it changes pending until a MapStoreFlush clears them, so that any later leader can
+	// persist them.
+	WriteBehind bool `json:"write_behind,omitempty"`
+	// Index, Term and AppendedAt describe the raft log entry of the command. They are set when the command
+	// is applied and not replicated as part of it.
+	Index      uint64    `json:"-"`
+	Term       uint64    `json:"-"`
+	AppendedAt time.Time `json:"-"`
 }
 
 type ApplyResponse struct {
 	Data  interface{}
 	Error error
 }
+
+// State is the replicated state of a node, as seen by the FSM.
+type State struct {
+	// Apply applies a decoded command to the data structures of the node.
+	Apply func(request ApplyRequest) ApplyResponse
+	// Snapshot writes the data structures of the node to w.
+	Snapshot func(w io.Writer) error
+	// Restore replaces the data structures of the node with a snapshot read from r.
+	Restore func(r io.Reader) error
+}
+
+// RegisterCommand names a command type defined outside of this package. Structure types defined elsewhere
+// use types from FirstCustomCommand on, and register their names before nodes are created.
+func RegisterCommand(t CommandType, name string) {
+	if _, ok := commandNames[t]; ok {
+		panic(fmt.Sprintf("command type %d is already registered", uint16(t)))
+	}
+	commandNames[t] = name
+}
@@ -1,6 +1,7 @@
 package fsm
 
 import (
+	"bytes"
 	"encoding/json"
 	transport "github.com/Jille/raft-grpc-transport"
 	"github.com/hashicorp/raft"
@@ -17,13 +18,14 @@ import (
 type Fsm struct {
 	Raft    *raft.Raft
 	Manager *transport.Manager
+	state   State
 	mutex   sync.RWMutex
 }
 
 var _ raft.FSM = &Fsm{}
 
-func New(nodeConfig node.Config) *Fsm {
-	var fsm *Fsm
+func New(nodeConfig node.Config, state State) *Fsm {
+	fsm := &Fsm{state: state}
 
 	config := raft.DefaultConfig()
 
@@ -53,17 +55,16 @@ func New(nodeConfig node.Config) *Fsm {
 		log.Fatalf("snapshotstore error %v", snapshotStoreErr)
 	}
 
-	manager := transport.New(raft.ServerAddress(nodeConfig.NodeAddress), []grpc.DialOption{grpc.WithInsecure()})
+	fsm.Manager = transport.New(raft.ServerAddress(nodeConfig.NodeAddress), []grpc.DialOption{grpc.WithInsecure()})
 
-	r, raftErr := raft.NewRaft(config, fsm, logStore, stableStore, snapshotStore, manager.Transport())
+	r, raftErr := raft.NewRaft(config, fsm, logStore, stableStore, snapshotStore, fsm.Manager.Transport())
 
 	if raftErr != nil {
 		log.Fatalf("raft error %v", raftErr)
 	}
-	return &Fsm{
-		Raft:    r,
-		Manager: manager,
-	}
+	fsm.Raft = r
+
+	return fsm
 }
 
 func (f *Fsm) Apply(log *raft.Log) interface{} {
@@ -81,16 +82,56 @@ func (f *Fsm) Apply(log *raft.Log) interface{} {
 		}
 	}
 
-	return ApplyResponse{
-		Data:  applyRequest.Command(),
-		Error: nil,
-	}
+	applyRequest.Index = log.Index
+	applyRequest.Term = log.Term
+	applyRequest.AppendedAt = log.AppendedAt
+
+	return f.state.Apply(applyRequest)
 }
 
+// Read runs fn while no command is being applied, so that reads see a consistent state.
+func (f *Fsm) Read(fn func()) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	fn()
+}
+
+// Snapshot captures the state while no command is being applied. It is written to the snapshot store later,
+// concurrently with new commands.
 func (f *Fsm) Snapshot() (raft.FSMSnapshot, error) {
-	panic("implement me")
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	var buffer bytes.Buffer
+	if err := f.state.Snapshot(&buffer); err != nil {
+		return nil, err
+	}
+
+	return &snapshot{data: buffer.Bytes()}, nil
 }
 
 func (f *Fsm) Restore(closer io.ReadCloser) error {
-	panic("implement me")
+	defer closer.Close()
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.state.Restore(closer)
+}
+
+type snapshot struct {
+	data []byte
 }
+
+var _ raft.FSMSnapshot = &snapshot{}
+
+func (s *snapshot) Persist(sink raft.SnapshotSink) error {
+	if _, err := sink.Write(s.data); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s *snapshot) Release() {}
@@ -0,0 +1,197 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/huseyinbabal/demory/ds/geo"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/structure"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	structure.Register(newGeoStructure)
+}
+
+// geoStructure serves and applies geo sets.
+type geoStructure struct {
+	host structure.Host
+	sets *geo.Sets
+}
+
+func newGeoStructure(host structure.Host) structure.Structure {
+	return &geoStructure{host: host, sets: geo.New()}
+}
+
+func (s *geoStructure) Kind() string {
+	return "geos"
+}
+
+func (s *geoStructure) Commands() []fsm.CommandType {
+	return []fsm.CommandType{fsm.GeoAdd, fsm.GeoRemove}
+}
+
+func (s *geoStructure) Register(server grpc.ServiceRegistrar) {
+	rpc.RegisterGeoServer(server, s)
+}
+
+func (s *geoStructure) Snapshot() ([]byte, error) {
+	return json.Marshal(s.sets)
+}
+
+func (s *geoStructure) Restore(data []byte) (func(), error) {
+	sets := geo.New()
+	if err := structure.Unmarshal(data, sets); err != nil {
+		return nil, err
+	}
+	return func() { s.sets = sets }, nil
+}
+
+func (s *geoStructure) Stats() structure.Stats {
+	return structure.Stats{Bytes: s.sets.Bytes()}
+}
+
+func (s *geoStructure) Destroy(name string) bool {
+	return s.sets.Destroy(name)
+}
+
+// geoArgs are the arguments of replicated geo set commands.
+type geoArgs struct {
+	Members []geo.Member `json:"members,omitempty"`
+}
+
+// GeoAdd adds members to a geo set, or moves them if they are in it already.
+func (s *geoStructure) GeoAdd(ctx context.Context, req *rpc.GeoAddRequest) (*rpc.GeoAddResponse, error) {
+	for _, m := range req.Members {
+		if err := m.Validate(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "member %s: %v", m.Member, err)
+		}
+	}
+
+	data, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.GeoAdd, Name: req.Name}, geoArgs{Members: req.Members})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.GeoAddResponse{Added: data.(int)}, nil
+}
+
+// GeoRemove removes members from a geo set.
+func (s *geoStructure) GeoRemove(ctx context.Context, req *rpc.GeoRemoveRequest) (*rpc.GeoRemoveResponse, error) {
+	data, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.GeoRemove, Name: req.Name, Keys: req.Members}, geoArgs{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.GeoRemoveResponse{Removed: data.(int)}, nil
+}
+
+// GeoPosition returns the positions of members of a geo set.
+func (s *geoStructure) GeoPosition(ctx context.Context, req *rpc.GeoPositionRequest) (*rpc.GeoPositionResponse, error) {
+	response := &rpc.GeoPositionResponse{Positions: make([]*geo.Point, len(req.Members))}
+	s.host.Read(func() {
+		for i, member := range req.Members {
+			if p, ok := s.sets.Position(req.Name, member); ok {
+				response.Positions[i] = &p
+			}
+		}
+	})
+
+	return response, nil
+}
+
+// GeoDistance returns the distance between two members of a geo set in meters.
+func (s *geoStructure) GeoDistance(ctx context.Context, req *rpc.GeoDistanceRequest) (*rpc.GeoDistanceResponse, error) {
+	response := &rpc.GeoDistanceResponse{}
+	s.host.Read(func() {
+		from, fromOk := s.sets.Position(req.Name, req.From)
+		to, toOk := s.sets.Position(req.Name, req.To)
+		if fromOk && toOk {
+			distance := geo.Distance(from, to)
+			response.Distance = &distance
+		}
+	})
+
+	return response, nil
+}
+
+// GeoSearch returns the members of a geo set within a radius or a box around a point or a member, nearest
+// first.
+func (s *geoStructure) GeoSearch(ctx context.Context, req *rpc.GeoSearchRequest) (*rpc.GeoSearchResponse, error) {
+	if req.Radius < 0 || req.Width < 0 || req.Height < 0 {
+		return nil, status.Error(codes.InvalidArgument, "radius, width and height must not be negative")
+	}
+	if req.Radius == 0 && (req.Width == 0 || req.Height == 0) {
+		return nil, status.Error(codes.InvalidArgument, "a radius or a width and a height must be set")
+	}
+	if req.Center != nil {
+		if err := req.Center.Validate(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	response := &rpc.GeoSearchResponse{}
+	var err error
+	s.host.Read(func() {
+		var center geo.Point
+		if req.Center != nil {
+			center = *req.Center
+		} else if p, ok := s.sets.Position(req.Name, req.Member); ok {
+			center = p
+		} else {
+			err = status.Errorf(codes.NotFound, "member %s is not in geo set %s", req.Member, req.Name)
+			return
+		}
+
+		if req.Radius > 0 {
+			response.Results = s.sets.Radius(req.Name, center, req.Radius, req.Limit)
+		} else {
+			response.Results = s.sets.Box(req.Name, center, req.Width, req.Height, req.Limit)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (s *geoStructure) Apply(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args geoArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	switch request.Type {
+	case fsm.GeoAdd:
+		added, err := s.sets.Add(request.Name, args.Members)
+		if err != nil {
+			return fsm.ApplyResponse{Error: status.Error(codes.InvalidArgument, err.Error())}
+		}
+		return fsm.ApplyResponse{Data: added}
+	default:
+		return fsm.ApplyResponse{Data: s.sets.Remove(request.Name, request.Keys)}
+	}
+}
+
+// Growth returns the number of bytes the members added by a geo set command take.
+func (s *geoStructure) Growth(request fsm.ApplyRequest) int64 {
+	var args geoArgs
+	if json.Unmarshal(request.Args, &args) != nil {
+		return 0
+	}
+
+	var growth int64
+	seen := make(map[string]bool)
+	for _, m := range args.Members {
+		if !seen[m.Member] && !s.sets.Contains(request.Name, m.Member) {
+			growth += geo.EntrySize(m.Member)
+		}
+		seen[m.Member] = true
+	}
+	return growth
+}
@@ -0,0 +1,119 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/huseyinbabal/demory/ds/hll"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/structure"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	structure.Register(newHLLStructure)
+}
+
+// hllStructure serves and applies HyperLogLog sketches.
+type hllStructure struct {
+	host     structure.Host
+	sketches *hll.Sketches
+}
+
+func newHLLStructure(host structure.Host) structure.Structure {
+	return &hllStructure{host: host, sketches: hll.New()}
+}
+
+func (s *hllStructure) Kind() string {
+	return "hlls"
+}
+
+func (s *hllStructure) Commands() []fsm.CommandType {
+	return []fsm.CommandType{fsm.HLLAdd, fsm.HLLMerge}
+}
+
+func (s *hllStructure) Register(server grpc.ServiceRegistrar) {
+	rpc.RegisterHyperLogLogServer(server, s)
+}
+
+func (s *hllStructure) Snapshot() ([]byte, error) {
+	return json.Marshal(s.sketches)
+}
+
+func (s *hllStructure) Restore(data []byte) (func(), error) {
+	sketches := hll.New()
+	if err := structure.Unmarshal(data, sketches); err != nil {
+		return nil, err
+	}
+	return func() { s.sketches = sketches }, nil
+}
+
+func (s *hllStructure) Stats() structure.Stats {
+	return structure.Stats{Bytes: s.sketches.Bytes()}
+}
+
+func (s *hllStructure) Destroy(name string) bool {
+	return s.sketches.Destroy(name)
+}
+
+// hllArgs are the arguments of replicated HyperLogLog commands.
+type hllArgs struct {
+	Elements [][]byte `json:"elements,omitempty"`
+	Sources  []string `json:"sources,omitempty"`
+}
+
+// HLLAdd adds elements to a HyperLogLog sketch.
+func (s *hllStructure) HLLAdd(ctx context.Context, req *rpc.HLLAddRequest) (*rpc.HLLAddResponse, error) {
+	data, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.HLLAdd, Name: req.Name}, hllArgs{Elements: req.Elements})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.HLLAddResponse{Changed: data.(bool)}, nil
+}
+
+// HLLCount returns the estimated number of distinct elements added to the union of sketches.
+func (s *hllStructure) HLLCount(ctx context.Context, req *rpc.HLLCountRequest) (*rpc.HLLCountResponse, error) {
+	if len(req.Names) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "names must be set")
+	}
+
+	response := &rpc.HLLCountResponse{}
+	s.host.Read(func() {
+		response.Count = s.sketches.Count(req.Names...)
+	})
+
+	return response, nil
+}
+
+// HLLMerge merges sketches into a destination sketch.
+func (s *hllStructure) HLLMerge(ctx context.Context, req *rpc.HLLMergeRequest) (*rpc.Empty, error) {
+	_, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.HLLMerge, Name: req.Dest}, hllArgs{Sources: req.Sources})
+	return new(rpc.Empty), err
+}
+
+func (s *hllStructure) Apply(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args hllArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	switch request.Type {
+	case fsm.HLLAdd:
+		return fsm.ApplyResponse{Data: s.sketches.Add(request.Name, args.Elements)}
+	default:
+		s.sketches.Merge(request.Name, args.Sources)
+		return fsm.ApplyResponse{}
+	}
+}
+
+// Growth returns the number of bytes of a new sketch, if the command creates one.
+func (s *hllStructure) Growth(request fsm.ApplyRequest) int64 {
+	if s.sketches.Exists(request.Name) {
+		return 0
+	}
+	return hll.Registers
+}
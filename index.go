@@ -0,0 +1,214 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/index"
+	"github.com/huseyinbabal/demory/jsonpath"
+	"github.com/huseyinbabal/demory/predicate"
+	"github.com/huseyinbabal/demory/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// indexArgs are the arguments of replicated IndexCreate and IndexDrop commands.
+type indexArgs struct {
+	Path string     `json:"path"`
+	Kind index.Kind `json:"kind,omitempty"`
+}
+
+// CreateIndex declares an index on a JSON path of a map. Existing entries are indexed when the command is applied.
+func (d *Demory) CreateIndex(ctx context.Context, req *rpc.CreateIndexRequest) (*rpc.Empty, error) {
+	if req.Kind != index.Hash && req.Kind != index.Sorted {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown index kind %q", req.Kind)
+	}
+	if _, err := jsonpath.Parse(req.Path); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return new(rpc.Empty), d.applyIndex(fsm.IndexCreate, req.Name, indexArgs{Path: req.Path, Kind: req.Kind})
+}
+
+// DropIndex removes the index on a JSON path of a map.
+func (d *Demory) DropIndex(ctx context.Context, req *rpc.DropIndexRequest) (*rpc.Empty, error) {
+	return new(rpc.Empty), d.applyIndex(fsm.IndexDrop, req.Name, indexArgs{Path: req.Path})
+}
+
+// ListIndexes returns the definitions of the indexes of one or all maps.
+func (d *Demory) ListIndexes(ctx context.Context, req *rpc.ListIndexesRequest) (*rpc.ListIndexesResponse, error) {
+	response := &rpc.ListIndexesResponse{Indexes: []index.Definition{}}
+	d.fsm.Read(func() {
+		for _, definition := range d.indexes.Definitions() {
+			if req.Name == "" || definition.Map == req.Name {
+				response.Indexes = append(response.Indexes, definition)
+			}
+		}
+	})
+
+	return response, nil
+}
+
+// IndexLookup returns the entries of a map whose indexed value equals a value or lies within a range.
+func (d *Demory) IndexLookup(ctx context.Context, req *rpc.IndexLookupRequest) (*rpc.IndexLookupResponse, error) {
+	if req.Value != nil && (req.From != nil || req.To != nil) {
+		return nil, status.Error(codes.InvalidArgument, "only one of value and range can be set")
+	}
+	if req.Limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+
+	var value interface{}
+	var from, to *index.Bound
+	var ok bool
+	if req.Value != nil {
+		if value, ok = predicate.Decode(req.Value); !ok {
+			return nil, status.Error(codes.InvalidArgument, "invalid value")
+		}
+	}
+	if req.From != nil {
+		from = &index.Bound{Exclusive: req.FromExclusive}
+		if from.Value, ok = predicate.Decode(req.From); !ok {
+			return nil, status.Error(codes.InvalidArgument, "invalid from bound")
+		}
+	}
+	if req.To != nil {
+		to = &index.Bound{Exclusive: req.ToExclusive}
+		if to.Value, ok = predicate.Decode(req.To); !ok {
+			return nil, status.Error(codes.InvalidArgument, "invalid to bound")
+		}
+	}
+
+	response := &rpc.IndexLookupResponse{Entries: []rpc.Entry{}}
+	var err error
+	d.fsm.Read(func() {
+		i, exists := d.indexes.Get(req.Name, req.Path)
+		if !exists {
+			err = status.Errorf(codes.NotFound, "no index on %s of map %s", req.Path, req.Name)
+			return
+		}
+
+		var keys []string
+		if req.Value != nil {
+			keys = i.Equal(value)
+			if req.Limit > 0 && len(keys) > req.Limit {
+				keys = keys[:req.Limit]
+			}
+		} else if keys, err = i.Range(from, to, req.Limit); err != nil {
+			err = status.Error(codes.FailedPrecondition, err.Error())
+			return
+		}
+
+		for _, key := range keys {
+			response.Entries = append(response.Entries, rpc.Entry{Key: key, Value: d.hashMap.Get(req.Name, key)})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (d *Demory) applyIndex(commandType fsm.CommandType, name string, args indexArgs) error {
+	_, err := d.propose(fsm.ApplyRequest{Type: commandType, Name: name}, args)
+	return err
+}
+
+// createIndex applies an IndexCreate command.
+func (d *Demory) createIndex(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args indexArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	definition := index.Definition{Map: request.Name, Path: args.Path, Kind: args.Kind}
+	err := d.indexes.Create(definition, func(fn func(key string, value []byte) bool) {
+		d.hashMap.Range(request.Name, fn)
+	})
+	switch {
+	case errors.Is(err, index.ErrIndexExists):
+		return fsm.ApplyResponse{Error: status.Error(codes.AlreadyExists, err.Error())}
+	case err != nil:
+		return fsm.ApplyResponse{Error: status.Error(codes.InvalidArgument, err.Error())}
+	}
+
+	return fsm.ApplyResponse{}
+}
+
+// dropIndex applies an IndexDrop command.
+func (d *Demory) dropIndex(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args indexArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	if err := d.indexes.Drop(request.Name, args.Path); err != nil {
+		return fsm.ApplyResponse{Error: status.Error(codes.NotFound, err.Error())}
+	}
+
+	return fsm.ApplyResponse{}
+}
+
+// indexedKeys narrows a query to the keys found by an index, if p or one of the operands of a top level AND
+// compares an indexed path. The keys are only candidates, the query still evaluates p on them.
+func (d *Demory) indexedKeys(name string, p *predicate.Predicate) ([]string, bool) {
+	if p == nil {
+		return nil, false
+	}
+	if p.Op == predicate.And {
+		for _, operand := range p.Operands {
+			if keys, ok := d.indexedKeys(name, operand); ok {
+				return keys, true
+			}
+		}
+		return nil, false
+	}
+
+	i, ok := d.indexes.Get(name, p.Path)
+	if !ok {
+		return nil, false
+	}
+
+	switch p.Op {
+	case predicate.Equal:
+		value, ok := predicate.Decode(p.Value)
+		if !ok {
+			return nil, false
+		}
+		return i.Equal(value), true
+	case predicate.In:
+		var keys []string
+		seen := make(map[string]bool)
+		for _, raw := range p.Values {
+			value, ok := predicate.Decode(raw)
+			if !ok {
+				return nil, false
+			}
+			for _, key := range i.Equal(value) {
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+		return keys, true
+	case predicate.Less, predicate.LessOrEqual, predicate.Greater, predicate.GreaterOrEqual:
+		value, ok := predicate.Decode(p.Value)
+		if !ok || i.Definition().Kind != index.Sorted {
+			return nil, false
+		}
+		bound := &index.Bound{Value: value, Exclusive: p.Op == predicate.Less || p.Op == predicate.Greater}
+		var keys []string
+		if p.Op == predicate.Less || p.Op == predicate.LessOrEqual {
+			keys, _ = i.Range(nil, bound, 0)
+		} else {
+			keys, _ = i.Range(bound, nil, 0)
+		}
+		return keys, true
+	default:
+		return nil, false
+	}
+}
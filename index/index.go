@@ -0,0 +1,179 @@
+// Package index maintains secondary indexes on JSON paths of map values.
+package index
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/huseyinbabal/demory/jsonpath"
+	"github.com/huseyinbabal/demory/predicate"
+)
+
+// Kind of an index.
+type Kind string
+
+const (
+	// Hash indexes answer equality lookups.
+	Hash Kind = "hash"
+	// Sorted indexes answer equality and range lookups.
+	Sorted Kind = "sorted"
+)
+
+var (
+	ErrUnknownKind  = errors.New("unknown index kind")
+	ErrRangeOnHash  = errors.New("hash indexes do not support range lookups")
+	ErrIndexExists  = errors.New("index already exists")
+	ErrIndexMissing = errors.New("index does not exist")
+)
+
+// Definition describes an index on the values at Path of the map named Map.
+type Definition struct {
+	Map  string `json:"map"`
+	Path string `json:"path"`
+	Kind Kind   `json:"kind"`
+}
+
+// Bound is one end of a range lookup. A nil Value leaves the range open at that end.
+type Bound struct {
+	Value     interface{}
+	Exclusive bool
+}
+
+// Index holds the keys of a map by the value found at the indexed path. Entries whose value is not JSON,
+// or lacks the path, are not indexed.
+type Index struct {
+	definition Definition
+	segments   []jsonpath.Segment
+	values     map[string]interface{}
+	hash       map[string]map[string]struct{}
+	sorted     *skipList
+}
+
+// New creates an empty index.
+func New(definition Definition) (*Index, error) {
+	segments, err := jsonpath.Parse(definition.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Index{
+		definition: definition,
+		segments:   segments,
+		values:     make(map[string]interface{}),
+	}
+	switch definition.Kind {
+	case Hash:
+		i.hash = make(map[string]map[string]struct{})
+	case Sorted:
+		i.sorted = newSkipList()
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownKind, definition.Kind)
+	}
+
+	return i, nil
+}
+
+// Definition returns the definition of the index.
+func (i *Index) Definition() Definition {
+	return i.definition
+}
+
+// Len returns the number of indexed entries.
+func (i *Index) Len() int {
+	return len(i.values)
+}
+
+// Update reindexes the entry at key after its value changed or it was removed.
+func (i *Index) Update(key string, value []byte, removed bool) {
+	i.remove(key)
+	if removed {
+		return
+	}
+
+	doc, ok := predicate.Decode(value)
+	if !ok {
+		return
+	}
+	field, ok := jsonpath.Lookup(doc, i.segments)
+	if !ok {
+		return
+	}
+
+	i.values[key] = field
+	if i.hash != nil {
+		c := canonical(field)
+		if i.hash[c] == nil {
+			i.hash[c] = make(map[string]struct{})
+		}
+		i.hash[c][key] = struct{}{}
+	} else {
+		i.sorted.insert(field, key)
+	}
+}
+
+func (i *Index) remove(key string) {
+	field, ok := i.values[key]
+	if !ok {
+		return
+	}
+	delete(i.values, key)
+
+	if i.hash != nil {
+		c := canonical(field)
+		delete(i.hash[c], key)
+		if len(i.hash[c]) == 0 {
+			delete(i.hash, c)
+		}
+	} else {
+		i.sorted.delete(field, key)
+	}
+}
+
+// Equal returns the keys whose indexed value equals value, in lexical order.
+func (i *Index) Equal(value interface{}) []string {
+	if i.hash == nil {
+		return i.sorted.scan(&Bound{Value: value}, &Bound{Value: value}, 0)
+	}
+
+	keys := make([]string, 0, len(i.hash[canonical(value)]))
+	for key := range i.hash[canonical(value)] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Range returns up to limit keys whose indexed value lies between from and to, ordered by value and then
+// by key. A nil bound leaves the range open, and a non positive limit returns every key.
+func (i *Index) Range(from, to *Bound, limit int) ([]string, error) {
+	if i.sorted == nil {
+		return nil, ErrRangeOnHash
+	}
+	return i.sorted.scan(from, to, limit), nil
+}
+
+// canonical encodes a JSON value so that values comparing equal encode identically.
+func canonical(value interface{}) string {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return "n" + strconv.FormatInt(n, 10)
+		}
+		f, _ := v.Float64()
+		if f == float64(int64(f)) {
+			return "n" + strconv.FormatInt(int64(f), 10)
+		}
+		return "n" + strconv.FormatFloat(f, 'g', -1, 64)
+	case float64:
+		return canonical(json.Number(strconv.FormatFloat(v, 'g', -1, 64)))
+	case string:
+		return "s" + v
+	default:
+		encoded, _ := json.Marshal(v)
+		return "j" + string(encoded)
+	}
+}
@@ -0,0 +1,121 @@
+package index
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func entries() map[string][]byte {
+	return map[string][]byte{
+		"a": []byte(`{"age": 30, "city": "izmir"}`),
+		"b": []byte(`{"age": 25.0, "city": "ankara"}`),
+		"c": []byte(`{"age": 41, "city": "izmir"}`),
+		"d": []byte(`{"age": "unknown"}`),
+		"e": []byte(`not json`),
+	}
+}
+
+func create(t *testing.T, kind Kind, path string) *Indexes {
+	x := NewIndexes()
+	err := x.Create(Definition{Map: "users", Path: path, Kind: kind}, func(fn func(key string, value []byte) bool) {
+		for key, value := range entries() {
+			fn(key, value)
+		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return x
+}
+
+func TestHashIndex(t *testing.T) {
+	x := create(t, Hash, "city")
+	i, _ := x.Get("users", "city")
+
+	if keys := i.Equal("izmir"); !reflect.DeepEqual(keys, []string{"a", "c"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+	if _, err := i.Range(&Bound{Value: "a"}, nil, 0); err != ErrRangeOnHash {
+		t.Errorf("expected range on a hash index to fail, got %v", err)
+	}
+
+	x.Update("users", "a", []byte(`{"city": "ankara"}`), false)
+	x.Update("users", "c", nil, true)
+	if keys := i.Equal("izmir"); len(keys) != 0 {
+		t.Errorf("expected no keys, got %v", keys)
+	}
+	if keys := i.Equal("ankara"); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+}
+
+func TestHashIndexNumbers(t *testing.T) {
+	x := create(t, Hash, "age")
+	i, _ := x.Get("users", "age")
+
+	if keys := i.Equal(json.Number("25")); !reflect.DeepEqual(keys, []string{"b"}) {
+		t.Errorf("expected 25 to equal 25.0, got %v", keys)
+	}
+}
+
+func TestSortedIndex(t *testing.T) {
+	x := create(t, Sorted, "age")
+	i, _ := x.Get("users", "age")
+
+	tests := []struct {
+		from, to *Bound
+		limit    int
+		expected []string
+	}{
+		{&Bound{Value: json.Number("25")}, &Bound{Value: json.Number("41")}, 0, []string{"b", "a", "c"}},
+		{&Bound{Value: json.Number("25"), Exclusive: true}, nil, 0, []string{"a", "c"}},
+		{nil, &Bound{Value: json.Number("41"), Exclusive: true}, 0, []string{"b", "a"}},
+		{nil, &Bound{Value: json.Number("100")}, 1, []string{"b"}},
+		{&Bound{Value: "a"}, nil, 0, []string{"d"}},
+	}
+	for _, test := range tests {
+		keys, err := i.Range(test.from, test.to, test.limit)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if !reflect.DeepEqual(keys, test.expected) {
+			t.Errorf("range %+v %+v: expected %v, got %v", test.from, test.to, test.expected, keys)
+		}
+	}
+
+	x.Update("users", "a", []byte(`{"age": 50}`), false)
+	if keys := i.Equal(json.Number("30")); len(keys) != 0 {
+		t.Errorf("expected stale value to be gone, got %v", keys)
+	}
+	if keys := i.Equal(json.Number("50")); !reflect.DeepEqual(keys, []string{"a"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+}
+
+func TestIndexes(t *testing.T) {
+	x := create(t, Hash, "city")
+	scan := func(fn func(key string, value []byte) bool) {}
+
+	if err := x.Create(Definition{Map: "users", Path: "city", Kind: Sorted}, scan); err == nil {
+		t.Error("expected a second index on the same path to fail")
+	}
+	if err := x.Create(Definition{Map: "users", Path: "age", Kind: "bitmap"}, scan); err == nil {
+		t.Error("expected an unknown kind to fail")
+	}
+	if err := x.Create(Definition{Map: "orders", Path: "total", Kind: Sorted}, scan); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	expected := []Definition{{Map: "orders", Path: "total", Kind: Sorted}, {Map: "users", Path: "city", Kind: Hash}}
+	if definitions := x.Definitions(); !reflect.DeepEqual(definitions, expected) {
+		t.Errorf("unexpected definitions %v", definitions)
+	}
+
+	if err := x.Drop("users", "city"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := x.Drop("users", "city"); err == nil {
+		t.Error("expected dropping a missing index to fail")
+	}
+}
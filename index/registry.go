@@ -0,0 +1,84 @@
+package index
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Indexes holds the indexes of all maps.
+type Indexes struct {
+	maps map[string]map[string]*Index
+}
+
+// NewIndexes creates an empty registry.
+func NewIndexes() *Indexes {
+	return &Indexes{maps: make(map[string]map[string]*Index)}
+}
+
+// Create adds an index and fills it by calling scan, which must yield every entry of the indexed map.
+func (x *Indexes) Create(definition Definition, scan func(fn func(key string, value []byte) bool)) error {
+	if _, ok := x.maps[definition.Map][definition.Path]; ok {
+		return fmt.Errorf("%w on %s %s", ErrIndexExists, definition.Map, definition.Path)
+	}
+
+	i, err := New(definition)
+	if err != nil {
+		return err
+	}
+	scan(func(key string, value []byte) bool {
+		i.Update(key, value, false)
+		return true
+	})
+
+	if x.maps[definition.Map] == nil {
+		x.maps[definition.Map] = make(map[string]*Index)
+	}
+	x.maps[definition.Map][definition.Path] = i
+
+	return nil
+}
+
+// Drop removes the index on path of a map.
+func (x *Indexes) Drop(name, path string) error {
+	if _, ok := x.maps[name][path]; !ok {
+		return fmt.Errorf("%w on %s %s", ErrIndexMissing, name, path)
+	}
+
+	delete(x.maps[name], path)
+	if len(x.maps[name]) == 0 {
+		delete(x.maps, name)
+	}
+
+	return nil
+}
+
+// Get returns the index on path of a map.
+func (x *Indexes) Get(name, path string) (*Index, bool) {
+	i, ok := x.maps[name][path]
+	return i, ok
+}
+
+// Definitions returns the definitions of all indexes, ordered by map and path.
+func (x *Indexes) Definitions() []Definition {
+	var definitions []Definition
+	for _, paths := range x.maps {
+		for _, i := range paths {
+			definitions = append(definitions, i.definition)
+		}
+	}
+	sort.Slice(definitions, func(a, b int) bool {
+		if definitions[a].Map != definitions[b].Map {
+			return definitions[a].Map < definitions[b].Map
+		}
+		return definitions[a].Path < definitions[b].Path
+	})
+
+	return definitions
+}
+
+// Update reindexes an entry of a map in every index of the map.
+func (x *Indexes) Update(name, key string, value []byte, removed bool) {
+	for _, i := range x.maps[name] {
+		i.Update(key, value, removed)
+	}
+}
@@ -0,0 +1,133 @@
+package index
+
+import (
+	"math/rand"
+
+	"github.com/huseyinbabal/demory/predicate"
+)
+
+const maxLevel = 24
+
+type node struct {
+	value interface{}
+	key   string
+	next  []*node
+}
+
+// skipList keeps (value, key) pairs ordered by value and then by key.
+type skipList struct {
+	head   *node
+	level  int
+	random *rand.Rand
+}
+
+func newSkipList() *skipList {
+	return &skipList{
+		head:   &node{next: make([]*node, maxLevel)},
+		level:  1,
+		random: rand.New(rand.NewSource(1)),
+	}
+}
+
+func compare(value interface{}, key string, n *node) int {
+	if c := predicate.Compare(value, n.value); c != 0 {
+		return c
+	}
+	switch {
+	case key < n.key:
+		return -1
+	case key > n.key:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// predecessors returns, for every level, the last node before (value, key).
+func (s *skipList) predecessors(value interface{}, key string) []*node {
+	update := make([]*node, maxLevel)
+	current := s.head
+	for level := s.level - 1; level >= 0; level-- {
+		for current.next[level] != nil && compare(value, key, current.next[level]) > 0 {
+			current = current.next[level]
+		}
+		update[level] = current
+	}
+	return update
+}
+
+func (s *skipList) insert(value interface{}, key string) {
+	update := s.predecessors(value, key)
+
+	level := 1
+	for level < maxLevel && s.random.Intn(4) == 0 {
+		level++
+	}
+	if level > s.level {
+		for l := s.level; l < level; l++ {
+			update[l] = s.head
+		}
+		s.level = level
+	}
+
+	n := &node{value: value, key: key, next: make([]*node, level)}
+	for l := 0; l < level; l++ {
+		n.next[l] = update[l].next[l]
+		update[l].next[l] = n
+	}
+}
+
+func (s *skipList) delete(value interface{}, key string) {
+	update := s.predecessors(value, key)
+	target := update[0].next[0]
+	if target == nil || compare(value, key, target) != 0 {
+		return
+	}
+
+	for l := 0; l < len(target.next); l++ {
+		if update[l].next[l] == target {
+			update[l].next[l] = target.next[l]
+		}
+	}
+	for s.level > 1 && s.head.next[s.level-1] == nil {
+		s.level--
+	}
+}
+
+// scan returns up to limit keys between from and to. Values of a different type than a bound are
+// outside of the range, so that a numeric range never returns strings.
+func (s *skipList) scan(from, to *Bound, limit int) []string {
+	before := func(n *node) bool {
+		if from != nil {
+			c := predicate.Compare(n.value, from.Value)
+			return c < 0 || (c == 0 && from.Exclusive)
+		}
+		return to != nil && !predicate.SameType(n.value, to.Value) && predicate.Compare(n.value, to.Value) < 0
+	}
+
+	current := s.head
+	for level := s.level - 1; level >= 0; level-- {
+		for current.next[level] != nil && before(current.next[level]) {
+			current = current.next[level]
+		}
+	}
+
+	var keys []string
+	for n := current.next[0]; n != nil; n = n.next[0] {
+		if limit > 0 && len(keys) >= limit {
+			break
+		}
+		if from != nil && !predicate.SameType(n.value, from.Value) {
+			break
+		}
+		if to != nil {
+			c := predicate.Compare(n.value, to.Value)
+			if c > 0 || (c == 0 && to.Exclusive) || !predicate.SameType(n.value, to.Value) {
+				break
+			}
+		}
+		keys = append(keys, n.key)
+	}
+
+	return keys
+}
@@ -0,0 +1,177 @@
+package demory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/processor"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/txn"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Begin opens an interactive transaction. Transactions live on the node that began them, so every call of a
+// transaction must reach the same node, and its commit needs that node to be the leader.
+func (d *Demory) Begin(ctx context.Context, req *rpc.Empty) (*rpc.BeginResponse, error) {
+	t, err := d.txns.Begin(d.fsm.Raft.AppliedIndex(), time.Now())
+	if err != nil {
+		return nil, txnError(err)
+	}
+
+	return &rpc.BeginResponse{ID: t.ID, StartIndex: t.Start, Deadline: t.Deadline.UnixMilli()}, nil
+}
+
+// Read returns an entry as seen by a transaction: its buffered writes applied on the current state. Reads of
+// entries written, removed or expired after the transaction began abort it.
+func (d *Demory) Read(ctx context.Context, req *rpc.ReadRequest) (*rpc.ReadResponse, error) {
+	structure := fsm.Structure(req.Structure)
+	if structure != fsm.StructureMap && structure != fsm.StructureCache {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown structure %q", req.Structure)
+	}
+
+	now := time.Now()
+	k := txn.Key{Structure: req.Structure, Name: req.Name, Key: req.Key}
+	var began time.Time
+	var writes []rpc.Operation
+	err := d.txns.Do(req.ID, now, func(t *txn.Transaction) error {
+		began, writes = t.Began, t.Written(k)
+		return nil
+	})
+	if err != nil {
+		return nil, txnError(err)
+	}
+
+	// Entries blindly written by the transaction do not depend on the current state.
+	blind := 0
+	for i, op := range writes {
+		if op.Type == rpc.OperationPut || op.Type == rpc.OperationRemove {
+			blind = i + 1
+		}
+	}
+
+	entry := processor.Entry{Key: req.Key}
+	if blind == 0 {
+		op := rpc.Operation{Structure: req.Structure, Name: req.Name, Key: req.Key}
+		read := txn.Read{Key: k}
+		expired := false
+		d.fsm.Read(func() {
+			read.Value, read.Exists = d.current(op, now)
+			read.Version = d.changed(op)
+			// Cache entries expire without a command, so an entry that expired since the transaction began is
+			// only seen by its expiry.
+			if !read.Exists && structure == fsm.StructureCache {
+				_, expired = d.current(op, began)
+			}
+		})
+
+		err := txn.ErrConflict
+		if !expired {
+			err = d.txns.Record(req.ID, now, read)
+		}
+		if errors.Is(err, txn.ErrConflict) {
+			d.txns.End(req.ID, now)
+		}
+		if err != nil {
+			return nil, txnError(err)
+		}
+		entry.Value, entry.Exists = read.Value, read.Exists
+	} else {
+		entry.Value, entry.Exists = writes[blind-1].Value, writes[blind-1].Type == rpc.OperationPut
+	}
+
+	for _, op := range writes[blind:] {
+		process, ok := processor.Get(op.Processor)
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown processor %s", op.Processor)
+		}
+		processed, err := process(entry, op.Arguments)
+		if err != nil {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		entry.Value, entry.Exists = processed.Value, processed.Exists
+	}
+
+	return &rpc.ReadResponse{Value: entry.Value, Exists: entry.Exists}, nil
+}
+
+// Write buffers put, remove and execute operations until the transaction commits.
+func (d *Demory) Write(ctx context.Context, req *rpc.WriteRequest) (*rpc.Empty, error) {
+	if err := validateOperations(req.Operations); err != nil {
+		return nil, err
+	}
+	for i, op := range req.Operations {
+		if op.Type == rpc.OperationGet {
+			return nil, status.Errorf(codes.InvalidArgument, "operation %d: reads go through Read", i)
+		}
+	}
+
+	if err := d.txns.Write(req.ID, time.Now(), req.Operations); err != nil {
+		return nil, txnError(err)
+	}
+	return new(rpc.Empty), nil
+}
+
+// Commit applies the buffered writes of a transaction if nothing it read changed since it began. Otherwise
+// it returns ABORTED, and the client can retry the transaction from its beginning. The transaction is closed
+// in either case.
+func (d *Demory) Commit(ctx context.Context, req *rpc.CommitRequest) (*rpc.TransactionResponse, error) {
+	t, err := d.txns.End(req.ID, time.Now())
+	if err != nil {
+		return nil, txnError(err)
+	}
+
+	// Reads become preconditions of a one-shot transaction: entries must still exist or not as they were read,
+	// and must not have been written or removed since the transaction began.
+	operations := make([]rpc.Operation, 0, len(t.Reads)+len(t.Writes))
+	for _, read := range t.Reads {
+		exists, start := read.Exists, t.Start
+		operations = append(operations, rpc.Operation{
+			Type:      rpc.OperationGet,
+			Structure: read.Structure,
+			Name:      read.Name,
+			Key:       read.Key.Key,
+			If:        &rpc.Precondition{Exists: &exists, UnchangedSince: &start},
+		})
+	}
+	operations = append(operations, t.Writes...)
+
+	response, err := d.applyTransaction(operations)
+	if err != nil {
+		return nil, err
+	}
+	if !response.Committed {
+		return nil, status.Error(codes.Aborted, txn.ErrConflict.Error())
+	}
+
+	response.Results = response.Results[len(t.Reads):]
+	if err := d.persistTransaction(t.Writes, response.Results); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Rollback discards a transaction.
+func (d *Demory) Rollback(ctx context.Context, req *rpc.RollbackRequest) (*rpc.Empty, error) {
+	if _, err := d.txns.End(req.ID, time.Now()); err != nil {
+		return nil, txnError(err)
+	}
+	return new(rpc.Empty), nil
+}
+
+// txnError converts errors of the txn package to gRPC statuses.
+func txnError(err error) error {
+	switch {
+	case errors.Is(err, txn.ErrConflict):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, txn.ErrUnknownTransaction):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, txn.ErrTooManyTransactions), errors.Is(err, txn.ErrTooManyOperations):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return err
+	}
+}
@@ -0,0 +1,105 @@
+package demory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	proto "github.com/huseyinbabal/demory-proto/golang/demory"
+	"github.com/huseyinbabal/demory/node"
+	"github.com/huseyinbabal/demory/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestInteractiveCommit(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	ctx := context.Background()
+	d.MapPut(ctx, &proto.MapPutRequest{Name: "users", Key: "a", Value: []byte("1")})
+
+	tx, _ := d.Begin(ctx, new(rpc.Empty))
+	read, err := d.Read(ctx, &rpc.ReadRequest{ID: tx.ID, Structure: "map", Name: "users", Key: "a"})
+	if err != nil || string(read.Value) != "1" {
+		t.Fatalf("unexpected read %v %v", read, err)
+	}
+	d.Write(ctx, &rpc.WriteRequest{ID: tx.ID, Operations: []rpc.Operation{
+		{Type: rpc.OperationPut, Structure: "map", Name: "users", Key: "b", Value: read.Value},
+	}})
+	if _, err := d.Commit(ctx, &rpc.CommitRequest{ID: tx.ID}); err != nil {
+		t.Errorf("expected the transaction to commit, got %v", err)
+	}
+}
+
+func TestInteractiveConflicts(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		setup  func()
+		read   rpc.ReadRequest
+		change func()
+	}{
+		{
+			name:  "map entry removed before the read",
+			setup: func() { d.MapPut(ctx, &proto.MapPutRequest{Name: "users", Key: "a", Value: []byte("1")}) },
+			read:  rpc.ReadRequest{Structure: "map", Name: "users", Key: "a"},
+			change: func() {
+				d.MapRemove(ctx, &proto.MapRemoveRequest{Name: "users", Key: "a"})
+			},
+		},
+		{
+			name: "absent map entry put and removed again",
+			read: rpc.ReadRequest{Structure: "map", Name: "users", Key: "b"},
+			change: func() {
+				d.MapPut(ctx, &proto.MapPutRequest{Name: "users", Key: "b", Value: []byte("1")})
+				d.MapRemove(ctx, &proto.MapRemoveRequest{Name: "users", Key: "b"})
+			},
+		},
+		{
+			name:  "cache entry written with the same value",
+			setup: func() { d.CachePut(ctx, &proto.CachePutRequest{Name: "sessions", Key: "a", Value: []byte("1")}) },
+			read:  rpc.ReadRequest{Structure: "cache", Name: "sessions", Key: "a"},
+			change: func() {
+				d.CachePut(ctx, &proto.CachePutRequest{Name: "sessions", Key: "a", Value: []byte("1")})
+			},
+		},
+		{
+			name: "cache entry expired before the read",
+			setup: func() {
+				d.CachePutWithTTL(ctx, &rpc.CachePutWithTTLRequest{Name: "sessions", Key: "b", Value: []byte("1"), TTL: 100})
+			},
+			read:   rpc.ReadRequest{Structure: "cache", Name: "sessions", Key: "b"},
+			change: func() { time.Sleep(200 * time.Millisecond) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+			tx, _ := d.Begin(ctx, new(rpc.Empty))
+			tt.read.ID = tx.ID
+
+			// Changes before the read abort the read, and changes after it abort the commit.
+			tt.change()
+			if _, err := d.Read(ctx, &tt.read); status.Code(err) != codes.Aborted {
+				t.Errorf("expected the read to abort, got %v", err)
+			}
+
+			if tt.setup != nil {
+				tt.setup()
+			}
+			tx, _ = d.Begin(ctx, new(rpc.Empty))
+			tt.read.ID = tx.ID
+			if _, err := d.Read(ctx, &tt.read); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			tt.change()
+			if _, err := d.Commit(ctx, &rpc.CommitRequest{ID: tx.ID}); status.Code(err) != codes.Aborted {
+				t.Errorf("expected the commit to abort, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+// Package jsonpath reads and writes values of decoded JSON documents at dotted paths such as
+// "address.city" or "$.tags[0]".
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPath = errors.New("invalid json path")
+
+// Segment is a single step of a path. It is either an object field or an array index.
+type Segment struct {
+	Field   string
+	Index   int
+	IsIndex bool
+}
+
+// Parse splits path into its segments. An empty path, or "$", refers to the whole document.
+func Parse(path string) ([]Segment, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil, nil
+	}
+
+	var segments []Segment
+	for _, part := range strings.Split(path, ".") {
+		field := part
+		var indexes []string
+		if open := strings.IndexByte(part, '['); open >= 0 {
+			field = part[:open]
+			for rest := part[open:]; rest != ""; {
+				end := strings.IndexByte(rest, ']')
+				if rest[0] != '[' || end < 0 {
+					return nil, fmt.Errorf("%w %q", ErrInvalidPath, path)
+				}
+				indexes = append(indexes, rest[1:end])
+				rest = rest[end+1:]
+			}
+		}
+
+		if field == "" && len(indexes) == 0 {
+			return nil, fmt.Errorf("%w %q", ErrInvalidPath, path)
+		}
+		if field != "" {
+			if index, err := strconv.Atoi(field); err == nil && len(indexes) == 0 && index >= 0 {
+				segments = append(segments, Segment{Field: field, Index: index, IsIndex: true})
+			} else {
+				segments = append(segments, Segment{Field: field})
+			}
+		}
+		for _, i := range indexes {
+			index, err := strconv.Atoi(i)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q", ErrInvalidPath, path)
+			}
+			segments = append(segments, Segment{Index: index, IsIndex: true})
+		}
+	}
+
+	return segments, nil
+}
+
+// Get returns the value at path within doc, and whether it exists. Negative indexes count from the end of arrays.
+func Get(doc interface{}, path string) (interface{}, bool, error) {
+	segments, err := Parse(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, ok := Lookup(doc, segments)
+	return value, ok, nil
+}
+
+// Lookup returns the value at the parsed path within doc, and whether it exists.
+func Lookup(doc interface{}, segments []Segment) (interface{}, bool) {
+	current := doc
+	for _, segment := range segments {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[segment.Field]
+			if !ok || segment.Field == "" {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			if !segment.IsIndex {
+				return nil, false
+			}
+			index := segment.Index
+			if index < 0 {
+				index += len(node)
+			}
+			if index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// Set stores value at path within doc and returns the updated document. Missing objects along the path are
+// created, and arrays are extended with nulls when an index past their end is set.
+func Set(doc interface{}, path string, value interface{}) (interface{}, error) {
+	segments, err := Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return set(doc, segments, value, path)
+}
+
+// SetSegments stores value at the parsed path within doc and returns the updated document.
+func SetSegments(doc interface{}, segments []Segment, value interface{}) (interface{}, error) {
+	return set(doc, segments, value, "")
+}
+
+func set(node interface{}, segments []Segment, value interface{}, path string) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+
+	segment := segments[0]
+	switch current := node.(type) {
+	case []interface{}:
+		if !segment.IsIndex {
+			return nil, fmt.Errorf("%w %q: %q is not an array index", ErrInvalidPath, path, segment.Field)
+		}
+		index := segment.Index
+		if index < 0 {
+			index += len(current)
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("%w %q: index out of range", ErrInvalidPath, path)
+		}
+		for len(current) <= index {
+			current = append(current, nil)
+		}
+		child, err := set(current[index], segments[1:], value, path)
+		if err != nil {
+			return nil, err
+		}
+		current[index] = child
+		return current, nil
+	case map[string]interface{}:
+		if segment.Field == "" {
+			return nil, fmt.Errorf("%w %q: index %d of an object", ErrInvalidPath, path, segment.Index)
+		}
+		child, err := set(current[segment.Field], segments[1:], value, path)
+		if err != nil {
+			return nil, err
+		}
+		current[segment.Field] = child
+		return current, nil
+	case nil:
+		if segment.IsIndex && segment.Field == "" {
+			return set([]interface{}{}, segments, value, path)
+		}
+		return set(map[string]interface{}{}, segments, value, path)
+	default:
+		return nil, fmt.Errorf("%w %q: cannot descend into a %T", ErrInvalidPath, path, node)
+	}
+}
+
+// Delete removes the value at the parsed path within doc and returns the updated document, and whether the
+// value existed. Elements after a deleted array element move down by one.
+func Delete(doc interface{}, segments []Segment) (interface{}, bool) {
+	if len(segments) == 0 {
+		return nil, doc != nil
+	}
+
+	parent, ok := Lookup(doc, segments[:len(segments)-1])
+	if !ok {
+		return doc, false
+	}
+
+	last := segments[len(segments)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := node[last.Field]; !ok || last.Field == "" {
+			return doc, false
+		}
+		delete(node, last.Field)
+		return doc, true
+	case []interface{}:
+		index := last.Index
+		if index < 0 {
+			index += len(node)
+		}
+		if !last.IsIndex || index < 0 || index >= len(node) {
+			return doc, false
+		}
+		// The shortened array is stored back, as its parent still holds the old length.
+		updated, err := SetSegments(doc, segments[:len(segments)-1], append(node[:index], node[index+1:]...))
+		return updated, err == nil
+	default:
+		return doc, false
+	}
+}
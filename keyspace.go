@@ -0,0 +1,241 @@
+package demory
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/huseyinbabal/demory/ds/list"
+	"github.com/huseyinbabal/demory/ds/multimap"
+	"github.com/huseyinbabal/demory/resp"
+)
+
+const (
+	wrongType  = "WRONGTYPE Operation against a key holding the wrong kind of value"
+	notInteger = "ERR value is not an integer or out of range"
+	notFloat   = "ERR value is not a valid float"
+)
+
+var errWrongType = errors.New(wrongType)
+
+// keyType is the type of the value held by a key of the RESP keyspace.
+type keyType string
+
+const (
+	typeNone   keyType = "none"
+	typeString keyType = "string"
+	typeHash   keyType = "hash"
+	typeSet    keyType = "set"
+	typeZSet   keyType = "zset"
+	typeList   keyType = "list"
+)
+
+// respState holds the sets and lists of RESP keyspaces. It is kept by the node itself, next to maps and
+// caches, as RESP batches write all of them within single commands.
+type respState struct {
+	Sets  *multimap.MultiMap `json:"sets"`
+	Lists *list.Lists        `json:"lists"`
+}
+
+func newRespState() *respState {
+	return &respState{Sets: multimap.New(), Lists: list.New()}
+}
+
+// Bytes returns the number of bytes held by all sets and lists.
+func (s *respState) Bytes() int64 {
+	return s.Sets.Bytes() + s.Lists.Bytes()
+}
+
+// keyspace is the Redis keyspace of the RESP listener at a point in time. Strings are kept in the map named by
+// the keyspace, or in its cache while they have a TTL. Hashes and sorted sets are kept in maps of their own,
+// named by the keyspace, their type and their key, which map fields to values and members to scores. Sets are
+// kept in the multimap named by the keyspace, and lists in lists named like the maps of hashes. A key has the
+// type of the structure it is found in, and commands on a key of another type fail with WRONGTYPE.
+//
+// Commands read and write the state of the node directly. Batches that write run while their RespBatch command
+// is applied, so that every replica runs them on the same state, and read-only batches run within fsm.Read.
+type keyspace struct {
+	d    *Demory
+	name string
+	now  time.Time
+}
+
+func newKeyspace(d *Demory, name string, now time.Time) *keyspace {
+	return &keyspace{d: d, name: name, now: now}
+}
+
+// run runs commands one after the other and returns their replies.
+func (ks *keyspace) run(commands [][][]byte) []resp.Reply {
+	replies := make([]resp.Reply, 0, len(commands))
+	for _, args := range commands {
+		if len(args) == 0 {
+			replies = append(replies, resp.Errorf("ERR empty command"))
+			continue
+		}
+		command, ok := respCommands[strings.ToUpper(string(args[0]))]
+		if !ok {
+			replies = append(replies, resp.Errorf("ERR unknown command '%s'", args[0]))
+			continue
+		}
+		replies = append(replies, command.run(ks, args[1:]))
+	}
+	return replies
+}
+
+// collection returns the name of the map or the list holding the hash, sorted set or list at key.
+func (ks *keyspace) collection(t keyType, key string) string {
+	return ks.name + ":" + string(t) + ":" + key
+}
+
+// typeOf returns the type of the value held by key.
+func (ks *keyspace) typeOf(key string) keyType {
+	switch {
+	case ks.hasStr(key):
+		return typeString
+	case ks.d.hashMap.Len(ks.collection(typeHash, key)) > 0:
+		return typeHash
+	case ks.d.hashMap.Len(ks.collection(typeZSet, key)) > 0:
+		return typeZSet
+	case ks.d.resp.Sets.Exists(ks.name, key):
+		return typeSet
+	case ks.d.resp.Lists.Len(ks.collection(typeList, key)) > 0:
+		return typeList
+	default:
+		return typeNone
+	}
+}
+
+// check returns errWrongType if key holds a value of another type than t.
+func (ks *keyspace) check(key string, t keyType) error {
+	if current := ks.typeOf(key); current != typeNone && current != t {
+		return errWrongType
+	}
+	return nil
+}
+
+func (ks *keyspace) hasStr(key string) bool {
+	if _, ok := ks.d.cache.TTL(ks.name, key, ks.now); ok {
+		return true
+	}
+	_, ok := ks.d.hashMap.EntrySize(ks.name, key)
+	return ok
+}
+
+// str returns the string at key with its TTL, which is zero if it never expires.
+func (ks *keyspace) str(key string) ([]byte, time.Duration, bool) {
+	if ttl, ok := ks.d.cache.TTL(ks.name, key, ks.now); ok {
+		value, _ := ks.d.cache.Peek(ks.name, key, ks.now)
+		return nonNil(value), ttl, true
+	}
+	if _, ok := ks.d.hashMap.EntrySize(ks.name, key); ok {
+		return nonNil(ks.d.hashMap.Get(ks.name, key)), 0, true
+	}
+	return nil, 0, false
+}
+
+// setStr sets the string at key, replacing whatever key held. It expires after ttl if ttl is positive. The cache
+// of the keyspace has no capacity, as Redis only drops keys that expire or to free memory.
+func (ks *keyspace) setStr(key string, value []byte, ttl time.Duration) {
+	if t := ks.typeOf(key); t != typeNone && t != typeString {
+		ks.del(key)
+	}
+	if ttl > 0 {
+		ks.d.cache.SetCapacity(ks.name, 0)
+		ks.d.cache.Put(ks.name, key, value, ttl, ks.now)
+		ks.d.hashMap.Remove(ks.name, key)
+		return
+	}
+	ks.d.hashMap.Put(ks.name, key, value)
+	ks.d.cache.Remove(ks.name, key)
+}
+
+// removeStr removes the string at key and reports whether it existed.
+func (ks *keyspace) removeStr(key string) bool {
+	_, inCache := ks.d.cache.TTL(ks.name, key, ks.now)
+	ks.d.cache.Remove(ks.name, key)
+	inMap := ks.d.hashMap.Remove(ks.name, key) == 1
+	return inCache || inMap
+}
+
+// del removes key, whatever it holds, and reports whether it existed.
+func (ks *keyspace) del(key string) bool {
+	switch ks.typeOf(key) {
+	case typeString:
+		return ks.removeStr(key)
+	case typeHash, typeZSet:
+		ks.d.hashMap.Clear(ks.collection(typeHash, key))
+		ks.d.hashMap.Clear(ks.collection(typeZSet, key))
+	case typeSet:
+		ks.d.resp.Sets.RemoveAll(ks.name, key)
+	case typeList:
+		ks.d.resp.Lists.Destroy(ks.collection(typeList, key))
+	default:
+		return false
+	}
+	return true
+}
+
+// zmember is a member of a sorted set.
+type zmember struct {
+	member string
+	score  float64
+}
+
+// score returns the score of a member of the sorted set at key.
+func (ks *keyspace) score(key, member string) (float64, bool) {
+	value := ks.d.hashMap.Get(ks.collection(typeZSet, key), member)
+	if value == nil {
+		return 0, false
+	}
+	score, _ := resp.ParseFloat(value)
+	return score, true
+}
+
+// setScore sets the score of a member of the sorted set at key.
+func (ks *keyspace) setScore(key, member string, score float64) {
+	ks.d.hashMap.Put(ks.collection(typeZSet, key), member, []byte(resp.FormatFloat(score)))
+}
+
+// zset returns the members of the sorted set at key, ordered by score, then by member.
+func (ks *keyspace) zset(key string) []zmember {
+	var members []zmember
+	ks.d.hashMap.Range(ks.collection(typeZSet, key), func(member string, value []byte) bool {
+		score, _ := resp.ParseFloat(value)
+		members = append(members, zmember{member: member, score: score})
+		return true
+	})
+
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].score != members[j].score {
+			return members[i].score < members[j].score
+		}
+		return members[i].member < members[j].member
+	})
+	return members
+}
+
+// span converts Redis start and stop indexes, which count from the end if negative, to a range of n elements.
+func span(start, stop, n int64) (int64, int64, bool) {
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	return start, stop, start <= stop
+}
+
+// nonNil returns an empty value for nil, as existing strings are never nil.
+func nonNil(value []byte) []byte {
+	if value == nil {
+		return []byte{}
+	}
+	return value
+}
@@ -0,0 +1,94 @@
+package mapstore
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// FileStore keeps every entry in its own file, under a directory per map.
+type FileStore struct {
+	dir string
+}
+
+var _ MapStore = &FileStore{}
+
+// NewFileStore creates a FileStore rooted at dir, creating the directory if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (f *FileStore) Load(name, key string) ([]byte, bool, error) {
+	value, err := os.ReadFile(f.path(name, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (f *FileStore) Store(name string, entries map[string][]byte) error {
+	if err := os.MkdirAll(filepath.Join(f.dir, encode(name)), os.ModePerm); err != nil {
+		return err
+	}
+
+	for key, value := range entries {
+		if err := f.write(f.path(name, key), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *FileStore) Delete(name string, keys []string) error {
+	for _, key := range keys {
+		if err := os.Remove(f.path(name, key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// write replaces the file at path atomically, so that readers never see a partial value.
+func (f *FileStore) write(path string, value []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (f *FileStore) path(name, key string) string {
+	return filepath.Join(f.dir, encode(name), encode(key))
+}
+
+// maxFileName is the longest file name most file systems accept.
+const maxFileName = 255
+
+// encode turns arbitrary names and keys into safe file names. Names and keys too long to be encoded within
+// maxFileName are hashed instead. Entries are only ever looked up by key, so their files need not tell the
+// original key.
+func encode(s string) string {
+	if encoded := "_" + base64.RawURLEncoding.EncodeToString([]byte(s)); len(encoded) <= maxFileName {
+		return encoded
+	}
+	sum := sha256.Sum256([]byte(s))
+	return "#" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,153 @@
+// Package mapstore connects named maps to an external system of record. Missing keys are loaded through a
+// MapLoader, and writes are persisted through a MapStore, either synchronously or in batches.
+package mapstore
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"time"
+)
+
+// MapLoader loads entries of named maps from an external store.
+type MapLoader interface {
+	// Load returns the value stored at key of map name, and whether it exists.
+	Load(name, key string) ([]byte, bool, error)
+}
+
+// MapStore persists entries of named maps to an external store. Implementations must be idempotent,
+// since a batch may be stored again after a failure.
+type MapStore interface {
+	MapLoader
+	// Store saves entries of map name.
+	Store(name string, entries map[string][]byte) error
+	// Delete removes keys of map name. Missing keys are ignored.
+	Delete(name string, keys []string) error
+}
+
+// Mode decides when writes reach the store.
+type Mode string
+
+const (
+	// WriteThrough persists every write once it is applied to the map, before the write returns. A write
+	// the store fails on is still applied to the map, and returns the error of the store.
+	WriteThrough Mode = "write-through"
+	// WriteBehind keeps writes pending and persists them in batches after a delay.
+	WriteBehind Mode = "write-behind"
+)
+
+const (
+	DefaultWriteDelay = time.Second
+	DefaultBatchSize  = 100
+)
+
+var ErrUnknownMode = errors.New("unknown map store mode")
+
+// Config controls how a Persister writes to its store.
+type Config struct {
+	Mode Mode
+	// WriteDelay is how long writes stay pending before they are persisted in write-behind mode.
+	WriteDelay time.Duration
+	// BatchSize is the maximum number of writes persisted at once in write-behind mode.
+	BatchSize int
+}
+
+// Write is a change of an entry to persist. Deleted writes remove the entry from the store.
+type Write struct {
+	Name    string
+	Key     string
+	Value   []byte
+	Deleted bool
+}
+
+// Persister writes map changes to a MapStore according to its Config. It is only used on the leader,
+// which is the node accepting writes.
+type Persister struct {
+	store  MapStore
+	config Config
+}
+
+// New creates a Persister.
+func New(store MapStore, config Config) (*Persister, error) {
+	if config.WriteDelay <= 0 {
+		config.WriteDelay = DefaultWriteDelay
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.Mode != WriteThrough && config.Mode != WriteBehind {
+		return nil, ErrUnknownMode
+	}
+
+	return &Persister{store: store, config: config}, nil
+}
+
+// Mode returns the write mode of the Persister.
+func (p *Persister) Mode() Mode {
+	return p.config.Mode
+}
+
+// WriteDelay returns how long writes stay pending in write-behind mode.
+func (p *Persister) WriteDelay() time.Duration {
+	return p.config.WriteDelay
+}
+
+// BatchSize returns the maximum number of writes persisted at once in write-behind mode.
+func (p *Persister) BatchSize() int {
+	return p.config.BatchSize
+}
+
+// Load returns the value stored at key of map name, and whether it exists.
+func (p *Persister) Load(name, key string) ([]byte, bool, error) {
+	return p.store.Load(name, key)
+}
+
+// Put persists value at key of map name.
+func (p *Persister) Put(name, key string, value []byte) error {
+	return p.store.Store(name, map[string][]byte{key: value})
+}
+
+// Delete removes keys of map name from the store.
+func (p *Persister) Delete(name string, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return p.store.Delete(name, keys)
+}
+
+// Persist stores a batch of writes, grouped by map.
+func (p *Persister) Persist(writes []Write) error {
+	stores := make(map[string]map[string][]byte)
+	deletes := make(map[string][]string)
+	for _, w := range writes {
+		if w.Deleted {
+			deletes[w.Name] = append(deletes[w.Name], w.Key)
+			continue
+		}
+		if stores[w.Name] == nil {
+			stores[w.Name] = make(map[string][]byte)
+		}
+		stores[w.Name][w.Key] = w.Value
+	}
+
+	for name, entries := range stores {
+		if err := p.store.Store(name, entries); err != nil {
+			return err
+		}
+	}
+	for name, keys := range deletes {
+		sort.Strings(keys)
+		if err := p.store.Delete(name, keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the store, if it holds resources such as a database connection.
+func (p *Persister) Close() error {
+	if closer, ok := p.store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package mapstore
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordingStore struct {
+	mutex   sync.Mutex
+	stored  []map[string][]byte
+	deleted []string
+	fail    bool
+}
+
+func (r *recordingStore) Load(name, key string) ([]byte, bool, error) {
+	return nil, false, nil
+}
+
+func (r *recordingStore) Store(name string, entries map[string][]byte) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.fail {
+		return errors.New("unavailable")
+	}
+	r.stored = append(r.stored, entries)
+	return nil
+}
+
+func (r *recordingStore) Delete(name string, keys []string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.fail {
+		return errors.New("unavailable")
+	}
+	r.deleted = append(r.deleted, keys...)
+	return nil
+}
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store %v", err)
+	}
+
+	if err := store.Store("users/eu", map[string][]byte{"../a": []byte("1")}); err != nil {
+		t.Fatalf("failed to store %v", err)
+	}
+
+	value, ok, err := store.Load("users/eu", "../a")
+	if err != nil || !ok || string(value) != "1" {
+		t.Errorf("expected stored value, got %q %v %v", value, ok, err)
+	}
+
+	if err := store.Delete("users/eu", []string{"../a", "missing"}); err != nil {
+		t.Fatalf("failed to delete %v", err)
+	}
+	if _, ok, _ := store.Load("users/eu", "../a"); ok {
+		t.Error("expected value to be deleted")
+	}
+
+	// Names and keys too long for a file name are hashed.
+	name, key := strings.Repeat("n", 300), strings.Repeat("k", 1000)
+	if err := store.Store(name, map[string][]byte{key: []byte("2"), key + "x": []byte("3")}); err != nil {
+		t.Fatalf("failed to store long keys %v", err)
+	}
+	value, ok, err = store.Load(name, key)
+	if err != nil || !ok || string(value) != "2" {
+		t.Errorf("expected stored value at a long key, got %q %v %v", value, ok, err)
+	}
+}
+
+func TestSQLStore(t *testing.T) {
+	store, err := OpenSQLite(DefaultSQLiteDriver, filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatalf("failed to open store %v", err)
+	}
+	defer store.Close()
+
+	if err := store.Store("users", map[string][]byte{"a": []byte("1"), "b": nil}); err != nil {
+		t.Fatalf("failed to store %v", err)
+	}
+	if err := store.Store("users", map[string][]byte{"a": []byte("2")}); err != nil {
+		t.Fatalf("failed to overwrite %v", err)
+	}
+
+	value, ok, err := store.Load("users", "a")
+	if err != nil || !ok || string(value) != "2" {
+		t.Errorf("expected stored value, got %q %v %v", value, ok, err)
+	}
+	if value, ok, _ := store.Load("users", "b"); !ok || len(value) != 0 {
+		t.Errorf("expected an empty value, got %q %v", value, ok)
+	}
+
+	if err := store.Delete("users", []string{"a", "missing"}); err != nil {
+		t.Fatalf("failed to delete %v", err)
+	}
+	if _, ok, _ := store.Load("users", "a"); ok {
+		t.Error("expected value to be deleted")
+	}
+}
+
+func TestPersist(t *testing.T) {
+	store := &recordingStore{}
+	p, err := New(store, Config{Mode: WriteBehind})
+	if err != nil {
+		t.Fatalf("failed to create persister %v", err)
+	}
+
+	err = p.Persist([]Write{
+		{Name: "users", Key: "a", Value: []byte("1")},
+		{Name: "users", Key: "c", Deleted: true},
+		{Name: "users", Key: "b", Deleted: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to persist %v", err)
+	}
+
+	if len(store.stored) != 1 || string(store.stored[0]["a"]) != "1" {
+		t.Errorf("expected a to be stored, got %v", store.stored)
+	}
+	if len(store.deleted) != 2 || store.deleted[0] != "b" || store.deleted[1] != "c" {
+		t.Errorf("expected b and c to be deleted, got %v", store.deleted)
+	}
+
+	store.fail = true
+	if err := p.Persist([]Write{{Name: "users", Key: "a"}}); err == nil {
+		t.Error("expected the failure of the store")
+	}
+}
+
+func TestPendingCoalescesWrites(t *testing.T) {
+	p := NewPending()
+	p.Mark("users", "a", 1, 10)
+	p.Mark("users", "b", 2, 20)
+	p.Mark("users", "a", 3, 30)
+
+	due := p.Due(15, 10)
+	if len(due) != 1 || due[0] != (Mark{Name: "users", Key: "a", Index: 3, Time: 10}) {
+		t.Fatalf("expected the latest change of a to be due, got %v", due)
+	}
+
+	// a changes again while the batch is persisted, so it stays pending.
+	due = p.Due(20, 10)
+	p.Mark("users", "a", 4, 40)
+	p.Clear(due, 50)
+
+	if p.Len() != 1 {
+		t.Fatalf("expected a to stay pending, got %d entries", p.Len())
+	}
+	if due := p.Due(49, 10); len(due) != 0 {
+		t.Errorf("expected a to wait another delay, got %v", due)
+	}
+	if due := p.Due(50, 10); len(due) != 1 || due[0].Index != 4 {
+		t.Errorf("expected the new change of a, got %v", due)
+	}
+}
+
+func TestPendingSnapshot(t *testing.T) {
+	p := NewPending()
+	p.Mark("users", "a", 1, 10)
+	p.Mark("orders", "b", 2, 20)
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("failed to encode %v", err)
+	}
+	restored := NewPending()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("failed to decode %v", err)
+	}
+
+	if !reflect.DeepEqual(restored.Due(20, 10), p.Due(20, 10)) {
+		t.Errorf("expected %v, got %v", p.Due(20, 10), restored.Due(20, 10))
+	}
+}
@@ -0,0 +1,110 @@
+package mapstore
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Mark is an entry changed since it was last persisted in write-behind mode. Index is the raft index of its
+// latest change, and Time when it became due, in unix nanoseconds.
+type Mark struct {
+	Name  string `json:"name"`
+	Key   string `json:"key"`
+	Index uint64 `json:"index"`
+	Time  int64  `json:"time"`
+}
+
+type entryKey struct {
+	name string
+	key  string
+}
+
+// Pending tracks the entries waiting to be persisted in write-behind mode. It is part of the replicated state,
+// so whichever node leads persists what earlier leaders had not. Changes of the same entry are coalesced, so
+// only its latest value reaches the store.
+type Pending struct {
+	marks map[entryKey]Mark
+}
+
+// NewPending creates an empty Pending.
+func NewPending() *Pending {
+	return &Pending{marks: make(map[entryKey]Mark)}
+}
+
+// Mark records a change of key of map name, applied at index at time. A key that is already pending keeps its
+// time, so that a frequently changed key is not delayed forever.
+func (p *Pending) Mark(name, key string, index uint64, time int64) {
+	k := entryKey{name: name, key: key}
+	if previous, ok := p.marks[k]; ok {
+		time = previous.Time
+	}
+	p.marks[k] = Mark{Name: name, Key: key, Index: index, Time: time}
+}
+
+// Due returns up to limit entries pending since before deadline, oldest first.
+func (p *Pending) Due(deadline int64, limit int) []Mark {
+	var due []Mark
+	for _, m := range p.marks {
+		if m.Time <= deadline {
+			due = append(due, m)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].Time != due[j].Time {
+			return due[i].Time < due[j].Time
+		}
+		return due[i].Index < due[j].Index
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due
+}
+
+// Clear removes the entries persisted at time. Entries changed again since they were read stay pending, and
+// become due a write delay after time.
+func (p *Pending) Clear(persisted []Mark, time int64) {
+	for _, m := range persisted {
+		k := entryKey{name: m.Name, key: m.Key}
+		current, ok := p.marks[k]
+		switch {
+		case !ok:
+		case current.Index == m.Index:
+			delete(p.marks, k)
+		default:
+			current.Time = time
+			p.marks[k] = current
+		}
+	}
+}
+
+// Len returns the number of pending entries.
+func (p *Pending) Len() int {
+	return len(p.marks)
+}
+
+// MarshalJSON encodes the pending entries for a snapshot.
+func (p *Pending) MarshalJSON() ([]byte, error) {
+	marks := make([]Mark, 0, len(p.marks))
+	for _, m := range p.marks {
+		marks = append(marks, m)
+	}
+	sort.Slice(marks, func(i, j int) bool {
+		return marks[i].Index < marks[j].Index
+	})
+	return json.Marshal(marks)
+}
+
+// UnmarshalJSON replaces the pending entries with a snapshot.
+func (p *Pending) UnmarshalJSON(data []byte) error {
+	var marks []Mark
+	if err := json.Unmarshal(data, &marks); err != nil {
+		return err
+	}
+
+	p.marks = make(map[entryKey]Mark, len(marks))
+	for _, m := range marks {
+		p.marks[entryKey{name: m.Name, key: m.Key}] = m
+	}
+	return nil
+}
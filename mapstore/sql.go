@@ -0,0 +1,103 @@
+package mapstore
+
+import (
+	"database/sql"
+	"errors"
+
+	// Registers the pure Go SQLite driver, so that SQLite stores work without cgo.
+	_ "modernc.org/sqlite"
+)
+
+// DefaultSQLiteDriver is the database/sql driver name SQLite stores are opened with.
+const DefaultSQLiteDriver = "sqlite"
+
+// SQLStore keeps entries in a single table of a SQLite database. The pure Go driver registered as
+// DefaultSQLiteDriver is linked in; other drivers can be registered by binaries with a blank import.
+type SQLStore struct {
+	db *sql.DB
+}
+
+var _ MapStore = &SQLStore{}
+
+// OpenSQLite opens the SQLite database file at path with driver and creates its table if needed.
+func OpenSQLite(driver, path string) (*SQLStore, error) {
+	db, err := sql.Open(driver, path)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := NewSQLStore(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// NewSQLStore creates a SQLStore on db and creates its table if needed.
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS demory_entries (
+		name  TEXT NOT NULL,
+		key   TEXT NOT NULL,
+		value BLOB NOT NULL,
+		PRIMARY KEY (name, key)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLStore{db: db}, nil
+}
+
+func (s *SQLStore) Load(name, key string) ([]byte, bool, error) {
+	var value []byte
+	err := s.db.QueryRow(`SELECT value FROM demory_entries WHERE name = ? AND key = ?`, name, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (s *SQLStore) Store(name string, entries map[string][]byte) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		for key, value := range entries {
+			// Drivers may bind empty values as NULL.
+			_, err := tx.Exec(`INSERT INTO demory_entries (name, key, value) VALUES (?, ?, COALESCE(?, x''))
+				ON CONFLICT (name, key) DO UPDATE SET value = excluded.value`, name, key, value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) Delete(name string, keys []string) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		for _, key := range keys {
+			if _, err := tx.Exec(`DELETE FROM demory_entries WHERE name = ? AND key = ?`, name, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close closes the underlying database.
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLStore) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,101 @@
+package demory
+
+import (
+	"github.com/huseyinbabal/demory/ds/cache"
+	"github.com/huseyinbabal/demory/fsm"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MaxMemoryPolicy decides what a node does once its data reaches the configured maxmemory.
+type MaxMemoryPolicy string
+
+const (
+	// PolicyEvictCache evicts the least recently written cache entries to make room for a write.
+	PolicyEvictCache MaxMemoryPolicy = "evict-cache"
+	// PolicyEvictVolatile evicts the least recently written cache entries that expire. Entries without a
+	// TTL, in caches or maps, are never evicted.
+	PolicyEvictVolatile MaxMemoryPolicy = "evict-volatile"
+	// PolicyReject rejects writes that do not fit.
+	PolicyReject MaxMemoryPolicy = "reject"
+)
+
+var errOutOfMemory = status.Error(codes.ResourceExhausted, "maxmemory reached")
+
+// usedMemory returns the number of bytes held by map and cache entries, by the sets and lists of the RESP
+// keyspace and by all structure types on this node.
+func (d *Demory) usedMemory() int64 {
+	used := d.hashMap.Bytes() + d.cache.Bytes() + d.resp.Bytes()
+	for _, s := range d.structures.Structures() {
+		used += s.Stats().Bytes
+	}
+	return used
+}
+
+// growth returns the number of bytes the node would grow by after applying request.
+func (d *Demory) growth(request fsm.ApplyRequest) int64 {
+	entry := int64(len(request.Key) + len(request.Value))
+
+	switch request.Type {
+	case fsm.MapPut:
+		current, _ := d.hashMap.EntrySize(request.Name, request.Key)
+		return entry - current
+	case fsm.MapPutIfAbsent:
+		if _, ok := d.hashMap.EntrySize(request.Name, request.Key); ok {
+			return 0
+		}
+		return entry
+	case fsm.CachePut:
+		current, _ := d.cache.EntrySize(request.Name, request.Key)
+		return entry - current
+	case fsm.RespBatch:
+		return respGrowth(request)
+	case fsm.MapExecute:
+		_, growth, _ := d.process(request)
+		return growth
+	case fsm.Transaction:
+		return d.transactionGrowth(request)
+	default:
+		if s, ok := d.structures.Applying(request.Type); ok {
+			return s.Growth(request)
+		}
+		return 0
+	}
+}
+
+// reserve makes room for request under the maxmemory limit it was proposed with. It evicts the oldest
+// entries the policy of the command allows, but only if they free enough: otherwise the command is rejected
+// and nothing is evicted. Entries are chosen from replicated state while the command is applied, so every
+// replica evicts the same ones and commands applied one after the other never count on the same entries.
+func (d *Demory) reserve(request fsm.ApplyRequest) error {
+	growth := d.growth(request)
+	if d.fits(request.MaxMemory, growth) {
+		return nil
+	}
+
+	need := d.usedMemory() + growth - request.MaxMemory
+	skip := func(name, key string) bool {
+		return request.Type == fsm.CachePut && name == request.Name && key == request.Key
+	}
+	var keys []cache.Key
+	var freed int64
+	switch MaxMemoryPolicy(request.MaxMemoryPolicy) {
+	case PolicyEvictCache:
+		keys, freed = d.cache.Oldest(need, skip)
+	case PolicyEvictVolatile:
+		keys, freed = d.cache.OldestVolatile(need, skip)
+	}
+	if freed < need {
+		return errOutOfMemory
+	}
+
+	for _, k := range keys {
+		d.cache.Evict(k.Name, k.Key)
+	}
+	return nil
+}
+
+// fits reports whether the node can grow by growth bytes without exceeding limit.
+func (d *Demory) fits(limit, growth int64) bool {
+	return limit <= 0 || growth <= 0 || d.usedMemory()+growth <= limit
+}
@@ -0,0 +1,164 @@
+package demory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	proto "github.com/huseyinbabal/demory-proto/golang/demory"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/node"
+	"github.com/huseyinbabal/demory/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUsedMemory(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	ctx := context.Background()
+
+	d.MapPut(ctx, &proto.MapPutRequest{Name: "users", Key: "a", Value: []byte("1234")})
+	d.MapPut(ctx, &proto.MapPutRequest{Name: "users", Key: "a", Value: []byte("12")})
+	d.CachePut(ctx, &proto.CachePutRequest{Name: "sessions", Key: "bb", Value: []byte("123")})
+
+	var used int64
+	d.fsm.Read(func() {
+		used = d.usedMemory()
+	})
+	if used != 8 {
+		t.Errorf("expected 8 bytes to be used, got %d", used)
+	}
+}
+
+func TestEvictCache(t *testing.T) {
+	d := newTestNode(t, node.Config{MaxMemory: 20, MaxMemoryPolicy: string(PolicyEvictCache)})
+	ctx := context.Background()
+
+	d.CachePut(ctx, &proto.CachePutRequest{Name: "c", Key: "a", Value: []byte("12345678")})
+	d.CachePut(ctx, &proto.CachePutRequest{Name: "c", Key: "b", Value: []byte("12345678")})
+	if _, err := d.CachePut(ctx, &proto.CachePutRequest{Name: "c", Key: "c", Value: []byte("12345678")}); err != nil {
+		t.Fatalf("expected the put to evict, got %v", err)
+	}
+
+	d.fsm.Read(func() {
+		if _, ok := d.cache.EntrySize("c", "a"); ok {
+			t.Error("expected the oldest entry to be evicted")
+		}
+		if d.usedMemory() != 18 {
+			t.Errorf("expected 18 bytes to be used, got %d", d.usedMemory())
+		}
+	})
+}
+
+func TestEvictVolatile(t *testing.T) {
+	d := newTestNode(t, node.Config{MaxMemory: 20, MaxMemoryPolicy: string(PolicyEvictVolatile)})
+	ctx := context.Background()
+
+	d.CachePut(ctx, &proto.CachePutRequest{Name: "c", Key: "a", Value: []byte("12345678")})
+	d.CachePutWithTTL(ctx, &rpc.CachePutWithTTLRequest{Name: "c", Key: "b", Value: []byte("12345678"), TTL: 60000})
+	if _, err := d.CachePut(ctx, &proto.CachePutRequest{Name: "c", Key: "c", Value: []byte("12345678")}); err != nil {
+		t.Fatalf("expected the put to evict, got %v", err)
+	}
+	d.fsm.Read(func() {
+		if _, ok := d.cache.EntrySize("c", "b"); ok {
+			t.Error("expected the expiring entry to be evicted")
+		}
+		if _, ok := d.cache.EntrySize("c", "a"); !ok {
+			t.Error("expected the entry without a TTL to be kept")
+		}
+	})
+
+	_, err := d.MapPut(ctx, &proto.MapPutRequest{Name: "m", Key: "d", Value: []byte("12345678")})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected the put to be rejected, got %v", err)
+	}
+	d.fsm.Read(func() {
+		if d.usedMemory() != 18 {
+			t.Errorf("expected no entry to be evicted, got %d bytes", d.usedMemory())
+		}
+	})
+}
+
+func TestReserve(t *testing.T) {
+	d := newTestNode(t, node.Config{MaxMemory: 10})
+	ctx := context.Background()
+	d.CachePut(ctx, &proto.CachePutRequest{Name: "c", Key: "a", Value: []byte("1234")})
+
+	// Evicting every cache entry would not free enough, so the write is rejected and nothing is evicted for it.
+	response := apply(t, d, fsm.ApplyRequest{
+		Type:            fsm.CachePut,
+		Name:            "c",
+		Key:             "b",
+		Value:           []byte("12345678901"),
+		MaxMemory:       10,
+		MaxMemoryPolicy: string(PolicyEvictCache),
+	})
+	if response.Error != errOutOfMemory {
+		t.Errorf("expected the write to be rejected, got %v", response.Error)
+	}
+	d.fsm.Read(func() {
+		if _, ok := d.cache.EntrySize("c", "a"); !ok {
+			t.Error("expected nothing to be evicted for a rejected write")
+		}
+	})
+
+	// Replicas check the limit the command was proposed with, not their own.
+	response = apply(t, d, fsm.ApplyRequest{Type: fsm.CachePut, Name: "c", Key: "b", Value: []byte("12345678901")})
+	if response.Error != nil {
+		t.Errorf("expected the write to be accepted without a limit, got %v", response.Error)
+	}
+}
+
+func TestEvictConcurrently(t *testing.T) {
+	d := newTestNode(t, node.Config{MaxMemory: 1000, MaxMemoryPolicy: string(PolicyEvictCache)})
+	ctx := context.Background()
+	value := []byte(strings.Repeat("x", 97))
+	for i := 0; i < 10; i++ {
+		d.CachePut(ctx, &proto.CachePutRequest{Name: "c", Key: fmt.Sprintf("a%02d", i), Value: value})
+	}
+
+	// Writers proposing at the same time see the same oldest entries, but each write evicts its own.
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		go func(i int) {
+			_, err := d.CachePut(ctx, &proto.CachePutRequest{Name: "c", Key: fmt.Sprintf("b%02d", i), Value: value})
+			errs <- err
+		}(i)
+	}
+	for i := 0; i < 20; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("expected every put to evict, got %v", err)
+		}
+	}
+
+	d.fsm.Read(func() {
+		if d.usedMemory() != 1000 {
+			t.Errorf("expected 1000 bytes to be used, got %d", d.usedMemory())
+		}
+	})
+}
+
+func TestEvictForTransaction(t *testing.T) {
+	d := newTestNode(t, node.Config{MaxMemory: 20, MaxMemoryPolicy: string(PolicyEvictCache)})
+	ctx := context.Background()
+
+	d.CachePut(ctx, &proto.CachePutRequest{Name: "c", Key: "a", Value: []byte("12345678")})
+	d.CachePut(ctx, &proto.CachePutRequest{Name: "c", Key: "b", Value: []byte("12345678")})
+	_, err := d.Execute(ctx, &rpc.TransactionRequest{Operations: []rpc.Operation{
+		{Type: rpc.OperationPut, Structure: string(fsm.StructureMap), Name: "m", Key: "c", Value: []byte("1234")},
+		{Type: rpc.OperationPut, Structure: string(fsm.StructureMap), Name: "m", Key: "d", Value: []byte("1234")},
+	}})
+	if err != nil {
+		t.Fatalf("expected the transaction to evict, got %v", err)
+	}
+
+	d.fsm.Read(func() {
+		if _, ok := d.cache.EntrySize("c", "a"); ok {
+			t.Error("expected the oldest entry to be evicted")
+		}
+		if d.usedMemory() != 19 {
+			t.Errorf("expected 19 bytes to be used, got %d", d.usedMemory())
+		}
+	})
+}
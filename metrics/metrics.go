@@ -0,0 +1,70 @@
+// Package metrics exposes node metrics in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	Counter = "counter"
+	Gauge   = "gauge"
+)
+
+// Sample is a single value of a metric family.
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Family is a named group of samples sharing the same help text and type.
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Collector returns the current metric families of a component.
+type Collector func() []Family
+
+// Handler serves the families returned by collectors on every scrape.
+func Handler(collectors ...Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		for _, collect := range collectors {
+			for _, family := range collect() {
+				write(w, family)
+			}
+		}
+	})
+}
+
+func write(w io.Writer, family Family) {
+	fmt.Fprintf(w, "# HELP %s %s\n", family.Name, family.Help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", family.Name, family.Type)
+	for _, sample := range family.Samples {
+		fmt.Fprintf(w, "%s%s %v\n", family.Name, labels(sample.Labels), sample.Value)
+	}
+}
+
+func labels(l map[string]string) string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, l[name]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,188 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/huseyinbabal/demory/ds/multimap"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/structure"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	structure.Register(newMultiMapStructure)
+}
+
+// multiMapStructure serves and applies multimaps.
+type multiMapStructure struct {
+	host structure.Host
+	maps *multimap.MultiMap
+}
+
+func newMultiMapStructure(host structure.Host) structure.Structure {
+	return &multiMapStructure{host: host, maps: multimap.New()}
+}
+
+func (s *multiMapStructure) Kind() string {
+	return "multiMaps"
+}
+
+func (s *multiMapStructure) Commands() []fsm.CommandType {
+	return []fsm.CommandType{fsm.MultiMapPut, fsm.MultiMapRemove, fsm.MultiMapRemoveAll}
+}
+
+func (s *multiMapStructure) Register(server grpc.ServiceRegistrar) {
+	rpc.RegisterMultiMapServer(server, s)
+}
+
+func (s *multiMapStructure) Snapshot() ([]byte, error) {
+	return json.Marshal(s.maps)
+}
+
+func (s *multiMapStructure) Restore(data []byte) (func(), error) {
+	maps := multimap.New()
+	if err := structure.Unmarshal(data, maps); err != nil {
+		return nil, err
+	}
+	return func() { s.maps = maps }, nil
+}
+
+func (s *multiMapStructure) Stats() structure.Stats {
+	return structure.Stats{Bytes: s.maps.Bytes()}
+}
+
+func (s *multiMapStructure) Destroy(name string) bool {
+	return s.maps.Destroy(name)
+}
+
+// multiMapArgs are the arguments of replicated multimap commands. Removed values travel as the request value.
+type multiMapArgs struct {
+	Values [][]byte      `json:"values,omitempty"`
+	Mode   multimap.Mode `json:"mode,omitempty"`
+}
+
+// MultiMapPut adds values to a key of a multimap.
+func (s *multiMapStructure) MultiMapPut(ctx context.Context,
+	req *rpc.MultiMapPutRequest) (*rpc.MultiMapPutResponse, error) {
+	if len(req.Values) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "values are required")
+	}
+	if err := multimap.ValidateMode(req.Mode); err != nil {
+		return nil, multiMapError(err)
+	}
+
+	request := fsm.ApplyRequest{Type: fsm.MultiMapPut, Name: req.Name, Key: req.Key}
+	data, err := s.host.Propose(request, multiMapArgs{Values: req.Values, Mode: req.Mode})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.MultiMapPutResponse{Added: data.(int)}, nil
+}
+
+// MultiMapGet returns all values of a key of a multimap.
+func (s *multiMapStructure) MultiMapGet(ctx context.Context,
+	req *rpc.MultiMapKeyRequest) (*rpc.MultiMapGetResponse, error) {
+	response := &rpc.MultiMapGetResponse{}
+	s.host.Read(func() {
+		response.Values, response.Mode = s.maps.Get(req.Name, req.Key)
+	})
+
+	return response, nil
+}
+
+// MultiMapRemove removes one occurrence of a value from a key of a multimap.
+func (s *multiMapStructure) MultiMapRemove(ctx context.Context,
+	req *rpc.MultiMapRemoveRequest) (*rpc.MultiMapRemoveResponse, error) {
+	request := fsm.ApplyRequest{Type: fsm.MultiMapRemove, Name: req.Name, Key: req.Key, Value: req.Value}
+	data, err := s.host.Propose(request, multiMapArgs{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.MultiMapRemoveResponse{Removed: data.(bool)}, nil
+}
+
+// MultiMapRemoveAll removes a key of a multimap with all its values.
+func (s *multiMapStructure) MultiMapRemoveAll(ctx context.Context,
+	req *rpc.MultiMapKeyRequest) (*rpc.MultiMapRemoveAllResponse, error) {
+	request := fsm.ApplyRequest{Type: fsm.MultiMapRemoveAll, Name: req.Name, Key: req.Key}
+	data, err := s.host.Propose(request, multiMapArgs{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.MultiMapRemoveAllResponse{Removed: data.(int)}, nil
+}
+
+// MultiMapCount returns the number of values of a key of a multimap.
+func (s *multiMapStructure) MultiMapCount(ctx context.Context,
+	req *rpc.MultiMapKeyRequest) (*rpc.MultiMapCountResponse, error) {
+	response := &rpc.MultiMapCountResponse{}
+	s.host.Read(func() {
+		response.Count = s.maps.Count(req.Name, req.Key)
+	})
+
+	return response, nil
+}
+
+// MultiMapKeys returns the keys of a multimap.
+func (s *multiMapStructure) MultiMapKeys(ctx context.Context,
+	req *rpc.MultiMapKeysRequest) (*rpc.MultiMapKeysResponse, error) {
+	response := &rpc.MultiMapKeysResponse{}
+	s.host.Read(func() {
+		response.Keys = s.maps.Keys(req.Name)
+	})
+
+	return response, nil
+}
+
+func (s *multiMapStructure) Apply(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args multiMapArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	switch request.Type {
+	case fsm.MultiMapPut:
+		added, err := s.maps.Put(request.Name, request.Key, args.Values, args.Mode)
+		if err != nil {
+			return fsm.ApplyResponse{Error: multiMapError(err)}
+		}
+		return fsm.ApplyResponse{Data: added}
+	case fsm.MultiMapRemove:
+		return fsm.ApplyResponse{Data: s.maps.Remove(request.Name, request.Key, request.Value)}
+	default:
+		return fsm.ApplyResponse{Data: s.maps.RemoveAll(request.Name, request.Key)}
+	}
+}
+
+// Growth returns the number of bytes a put adds at most, as values already in a set are not added.
+func (s *multiMapStructure) Growth(request fsm.ApplyRequest) int64 {
+	var args multiMapArgs
+	if json.Unmarshal(request.Args, &args) != nil {
+		return 0
+	}
+
+	var growth int64
+	if !s.maps.Exists(request.Name, request.Key) {
+		growth = int64(len(request.Key))
+	}
+	for _, value := range args.Values {
+		growth += int64(len(value))
+	}
+	return growth
+}
+
+// multiMapError converts errors of the multimap package to gRPC statuses.
+func multiMapError(err error) error {
+	if errors.Is(err, multimap.ErrMode) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
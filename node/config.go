@@ -16,6 +16,23 @@ type Config struct {
 	DiscoveryStrategy   string `mapstructure:"DISCOVERY_STRATEGY"`
 	KubernetesService   string `mapstructure:"KUBERNETES_SERVICE"`
 	KubernetesNamespace string `mapstructure:"KUBERNETES_NAMESPACE"`
+	MaxMemory           int64  `mapstructure:"MAX_MEMORY"`
+	MaxMemoryPolicy     string `mapstructure:"MAX_MEMORY_POLICY"`
+	MetricsPort         int    `mapstructure:"METRICS_PORT"`
+	MapStore            string `mapstructure:"MAP_STORE"`
+	MapStorePath        string `mapstructure:"MAP_STORE_PATH"`
+	MapStoreDriver      string `mapstructure:"MAP_STORE_DRIVER"`
+	MapStoreMode        string `mapstructure:"MAP_STORE_MODE"`
+	MapStoreMaps        string `mapstructure:"MAP_STORE_MAPS"`
+	MapStoreWriteDelay  int    `mapstructure:"MAP_STORE_WRITE_DELAY"`
+	MapStoreBatchSize   int    `mapstructure:"MAP_STORE_BATCH_SIZE"`
+	MaxTransactions     int    `mapstructure:"MAX_TRANSACTIONS"`
+	TransactionTimeout  int    `mapstructure:"TRANSACTION_TIMEOUT"`
+	WatchHistory        int    `mapstructure:"WATCH_HISTORY"`
+	CDCHistory          int    `mapstructure:"CDC_HISTORY"`
+	CDCFile             string `mapstructure:"CDC_FILE"`
+	RespPort            int    `mapstructure:"RESP_PORT"`
+	RespKeyspace        string `mapstructure:"RESP_KEYSPACE"`
 }
 
 func LoadConfig() (config *Config, e error) {
@@ -28,6 +45,23 @@ func LoadConfig() (config *Config, e error) {
 	bindEnv("DISCOVERY_STRATEGY")
 	bindEnv("KUBERNETES_SERVICE")
 	bindEnv("KUBERNETES_NAMESPACE")
+	bindEnv("MAX_MEMORY")
+	bindEnv("MAX_MEMORY_POLICY")
+	bindEnv("METRICS_PORT")
+	bindEnv("MAP_STORE")
+	bindEnv("MAP_STORE_PATH")
+	bindEnv("MAP_STORE_DRIVER")
+	bindEnv("MAP_STORE_MODE")
+	bindEnv("MAP_STORE_MAPS")
+	bindEnv("MAP_STORE_WRITE_DELAY")
+	bindEnv("MAP_STORE_BATCH_SIZE")
+	bindEnv("MAX_TRANSACTIONS")
+	bindEnv("TRANSACTION_TIMEOUT")
+	bindEnv("WATCH_HISTORY")
+	bindEnv("CDC_HISTORY")
+	bindEnv("CDC_FILE")
+	bindEnv("RESP_PORT")
+	bindEnv("RESP_KEYSPACE")
 	viper.AutomaticEnv()
 	viper.SetConfigType("yaml")
 	configFile := viper.GetString("config")
@@ -0,0 +1,232 @@
+package demory
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/huseyinbabal/demory/ds/hashmap"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/mapstore"
+	"github.com/huseyinbabal/demory/node"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	mapStoreFile   = "file"
+	mapStoreSQLite = "sqlite"
+	// maxRetryDelay caps the delay between attempts to persist write-behind changes.
+	maxRetryDelay = time.Minute
+)
+
+// newPersister creates the map store configured for the node, or returns nil if there is none.
+func newPersister(config *node.Config) (*mapstore.Persister, error) {
+	var store mapstore.MapStore
+	var err error
+
+	switch config.MapStore {
+	case "":
+		return nil, nil
+	case mapStoreFile:
+		store, err = mapstore.NewFileStore(config.MapStorePath)
+	case mapStoreSQLite:
+		driver := config.MapStoreDriver
+		if driver == "" {
+			driver = mapstore.DefaultSQLiteDriver
+		}
+		store, err = mapstore.OpenSQLite(driver, config.MapStorePath)
+	default:
+		return nil, fmt.Errorf("invalid map store %s", config.MapStore)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mode := mapstore.Mode(config.MapStoreMode)
+	if mode == "" {
+		mode = mapstore.WriteThrough
+	}
+
+	return mapstore.New(store, mapstore.Config{
+		Mode:       mode,
+		WriteDelay: time.Duration(config.MapStoreWriteDelay) * time.Millisecond,
+		BatchSize:  config.MapStoreBatchSize,
+	})
+}
+
+// isStored reports whether map name is backed by the map store. All maps are if no names are configured.
+func (d *Demory) isStored(name string) bool {
+	if d.persister == nil {
+		return false
+	}
+	if d.config.MapStoreMaps == "" {
+		return true
+	}
+
+	for _, stored := range strings.Split(d.config.MapStoreMaps, ",") {
+		if strings.TrimSpace(stored) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// persist passes a change of map name to the map store once it is applied, if the map is stored in
+// write-through mode. Only the leader applies changes, so only the leader persists them. Changes of maps
+// stored in write-behind mode are kept pending by the replicated state instead, see writeBehind.
+//
+// The change is already replicated when it is persisted, and is not undone if the store fails: the client
+// gets an Unavailable error, but the map keeps the change while the store misses it until the entry is
+// written again. Maps that must not miss changes are better stored in write-behind mode, which retries them.
+func (d *Demory) persist(name string, fn func(p *mapstore.Persister) error) error {
+	if !d.isStored(name) || d.persister.Mode() != mapstore.WriteThrough {
+		return nil
+	}
+
+	if err := fn(d.persister); err != nil {
+		return status.Errorf(codes.Unavailable, "map store error %v", err)
+	}
+	return nil
+}
+
+// writesBehind reports whether any of the maps names is stored in write-behind mode.
+func (d *Demory) writesBehind(names ...string) bool {
+	if d.persister == nil || d.persister.Mode() != mapstore.WriteBehind {
+		return false
+	}
+	for _, name := range names {
+		if d.isStored(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// markPending keeps the map entries changed by a write-behind command pending, until the leader persists them.
+// Evicted entries are still in the store, so they are not persisted.
+func (d *Demory) markPending(request fsm.ApplyRequest) {
+	if !request.WriteBehind {
+		return
+	}
+	for _, change := range d.changes {
+		if change.Structure == string(fsm.StructureMap) && change.Type != string(hashmap.Evict) {
+			d.pending.Mark(change.Name, change.Key, request.Index, request.Time)
+		}
+	}
+}
+
+// writeBehind persists pending map entries while this node leads, and retries failed batches after a
+// growing delay. Pending entries are replicated, so a new leader picks up what the previous one left.
+func (d *Demory) writeBehind() {
+	delay := d.persister.WriteDelay()
+	retryDelay := delay
+	for {
+		time.Sleep(retryDelay)
+		if d.fsm.Raft.State() != raft.Leader {
+			retryDelay = delay
+			continue
+		}
+
+		if err := d.flushPending(time.Now().Add(-delay)); err != nil {
+			retryDelay *= 2
+			if retryDelay > maxRetryDelay {
+				retryDelay = maxRetryDelay
+			}
+			log.Printf("failed to persist map writes, retrying in %v %v.\n", retryDelay, err)
+			continue
+		}
+		retryDelay = delay
+	}
+}
+
+// flushPending persists the entries pending since before deadline in batches, with the values they hold
+// now, and clears them through the raft log. Entries of maps that are no longer stored are cleared only.
+func (d *Demory) flushPending(deadline time.Time) error {
+	for {
+		var marks []mapstore.Mark
+		var writes []mapstore.Write
+		d.fsm.Read(func() {
+			marks = d.pending.Due(deadline.UnixNano(), d.persister.BatchSize())
+			for _, m := range marks {
+				if !d.isStored(m.Name) {
+					continue
+				}
+				_, exists := d.hashMap.EntrySize(m.Name, m.Key)
+				value := d.hashMap.Get(m.Name, m.Key)
+				writes = append(writes, mapstore.Write{Name: m.Name, Key: m.Key, Value: value, Deleted: !exists})
+			}
+		})
+		if len(marks) == 0 {
+			return nil
+		}
+
+		if err := d.persister.Persist(writes); err != nil {
+			return err
+		}
+		if _, err := d.propose(fsm.ApplyRequest{Type: fsm.MapStoreFlush}, marks); err != nil {
+			return err
+		}
+	}
+}
+
+// flushed clears the pending entries a MapStoreFlush command persisted.
+func (d *Demory) flushed(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var marks []mapstore.Mark
+	if err := json.Unmarshal(request.Args, &marks); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	d.pending.Clear(marks, request.Time)
+	return fsm.ApplyResponse{}
+}
+
+// closeMapStore persists everything pending if this node leads, and closes the map store.
+func (d *Demory) closeMapStore() {
+	if d.persister == nil {
+		return
+	}
+	if d.persister.Mode() == mapstore.WriteBehind && d.fsm.Raft.State() == raft.Leader {
+		if err := d.flushPending(time.Now()); err != nil {
+			log.Printf("failed to persist map writes on shutdown %v.\n", err)
+		}
+	}
+	if err := d.persister.Close(); err != nil {
+		log.Printf("failed to close map store %v.\n", err)
+	}
+}
+
+// load reads key of map name through the map store. It returns false if the map is not stored
+// or the key does not exist in the store.
+func (d *Demory) load(name, key string) ([]byte, bool, error) {
+	if !d.isStored(name) {
+		return nil, false, nil
+	}
+
+	value, ok, err := d.persister.Load(name, key)
+	if err != nil {
+		return nil, false, status.Errorf(codes.Unavailable, "map store error %v", err)
+	}
+	return value, ok, nil
+}
+
+// cacheLoaded keeps a value loaded from the map store in the map, so that later reads do not hit the store.
+// Only the leader can propose it; other nodes serve loaded values without keeping them.
+func (d *Demory) cacheLoaded(name, key string, value []byte) {
+	if d.fsm.Raft.State() != raft.Leader {
+		return
+	}
+
+	request := fsm.ApplyRequest{
+		Type:  fsm.MapPutIfAbsent,
+		Name:  name,
+		Key:   key,
+		Value: value,
+	}
+	if _, err := d.propose(request, nil); err != nil {
+		log.Printf("failed to keep loaded entry %s of map %s %v.\n", key, name, err)
+	}
+}
@@ -0,0 +1,94 @@
+package demory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	proto "github.com/huseyinbabal/demory-proto/golang/demory"
+	"github.com/huseyinbabal/demory/mapstore"
+	"github.com/huseyinbabal/demory/node"
+	"github.com/huseyinbabal/demory/rpc"
+)
+
+func TestWriteBehind(t *testing.T) {
+	dir := t.TempDir()
+	d := newTestNode(t, node.Config{
+		MapStore:     mapStoreFile,
+		MapStorePath: dir,
+		MapStoreMode: string(mapstore.WriteBehind),
+		MapStoreMaps: "users",
+	})
+	ctx := context.Background()
+
+	d.MapPut(ctx, &proto.MapPutRequest{Name: "users", Key: "a", Value: []byte("1")})
+	d.MapPut(ctx, &proto.MapPutRequest{Name: "users", Key: "a", Value: []byte("2")})
+	d.MapPut(ctx, &proto.MapPutRequest{Name: "users", Key: "b", Value: []byte("3")})
+	d.MapRemove(ctx, &proto.MapRemoveRequest{Name: "users", Key: "b"})
+	d.MapPut(ctx, &proto.MapPutRequest{Name: "orders", Key: "c", Value: []byte("4")})
+	d.Execute(ctx, &rpc.TransactionRequest{Operations: []rpc.Operation{
+		{Type: rpc.OperationPut, Structure: "map", Name: "users", Key: "d", Value: []byte("5")},
+	}})
+
+	store, _ := mapstore.NewFileStore(dir)
+	if _, ok, _ := store.Load("users", "a"); ok {
+		t.Error("expected nothing to be persisted before the flush")
+	}
+	d.fsm.Read(func() {
+		if d.pending.Len() != 3 {
+			t.Errorf("expected 3 pending entries of the stored map, got %d", d.pending.Len())
+		}
+	})
+
+	if err := d.flushPending(time.Now()); err != nil {
+		t.Fatalf("failed to flush %v", err)
+	}
+
+	for key, expected := range map[string]string{"a": "2", "d": "5"} {
+		if value, ok, _ := store.Load("users", key); !ok || string(value) != expected {
+			t.Errorf("expected %s to be persisted as %s, got %q", key, expected, value)
+		}
+	}
+	if _, ok, _ := store.Load("users", "b"); ok {
+		t.Error("expected b to be removed")
+	}
+	if _, ok, _ := store.Load("orders", "c"); ok {
+		t.Error("expected maps that are not stored to be left out")
+	}
+	d.fsm.Read(func() {
+		if d.pending.Len() != 0 {
+			t.Errorf("expected the pending entries to be cleared, got %d", d.pending.Len())
+		}
+	})
+}
+
+func TestWriteThrough(t *testing.T) {
+	dir := t.TempDir()
+	d := newTestNode(t, node.Config{MapStore: mapStoreFile, MapStorePath: dir})
+	ctx := context.Background()
+
+	d.MapPut(ctx, &proto.MapPutRequest{Name: "users", Key: "a", Value: []byte("1")})
+	d.MapPutIfAbsent(ctx, &proto.MapPutIfAbsentRequest{Name: "users", Key: "a", Value: []byte("2")})
+
+	store, _ := mapstore.NewFileStore(dir)
+	if value, ok, _ := store.Load("users", "a"); !ok || string(value) != "1" {
+		t.Errorf("expected the applied value to be persisted, got %q", value)
+	}
+
+	d.MapClear(ctx, &proto.MapClearRequest{Name: "users"})
+	if _, ok, _ := store.Load("users", "a"); ok {
+		t.Error("expected a to be removed")
+	}
+
+	// Read-through loads missing keys from the store.
+	store.Store("users", map[string][]byte{"b": []byte("3")})
+	response, err := d.MapGet(ctx, &proto.MapGetRequest{Name: "users", Key: "b"})
+	if err != nil || string(response.Value) != "3" {
+		t.Errorf("expected b to be loaded, got %v %v", response, err)
+	}
+	d.fsm.Read(func() {
+		if d.pending.Len() != 0 {
+			t.Errorf("expected write-through changes not to be pending, got %d", d.pending.Len())
+		}
+	})
+}
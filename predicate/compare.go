@@ -0,0 +1,121 @@
+package predicate
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// rank orders values of different JSON types: null < false < true < numbers < strings < arrays < objects.
+func rank(v interface{}) int {
+	switch t := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if t {
+			return 2
+		}
+		return 1
+	case json.Number, float64:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	default:
+		return 6
+	}
+}
+
+// SameType reports whether a and b are of the same JSON type, so that ordering them is meaningful.
+// Booleans count as one type.
+func SameType(a, b interface{}) bool {
+	ra, rb := rank(a), rank(b)
+	if ra == 1 || ra == 2 {
+		return rb == 1 || rb == 2
+	}
+	return ra == rb
+}
+
+// Compare returns -1, 0 or 1 depending on whether a sorts before, equal to or after b. Values of
+// different types are ordered by type, numbers by value and strings lexically.
+func Compare(a, b interface{}) int {
+	ra, rb := rank(a), rank(b)
+	if ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+
+	switch x := a.(type) {
+	case json.Number, float64:
+		return compareNumbers(x, b)
+	case string:
+		return strings.Compare(x, b.(string))
+	case []interface{}:
+		y := b.([]interface{})
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := Compare(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return Compare(float64(len(x)), float64(len(y)))
+	default:
+		if reflect.DeepEqual(a, b) {
+			return 0
+		}
+		if ra == 6 {
+			// Objects are only equal or not; order them consistently by their encoding.
+			ea, _ := json.Marshal(a)
+			eb, _ := json.Marshal(b)
+			return strings.Compare(string(ea), string(eb))
+		}
+		return 0
+	}
+}
+
+// compareNumbers compares integers exactly, and falls back to floating point for other numbers.
+func compareNumbers(a, b interface{}) int {
+	if x, ok := integer(a); ok {
+		if y, ok := integer(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+
+	x, y := float(a), float(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func integer(v interface{}) (int64, bool) {
+	if n, ok := v.(json.Number); ok {
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func float(v interface{}) float64 {
+	switch n := v.(type) {
+	case json.Number:
+		f, _ := n.Float64()
+		return f
+	case float64:
+		return n
+	}
+	return 0
+}
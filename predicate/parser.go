@@ -0,0 +1,305 @@
+package predicate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Parse reads a predicate written in the query language, for example:
+//
+//	age >= 18 AND (address.city IN ('izmir', 'istanbul') OR name LIKE 'jo%') AND NOT active = false
+//
+// Paths are dotted JSON paths, literals are JSON values with strings in single or double quotes, and
+// keywords are case insensitive. BETWEEN a AND b is a shorthand for >= a AND <= b.
+func Parse(text string) (*Predicate, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if len(tokens) == 0 {
+		return &Predicate{Op: True}, nil
+	}
+
+	predicate, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.peek().text, p.peek().pos)
+	}
+	return predicate, nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(text string) ([]token, error) {
+	var tokens []token
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'' || r == '"':
+			start := i
+			var value strings.Builder
+			for i++; ; i++ {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("unterminated string at position %d", start)
+				}
+				if runes[i] == r {
+					// A doubled quote stands for the quote itself.
+					if i+1 < len(runes) && runes[i+1] == r {
+						value.WriteRune(r)
+						i++
+						continue
+					}
+					break
+				}
+				value.WriteRune(runes[i])
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: value.String(), pos: start})
+		case r == '-' || unicode.IsDigit(r):
+			start := i
+			for i++; i < len(runes) && strings.ContainsRune("0123456789.eE+-", runes[i]); i++ {
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), pos: start})
+		case strings.ContainsRune("(),", r):
+			tokens = append(tokens, token{kind: tokenSymbol, text: string(r), pos: i})
+			i++
+		case strings.ContainsRune("=!<>", r):
+			start := i
+			for i++; i < len(runes) && strings.ContainsRune("=<>", runes[i]); i++ {
+			}
+			tokens = append(tokens, token{kind: tokenSymbol, text: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(r) || strings.ContainsRune("_$[", r):
+			start := i
+			for i++; i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) ||
+				strings.ContainsRune("_$.[]-", runes[i])); i++ {
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: string(runes[start:i]), pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d", r, i)
+		}
+	}
+
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{kind: tokenSymbol, text: "end of input", pos: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	if t.kind == tokenWord && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) symbol(symbol string) bool {
+	t := p.peek()
+	if t.kind == tokenSymbol && t.text == symbol {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(symbol string) error {
+	if !p.symbol(symbol) {
+		return fmt.Errorf("expected %q at position %d, got %q", symbol, p.peek().pos, p.peek().text)
+	}
+	return nil
+}
+
+func (p *parser) or() (*Predicate, error) {
+	return p.combine(Or, "OR", p.and)
+}
+
+func (p *parser) and() (*Predicate, error) {
+	return p.combine(And, "AND", p.not)
+}
+
+func (p *parser) combine(op Operator, keyword string, next func() (*Predicate, error)) (*Predicate, error) {
+	first, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	operands := []*Predicate{first}
+	for p.keyword(keyword) {
+		operand, err := next()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+
+	if len(operands) == 1 {
+		return first, nil
+	}
+	return &Predicate{Op: op, Operands: operands}, nil
+}
+
+func (p *parser) not() (*Predicate, error) {
+	if p.keyword("NOT") {
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &Predicate{Op: Not, Operands: []*Predicate{operand}}, nil
+	}
+	return p.primary()
+}
+
+var comparisons = map[string]Operator{
+	"=":  Equal,
+	"==": Equal,
+	"!=": NotEqual,
+	"<>": NotEqual,
+	"<":  Less,
+	"<=": LessOrEqual,
+	">":  Greater,
+	">=": GreaterOrEqual,
+}
+
+func (p *parser) primary() (*Predicate, error) {
+	if p.symbol("(") {
+		predicate, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return predicate, p.expect(")")
+	}
+	if p.keyword("TRUE") {
+		return &Predicate{Op: True}, nil
+	}
+
+	path := p.peek()
+	if path.kind != tokenWord {
+		return nil, fmt.Errorf("expected a path at position %d, got %q", path.pos, path.text)
+	}
+	p.pos++
+
+	negate := p.keyword("NOT")
+	var predicate *Predicate
+
+	switch {
+	case p.keyword("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		predicate = &Predicate{Op: In, Path: path.text}
+		for {
+			value, err := p.literal()
+			if err != nil {
+				return nil, err
+			}
+			predicate.Values = append(predicate.Values, value)
+			if !p.symbol(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	case p.keyword("LIKE"):
+		value, err := p.literal()
+		if err != nil {
+			return nil, err
+		}
+		predicate = &Predicate{Op: Like, Path: path.text, Value: value}
+	case p.keyword("BETWEEN"):
+		low, err := p.literal()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword("AND") {
+			return nil, fmt.Errorf("expected AND at position %d", p.peek().pos)
+		}
+		high, err := p.literal()
+		if err != nil {
+			return nil, err
+		}
+		predicate = &Predicate{Op: And, Operands: []*Predicate{
+			{Op: GreaterOrEqual, Path: path.text, Value: low},
+			{Op: LessOrEqual, Path: path.text, Value: high},
+		}}
+	case negate:
+		return nil, fmt.Errorf("expected IN, LIKE or BETWEEN after NOT at position %d", p.peek().pos)
+	default:
+		t := p.peek()
+		op, ok := comparisons[t.text]
+		if t.kind != tokenSymbol || !ok {
+			return nil, fmt.Errorf("expected an operator at position %d, got %q", t.pos, t.text)
+		}
+		p.pos++
+		value, err := p.literal()
+		if err != nil {
+			return nil, err
+		}
+		predicate = &Predicate{Op: op, Path: path.text, Value: value}
+	}
+
+	if negate {
+		return &Predicate{Op: Not, Operands: []*Predicate{predicate}}, nil
+	}
+	return predicate, nil
+}
+
+func (p *parser) literal() (json.RawMessage, error) {
+	t := p.peek()
+	p.pos++
+
+	switch t.kind {
+	case tokenString:
+		return json.Marshal(t.text)
+	case tokenNumber:
+		if !json.Valid([]byte(t.text)) {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos)
+		}
+		return json.RawMessage(t.text), nil
+	case tokenWord:
+		switch strings.ToLower(t.text) {
+		case "true", "false", "null":
+			return json.RawMessage(strings.ToLower(t.text)), nil
+		}
+	}
+
+	p.pos--
+	return nil, fmt.Errorf("expected a value at position %d, got %q", t.pos, t.text)
+}
@@ -0,0 +1,238 @@
+// Package predicate evaluates conditions on JSON values stored in maps.
+package predicate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/huseyinbabal/demory/jsonpath"
+)
+
+// Operator of a predicate.
+type Operator string
+
+const (
+	// Equal matches values whose field at Path equals Value.
+	Equal Operator = "eq"
+	// NotEqual matches values whose field at Path exists and differs from Value.
+	NotEqual Operator = "ne"
+	// Less, LessOrEqual, Greater and GreaterOrEqual compare the field at Path with Value.
+	Less           Operator = "lt"
+	LessOrEqual    Operator = "le"
+	Greater        Operator = "gt"
+	GreaterOrEqual Operator = "ge"
+	// In matches values whose field at Path equals one of Values.
+	In Operator = "in"
+	// Like matches string fields against a pattern where % matches any run of characters and _ a single one.
+	Like Operator = "like"
+	// And, Or and Not combine Operands.
+	And Operator = "and"
+	Or  Operator = "or"
+	Not Operator = "not"
+	// True matches every value.
+	True Operator = "true"
+)
+
+// Predicate is a condition on the JSON value of an entry.
+type Predicate struct {
+	Op       Operator          `json:"op"`
+	Path     string            `json:"path,omitempty"`
+	Value    json.RawMessage   `json:"value,omitempty"`
+	Values   []json.RawMessage `json:"values,omitempty"`
+	Operands []*Predicate      `json:"operands,omitempty"`
+}
+
+// Matcher is a compiled predicate. It evaluates decoded JSON documents.
+type Matcher func(doc interface{}) bool
+
+// Match reports whether value satisfies p. Values that are not valid JSON never match.
+func (p *Predicate) Match(value []byte) (bool, error) {
+	matcher, err := p.Compile()
+	if err != nil {
+		return false, err
+	}
+
+	doc, ok := Decode(value)
+	if !ok {
+		return false, nil
+	}
+	return matcher(doc), nil
+}
+
+// Eval reports whether the decoded JSON document doc satisfies p.
+func (p *Predicate) Eval(doc interface{}) (bool, error) {
+	matcher, err := p.Compile()
+	if err != nil {
+		return false, err
+	}
+	return matcher(doc), nil
+}
+
+// Compile validates p and turns it into a Matcher, so that paths and values are only parsed once.
+func (p *Predicate) Compile() (Matcher, error) {
+	switch p.Op {
+	case True:
+		return func(interface{}) bool { return true }, nil
+	case And, Or:
+		if len(p.Operands) == 0 {
+			return nil, fmt.Errorf("%s needs operands", p.Op)
+		}
+		matchers := make([]Matcher, 0, len(p.Operands))
+		for _, operand := range p.Operands {
+			matcher, err := operand.Compile()
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, matcher)
+		}
+		if p.Op == And {
+			return func(doc interface{}) bool {
+				for _, matcher := range matchers {
+					if !matcher(doc) {
+						return false
+					}
+				}
+				return true
+			}, nil
+		}
+		return func(doc interface{}) bool {
+			for _, matcher := range matchers {
+				if matcher(doc) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case Not:
+		if len(p.Operands) != 1 {
+			return nil, fmt.Errorf("%s needs exactly one operand", p.Op)
+		}
+		matcher, err := p.Operands[0].Compile()
+		if err != nil {
+			return nil, err
+		}
+		return func(doc interface{}) bool { return !matcher(doc) }, nil
+	}
+
+	segments, err := jsonpath.Parse(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	field := func(doc interface{}) (interface{}, bool) {
+		return jsonpath.Lookup(doc, segments)
+	}
+
+	switch p.Op {
+	case In:
+		values := make([]interface{}, 0, len(p.Values))
+		for _, raw := range p.Values {
+			value, ok := Decode(raw)
+			if !ok {
+				return nil, fmt.Errorf("invalid value for %s", p.Op)
+			}
+			values = append(values, value)
+		}
+		return func(doc interface{}) bool {
+			actual, ok := field(doc)
+			if !ok {
+				return false
+			}
+			for _, value := range values {
+				if Compare(actual, value) == 0 {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case Like:
+		pattern, ok := Decode(p.Value)
+		if s, isString := pattern.(string); ok && isString {
+			return func(doc interface{}) bool {
+				actual, ok := field(doc)
+				if !ok {
+					return false
+				}
+				text, ok := actual.(string)
+				return ok && like(text, s)
+			}, nil
+		}
+		return nil, fmt.Errorf("%s needs a string pattern", p.Op)
+	}
+
+	expected, ok := Decode(p.Value)
+	if !ok {
+		return nil, fmt.Errorf("invalid value for %s", p.Op)
+	}
+
+	var accept func(c int) bool
+	switch p.Op {
+	case Equal:
+		accept = func(c int) bool { return c == 0 }
+	case NotEqual:
+		accept = func(c int) bool { return c != 0 }
+	case Less:
+		accept = func(c int) bool { return c < 0 }
+	case LessOrEqual:
+		accept = func(c int) bool { return c <= 0 }
+	case Greater:
+		accept = func(c int) bool { return c > 0 }
+	case GreaterOrEqual:
+		accept = func(c int) bool { return c >= 0 }
+	default:
+		return nil, fmt.Errorf("unknown predicate operator %q", p.Op)
+	}
+
+	ordered := p.Op != Equal && p.Op != NotEqual
+	return func(doc interface{}) bool {
+		actual, ok := field(doc)
+		if !ok {
+			return false
+		}
+		// Ranges only hold between values of the same type, so that 5 is not "less than" "a".
+		if ordered && !SameType(actual, expected) {
+			return false
+		}
+		return accept(Compare(actual, expected))
+	}, nil
+}
+
+// like matches text against a LIKE pattern.
+func like(text, pattern string) bool {
+	t, p := []rune(text), []rune(pattern)
+	ti, pi := 0, 0
+	star, mark := -1, 0
+
+	for ti < len(t) {
+		switch {
+		case pi < len(p) && (p[pi] == '_' || p[pi] == t[ti]):
+			ti++
+			pi++
+		case pi < len(p) && p[pi] == '%':
+			star, mark = pi, ti
+			pi++
+		case star >= 0:
+			pi = star + 1
+			mark++
+			ti = mark
+		default:
+			return false
+		}
+	}
+	for pi < len(p) && p[pi] == '%' {
+		pi++
+	}
+	return pi == len(p)
+}
+
+// Decode decodes a JSON value, keeping numbers as json.Number so that they are compared exactly.
+func Decode(value []byte) (interface{}, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, false
+	}
+	return doc, true
+}
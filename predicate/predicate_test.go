@@ -0,0 +1,46 @@
+package predicate
+
+import "testing"
+
+func TestParseAndMatch(t *testing.T) {
+	user := []byte(`{"name":"john","age":30,"address":{"city":"izmir"},"tags":["admin"],"active":true}`)
+
+	cases := map[string]bool{
+		`age = 30`:                                       true,
+		`age >= 31`:                                      false,
+		`age BETWEEN 18 AND 30`:                          true,
+		`name LIKE 'jo%'`:                                true,
+		`name LIKE '_ohn'`:                               true,
+		`name NOT LIKE 'j%'`:                             false,
+		`address.city IN ('istanbul', "izmir")`:          true,
+		`tags[0] = 'admin' AND NOT active = false`:       true,
+		`age < 18 OR (address.city = 'izmir' AND age>1)`: true,
+		`missing != 1`:                                   false,
+		`age > 'a'`:                                      false,
+		``:                                               true,
+	}
+
+	for text, expected := range cases {
+		p, err := Parse(text)
+		if err != nil {
+			t.Errorf("failed to parse %q %v", text, err)
+			continue
+		}
+		matches, err := p.Match(user)
+		if err != nil {
+			t.Errorf("failed to match %q %v", text, err)
+			continue
+		}
+		if matches != expected {
+			t.Errorf("expected %q to be %v", text, expected)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, text := range []string{`age =`, `age >> 1`, `(age = 1`, `name LIKE`, `age = 1 name = 2`, `'a' = 1`} {
+		if _, err := Parse(text); err == nil {
+			t.Errorf("expected %q to fail", text)
+		}
+	}
+}
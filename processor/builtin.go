@@ -0,0 +1,154 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/huseyinbabal/demory/jsonpath"
+)
+
+const (
+	Increment  = "increment"
+	Append     = "append"
+	MergePatch = "merge-patch"
+	SetField   = "set-field"
+)
+
+func init() {
+	Register(Increment, increment)
+	Register(Append, appendBytes)
+	Register(MergePatch, mergePatch)
+	Register(SetField, setField)
+}
+
+// increment adds {"delta": n} to a value holding a decimal number. Missing values count as zero.
+func increment(entry Entry, args json.RawMessage) (Entry, error) {
+	var arguments struct {
+		Delta json.Number `json:"delta"`
+	}
+	if err := json.Unmarshal(args, &arguments); err != nil {
+		return entry, fmt.Errorf("invalid %s arguments %w", Increment, err)
+	}
+
+	delta, ok := new(big.Int).SetString(string(arguments.Delta), 10)
+	if !ok {
+		return entry, fmt.Errorf("delta of %s must be an integer", Increment)
+	}
+
+	current := new(big.Int)
+	if entry.Exists && len(entry.Value) > 0 {
+		if _, ok := current.SetString(string(bytes.TrimSpace(entry.Value)), 10); !ok {
+			return entry, fmt.Errorf("value of %s is not an integer", entry.Key)
+		}
+	}
+
+	entry.Value = []byte(current.Add(current, delta).String())
+	entry.Exists = true
+	return entry, nil
+}
+
+// appendBytes appends {"value": base64} to the value.
+func appendBytes(entry Entry, args json.RawMessage) (Entry, error) {
+	var arguments struct {
+		Value []byte `json:"value"`
+	}
+	if err := json.Unmarshal(args, &arguments); err != nil {
+		return entry, fmt.Errorf("invalid %s arguments %w", Append, err)
+	}
+
+	value := make([]byte, 0, len(entry.Value)+len(arguments.Value))
+	entry.Value = append(append(value, entry.Value...), arguments.Value...)
+	entry.Exists = true
+	return entry, nil
+}
+
+// mergePatch applies the arguments as a JSON merge patch (RFC 7386) to a JSON value.
+func mergePatch(entry Entry, args json.RawMessage) (Entry, error) {
+	patch, err := decode(args)
+	if err != nil {
+		return entry, fmt.Errorf("invalid %s arguments %w", MergePatch, err)
+	}
+
+	var target interface{}
+	if entry.Exists && len(entry.Value) > 0 {
+		if target, err = decode(entry.Value); err != nil {
+			return entry, fmt.Errorf("value of %s is not json %w", entry.Key, err)
+		}
+	}
+
+	return encode(entry, merge(target, patch))
+}
+
+func merge(target, patch interface{}) interface{} {
+	fields, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = make(map[string]interface{})
+	}
+	for field, value := range fields {
+		if value == nil {
+			delete(object, field)
+			continue
+		}
+		object[field] = merge(object[field], value)
+	}
+	return object
+}
+
+// setField stores {"path": "a.b", "value": json} within a JSON value.
+func setField(entry Entry, args json.RawMessage) (Entry, error) {
+	var arguments struct {
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(args, &arguments); err != nil {
+		return entry, fmt.Errorf("invalid %s arguments %w", SetField, err)
+	}
+
+	value, err := decode(arguments.Value)
+	if err != nil {
+		return entry, fmt.Errorf("invalid %s value %w", SetField, err)
+	}
+
+	var doc interface{}
+	if entry.Exists && len(entry.Value) > 0 {
+		if doc, err = decode(entry.Value); err != nil {
+			return entry, fmt.Errorf("value of %s is not json %w", entry.Key, err)
+		}
+	}
+
+	if doc, err = jsonpath.Set(doc, arguments.Path, value); err != nil {
+		return entry, err
+	}
+	return encode(entry, doc)
+}
+
+func decode(value []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// encode stores doc as the value of entry. Object fields are encoded in sorted order, so the result
+// is the same on every replica.
+func encode(entry Entry, doc interface{}) (Entry, error) {
+	value, err := json.Marshal(doc)
+	if err != nil {
+		return entry, err
+	}
+
+	entry.Value = value
+	entry.Exists = true
+	return entry, nil
+}
@@ -0,0 +1,59 @@
+package processor
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func run(t *testing.T, name string, entry Entry, args string) Entry {
+	t.Helper()
+
+	p, ok := Get(name)
+	if !ok {
+		t.Fatalf("processor %s is not registered", name)
+	}
+	result, err := p(entry, json.RawMessage(args))
+	if err != nil {
+		t.Fatalf("processor %s failed %v", name, err)
+	}
+	return result
+}
+
+func TestIncrement(t *testing.T) {
+	result := run(t, Increment, Entry{Key: "visits"}, `{"delta": 5}`)
+	result = run(t, Increment, result, `{"delta": -2}`)
+
+	if string(result.Value) != "3" || !result.Exists {
+		t.Errorf("expected 3, got %q", result.Value)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	original := []byte("ab")
+	result := run(t, Append, Entry{Key: "log", Value: original, Exists: true}, `{"value": "Y2Q="}`)
+
+	if string(result.Value) != "abcd" || string(original) != "ab" {
+		t.Errorf("expected abcd without touching the original, got %q %q", result.Value, original)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	entry := Entry{Key: "user", Value: []byte(`{"name":"john","address":{"city":"izmir","zip":"35000"}}`), Exists: true}
+	result := run(t, MergePatch, entry, `{"address":{"city":"istanbul","zip":null},"age":30}`)
+
+	expected := `{"address":{"city":"istanbul"},"age":30,"name":"john"}`
+	if string(result.Value) != expected {
+		t.Errorf("expected %s, got %s", expected, result.Value)
+	}
+}
+
+func TestSetField(t *testing.T) {
+	entry := Entry{Key: "user", Value: []byte(`{"tags":["a"]}`), Exists: true}
+	result := run(t, SetField, entry, `{"path": "tags[1]", "value": "b"}`)
+	result = run(t, SetField, result, `{"path": "address.city", "value": "izmir"}`)
+
+	expected := `{"address":{"city":"izmir"},"tags":["a","b"]}`
+	if string(result.Value) != expected {
+		t.Errorf("expected %s, got %s", expected, result.Value)
+	}
+}
@@ -0,0 +1,63 @@
+// Package processor holds the entry processors that can be executed on map entries. Processors run inside
+// the FSM on every replica, so they must be deterministic: the same entry and arguments must always
+// produce the same result.
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Entry is a map entry seen by a processor. Exists is false for keys without a value.
+type Entry struct {
+	Key    string
+	Value  []byte
+	Exists bool
+}
+
+// Processor computes the new state of an entry from its current state and the arguments given by the
+// client. Returning an entry that does not exist removes it from the map. Processors must not modify the
+// value they are given in place.
+type Processor func(entry Entry, args json.RawMessage) (Entry, error)
+
+var (
+	mutex      sync.RWMutex
+	processors = make(map[string]Processor)
+)
+
+// Register makes a processor available under name. Every node of a cluster must register the same
+// processors before it starts applying commands.
+func Register(name string, p Processor) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, ok := processors[name]; ok {
+		panic(fmt.Sprintf("processor %s is already registered", name))
+	}
+	processors[name] = p
+}
+
+// Get returns the processor registered under name.
+func Get(name string) (Processor, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	p, ok := processors[name]
+	return p, ok
+}
+
+// Names returns the names of all registered processors in lexical order.
+func Names() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	names := make([]string, 0, len(processors))
+	for name := range processors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
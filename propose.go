@@ -0,0 +1,41 @@
+package demory
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/huseyinbabal/demory/fsm"
+)
+
+// propose replicates a command with its arguments, if any, stamped with the time and the maxmemory limit and
+// policy of the leader, and returns the data of the applied command.
+func (d *Demory) propose(request fsm.ApplyRequest, args interface{}) (interface{}, error) {
+	if args != nil {
+		encoded, argsErr := json.Marshal(args)
+		if argsErr != nil {
+			return nil, argsErr
+		}
+		request.Args = encoded
+	}
+	request.Time = time.Now().UnixNano()
+	request.MaxMemory = d.config.MaxMemory
+	request.MaxMemoryPolicy = d.config.MaxMemoryPolicy
+
+	bytes, bytesErr := json.Marshal(request)
+	if bytesErr != nil {
+		return nil, bytesErr
+	}
+
+	apply := d.fsm.Raft.Apply(bytes, time.Second)
+
+	if err := apply.Error(); err != nil {
+		return nil, err
+	}
+
+	response := apply.Response().(fsm.ApplyResponse)
+	if response.Error != nil {
+		return nil, response.Error
+	}
+
+	return response.Data, nil
+}
@@ -0,0 +1,206 @@
+// Package pubsub delivers the messages published to topics to the subscribers of a node.
+package pubsub
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Policy decides what happens when a subscriber does not keep up and its buffer is full.
+type Policy string
+
+const (
+	// Drop discards the messages that do not fit. The next delivered message tells how many were dropped.
+	Drop Policy = "drop"
+	// Disconnect ends the subscription.
+	Disconnect Policy = "disconnect"
+)
+
+const (
+	DefaultBuffer = 256
+	MaxBuffer     = 1 << 16
+)
+
+var (
+	ErrSlowSubscriber = errors.New("subscriber did not keep up with published messages")
+	ErrInvalidTopic   = errors.New("invalid topic")
+	ErrUnknownPolicy  = errors.New("unknown policy")
+)
+
+// Message is a message published to a topic.
+type Message struct {
+	Topic   string `json:"topic"`
+	Payload []byte `json:"payload"`
+	// Index is the raft log index the message was published at.
+	Index uint64 `json:"index"`
+	// Time is the time the message was published at, in unix nanoseconds.
+	Time int64 `json:"time"`
+	// Dropped is the number of messages dropped for the subscriber since the previous delivered message.
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+// ValidateTopic checks that topic can be published to. Topics are dot separated, non-empty tokens
+// without wildcards.
+func ValidateTopic(topic string) error {
+	for _, token := range strings.Split(topic, ".") {
+		if token == "" || token == "*" || token == ">" {
+			return ErrInvalidTopic
+		}
+	}
+	return nil
+}
+
+// ValidatePattern checks that pattern can be subscribed to. In patterns, * matches exactly one token
+// and a trailing > matches one or more tokens.
+func ValidatePattern(pattern string) error {
+	tokens := strings.Split(pattern, ".")
+	for i, token := range tokens {
+		if token == "" || (token == ">" && i != len(tokens)-1) {
+			return ErrInvalidTopic
+		}
+	}
+	return nil
+}
+
+// Match reports whether topic matches pattern.
+func Match(pattern, topic string) bool {
+	return match(strings.Split(pattern, "."), strings.Split(topic, "."))
+}
+
+func match(pattern, topic []string) bool {
+	for i, token := range pattern {
+		if token == ">" {
+			return len(topic) > i
+		}
+		if i >= len(topic) || (token != "*" && token != topic[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(topic)
+}
+
+// Subscription receives the messages published to topics matching its pattern.
+type Subscription struct {
+	broker   *Broker
+	pattern  []string
+	policy   Policy
+	messages chan Message
+	dropped  uint64
+	done     chan struct{}
+	err      error
+}
+
+// Messages returns the channel messages are delivered on.
+func (s *Subscription) Messages() <-chan Message {
+	return s.messages
+}
+
+// Done is closed once the subscription ends because the subscriber was too slow, see Err.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns why the subscription ended.
+func (s *Subscription) Err() error {
+	s.broker.mutex.Lock()
+	defer s.broker.mutex.Unlock()
+
+	return s.err
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.broker.mutex.Lock()
+	defer s.broker.mutex.Unlock()
+
+	s.broker.remove(s, nil)
+}
+
+// Broker delivers published messages to subscribers without blocking the publisher.
+type Broker struct {
+	mutex         sync.Mutex
+	subscriptions map[*Subscription]struct{}
+}
+
+// New creates a broker without subscribers.
+func New() *Broker {
+	return &Broker{subscriptions: make(map[*Subscription]struct{})}
+}
+
+// Subscribe subscribes to the topics matching pattern, buffering up to buffer messages.
+func (b *Broker) Subscribe(pattern string, buffer int, policy Policy) (*Subscription, error) {
+	if err := ValidatePattern(pattern); err != nil {
+		return nil, err
+	}
+	if policy == "" {
+		policy = Drop
+	}
+	if policy != Drop && policy != Disconnect {
+		return nil, ErrUnknownPolicy
+	}
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	if buffer > MaxBuffer {
+		buffer = MaxBuffer
+	}
+
+	s := &Subscription{
+		broker:   b,
+		pattern:  strings.Split(pattern, "."),
+		policy:   policy,
+		messages: make(chan Message, buffer),
+		done:     make(chan struct{}),
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.subscriptions[s] = struct{}{}
+
+	return s, nil
+}
+
+// Publish delivers m to every subscriber of a matching pattern whose buffer has room.
+func (b *Broker) Publish(m Message) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	topic := strings.Split(m.Topic, ".")
+	for s := range b.subscriptions {
+		if !match(s.pattern, topic) {
+			continue
+		}
+
+		delivered := m
+		delivered.Dropped = s.dropped
+		select {
+		case s.messages <- delivered:
+			s.dropped = 0
+		default:
+			if s.policy == Disconnect {
+				b.remove(s, ErrSlowSubscriber)
+			} else {
+				s.dropped++
+			}
+		}
+	}
+}
+
+// Len returns the number of subscriptions.
+func (b *Broker) Len() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return len(b.subscriptions)
+}
+
+func (b *Broker) remove(s *Subscription, err error) {
+	if _, ok := b.subscriptions[s]; !ok {
+		return
+	}
+	delete(b.subscriptions, s)
+	s.err = err
+	close(s.done)
+}
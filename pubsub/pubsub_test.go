@@ -0,0 +1,73 @@
+package pubsub
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, topic string
+		expected       bool
+	}{
+		{"orders", "orders", true},
+		{"orders", "orders.created", false},
+		{"orders.*", "orders.created", true},
+		{"orders.*", "orders.created.eu", false},
+		{"orders.>", "orders.created.eu", true},
+		{"orders.>", "orders", false},
+		{"*.created", "users.created", true},
+		{">", "users.created", true},
+	}
+	for _, test := range tests {
+		if got := Match(test.pattern, test.topic); got != test.expected {
+			t.Errorf("match %s %s: expected %v, got %v", test.pattern, test.topic, test.expected, got)
+		}
+	}
+
+	if ValidateTopic("orders.*") == nil {
+		t.Error("expected wildcards to be rejected in topics")
+	}
+	if ValidatePattern("orders.>.eu") == nil {
+		t.Error("expected > to be rejected before the last token")
+	}
+}
+
+func TestDrop(t *testing.T) {
+	b := New()
+	s, err := b.Subscribe("orders.*", 2, Drop)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for i := uint64(1); i <= 4; i++ {
+		b.Publish(Message{Topic: "orders.created", Index: i})
+	}
+	b.Publish(Message{Topic: "users.created", Index: 5})
+
+	<-s.Messages()
+	<-s.Messages()
+	b.Publish(Message{Topic: "orders.created", Index: 6})
+
+	if m := <-s.Messages(); m.Index != 6 || m.Dropped != 2 {
+		t.Errorf("expected message 6 after 2 drops, got %+v", m)
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	b := New()
+	s, _ := b.Subscribe("orders", 1, Disconnect)
+
+	b.Publish(Message{Topic: "orders", Index: 1})
+	b.Publish(Message{Topic: "orders", Index: 2})
+
+	select {
+	case <-s.Done():
+	default:
+		t.Fatal("expected slow subscriber to be disconnected")
+	}
+	if s.Err() != ErrSlowSubscriber {
+		t.Errorf("expected %v, got %v", ErrSlowSubscriber, s.Err())
+	}
+	if b.Len() != 0 {
+		t.Errorf("expected no subscriptions, got %d", b.Len())
+	}
+	s.Close()
+}
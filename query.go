@@ -0,0 +1,86 @@
+package demory
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/huseyinbabal/demory/predicate"
+	"github.com/huseyinbabal/demory/query"
+	"github.com/huseyinbabal/demory/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pageToken is the state of a paged query, handed to clients as an opaque token.
+type pageToken struct {
+	Offset int `json:"offset"`
+}
+
+// MapQuery returns the entries of a map whose JSON values match a predicate. Queries are served from the
+// local state of the node, using an index of the map to narrow the scan when the predicate allows it.
+func (d *Demory) MapQuery(ctx context.Context, req *rpc.MapQueryRequest) (*rpc.MapQueryResponse, error) {
+	if req.Where != "" && req.Predicate != nil {
+		return nil, status.Error(codes.InvalidArgument, "only one of where and predicate can be set")
+	}
+	if req.Limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+
+	p := req.Predicate
+	if req.Where != "" {
+		var err error
+		if p, err = predicate.Parse(req.Where); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	var token pageToken
+	if req.PageToken != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(req.PageToken)
+		if err != nil || json.Unmarshal(decoded, &token) != nil || token.Offset < 0 {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+	}
+
+	q := query.Query{
+		Predicate: p,
+		Fields:    req.Fields,
+		OrderBy:   req.OrderBy,
+		Offset:    token.Offset,
+		Limit:     req.Limit,
+	}
+
+	var results []query.Result
+	var more bool
+	var err error
+	d.fsm.Read(func() {
+		results, more, err = query.Run(q, func(fn func(key string, value []byte) bool) {
+			keys, indexed := d.indexedKeys(req.Name, p)
+			if !indexed {
+				d.hashMap.Range(req.Name, fn)
+				return
+			}
+			for _, key := range keys {
+				if !fn(key, d.hashMap.Get(req.Name, key)) {
+					return
+				}
+			}
+		})
+	})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	response := &rpc.MapQueryResponse{Entries: make([]rpc.Entry, 0, len(results))}
+	for _, result := range results {
+		response.Entries = append(response.Entries, rpc.Entry{Key: result.Key, Value: result.Value})
+	}
+
+	if more {
+		next, _ := json.Marshal(pageToken{Offset: token.Offset + len(results)})
+		response.NextPageToken = base64.RawURLEncoding.EncodeToString(next)
+	}
+
+	return response, nil
+}
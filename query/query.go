@@ -0,0 +1,223 @@
+// Package query runs predicate queries over the JSON values of a map, with projection, sorting and paging.
+package query
+
+import (
+	"container/heap"
+	"encoding/json"
+
+	"github.com/huseyinbabal/demory/jsonpath"
+	"github.com/huseyinbabal/demory/predicate"
+)
+
+// KeyPath is the pseudo path that refers to the key of an entry in an ordering.
+const KeyPath = "__key"
+
+// Order sorts results by the value at Path. Entries missing the path sort first.
+type Order struct {
+	Path       string `json:"path"`
+	Descending bool   `json:"descending,omitempty"`
+}
+
+// Query selects, orders and shapes entries of a map.
+type Query struct {
+	Predicate *predicate.Predicate
+	// Fields projects the values to the given paths. Values are returned as is if it is empty.
+	Fields []string
+	// OrderBy sorts the results. Results are always ordered by key last, so pages are stable.
+	OrderBy []Order
+	// Offset skips the first results, and Limit caps their number if it is positive.
+	Offset int
+	Limit  int
+}
+
+// Result is an entry returned by a query.
+type Result struct {
+	Key   string
+	Value []byte
+}
+
+// Scan passes every entry of a map to fn until fn returns false.
+type Scan func(fn func(key string, value []byte) bool)
+
+type candidate struct {
+	key    string
+	value  []byte
+	doc    interface{}
+	fields []interface{}
+	found  []bool
+}
+
+// Run executes q over the entries produced by scan. It returns the requested page and whether more
+// results follow it. Values that are not valid JSON never match.
+func Run(q Query, scan Scan) ([]Result, bool, error) {
+	match := predicate.Matcher(func(interface{}) bool { return true })
+	if q.Predicate != nil {
+		var err error
+		if match, err = q.Predicate.Compile(); err != nil {
+			return nil, false, err
+		}
+	}
+
+	orders := make([][]jsonpath.Segment, len(q.OrderBy))
+	for i, order := range q.OrderBy {
+		if order.Path == KeyPath {
+			continue
+		}
+		segments, err := jsonpath.Parse(order.Path)
+		if err != nil {
+			return nil, false, err
+		}
+		orders[i] = segments
+	}
+
+	projections := make([][]jsonpath.Segment, len(q.Fields))
+	for i, field := range q.Fields {
+		segments, err := jsonpath.Parse(field)
+		if err != nil {
+			return nil, false, err
+		}
+		projections[i] = segments
+	}
+
+	less := func(a, b *candidate) bool {
+		for i, order := range q.OrderBy {
+			var c int
+			switch {
+			case order.Path == KeyPath:
+				c = compareKeys(a.key, b.key)
+			case a.found[i] != b.found[i]:
+				c = 1
+				if !a.found[i] {
+					c = -1
+				}
+			default:
+				c = predicate.Compare(a.fields[i], b.fields[i])
+			}
+			if order.Descending {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return a.key < b.key
+	}
+
+	// Only the best offset+limit+1 results are kept while scanning, so a page of a large map does not
+	// require sorting every match. The extra result tells whether another page follows.
+	keep := -1
+	if q.Limit > 0 {
+		keep = q.Offset + q.Limit + 1
+	}
+	best := &bounded{less: less}
+
+	scan(func(key string, value []byte) bool {
+		doc, ok := predicate.Decode(value)
+		if !ok || !match(doc) {
+			return true
+		}
+
+		c := &candidate{key: key, value: value, doc: doc}
+		if len(orders) > 0 {
+			c.fields = make([]interface{}, len(orders))
+			c.found = make([]bool, len(orders))
+			for i, segments := range orders {
+				if q.OrderBy[i].Path != KeyPath {
+					c.fields[i], c.found[i] = jsonpath.Lookup(doc, segments)
+				}
+			}
+		}
+
+		if keep < 0 || best.Len() < keep {
+			heap.Push(best, c)
+		} else if less(c, best.items[0]) {
+			best.items[0] = c
+			heap.Fix(best, 0)
+		}
+		return true
+	})
+
+	sorted := make([]*candidate, best.Len())
+	for i := len(sorted) - 1; i >= 0; i-- {
+		sorted[i] = heap.Pop(best).(*candidate)
+	}
+
+	if q.Offset >= len(sorted) {
+		return nil, false, nil
+	}
+	sorted = sorted[q.Offset:]
+	more := false
+	if q.Limit > 0 && len(sorted) > q.Limit {
+		sorted, more = sorted[:q.Limit], true
+	}
+
+	results := make([]Result, 0, len(sorted))
+	for _, c := range sorted {
+		value, err := project(c, projections)
+		if err != nil {
+			return nil, false, err
+		}
+		results = append(results, Result{Key: c.key, Value: value})
+	}
+
+	return results, more, nil
+}
+
+// project keeps the fields of a value at the given paths, at the same place in the document.
+func project(c *candidate, projections [][]jsonpath.Segment) ([]byte, error) {
+	if len(projections) == 0 {
+		return c.value, nil
+	}
+
+	var projected interface{} = map[string]interface{}{}
+	for _, segments := range projections {
+		value, ok := jsonpath.Lookup(c.doc, segments)
+		if !ok {
+			continue
+		}
+		var err error
+		if projected, err = jsonpath.SetSegments(projected, segments, value); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(projected)
+}
+
+func compareKeys(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// bounded is a heap whose root is the worst result kept so far.
+type bounded struct {
+	items []*candidate
+	less  func(a, b *candidate) bool
+}
+
+func (b *bounded) Len() int {
+	return len(b.items)
+}
+
+func (b *bounded) Less(i, j int) bool {
+	return b.less(b.items[j], b.items[i])
+}
+
+func (b *bounded) Swap(i, j int) {
+	b.items[i], b.items[j] = b.items[j], b.items[i]
+}
+
+func (b *bounded) Push(x interface{}) {
+	b.items = append(b.items, x.(*candidate))
+}
+
+func (b *bounded) Pop() interface{} {
+	last := b.items[len(b.items)-1]
+	b.items = b.items[:len(b.items)-1]
+	return last
+}
@@ -0,0 +1,88 @@
+package query
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/huseyinbabal/demory/predicate"
+)
+
+func scanOf(entries map[string]string) Scan {
+	return func(fn func(key string, value []byte) bool) {
+		for key, value := range entries {
+			if !fn(key, []byte(value)) {
+				return
+			}
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	entries := map[string]string{
+		"1": `{"name":"john","age":30,"city":"izmir"}`,
+		"2": `{"name":"jane","age":25,"city":"izmir"}`,
+		"3": `{"name":"jack","age":35,"city":"ankara"}`,
+		"4": `{"name":"jill","age":25,"city":"izmir"}`,
+		"5": `not json`,
+	}
+	p, err := predicate.Parse(`city = 'izmir'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := Query{
+		Predicate: p,
+		Fields:    []string{"name"},
+		OrderBy:   []Order{{Path: "age"}, {Path: "name", Descending: true}},
+		Limit:     2,
+	}
+	results, more, err := Run(q, scanOf(entries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !more || len(results) != 2 || results[0].Key != "4" || results[1].Key != "2" {
+		t.Fatalf("unexpected first page %v %v", results, more)
+	}
+	if string(results[0].Value) != `{"name":"jill"}` {
+		t.Errorf("unexpected projection %s", results[0].Value)
+	}
+
+	q.Offset = 2
+	results, more, err = Run(q, scanOf(entries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if more || len(results) != 1 || results[0].Key != "1" {
+		t.Errorf("unexpected second page %v %v", results, more)
+	}
+}
+
+func BenchmarkRunMillionEntries(b *testing.B) {
+	const size = 1000000
+	keys := make([]string, size)
+	values := make([][]byte, size)
+	for i := 0; i < size; i++ {
+		keys[i] = fmt.Sprintf("user-%d", i)
+		values[i] = []byte(fmt.Sprintf(`{"id":%d,"age":%d,"city":"city-%d","active":%t}`, i, i%90, i%50, i%3 == 0))
+	}
+	scan := func(fn func(key string, value []byte) bool) {
+		for i := range keys {
+			if !fn(keys[i], values[i]) {
+				return
+			}
+		}
+	}
+
+	p, err := predicate.Parse(`age BETWEEN 20 AND 40 AND city IN ('city-1', 'city-2') AND active = true`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	q := Query{Predicate: p, OrderBy: []Order{{Path: "age", Descending: true}}, Limit: 100}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := Run(q, scan); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/huseyinbabal/demory/ds/ratelimit"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/structure"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	structure.Register(newRateLimitStructure)
+}
+
+// rateLimitStructure serves and applies rate limiters.
+type rateLimitStructure struct {
+	host     structure.Host
+	limiters *ratelimit.Limiters
+	acquirer *ratelimit.Batcher
+}
+
+func newRateLimitStructure(host structure.Host) structure.Structure {
+	s := &rateLimitStructure{host: host, limiters: ratelimit.New()}
+	s.acquirer = ratelimit.NewBatcher(maxAcquireBatch, s.acquireBatch)
+	return s
+}
+
+func (s *rateLimitStructure) Kind() string {
+	return "limiters"
+}
+
+func (s *rateLimitStructure) Commands() []fsm.CommandType {
+	return []fsm.CommandType{fsm.RateLimitDefine, fsm.RateLimitDelete, fsm.RateLimitAcquire}
+}
+
+func (s *rateLimitStructure) Register(server grpc.ServiceRegistrar) {
+	rpc.RegisterRateLimiterServer(server, s)
+}
+
+func (s *rateLimitStructure) Snapshot() ([]byte, error) {
+	return json.Marshal(s.limiters)
+}
+
+func (s *rateLimitStructure) Restore(data []byte) (func(), error) {
+	limiters := ratelimit.New()
+	if err := structure.Unmarshal(data, limiters); err != nil {
+		return nil, err
+	}
+	return func() { s.limiters = limiters }, nil
+}
+
+func (s *rateLimitStructure) Stats() structure.Stats {
+	return structure.Stats{Bytes: s.limiters.Bytes()}
+}
+
+func (s *rateLimitStructure) Destroy(name string) bool {
+	return s.limiters.Delete(name)
+}
+
+// maxAcquireBatch is the number of acquisitions replicated together at most.
+const maxAcquireBatch = 256
+
+// rateLimitArgs are the arguments of replicated rate limiter commands.
+type rateLimitArgs struct {
+	Definition   *ratelimit.Definition `json:"definition,omitempty"`
+	Acquisitions []ratelimit.Acquire   `json:"acquisitions,omitempty"`
+}
+
+// acquired is the outcome of a batch of acquisitions.
+type acquired struct {
+	results []ratelimit.Result
+	errs    []error
+}
+
+// DefineRateLimiter creates a rate limiter or changes its definition, which resets the state of its keys.
+func (s *rateLimitStructure) DefineRateLimiter(ctx context.Context,
+	req *rpc.RateLimiterDefineRequest) (*rpc.Empty, error) {
+	if err := req.Definition.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	request := fsm.ApplyRequest{Type: fsm.RateLimitDefine, Name: req.Name}
+	_, err := s.host.Propose(request, rateLimitArgs{Definition: &req.Definition})
+	return new(rpc.Empty), err
+}
+
+// DeleteRateLimiter removes a rate limiter with the state of its keys.
+func (s *rateLimitStructure) DeleteRateLimiter(ctx context.Context,
+	req *rpc.RateLimiterNameRequest) (*rpc.Empty, error) {
+	_, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.RateLimitDelete, Name: req.Name}, rateLimitArgs{})
+	return new(rpc.Empty), err
+}
+
+// GetRateLimiter returns the definition of a rate limiter.
+func (s *rateLimitStructure) GetRateLimiter(ctx context.Context,
+	req *rpc.RateLimiterNameRequest) (*rpc.RateLimiterGetResponse, error) {
+	var definition ratelimit.Definition
+	var ok bool
+	s.host.Read(func() {
+		definition, ok = s.limiters.Get(req.Name)
+	})
+	if !ok {
+		return nil, status.Error(codes.NotFound, ratelimit.ErrNoLimiter.Error())
+	}
+
+	return &rpc.RateLimiterGetResponse{Definition: definition}, nil
+}
+
+// TryAcquire takes permits for a key of a rate limiter if they are available, or tells how long to wait
+// before retrying. Concurrent acquisitions are replicated in batches, and decided with the time of the leader.
+func (s *rateLimitStructure) TryAcquire(ctx context.Context,
+	req *rpc.TryAcquireRequest) (*rpc.TryAcquireResponse, error) {
+	permits := req.Permits
+	if permits == 0 {
+		permits = 1
+	}
+
+	result, err := s.acquirer.TryAcquire(ctx, ratelimit.Acquire{Name: req.Name, Key: req.Key, Permits: permits})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.TryAcquireResponse{Result: result}, nil
+}
+
+// acquireBatch replicates a batch of acquisitions as one command.
+func (s *rateLimitStructure) acquireBatch(batch []ratelimit.Acquire) ([]ratelimit.Result, []error, error) {
+	request := fsm.ApplyRequest{Type: fsm.RateLimitAcquire}
+	data, err := s.host.Propose(request, rateLimitArgs{Acquisitions: batch})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outcome := data.(acquired)
+	return outcome.results, outcome.errs, nil
+}
+
+func (s *rateLimitStructure) Apply(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args rateLimitArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	switch request.Type {
+	case fsm.RateLimitDefine:
+		if args.Definition == nil {
+			return fsm.ApplyResponse{Error: status.Error(codes.InvalidArgument, "definition must be set")}
+		}
+		if err := s.limiters.Define(request.Name, *args.Definition); err != nil {
+			return fsm.ApplyResponse{Error: rateLimitError(err)}
+		}
+		return fsm.ApplyResponse{}
+	case fsm.RateLimitDelete:
+		if !s.limiters.Delete(request.Name) {
+			return fsm.ApplyResponse{Error: rateLimitError(ratelimit.ErrNoLimiter)}
+		}
+		return fsm.ApplyResponse{}
+	default:
+		outcome := acquired{
+			results: make([]ratelimit.Result, len(args.Acquisitions)),
+			errs:    make([]error, len(args.Acquisitions)),
+		}
+		for i, a := range args.Acquisitions {
+			result, err := s.limiters.TryAcquire(a, request.Time)
+			if err != nil {
+				err = rateLimitError(err)
+			}
+			outcome.results[i], outcome.errs[i] = result, err
+		}
+		return fsm.ApplyResponse{Data: outcome}
+	}
+}
+
+// rateLimitError converts errors of the ratelimit package to gRPC statuses.
+func rateLimitError(err error) error {
+	if errors.Is(err, ratelimit.ErrNoLimiter) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+// Growth returns the number of bytes the state of keys acquired from for the first time takes. Idle keys are
+// pruned as acquisitions go, which is not counted ahead.
+func (s *rateLimitStructure) Growth(request fsm.ApplyRequest) int64 {
+	var args rateLimitArgs
+	if request.Type != fsm.RateLimitAcquire || json.Unmarshal(request.Args, &args) != nil {
+		return 0
+	}
+	return s.limiters.Growth(args.Acquisitions)
+}
@@ -0,0 +1,994 @@
+package demory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/huseyinbabal/demory/cdc"
+	"github.com/huseyinbabal/demory/ds/hashmap"
+	"github.com/huseyinbabal/demory/ds/multimap"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/mapstore"
+	"github.com/huseyinbabal/demory/resp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultRespKeyspace names the map and the cache holding the strings of the RESP keyspace, and prefixes the
+// names of the structures holding its other keys.
+const defaultRespKeyspace = "resp"
+
+// respCommand is a command of the RESP listener. run gets the arguments after the name of the command.
+type respCommand struct {
+	// arity is the number of arguments including the name, or its negated minimum for variadic commands.
+	arity int
+	// write is set for commands that may change the keyspace, which only run on the leader.
+	write bool
+	run   func(ks *keyspace, args [][]byte) resp.Reply
+}
+
+const (
+	respRead  = false
+	respWrite = true
+)
+
+var respCommands map[string]respCommand
+
+func init() {
+	respCommands = map[string]respCommand{
+		"GET":       {2, respRead, respGet},
+		"SET":       {-3, respWrite, respSet},
+		"SETNX":     {3, respWrite, respSetNX},
+		"SETEX":     {4, respWrite, respSetEX(time.Second)},
+		"PSETEX":    {4, respWrite, respSetEX(time.Millisecond)},
+		"MGET":      {-2, respRead, respMGet},
+		"MSET":      {-3, respWrite, respMSet},
+		"APPEND":    {3, respWrite, respAppend},
+		"STRLEN":    {2, respRead, respStrlen},
+		"INCR":      {2, respWrite, respIncr(1)},
+		"DECR":      {2, respWrite, respIncr(-1)},
+		"INCRBY":    {3, respWrite, respIncrBy(1)},
+		"DECRBY":    {3, respWrite, respIncrBy(-1)},
+		"DEL":       {-2, respWrite, respDel},
+		"EXISTS":    {-2, respRead, respExists},
+		"TYPE":      {2, respRead, respType},
+		"EXPIRE":    {3, respWrite, respExpire(time.Second)},
+		"PEXPIRE":   {3, respWrite, respExpire(time.Millisecond)},
+		"TTL":       {2, respRead, respTTL(time.Second)},
+		"PTTL":      {2, respRead, respTTL(time.Millisecond)},
+		"PERSIST":   {2, respWrite, respPersist},
+		"HSET":      {-4, respWrite, respHSet},
+		"HMSET":     {-4, respWrite, respHMSet},
+		"HGET":      {3, respRead, respHGet},
+		"HMGET":     {-3, respRead, respHMGet},
+		"HDEL":      {-3, respWrite, respHDel},
+		"HGETALL":   {2, respRead, respHGetAll},
+		"HEXISTS":   {3, respRead, respHExists},
+		"HLEN":      {2, respRead, respHLen},
+		"HKEYS":     {2, respRead, respHKeys},
+		"HVALS":     {2, respRead, respHVals},
+		"HINCRBY":   {4, respWrite, respHIncrBy},
+		"SADD":      {-3, respWrite, respSAdd},
+		"SREM":      {-3, respWrite, respSRem},
+		"SMEMBERS":  {2, respRead, respSMembers},
+		"SISMEMBER": {3, respRead, respSIsMember},
+		"SCARD":     {2, respRead, respSCard},
+		"ZADD":      {-4, respWrite, respZAdd},
+		"ZINCRBY":   {4, respWrite, respZIncrBy},
+		"ZSCORE":    {3, respRead, respZScore},
+		"ZREM":      {-3, respWrite, respZRem},
+		"ZCARD":     {2, respRead, respZCard},
+		"ZRANGE":    {-4, respRead, respZRange},
+		"LPUSH":     {-3, respWrite, respPush(true)},
+		"RPUSH":     {-3, respWrite, respPush(false)},
+		"LPOP":      {-2, respWrite, respPop(true)},
+		"RPOP":      {-2, respWrite, respPop(false)},
+		"LLEN":      {2, respRead, respLLen},
+		"LRANGE":    {4, respRead, respLRange},
+		"LINDEX":    {3, respRead, respLIndex},
+	}
+}
+
+// respHandler runs the commands of RESP clients on the keyspace of the node. Every command, or every batch
+// queued within MULTI, that writes is replicated as one RespBatch command, so batches are atomic. Writes are
+// only accepted by the leader, which followers redirect clients to with a MOVED error.
+type respHandler struct {
+	d *Demory
+}
+
+// respArgs are the arguments of a replicated RespBatch command. The keyspace is the request name.
+type respArgs struct {
+	Commands [][][]byte `json:"commands"`
+}
+
+// respResult is the data of an applied RespBatch command.
+type respResult struct {
+	replies []resp.Reply
+	// changes are the map and cache entries the batch changed, for the leader to write them to the map store.
+	changes []cdc.Record
+}
+
+// serveResp accepts RESP clients on port.
+func (d *Demory) serveResp(port int) {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		log.Fatalf("resp socket error %v", err)
+	}
+
+	if err := resp.Serve(listener, respHandler{d: d}); err != nil {
+		log.Fatalf("resp serve error %v", err)
+	}
+}
+
+func (h respHandler) Command(args [][]byte) resp.Reply {
+	if reply := h.Queue(args); reply != nil {
+		return reply
+	}
+
+	replies, err := h.run([][][]byte{args})
+	if err != nil {
+		return err
+	}
+	return replies[0]
+}
+
+func (h respHandler) Queue(args [][]byte) resp.Reply {
+	if len(args) == 0 {
+		return resp.Errorf("ERR empty command")
+	}
+	command, ok := respCommands[strings.ToUpper(string(args[0]))]
+	if !ok {
+		return resp.Errorf("ERR unknown command '%s'", args[0])
+	}
+	if command.arity > 0 && len(args) != command.arity || command.arity < 0 && len(args) < -command.arity {
+		return resp.ArityError(args[0])
+	}
+	return nil
+}
+
+func (h respHandler) Exec(commands [][][]byte) resp.Reply {
+	replies, err := h.run(commands)
+	if err != nil {
+		return err
+	}
+	return resp.Array(replies)
+}
+
+// run runs commands on the keyspace. Batches that only read run on this node, and the others are replicated
+// and run as they are applied. It returns the replies of the commands, or an error reply if the batch could
+// not be applied.
+func (h respHandler) run(commands [][][]byte) ([]resp.Reply, resp.Reply) {
+	name := h.d.config.RespKeyspace
+	if name == "" {
+		name = defaultRespKeyspace
+	}
+
+	if !respWrites(commands) {
+		var replies []resp.Reply
+		h.d.fsm.Read(func() {
+			replies = newKeyspace(h.d, name, time.Now()).run(commands)
+		})
+		return replies, nil
+	}
+	if h.d.fsm.Raft.State() != raft.Leader {
+		return nil, h.moved()
+	}
+
+	request := fsm.ApplyRequest{Type: fsm.RespBatch, Name: name, WriteBehind: h.d.writesBehind(name)}
+	data, err := h.d.propose(request, respArgs{Commands: commands})
+	switch {
+	case errors.Is(err, raft.ErrNotLeader):
+		return nil, h.moved()
+	case status.Code(err) == codes.ResourceExhausted:
+		return nil, resp.Errorf("OOM command not allowed when used memory > 'maxmemory'.")
+	case err != nil:
+		return nil, resp.Errorf("ERR %s", status.Convert(err).Message())
+	}
+
+	result := data.(respResult)
+	if err := h.d.persistChanges(result.changes); err != nil {
+		return nil, resp.Errorf("ERR %s", status.Convert(err).Message())
+	}
+	return result.replies, nil
+}
+
+// moved redirects clients to the RESP listener of the leader, which listens on the same port as this node.
+func (h respHandler) moved() resp.Reply {
+	host, _, err := net.SplitHostPort(string(h.d.fsm.Raft.Leader()))
+	if err != nil {
+		return resp.Errorf("CLUSTERDOWN no leader elected")
+	}
+	return resp.Errorf("MOVED 0 %s", net.JoinHostPort(host, strconv.Itoa(h.d.config.RespPort)))
+}
+
+// respWrites reports whether any of commands may change the keyspace.
+func respWrites(commands [][][]byte) bool {
+	for _, args := range commands {
+		if len(args) > 0 && respCommands[strings.ToUpper(string(args[0]))].write {
+			return true
+		}
+	}
+	return false
+}
+
+// respBatch applies a RespBatch command. Its commands run one after the other, like the commands of a MULTI
+// block in Redis: a command that fails replies with an error, and does not undo the others.
+func (d *Demory) respBatch(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args respArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	changed := len(d.changes)
+	replies := newKeyspace(d, request.Name, time.Unix(0, request.Time)).run(args.Commands)
+	changes := append([]cdc.Record(nil), d.changes[changed:]...)
+	return fsm.ApplyResponse{Data: respResult{replies: replies, changes: changes}}
+}
+
+// respGrowth returns the number of bytes a RespBatch command may add: the size of the arguments of its
+// commands that write, which is what they store at most, besides the formatted scores of sorted sets.
+func respGrowth(request fsm.ApplyRequest) int64 {
+	var args respArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return 0
+	}
+
+	var growth int64
+	for _, command := range args.Commands {
+		if !respWrites([][][]byte{command}) {
+			continue
+		}
+		for _, arg := range command[1:] {
+			growth += int64(len(arg))
+		}
+	}
+	return growth
+}
+
+// persistChanges writes the map entries changed by a RespBatch command to the map store, if it is in
+// write-through mode.
+func (d *Demory) persistChanges(changes []cdc.Record) error {
+	for _, change := range changes {
+		if change.Structure != string(fsm.StructureMap) || change.Type == string(hashmap.Evict) {
+			continue
+		}
+		err := d.persist(change.Name, func(p *mapstore.Persister) error {
+			if change.Type == string(hashmap.Put) {
+				return p.Put(change.Name, change.Key, change.Value)
+			}
+			return p.Delete(change.Name, change.Key)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func respGet(ks *keyspace, args [][]byte) resp.Reply {
+	if err := ks.check(string(args[0]), typeString); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	if value, _, ok := ks.str(string(args[0])); ok {
+		return resp.Bulk(value)
+	}
+	return resp.Nil
+}
+
+// respSet sets a string, replacing a key of any type, with the options NX, XX, EX, PX, KEEPTTL and GET.
+func respSet(ks *keyspace, args [][]byte) resp.Reply {
+	key, value := string(args[0]), args[1]
+	var nx, xx, keepTTL, get bool
+	var ttl time.Duration
+	for i := 2; i < len(args); i++ {
+		switch option := strings.ToUpper(string(args[i])); {
+		case option == "NX" && !xx:
+			nx = true
+		case option == "XX" && !nx:
+			xx = true
+		case option == "KEEPTTL" && ttl == 0:
+			keepTTL = true
+		case option == "GET":
+			get = true
+		case (option == "EX" || option == "PX") && !keepTTL && ttl == 0 && i+1 < len(args):
+			n, ok := resp.ParseInt(args[i+1])
+			if !ok {
+				return resp.Errorf(notInteger)
+			}
+			unit := time.Second
+			if option == "PX" {
+				unit = time.Millisecond
+			}
+			if n <= 0 || n > math.MaxInt64/int64(unit) {
+				return resp.Errorf("ERR invalid expire time in 'set' command")
+			}
+			ttl = time.Duration(n) * unit
+			i++
+		default:
+			return resp.Errorf("ERR syntax error")
+		}
+	}
+
+	t := ks.typeOf(key)
+	if get && t != typeNone && t != typeString {
+		return resp.Errorf(wrongType)
+	}
+	exists := t != typeNone
+	old, oldTTL, _ := ks.str(key)
+	var reply resp.Reply = resp.OK
+	if get {
+		reply = resp.Nil
+		if exists {
+			reply = resp.Bulk(old)
+		}
+	}
+	if nx && exists || xx && !exists {
+		if get {
+			return reply
+		}
+		return resp.Nil
+	}
+
+	if keepTTL {
+		ttl = oldTTL
+	}
+	ks.setStr(key, value, ttl)
+	return reply
+}
+
+func respSetNX(ks *keyspace, args [][]byte) resp.Reply {
+	if ks.typeOf(string(args[0])) != typeNone {
+		return resp.Integer(0)
+	}
+	ks.setStr(string(args[0]), args[1], 0)
+	return resp.Integer(1)
+}
+
+func respSetEX(unit time.Duration) func(ks *keyspace, args [][]byte) resp.Reply {
+	return func(ks *keyspace, args [][]byte) resp.Reply {
+		n, ok := resp.ParseInt(args[1])
+		if !ok {
+			return resp.Errorf(notInteger)
+		}
+		if n <= 0 || n > math.MaxInt64/int64(unit) {
+			return resp.Errorf("ERR invalid expire time")
+		}
+		ks.setStr(string(args[0]), args[2], time.Duration(n)*unit)
+		return resp.OK
+	}
+}
+
+func respMGet(ks *keyspace, args [][]byte) resp.Reply {
+	values := make([][]byte, len(args))
+	for i, key := range args {
+		values[i], _, _ = ks.str(string(key))
+	}
+	return resp.BulkArray(values)
+}
+
+func respMSet(ks *keyspace, args [][]byte) resp.Reply {
+	if len(args)%2 != 0 {
+		return resp.ArityError([]byte("mset"))
+	}
+	for i := 0; i < len(args); i += 2 {
+		ks.setStr(string(args[i]), args[i+1], 0)
+	}
+	return resp.OK
+}
+
+// respAppend appends to a string, keeping its TTL.
+func respAppend(ks *keyspace, args [][]byte) resp.Reply {
+	if err := ks.check(string(args[0]), typeString); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	old, ttl, _ := ks.str(string(args[0]))
+	value := append(append([]byte{}, old...), args[1]...)
+	ks.setStr(string(args[0]), value, ttl)
+	return resp.Integer(int64(len(value)))
+}
+
+func respStrlen(ks *keyspace, args [][]byte) resp.Reply {
+	if err := ks.check(string(args[0]), typeString); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	value, _, _ := ks.str(string(args[0]))
+	return resp.Integer(int64(len(value)))
+}
+
+func respIncr(delta int64) func(ks *keyspace, args [][]byte) resp.Reply {
+	return func(ks *keyspace, args [][]byte) resp.Reply {
+		return incr(ks, string(args[0]), delta)
+	}
+}
+
+func respIncrBy(sign int64) func(ks *keyspace, args [][]byte) resp.Reply {
+	return func(ks *keyspace, args [][]byte) resp.Reply {
+		delta, ok := resp.ParseInt(args[1])
+		if !ok || sign < 0 && delta == math.MinInt64 {
+			return resp.Errorf(notInteger)
+		}
+		return incr(ks, string(args[0]), sign*delta)
+	}
+}
+
+// incr adds delta to the integer held by a string, keeping its TTL. Missing strings count as zero.
+func incr(ks *keyspace, key string, delta int64) resp.Reply {
+	if err := ks.check(key, typeString); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	value, ttl, exists := ks.str(key)
+	n, ok := add(value, exists, delta)
+	if !ok {
+		return resp.Errorf(notInteger)
+	}
+	ks.setStr(key, []byte(strconv.FormatInt(n, 10)), ttl)
+	return resp.Integer(n)
+}
+
+// add adds delta to the integer held by value, and reports whether neither parsing nor adding failed.
+func add(value []byte, exists bool, delta int64) (int64, bool) {
+	var n int64
+	if exists {
+		var ok bool
+		if n, ok = resp.ParseInt(value); !ok {
+			return 0, false
+		}
+	}
+	if delta > 0 && n > math.MaxInt64-delta || delta < 0 && n < math.MinInt64-delta {
+		return 0, false
+	}
+	return n + delta, true
+}
+
+func respDel(ks *keyspace, args [][]byte) resp.Reply {
+	var removed int64
+	for _, key := range args {
+		if ks.del(string(key)) {
+			removed++
+		}
+	}
+	return resp.Integer(removed)
+}
+
+func respExists(ks *keyspace, args [][]byte) resp.Reply {
+	var found int64
+	for _, key := range args {
+		if ks.typeOf(string(key)) != typeNone {
+			found++
+		}
+	}
+	return resp.Integer(found)
+}
+
+// respExpire sets the TTL of a string. Only strings expire, as the structures holding other keys do not.
+func respExpire(unit time.Duration) func(ks *keyspace, args [][]byte) resp.Reply {
+	return func(ks *keyspace, args [][]byte) resp.Reply {
+		n, ok := resp.ParseInt(args[1])
+		if !ok {
+			return resp.Errorf(notInteger)
+		}
+		if n > math.MaxInt64/int64(unit) {
+			return resp.Errorf("ERR invalid expire time")
+		}
+
+		key := string(args[0])
+		value, _, exists := ks.str(key)
+		if !exists {
+			return resp.Integer(0)
+		}
+		if n <= 0 {
+			ks.removeStr(key)
+		} else {
+			ks.setStr(key, value, time.Duration(n)*unit)
+		}
+		return resp.Integer(1)
+	}
+}
+
+func respTTL(unit time.Duration) func(ks *keyspace, args [][]byte) resp.Reply {
+	return func(ks *keyspace, args [][]byte) resp.Reply {
+		key := string(args[0])
+		_, ttl, exists := ks.str(key)
+		switch {
+		case exists && ttl > 0:
+			return resp.Integer(int64((ttl + unit/2) / unit))
+		case exists || ks.typeOf(key) != typeNone:
+			return resp.Integer(-1)
+		default:
+			return resp.Integer(-2)
+		}
+	}
+}
+
+func respType(ks *keyspace, args [][]byte) resp.Reply {
+	t := ks.typeOf(string(args[0]))
+	return func(w *resp.Writer) {
+		w.SimpleString(string(t))
+	}
+}
+
+func respPersist(ks *keyspace, args [][]byte) resp.Reply {
+	value, ttl, exists := ks.str(string(args[0]))
+	if !exists || ttl == 0 {
+		return resp.Integer(0)
+	}
+	ks.setStr(string(args[0]), value, 0)
+	return resp.Integer(1)
+}
+
+// respHSet sets fields of a hash and returns how many of them are new.
+func respHSet(ks *keyspace, args [][]byte) resp.Reply {
+	if len(args)%2 != 1 {
+		return resp.ArityError([]byte("hset"))
+	}
+	key := string(args[0])
+	if err := ks.check(key, typeHash); err != nil {
+		return resp.Errorf(err.Error())
+	}
+
+	name := ks.collection(typeHash, key)
+	var added int64
+	for i := 1; i < len(args); i += 2 {
+		added += int64(ks.d.hashMap.Put(name, string(args[i]), args[i+1]))
+	}
+	return resp.Integer(added)
+}
+
+func respHMSet(ks *keyspace, args [][]byte) resp.Reply {
+	if len(args)%2 != 1 {
+		return resp.ArityError([]byte("hmset"))
+	}
+	if err := ks.check(string(args[0]), typeHash); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	respHSet(ks, args)
+	return resp.OK
+}
+
+func respHGet(ks *keyspace, args [][]byte) resp.Reply {
+	key := string(args[0])
+	if err := ks.check(key, typeHash); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	if value, ok := ks.field(key, string(args[1])); ok {
+		return resp.Bulk(value)
+	}
+	return resp.Nil
+}
+
+func respHMGet(ks *keyspace, args [][]byte) resp.Reply {
+	key := string(args[0])
+	if err := ks.check(key, typeHash); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	values := make([][]byte, len(args)-1)
+	for i, field := range args[1:] {
+		values[i], _ = ks.field(key, string(field))
+	}
+	return resp.BulkArray(values)
+}
+
+// respHDel removes fields of a hash and returns how many of them existed.
+func respHDel(ks *keyspace, args [][]byte) resp.Reply {
+	return removeFields(ks, typeHash, args)
+}
+
+// respZRem removes members of a sorted set and returns how many of them existed.
+func respZRem(ks *keyspace, args [][]byte) resp.Reply {
+	return removeFields(ks, typeZSet, args)
+}
+
+// removeFields removes entries from the map of the hash or the sorted set in args[0].
+func removeFields(ks *keyspace, t keyType, args [][]byte) resp.Reply {
+	key := string(args[0])
+	if err := ks.check(key, t); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	var removed int64
+	for _, field := range args[1:] {
+		removed += int64(ks.d.hashMap.Remove(ks.collection(t, key), string(field)))
+	}
+	return resp.Integer(removed)
+}
+
+func respHGetAll(ks *keyspace, args [][]byte) resp.Reply {
+	key := string(args[0])
+	if err := ks.check(key, typeHash); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	fields := ks.d.hashMap.Keys(ks.collection(typeHash, key))
+	pairs := make([][]byte, 0, 2*len(fields))
+	for _, field := range fields {
+		value, _ := ks.field(key, field)
+		pairs = append(pairs, []byte(field), value)
+	}
+	return resp.BulkMap(pairs)
+}
+
+func respHExists(ks *keyspace, args [][]byte) resp.Reply {
+	key := string(args[0])
+	if err := ks.check(key, typeHash); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	if _, ok := ks.field(key, string(args[1])); ok {
+		return resp.Integer(1)
+	}
+	return resp.Integer(0)
+}
+
+func respHLen(ks *keyspace, args [][]byte) resp.Reply {
+	return count(ks, typeHash, string(args[0]))
+}
+
+func respZCard(ks *keyspace, args [][]byte) resp.Reply {
+	return count(ks, typeZSet, string(args[0]))
+}
+
+// count returns the number of entries of the map of the hash or the sorted set at key.
+func count(ks *keyspace, t keyType, key string) resp.Reply {
+	if err := ks.check(key, t); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	return resp.Integer(int64(ks.d.hashMap.Len(ks.collection(t, key))))
+}
+
+func respHKeys(ks *keyspace, args [][]byte) resp.Reply {
+	key := string(args[0])
+	if err := ks.check(key, typeHash); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	fields := ks.d.hashMap.Keys(ks.collection(typeHash, key))
+	keys := make([][]byte, len(fields))
+	for i, field := range fields {
+		keys[i] = []byte(field)
+	}
+	return resp.BulkArray(keys)
+}
+
+func respHVals(ks *keyspace, args [][]byte) resp.Reply {
+	key := string(args[0])
+	if err := ks.check(key, typeHash); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	fields := ks.d.hashMap.Keys(ks.collection(typeHash, key))
+	values := make([][]byte, len(fields))
+	for i, field := range fields {
+		values[i], _ = ks.field(key, field)
+	}
+	return resp.BulkArray(values)
+}
+
+func respHIncrBy(ks *keyspace, args [][]byte) resp.Reply {
+	delta, ok := resp.ParseInt(args[2])
+	if !ok {
+		return resp.Errorf(notInteger)
+	}
+	key, field := string(args[0]), string(args[1])
+	if err := ks.check(key, typeHash); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	value, exists := ks.field(key, field)
+	n, ok := add(value, exists, delta)
+	if !ok {
+		return resp.Errorf(notInteger)
+	}
+	ks.d.hashMap.Put(ks.collection(typeHash, key), field, []byte(strconv.FormatInt(n, 10)))
+	return resp.Integer(n)
+}
+
+// field returns the value of a field of the hash at key.
+func (ks *keyspace) field(key, field string) ([]byte, bool) {
+	name := ks.collection(typeHash, key)
+	if _, ok := ks.d.hashMap.EntrySize(name, field); !ok {
+		return nil, false
+	}
+	return nonNil(ks.d.hashMap.Get(name, field)), true
+}
+
+// respSAdd adds members to a set and returns how many of them are new.
+func respSAdd(ks *keyspace, args [][]byte) resp.Reply {
+	key := string(args[0])
+	if err := ks.check(key, typeSet); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	added, _ := ks.d.resp.Sets.Put(ks.name, key, args[1:], multimap.Set)
+	return resp.Integer(int64(added))
+}
+
+// respSRem removes members from a set and returns how many of them existed.
+func respSRem(ks *keyspace, args [][]byte) resp.Reply {
+	key := string(args[0])
+	if err := ks.check(key, typeSet); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	var removed int64
+	for _, member := range args[1:] {
+		if ks.d.resp.Sets.Remove(ks.name, key, member) {
+			removed++
+		}
+	}
+	return resp.Integer(removed)
+}
+
+func respSMembers(ks *keyspace, args [][]byte) resp.Reply {
+	key := string(args[0])
+	if err := ks.check(key, typeSet); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	members, _ := ks.d.resp.Sets.Get(ks.name, key)
+	return resp.BulkSet(members)
+}
+
+func respSIsMember(ks *keyspace, args [][]byte) resp.Reply {
+	key := string(args[0])
+	if err := ks.check(key, typeSet); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	if ks.d.resp.Sets.Contains(ks.name, key, args[1]) {
+		return resp.Integer(1)
+	}
+	return resp.Integer(0)
+}
+
+func respSCard(ks *keyspace, args [][]byte) resp.Reply {
+	key := string(args[0])
+	if err := ks.check(key, typeSet); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	return resp.Integer(int64(ks.d.resp.Sets.Count(ks.name, key)))
+}
+
+// respZAdd adds members to a sorted set or updates their scores, with the options NX, XX, CH and INCR.
+func respZAdd(ks *keyspace, args [][]byte) resp.Reply {
+	key := string(args[0])
+	var nx, xx, ch, incr bool
+	i := 1
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(string(args[i])) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "CH":
+			ch = true
+		case "INCR":
+			incr = true
+		default:
+			break options
+		}
+	}
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 || nx && xx || incr && len(pairs) != 2 {
+		return resp.Errorf("ERR syntax error")
+	}
+
+	scores := make([]float64, 0, len(pairs)/2)
+	for j := 0; j < len(pairs); j += 2 {
+		score, ok := resp.ParseFloat(pairs[j])
+		if !ok {
+			return resp.Errorf(notFloat)
+		}
+		scores = append(scores, score)
+	}
+	if err := ks.check(key, typeZSet); err != nil {
+		return resp.Errorf(err.Error())
+	}
+
+	var changed int64
+	for j, score := range scores {
+		member := string(pairs[2*j+1])
+		current, exists := ks.score(key, member)
+		if nx && exists || xx && !exists {
+			if incr {
+				return resp.Nil
+			}
+			continue
+		}
+		if incr {
+			score += current
+			if math.IsNaN(score) {
+				return resp.Errorf("ERR resulting score is not a number (NaN)")
+			}
+		}
+		if !exists || score != current {
+			ks.setScore(key, member, score)
+			if !exists || ch {
+				changed++
+			}
+		}
+		if incr {
+			return resp.Double(score)
+		}
+	}
+	return resp.Integer(changed)
+}
+
+func respZIncrBy(ks *keyspace, args [][]byte) resp.Reply {
+	delta, ok := resp.ParseFloat(args[1])
+	if !ok {
+		return resp.Errorf(notFloat)
+	}
+	key, member := string(args[0]), string(args[2])
+	if err := ks.check(key, typeZSet); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	current, _ := ks.score(key, member)
+	score := current + delta
+	if math.IsNaN(score) {
+		return resp.Errorf("ERR resulting score is not a number (NaN)")
+	}
+	ks.setScore(key, member, score)
+	return resp.Double(score)
+}
+
+func respZScore(ks *keyspace, args [][]byte) resp.Reply {
+	key := string(args[0])
+	if err := ks.check(key, typeZSet); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	if score, exists := ks.score(key, string(args[1])); exists {
+		return resp.Double(score)
+	}
+	return resp.Nil
+}
+
+// respZRange returns the members of a sorted set between two ranks, with the options REV and WITHSCORES.
+func respZRange(ks *keyspace, args [][]byte) resp.Reply {
+	start, okStart := resp.ParseInt(args[1])
+	stop, okStop := resp.ParseInt(args[2])
+	if !okStart || !okStop {
+		return resp.Errorf(notInteger)
+	}
+	var rev, withScores bool
+	for _, option := range args[3:] {
+		switch strings.ToUpper(string(option)) {
+		case "REV":
+			rev = true
+		case "WITHSCORES":
+			withScores = true
+		default:
+			return resp.Errorf("ERR syntax error")
+		}
+	}
+
+	key := string(args[0])
+	if err := ks.check(key, typeZSet); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	members := ks.zset(key)
+	if rev {
+		for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+			members[i], members[j] = members[j], members[i]
+		}
+	}
+	from, to, ok := span(start, stop, int64(len(members)))
+	if !ok {
+		members = nil
+	} else {
+		members = members[from : to+1]
+	}
+
+	return func(w *resp.Writer) {
+		switch {
+		case !withScores:
+			w.Array(len(members))
+		case w.Protocol >= 3:
+			w.Array(len(members))
+		default:
+			w.Array(2 * len(members))
+		}
+		for _, m := range members {
+			if withScores && w.Protocol >= 3 {
+				w.Array(2)
+			}
+			w.Bulk([]byte(m.member))
+			if withScores {
+				w.Double(m.score)
+			}
+		}
+	}
+}
+
+// respPush adds elements to the head or the tail of a list, and returns its length.
+func respPush(head bool) func(ks *keyspace, args [][]byte) resp.Reply {
+	return func(ks *keyspace, args [][]byte) resp.Reply {
+		key := string(args[0])
+		if err := ks.check(key, typeList); err != nil {
+			return resp.Errorf(err.Error())
+		}
+		return resp.Integer(int64(ks.d.resp.Lists.Push(ks.collection(typeList, key), args[1:], head)))
+	}
+}
+
+// respPop removes elements from the head or the tail of a list. Given a count, it replies with an array.
+func respPop(head bool) func(ks *keyspace, args [][]byte) resp.Reply {
+	return func(ks *keyspace, args [][]byte) resp.Reply {
+		if len(args) > 2 {
+			return resp.Errorf("ERR syntax error")
+		}
+		count := int64(1)
+		if len(args) == 2 {
+			var ok bool
+			if count, ok = resp.ParseInt(args[1]); !ok || count < 0 {
+				return resp.Errorf("ERR value is out of range, must be positive")
+			}
+		}
+
+		key := string(args[0])
+		if err := ks.check(key, typeList); err != nil {
+			return resp.Errorf(err.Error())
+		}
+		name := ks.collection(typeList, key)
+		if ks.d.resp.Lists.Len(name) == 0 {
+			if len(args) == 2 {
+				return func(w *resp.Writer) { w.NullArray() }
+			}
+			return resp.Nil
+		}
+
+		if n := int64(ks.d.resp.Lists.Len(name)); count > n {
+			count = n
+		}
+		popped := ks.d.resp.Lists.Pop(name, int(count), head)
+		if len(args) == 2 {
+			return resp.BulkArray(popped)
+		}
+		return resp.Bulk(popped[0])
+	}
+}
+
+func respLLen(ks *keyspace, args [][]byte) resp.Reply {
+	key := string(args[0])
+	if err := ks.check(key, typeList); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	return resp.Integer(int64(ks.d.resp.Lists.Len(ks.collection(typeList, key))))
+}
+
+func respLRange(ks *keyspace, args [][]byte) resp.Reply {
+	start, okStart := resp.ParseInt(args[1])
+	stop, okStop := resp.ParseInt(args[2])
+	if !okStart || !okStop {
+		return resp.Errorf(notInteger)
+	}
+
+	key := string(args[0])
+	if err := ks.check(key, typeList); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	name := ks.collection(typeList, key)
+	from, to, ok := span(start, stop, int64(ks.d.resp.Lists.Len(name)))
+	if !ok {
+		return resp.BulkArray(nil)
+	}
+	return resp.BulkArray(ks.d.resp.Lists.Range(name, int(from), int(to)))
+}
+
+func respLIndex(ks *keyspace, args [][]byte) resp.Reply {
+	index, ok := resp.ParseInt(args[1])
+	if !ok {
+		return resp.Errorf(notInteger)
+	}
+
+	key := string(args[0])
+	if err := ks.check(key, typeList); err != nil {
+		return resp.Errorf(err.Error())
+	}
+	name := ks.collection(typeList, key)
+	if index < 0 {
+		index += int64(ks.d.resp.Lists.Len(name))
+	}
+	if value, ok := ks.d.resp.Lists.Index(name, int(index)); ok {
+		return resp.Bulk(value)
+	}
+	return resp.Nil
+}
@@ -0,0 +1,89 @@
+package resp
+
+import "fmt"
+
+// Reply is the reply of a command, written once the command is done.
+type Reply func(w *Writer)
+
+// OK replies with the OK status.
+var OK Reply = func(w *Writer) {
+	w.SimpleString("OK")
+}
+
+// Nil replies with a null.
+var Nil Reply = func(w *Writer) {
+	w.Null()
+}
+
+// Errorf replies with an error. The message starts with an error code, such as "ERR" or "WRONGTYPE".
+func Errorf(format string, args ...interface{}) Reply {
+	msg := fmt.Sprintf(format, args...)
+	return func(w *Writer) {
+		w.Error(msg)
+	}
+}
+
+// Integer replies with an integer.
+func Integer(n int64) Reply {
+	return func(w *Writer) {
+		w.Integer(n)
+	}
+}
+
+// Bulk replies with a bulk string.
+func Bulk(b []byte) Reply {
+	return func(w *Writer) {
+		w.Bulk(b)
+	}
+}
+
+// Double replies with a floating point number.
+func Double(f float64) Reply {
+	return func(w *Writer) {
+		w.Double(f)
+	}
+}
+
+// Array replies with an array of replies.
+func Array(replies []Reply) Reply {
+	return func(w *Writer) {
+		w.Array(len(replies))
+		for _, reply := range replies {
+			reply(w)
+		}
+	}
+}
+
+// BulkArray replies with an array of bulk strings. Nil elements are written as nulls.
+func BulkArray(values [][]byte) Reply {
+	return func(w *Writer) {
+		w.Array(len(values))
+		for _, v := range values {
+			if v == nil {
+				w.Null()
+			} else {
+				w.Bulk(v)
+			}
+		}
+	}
+}
+
+// BulkSet replies with a set of bulk strings.
+func BulkSet(values [][]byte) Reply {
+	return func(w *Writer) {
+		w.Set(len(values))
+		for _, v := range values {
+			w.Bulk(v)
+		}
+	}
+}
+
+// BulkMap replies with a map of bulk strings, given as keys followed by their values.
+func BulkMap(pairs [][]byte) Reply {
+	return func(w *Writer) {
+		w.Map(len(pairs) / 2)
+		for _, v := range pairs {
+			w.Bulk(v)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+// Package resp implements the Redis serialization protocol, RESP2 and RESP3, for clients of a node to use
+// Redis tooling. It reads commands and writes replies, leaving what commands do to a Handler.
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+const (
+	// MaxBulk is the maximum length of a bulk string sent by a client.
+	MaxBulk = 512 << 20
+	// MaxArgs is the maximum number of arguments of a command.
+	MaxArgs = 1 << 20
+	// maxInline is the maximum length of an inline command.
+	maxInline = 64 << 10
+)
+
+var ErrProtocol = errors.New("protocol error")
+
+// Reader reads commands sent by a client, either as arrays of bulk strings or inline.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader creates a reader of commands from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Buffered returns the number of bytes received and not read yet, such as pipelined commands.
+func (r *Reader) Buffered() int {
+	return r.r.Buffered()
+}
+
+// ReadCommand reads the arguments of the next command, the first one being its name. Empty inline commands
+// and empty arrays are skipped, as Redis does.
+func (r *Reader) ReadCommand() ([][]byte, error) {
+	for {
+		line, err := r.line()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) > 0 && line[0] == '*' {
+			args, err := r.array(line)
+			if err != nil || len(args) > 0 {
+				return args, err
+			}
+			continue
+		}
+		if args := bytes.Fields(line); len(args) > 0 {
+			return args, nil
+		}
+	}
+}
+
+func (r *Reader) array(line []byte) ([][]byte, error) {
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n < 0 || n > MaxArgs {
+		return nil, fmt.Errorf("%w: invalid multibulk length", ErrProtocol)
+	}
+
+	args := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := r.line()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, fmt.Errorf("%w: expected '$', got '%s'", ErrProtocol, line)
+		}
+		length, err := strconv.Atoi(string(line[1:]))
+		if err != nil || length < 0 || length > MaxBulk {
+			return nil, fmt.Errorf("%w: invalid bulk length", ErrProtocol)
+		}
+
+		arg := make([]byte, length+2)
+		if _, err := io.ReadFull(r.r, arg); err != nil {
+			return nil, err
+		}
+		if arg[length] != '\r' || arg[length+1] != '\n' {
+			return nil, fmt.Errorf("%w: bulk string is not terminated", ErrProtocol)
+		}
+		args = append(args, arg[:length])
+	}
+
+	return args, nil
+}
+
+// line reads a line without its terminating CRLF. A bare LF is accepted for inline commands.
+func (r *Reader) line() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.r.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxInline {
+			return nil, fmt.Errorf("%w: too big inline request", ErrProtocol)
+		}
+		if !isPrefix {
+			return line, nil
+		}
+	}
+}
+
+// Writer writes replies in the protocol version of a connection. Types RESP2 lacks are written as their
+// closest RESP2 type: maps and sets as arrays, doubles as bulk strings and booleans as integers.
+type Writer struct {
+	w *bufio.Writer
+	// Protocol is 2 or 3.
+	Protocol int
+}
+
+// NewWriter creates a writer of RESP2 replies to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w), Protocol: 2}
+}
+
+// Flush sends the written replies.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// SimpleString writes a status reply, such as OK.
+func (w *Writer) SimpleString(s string) {
+	w.w.WriteByte('+')
+	w.w.WriteString(s)
+	w.w.WriteString("\r\n")
+}
+
+// Error writes an error reply. msg starts with an error code, such as "ERR" or "WRONGTYPE".
+func (w *Writer) Error(msg string) {
+	w.w.WriteByte('-')
+	w.w.WriteString(msg)
+	w.w.WriteString("\r\n")
+}
+
+// Integer writes an integer reply.
+func (w *Writer) Integer(n int64) {
+	w.header(':', int(n))
+}
+
+// Bulk writes a bulk string reply.
+func (w *Writer) Bulk(b []byte) {
+	w.header('$', len(b))
+	w.w.Write(b)
+	w.w.WriteString("\r\n")
+}
+
+// Null writes a null reply, which RESP2 sends as a null bulk string.
+func (w *Writer) Null() {
+	if w.Protocol >= 3 {
+		w.w.WriteString("_\r\n")
+		return
+	}
+	w.w.WriteString("$-1\r\n")
+}
+
+// NullArray writes a null reply where RESP2 expects a null array, such as an aborted EXEC.
+func (w *Writer) NullArray() {
+	if w.Protocol >= 3 {
+		w.w.WriteString("_\r\n")
+		return
+	}
+	w.w.WriteString("*-1\r\n")
+}
+
+// Array starts an array reply of n elements, to be written next.
+func (w *Writer) Array(n int) {
+	w.header('*', n)
+}
+
+// Map starts a map reply of n pairs, to be written next as keys followed by their values.
+func (w *Writer) Map(n int) {
+	if w.Protocol >= 3 {
+		w.header('%', n)
+		return
+	}
+	w.header('*', 2*n)
+}
+
+// Set starts a set reply of n elements, to be written next.
+func (w *Writer) Set(n int) {
+	if w.Protocol >= 3 {
+		w.header('~', n)
+		return
+	}
+	w.header('*', n)
+}
+
+// Double writes a floating point reply.
+func (w *Writer) Double(f float64) {
+	if w.Protocol >= 3 {
+		w.w.WriteByte(',')
+		w.w.WriteString(FormatFloat(f))
+		w.w.WriteString("\r\n")
+		return
+	}
+	w.Bulk([]byte(FormatFloat(f)))
+}
+
+// Boolean writes a boolean reply.
+func (w *Writer) Boolean(b bool) {
+	switch {
+	case w.Protocol < 3 && b:
+		w.Integer(1)
+	case w.Protocol < 3:
+		w.Integer(0)
+	case b:
+		w.w.WriteString("#t\r\n")
+	default:
+		w.w.WriteString("#f\r\n")
+	}
+}
+
+func (w *Writer) header(kind byte, n int) {
+	w.w.WriteByte(kind)
+	w.w.WriteString(strconv.Itoa(n))
+	w.w.WriteString("\r\n")
+}
+
+// FormatFloat formats f the way Redis does, as the shortest decimal reading back as f, or inf and -inf.
+func FormatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// ParseFloat parses a float argument, accepting inf and -inf but not NaN.
+func ParseFloat(b []byte) (float64, bool) {
+	f, err := strconv.ParseFloat(string(b), 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+// ParseInt parses an integer argument.
+func ParseInt(b []byte) (int64, bool) {
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	return n, err == nil
+}
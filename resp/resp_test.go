@@ -0,0 +1,245 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// store is a handler keeping strings in memory, applying batches under a single lock.
+type store struct {
+	mutex  sync.Mutex
+	values map[string][]byte
+}
+
+func (s *store) Command(args [][]byte) Reply {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.run(args)
+}
+
+func (s *store) Queue(args [][]byte) Reply {
+	switch strings.ToUpper(string(args[0])) {
+	case "GET", "SET":
+		return nil
+	default:
+		return Errorf("ERR unknown command '%s'", args[0])
+	}
+}
+
+func (s *store) Exec(commands [][][]byte) Reply {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	replies := make([]Reply, 0, len(commands))
+	for _, args := range commands {
+		replies = append(replies, s.run(args))
+	}
+	return Array(replies)
+}
+
+func (s *store) run(args [][]byte) Reply {
+	switch strings.ToUpper(string(args[0])) {
+	case "GET":
+		if v, ok := s.values[string(args[1])]; ok {
+			return Bulk(v)
+		}
+		return Nil
+	case "SET":
+		s.values[string(args[1])] = args[2]
+		return OK
+	case "MGET":
+		var values [][]byte
+		for _, key := range args[1:] {
+			values = append(values, s.values[string(key)])
+		}
+		return BulkArray(values)
+	default:
+		return Errorf("ERR unknown command '%s'", args[0])
+	}
+}
+
+// client talks to a server over a raw TCP connection.
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T) *client {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go Serve(l, &store{values: make(map[string][]byte)})
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// send writes raw bytes and expects the server to answer with exactly expected.
+func (c *client) send(raw, expected string) {
+	c.t.Helper()
+
+	if _, err := io.WriteString(c.conn, raw); err != nil {
+		c.t.Fatalf("failed to write %v", err)
+	}
+	got := make([]byte, len(expected))
+	if _, err := io.ReadFull(c.r, got); err != nil {
+		c.t.Fatalf("failed to read %q, got %q: %v", expected, got, err)
+	}
+	if string(got) != expected {
+		c.t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func command(args ...string) string {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		b.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	return b.String()
+}
+
+func TestCommands(t *testing.T) {
+	c := dial(t)
+
+	c.send(command("PING"), "+PONG\r\n")
+	c.send(command("SET", "greeting", "hello world"), "+OK\r\n")
+	c.send(command("GET", "greeting"), "$11\r\nhello world\r\n")
+	c.send(command("GET", "missing"), "$-1\r\n")
+	c.send(command("ECHO"), "-ERR wrong number of arguments for 'echo' command\r\n")
+	c.send("PING\r\n", "+PONG\r\n")
+	c.send("SET inline value\nGET inline\r\n", "+OK\r\n$5\r\nvalue\r\n")
+}
+
+func TestPipeline(t *testing.T) {
+	c := dial(t)
+
+	c.send(command("SET", "a", "1")+command("SET", "b", "2")+command("MGET", "a", "b", "c"),
+		"+OK\r\n+OK\r\n*3\r\n$1\r\n1\r\n$1\r\n2\r\n$-1\r\n")
+}
+
+func TestProtocolVersions(t *testing.T) {
+	c := dial(t)
+
+	c.send(command("HELLO", "4"), "-NOPROTO unsupported protocol version\r\n")
+	c.send(command("HELLO", "3"), "%5\r\n$6\r\nserver\r\n$6\r\ndemory\r\n$5\r\nproto\r\n:3\r\n"+
+		"$4\r\nmode\r\n$10\r\nstandalone\r\n$4\r\nrole\r\n$6\r\nmaster\r\n$7\r\nmodules\r\n*0\r\n")
+	c.send(command("GET", "missing"), "_\r\n")
+	c.send(command("HELLO", "2"), "*10\r\n$6\r\nserver\r\n$6\r\ndemory\r\n$5\r\nproto\r\n:2\r\n"+
+		"$4\r\nmode\r\n$10\r\nstandalone\r\n$4\r\nrole\r\n$6\r\nmaster\r\n$7\r\nmodules\r\n*0\r\n")
+	c.send(command("GET", "missing"), "$-1\r\n")
+}
+
+func TestMulti(t *testing.T) {
+	c := dial(t)
+
+	c.send(command("EXEC"), "-ERR EXEC without MULTI\r\n")
+	c.send(command("MULTI"), "+OK\r\n")
+	c.send(command("MULTI"), "-ERR MULTI calls can not be nested\r\n")
+	c.send(command("SET", "a", "1"), "+QUEUED\r\n")
+	c.send(command("GET", "a"), "+QUEUED\r\n")
+	c.send(command("EXEC"), "*2\r\n+OK\r\n$1\r\n1\r\n")
+
+	c.send(command("MULTI"), "+OK\r\n")
+	c.send(command("SET", "a", "2"), "+QUEUED\r\n")
+	c.send(command("DISCARD"), "+OK\r\n")
+	c.send(command("GET", "a"), "$1\r\n1\r\n")
+
+	c.send(command("MULTI"), "+OK\r\n")
+	c.send(command("SET", "a", "3"), "+QUEUED\r\n")
+	c.send(command("FLUSHALL"), "-ERR unknown command 'FLUSHALL'\r\n")
+	c.send(command("EXEC"), "-EXECABORT Transaction discarded because of previous errors.\r\n")
+	c.send(command("GET", "a"), "$1\r\n1\r\n")
+}
+
+func TestProtocolError(t *testing.T) {
+	c := dial(t)
+
+	c.send("*1\r\n+PING\r\n", "-ERR protocol error: expected '$', got '+PING'\r\n")
+	if _, err := c.r.ReadByte(); err != io.EOF {
+		t.Errorf("expected the connection to be closed, got %v", err)
+	}
+}
+
+func TestEmptyArrays(t *testing.T) {
+	c := dial(t)
+
+	c.send("*0\r\n"+command("PING"), "+PONG\r\n")
+	c.send("*-1\r\n", "-ERR protocol error: invalid multibulk length\r\n")
+	if _, err := c.r.ReadByte(); err != io.EOF {
+		t.Errorf("expected the connection to be closed, got %v", err)
+	}
+}
+
+func TestReadCommand(t *testing.T) {
+	r := NewReader(strings.NewReader("*0\r\n*1\r\n$4\r\nPING\r\n*-1\r\n"))
+	if args, err := r.ReadCommand(); err != nil || len(args) != 1 || string(args[0]) != "PING" {
+		t.Errorf("expected empty arrays to be skipped, got %q, error %v", args, err)
+	}
+	if _, err := r.ReadCommand(); !errors.Is(err, ErrProtocol) {
+		t.Errorf("expected %v, got %v", ErrProtocol, err)
+	}
+}
+
+func TestQuit(t *testing.T) {
+	c := dial(t)
+
+	c.send(command("QUIT"), "+OK\r\n")
+	if _, err := c.r.ReadByte(); err != io.EOF {
+		t.Errorf("expected the connection to be closed, got %v", err)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var b bytes.Buffer
+	w := NewWriter(&b)
+	w.Double(1.5)
+	w.Boolean(true)
+	w.Set(1)
+	w.Bulk([]byte("member"))
+	w.Protocol = 3
+	w.Double(-2)
+	w.Boolean(false)
+	w.Set(0)
+	w.NullArray()
+	w.Flush()
+
+	expected := "$3\r\n1.5\r\n:1\r\n*1\r\n$6\r\nmember\r\n,-2\r\n#f\r\n~0\r\n_\r\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestFloats(t *testing.T) {
+	for _, arg := range []string{"inf", "-inf", "+inf", "1e3", "0.1"} {
+		f, ok := ParseFloat([]byte(arg))
+		if !ok {
+			t.Errorf("expected %s to parse", arg)
+		}
+		if back, _ := ParseFloat([]byte(FormatFloat(f))); back != f {
+			t.Errorf("expected %s to format back to %v, got %s", arg, f, FormatFloat(f))
+		}
+	}
+	if _, ok := ParseFloat([]byte("nan")); ok {
+		t.Error("expected nan to be rejected")
+	}
+}
@@ -0,0 +1,197 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+)
+
+// Handler runs the commands of clients. Connection commands, such as HELLO, PING or MULTI, are handled by
+// Serve itself.
+type Handler interface {
+	// Command runs a command and returns its reply.
+	Command(args [][]byte) Reply
+	// Queue checks a command sent within MULTI, and returns an error reply if it could never run. The
+	// whole batch is discarded then.
+	Queue(args [][]byte) Reply
+	// Exec runs the commands queued within MULTI as an atomic batch, and returns an array of their replies,
+	// or an error reply if none of them ran.
+	Exec(commands [][][]byte) Reply
+}
+
+// Serve accepts connections on l and serves them until l is closed.
+func Serve(l net.Listener, handler Handler) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go serve(c, handler)
+	}
+}
+
+// conn is the state of a client connection.
+type conn struct {
+	r       *Reader
+	w       *Writer
+	handler Handler
+	// queue holds the commands sent since MULTI, and is nil outside of MULTI.
+	queue [][][]byte
+	// aborted is set once a queued command is rejected.
+	aborted bool
+}
+
+func serve(c net.Conn, handler Handler) {
+	defer c.Close()
+
+	cn := &conn{r: NewReader(c), w: NewWriter(c), handler: handler}
+	for {
+		args, err := cn.r.ReadCommand()
+		if err != nil {
+			if errors.Is(err, ErrProtocol) {
+				cn.w.Error("ERR " + err.Error())
+				cn.w.Flush()
+			} else if !errors.Is(err, io.EOF) {
+				log.Printf("resp connection error %v", err)
+			}
+			return
+		}
+
+		quit := cn.run(args)
+		// Pipelined commands are answered together.
+		if cn.r.Buffered() == 0 || quit {
+			if err := cn.w.Flush(); err != nil || quit {
+				return
+			}
+		}
+	}
+}
+
+// run writes the reply of a command, and reports whether the client asked to close the connection.
+func (cn *conn) run(args [][]byte) bool {
+	if len(args) == 0 {
+		return false
+	}
+	name := strings.ToUpper(string(args[0]))
+	if cn.queue != nil {
+		switch name {
+		case "EXEC", "DISCARD", "MULTI", "QUIT", "RESET":
+		default:
+			if reply := cn.handler.Queue(args); reply != nil {
+				cn.aborted = true
+				reply(cn.w)
+				return false
+			}
+			cn.queue = append(cn.queue, args)
+			cn.w.SimpleString("QUEUED")
+			return false
+		}
+	}
+
+	switch name {
+	case "PING":
+		switch len(args) {
+		case 1:
+			cn.w.SimpleString("PONG")
+		case 2:
+			cn.w.Bulk(args[1])
+		default:
+			ArityError(args[0])(cn.w)
+		}
+	case "ECHO":
+		if len(args) != 2 {
+			ArityError(args[0])(cn.w)
+			break
+		}
+		cn.w.Bulk(args[1])
+	case "HELLO":
+		cn.hello(args)
+	case "SELECT":
+		if len(args) != 2 {
+			ArityError(args[0])(cn.w)
+		} else if string(args[1]) != "0" {
+			cn.w.Error("ERR DB index is out of range")
+		} else {
+			cn.w.SimpleString("OK")
+		}
+	case "CLIENT":
+		cn.w.SimpleString("OK")
+	case "COMMAND":
+		cn.w.Array(0)
+	case "QUIT":
+		cn.w.SimpleString("OK")
+		return true
+	case "RESET":
+		cn.queue, cn.aborted = nil, false
+		cn.w.Protocol = 2
+		cn.w.SimpleString("RESET")
+	case "MULTI":
+		if cn.queue != nil {
+			cn.w.Error("ERR MULTI calls can not be nested")
+			break
+		}
+		cn.queue = [][][]byte{}
+		cn.w.SimpleString("OK")
+	case "DISCARD":
+		if cn.queue == nil {
+			cn.w.Error("ERR DISCARD without MULTI")
+			break
+		}
+		cn.queue, cn.aborted = nil, false
+		cn.w.SimpleString("OK")
+	case "EXEC":
+		if cn.queue == nil {
+			cn.w.Error("ERR EXEC without MULTI")
+			break
+		}
+		queue, aborted := cn.queue, cn.aborted
+		cn.queue, cn.aborted = nil, false
+		if aborted {
+			cn.w.Error("EXECABORT Transaction discarded because of previous errors.")
+			break
+		}
+		cn.handler.Exec(queue)(cn.w)
+	default:
+		cn.handler.Command(args)(cn.w)
+	}
+
+	return false
+}
+
+// hello switches the protocol version of the connection and describes the server.
+func (cn *conn) hello(args [][]byte) {
+	if len(args) > 1 {
+		switch string(args[1]) {
+		case "2":
+			cn.w.Protocol = 2
+		case "3":
+			cn.w.Protocol = 3
+		default:
+			cn.w.Error("NOPROTO unsupported protocol version")
+			return
+		}
+	}
+
+	cn.w.Map(5)
+	cn.w.Bulk([]byte("server"))
+	cn.w.Bulk([]byte("demory"))
+	cn.w.Bulk([]byte("proto"))
+	cn.w.Integer(int64(cn.w.Protocol))
+	cn.w.Bulk([]byte("mode"))
+	cn.w.Bulk([]byte("standalone"))
+	cn.w.Bulk([]byte("role"))
+	cn.w.Bulk([]byte("master"))
+	cn.w.Bulk([]byte("modules"))
+	cn.w.Array(0)
+}
+
+// ArityError replies that a command got a wrong number of arguments.
+func ArityError(name []byte) Reply {
+	return Errorf("ERR wrong number of arguments for '%s' command", bytes.ToLower(name))
+}
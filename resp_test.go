@@ -0,0 +1,246 @@
+package demory
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/node"
+	"github.com/huseyinbabal/demory/resp"
+	"google.golang.org/grpc"
+)
+
+// written renders a reply as a client receives it over RESP2.
+func written(reply resp.Reply) string {
+	var b bytes.Buffer
+	w := resp.NewWriter(&b)
+	reply(w)
+	w.Flush()
+	return b.String()
+}
+
+func command(args ...string) [][]byte {
+	result := make([][]byte, len(args))
+	for i, arg := range args {
+		result[i] = []byte(arg)
+	}
+	return result
+}
+
+// call runs a command given as one string of space separated arguments.
+func call(h respHandler, line string) string {
+	return written(h.Command(command(strings.Fields(line)...)))
+}
+
+// exec runs commands given as strings of space separated arguments as a MULTI batch.
+func exec(h respHandler, lines ...string) string {
+	commands := make([][][]byte, len(lines))
+	for i, line := range lines {
+		commands[i] = command(strings.Fields(line)...)
+	}
+	return written(h.Exec(commands))
+}
+
+func expectReplies(t *testing.T, h respHandler, calls [][2]string) {
+	t.Helper()
+	for _, c := range calls {
+		if reply := call(h, c[0]); reply != c[1] {
+			t.Errorf("%s: expected %q, got %q", c[0], c[1], reply)
+		}
+	}
+}
+
+func TestRespExec(t *testing.T) {
+	d := newTestNode(t, node.Config{MaxMemory: 64})
+	h := respHandler{d: d}
+
+	// The entry a new leader appends may come after it takes the lead.
+	if err := d.fsm.Raft.Barrier(0).Error(); err != nil {
+		t.Fatal(err)
+	}
+	last := d.fsm.Raft.LastIndex()
+	reply := exec(h, "SET counter 1", "INCR counter", "LPUSH jobs a b", "HSET user name ada", "INCR user", "GET counter")
+	if reply != "*6\r\n+OK\r\n:2\r\n:2\r\n:1\r\n-"+wrongType+"\r\n$1\r\n2\r\n" {
+		t.Errorf("unexpected replies %q", reply)
+	}
+	if d.fsm.Raft.LastIndex() != last+1 {
+		t.Errorf("expected the batch to be applied as one command, %d entries appended", d.fsm.Raft.LastIndex()-last)
+	}
+	if reply := call(h, "HLEN user"); reply != ":1\r\n" {
+		t.Errorf("expected the other commands of a batch to apply despite a failing one, got %q", reply)
+	}
+
+	// A batch that does not fit is rejected as a whole.
+	reply = exec(h, "SET small x", "SET large "+strings.Repeat("x", 64))
+	if !strings.HasPrefix(reply, "-OOM") {
+		t.Errorf("expected the batch to be rejected, got %q", reply)
+	}
+	if reply := call(h, "EXISTS small"); reply != ":0\r\n" {
+		t.Errorf("expected no command of a rejected batch to be applied, got %q", reply)
+	}
+
+	last = d.fsm.Raft.LastIndex()
+	if reply := exec(h, "GET counter", "LRANGE jobs 0 -1"); reply != "*2\r\n$1\r\n2\r\n*2\r\n$1\r\nb\r\n$1\r\na\r\n" {
+		t.Errorf("unexpected replies %q", reply)
+	}
+	if d.fsm.Raft.LastIndex() != last {
+		t.Error("expected a batch that only reads not to be replicated")
+	}
+}
+
+func TestRespTTL(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	h := respHandler{d: d}
+
+	expectReplies(t, h, [][2]string{
+		{"SET session token EX 100", "+OK\r\n"},
+		{"TTL session", ":100\r\n"},
+		{"PEXPIRE session 5000", ":1\r\n"},
+	})
+	// The TTL runs from the time the leader proposed the write.
+	if reply := call(h, "PTTL session"); reply < ":4900\r\n" || reply > ":5000\r\n" {
+		t.Errorf("expected about 5000 milliseconds left, got %q", reply)
+	}
+	expectReplies(t, h, [][2]string{
+		{"INCR session", "-" + notInteger + "\r\n"},
+		{"APPEND session s", ":6\r\n"},
+		{"TTL session", ":5\r\n"},
+		{"PERSIST session", ":1\r\n"},
+		{"TTL session", ":-1\r\n"},
+		{"GET session", "$6\r\ntokens\r\n"},
+		{"SETEX session 10 other", "+OK\r\n"},
+		{"TTL session", ":10\r\n"},
+		{"EXPIRE session 0", ":1\r\n"},
+		{"GET session", "$-1\r\n"},
+		{"TTL session", ":-2\r\n"},
+		{"SADD tags go", ":1\r\n"},
+		{"TTL tags", ":-1\r\n"},
+		{"EXPIRE tags 10", ":0\r\n"},
+	})
+
+	// Strings with a TTL are kept in the cache of the keyspace, and expire with it.
+	call(h, "SET session token PX 100")
+	apply(t, d, fsm.ApplyRequest{
+		Type: fsm.CacheExpire, Name: defaultRespKeyspace, Keys: []string{"session"},
+		Time: time.Now().Add(time.Second).UnixNano(),
+	})
+	if reply := call(h, "EXISTS session"); reply != ":0\r\n" {
+		t.Errorf("expected the string to expire, got %q", reply)
+	}
+}
+
+func TestRespVolatileKeys(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	h := respHandler{d: d}
+
+	commands := make([]string, 1001)
+	for i := range commands {
+		commands[i] = fmt.Sprintf("SETEX key%d 100 v", i)
+	}
+	exec(h, commands...)
+
+	if reply := call(h, "GET key0"); reply != "$1\r\nv\r\n" {
+		t.Errorf("expected no volatile key to be dropped, got %q", reply)
+	}
+	if reply := call(h, "EXISTS key0 key1000"); reply != ":2\r\n" {
+		t.Errorf("expected every volatile key to exist, got %q", reply)
+	}
+}
+
+func TestRespWrongType(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	h := respHandler{d: d}
+
+	// Maps of gRPC clients do not share the keyspace, even if they are named after one of its keys.
+	apply(t, d, fsm.ApplyRequest{Type: fsm.MapPut, Name: "user", Key: "name", Value: []byte("grpc")})
+
+	expectReplies(t, h, [][2]string{
+		{"TYPE user", "+none\r\n"},
+		{"HSET user name ada", ":1\r\n"},
+		{"TYPE user", "+hash\r\n"},
+		{"GET user", "-" + wrongType + "\r\n"},
+		{"LPUSH user x", "-" + wrongType + "\r\n"},
+		{"SADD user x", "-" + wrongType + "\r\n"},
+		{"ZADD user 1 x", "-" + wrongType + "\r\n"},
+		{"RPUSH jobs a", ":1\r\n"},
+		{"HGET jobs a", "-" + wrongType + "\r\n"},
+		{"ZADD ranks 1 a", ":1\r\n"},
+		{"SISMEMBER ranks a", "-" + wrongType + "\r\n"},
+		{"SADD tags go", ":1\r\n"},
+		{"ZSCORE tags go", "-" + wrongType + "\r\n"},
+		{"SET tags text", "+OK\r\n"},
+		{"TYPE tags", "+string\r\n"},
+		{"SMEMBERS tags", "-" + wrongType + "\r\n"},
+		{"DEL user jobs ranks", ":3\r\n"},
+		{"EXISTS user jobs ranks", ":0\r\n"},
+	})
+
+	if value := d.hashMap.Get("user", "name"); string(value) != "grpc" {
+		t.Errorf("expected the map of the gRPC client to be kept, got %q", value)
+	}
+}
+
+// newTestFollower starts a node serving raft over gRPC, joins it to the cluster of leader, and waits until it
+// knows the leader.
+func newTestFollower(t *testing.T, leader *Demory, config node.Config) *Demory {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.NodeID = fmt.Sprintf("demory-test-%d", time.Now().UnixNano())
+	config.NodeAddress = listener.Addr().String()
+	d := newDemory(&config)
+	server := grpc.NewServer()
+	d.fsm.Manager.Register(server)
+	go server.Serve(listener)
+	t.Cleanup(func() {
+		d.fsm.Raft.Shutdown().Error()
+		server.Stop()
+		os.RemoveAll(filepath.Join("/tmp", config.NodeID))
+	})
+
+	future := leader.fsm.Raft.AddVoter(raft.ServerID(config.NodeID), raft.ServerAddress(config.NodeAddress), 0, 0)
+	if err := future.Error(); err != nil {
+		t.Fatalf("failed to join %v", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for d.fsm.Raft.Leader() == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("node did not find the leader")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return d
+}
+
+func TestRespMoved(t *testing.T) {
+	leader := newTestNode(t, node.Config{})
+	call(respHandler{d: leader}, "SET greeting hello")
+	follower := newTestFollower(t, leader, node.Config{RespPort: 6380})
+	h := respHandler{d: follower}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for call(h, "GET greeting") != "$5\r\nhello\r\n" {
+		if time.Now().After(deadline) {
+			t.Fatal("expected reads to be served by the follower")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	moved := "-MOVED 0 127.0.0.1:6380\r\n"
+	if reply := call(h, "SET greeting hi"); reply != moved {
+		t.Errorf("expected writes to be redirected to the leader, got %q", reply)
+	}
+	if reply := exec(h, "GET greeting", "LPUSH jobs a"); reply != moved {
+		t.Errorf("expected batches that write to be redirected to the leader, got %q", reply)
+	}
+}
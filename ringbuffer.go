@@ -0,0 +1,219 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/huseyinbabal/demory/ds/ringbuffer"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/structure"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ structure.Waker = &ringbuffer.Buffers{}
+
+func init() {
+	structure.Register(newRingStructure)
+}
+
+// ringStructure serves and applies ring buffers.
+type ringStructure struct {
+	host    structure.Host
+	buffers *ringbuffer.Buffers
+}
+
+func newRingStructure(host structure.Host) structure.Structure {
+	return &ringStructure{host: host, buffers: ringbuffer.New()}
+}
+
+func (s *ringStructure) Kind() string {
+	return "rings"
+}
+
+func (s *ringStructure) Commands() []fsm.CommandType {
+	return []fsm.CommandType{fsm.RingCreate, fsm.RingDestroy, fsm.RingAdd}
+}
+
+func (s *ringStructure) Register(server grpc.ServiceRegistrar) {
+	rpc.RegisterRingBufferServer(server, s)
+}
+
+func (s *ringStructure) Snapshot() ([]byte, error) {
+	return json.Marshal(s.buffers)
+}
+
+func (s *ringStructure) Restore(data []byte) (func(), error) {
+	buffers := ringbuffer.New()
+	if err := structure.Unmarshal(data, buffers); err != nil {
+		return nil, err
+	}
+	return func() {
+		previous := s.buffers
+		s.buffers = buffers
+		previous.Wake()
+	}, nil
+}
+
+func (s *ringStructure) Stats() structure.Stats {
+	return structure.Stats{Bytes: s.buffers.Bytes()}
+}
+
+func (s *ringStructure) Destroy(name string) bool {
+	return s.buffers.Destroy(name)
+}
+
+// ringArgs are the arguments of replicated ring buffer commands.
+type ringArgs struct {
+	Capacity int               `json:"capacity,omitempty"`
+	Policy   ringbuffer.Policy `json:"policy,omitempty"`
+	TTL      int64             `json:"ttl,omitempty"`
+	Values   [][]byte          `json:"values,omitempty"`
+}
+
+// RingCreate creates a ring buffer with a fixed capacity and an overflow policy.
+func (s *ringStructure) RingCreate(ctx context.Context, req *rpc.RingCreateRequest) (*rpc.Empty, error) {
+	if err := ringbuffer.Validate(req.Capacity, req.Policy); err != nil {
+		return nil, ringError(err)
+	}
+	if req.TTL < 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl must not be negative")
+	}
+
+	args := ringArgs{Capacity: req.Capacity, Policy: req.Policy, TTL: int64(time.Duration(req.TTL) * time.Millisecond)}
+	_, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.RingCreate, Name: req.Name}, args)
+	return new(rpc.Empty), err
+}
+
+// RingDestroy removes a ring buffer with its items.
+func (s *ringStructure) RingDestroy(ctx context.Context, req *rpc.RingRequest) (*rpc.RingDestroyResponse, error) {
+	data, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.RingDestroy, Name: req.Name}, ringArgs{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.RingDestroyResponse{Destroyed: data.(bool)}, nil
+}
+
+// RingAdd appends items to a ring buffer and returns the sequence of the first one.
+func (s *ringStructure) RingAdd(ctx context.Context, req *rpc.RingAddRequest) (*rpc.RingAddResponse, error) {
+	if len(req.Values) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "values are required")
+	}
+
+	data, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.RingAdd, Name: req.Name}, ringArgs{Values: req.Values})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.RingAddResponse{Sequence: data.(uint64)}, nil
+}
+
+// RingRead returns the items of a ring buffer from a sequence on, waiting for new items if asked to.
+func (s *ringStructure) RingRead(ctx context.Context, req *rpc.RingReadRequest) (*rpc.RingReadResponse, error) {
+	response := &rpc.RingReadResponse{Next: req.Sequence}
+	signal := func() <-chan struct{} {
+		return s.buffers.Signal(req.Name)
+	}
+
+	err := structure.Block(ctx, s.host, signal, req.Block, func() (bool, error) {
+		var err error
+		s.host.Read(func() {
+			now := time.Now().UnixNano()
+			response.Items, err = s.buffers.Read(req.Name, response.Next, req.Count, now)
+			if errors.Is(err, ringbuffer.ErrStale) && req.SkipStale {
+				var info ringbuffer.Info
+				if info, err = s.buffers.Info(req.Name, now); err != nil {
+					return
+				}
+				response.Lost += info.Head - response.Next
+				response.Next = info.Head
+				response.Items, err = s.buffers.Read(req.Name, response.Next, req.Count, now)
+			}
+		})
+		if err != nil {
+			return false, ringError(err)
+		}
+		response.Next += uint64(len(response.Items))
+		return len(response.Items) > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// RingInfo describes a ring buffer.
+func (s *ringStructure) RingInfo(ctx context.Context, req *rpc.RingRequest) (*rpc.RingInfoResponse, error) {
+	response := &rpc.RingInfoResponse{}
+	var err error
+	s.host.Read(func() {
+		response.Info, err = s.buffers.Info(req.Name, time.Now().UnixNano())
+	})
+	if err != nil {
+		return nil, ringError(err)
+	}
+
+	return response, nil
+}
+
+func (s *ringStructure) Apply(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args ringArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	var data interface{}
+	var err error
+	switch request.Type {
+	case fsm.RingCreate:
+		err = s.buffers.Create(request.Name, args.Capacity, args.Policy, args.TTL)
+	case fsm.RingDestroy:
+		data = s.buffers.Destroy(request.Name)
+	case fsm.RingAdd:
+		data, err = s.buffers.Add(request.Name, args.Values, request.Time)
+	}
+	if err != nil {
+		return fsm.ApplyResponse{Error: ringError(err)}
+	}
+
+	return fsm.ApplyResponse{Data: data}
+}
+
+// Growth returns the number of bytes a ring buffer command adds, not counting the items it overwrites.
+func (s *ringStructure) Growth(request fsm.ApplyRequest) int64 {
+	var args ringArgs
+	if json.Unmarshal(request.Args, &args) != nil {
+		return 0
+	}
+
+	growth := int64(len(request.Name))
+	if request.Type == fsm.RingAdd {
+		growth = 0
+		for _, value := range args.Values {
+			growth += int64(len(value))
+		}
+	}
+	return growth
+}
+
+// ringError converts errors of the ringbuffer package to gRPC statuses.
+func ringError(err error) error {
+	switch {
+	case errors.Is(err, ringbuffer.ErrNoBuffer):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ringbuffer.ErrExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ringbuffer.ErrFull):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, ringbuffer.ErrStale), errors.Is(err, ringbuffer.ErrSequence):
+		return status.Error(codes.OutOfRange, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
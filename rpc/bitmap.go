@@ -0,0 +1,135 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/huseyinbabal/demory/ds/bitmap"
+	"google.golang.org/grpc"
+)
+
+const bitmapService = "demory.Bitmap"
+
+type BitmapSetBitRequest struct {
+	Name   string `json:"name"`
+	Offset uint32 `json:"offset"`
+	Value  bool   `json:"value"`
+}
+
+type BitmapGetBitRequest struct {
+	Name   string `json:"name"`
+	Offset uint32 `json:"offset"`
+}
+
+type BitmapBitResponse struct {
+	// Value is the bit, or its previous value for SetBit.
+	Value bool `json:"value"`
+}
+
+type BitmapCountRequest struct {
+	Name string `json:"name"`
+	// Start and End are bit offsets, both included. End defaults to the last offset.
+	Start uint32  `json:"start,omitempty"`
+	End   *uint32 `json:"end,omitempty"`
+}
+
+type BitmapCountResponse struct {
+	Count uint64 `json:"count"`
+}
+
+type BitmapPosRequest struct {
+	Name string `json:"name"`
+	// Bit is the value looked for, between Start and End, both included. End defaults to the last offset.
+	Bit   bool    `json:"bit"`
+	Start uint32  `json:"start,omitempty"`
+	End   *uint32 `json:"end,omitempty"`
+}
+
+type BitmapPosResponse struct {
+	// Pos is the offset of the first matching bit, or -1 if there is none.
+	Pos int64 `json:"pos"`
+}
+
+type BitmapOpRequest struct {
+	// Op is one of "and", "or", "xor" and "not". Not takes a single source.
+	Op      bitmap.Op `json:"op"`
+	Dest    string    `json:"dest"`
+	Sources []string  `json:"sources"`
+}
+
+// BitmapServer is the server API for the bitmap service.
+type BitmapServer interface {
+	BitmapSetBit(context.Context, *BitmapSetBitRequest) (*BitmapBitResponse, error)
+	BitmapGetBit(context.Context, *BitmapGetBitRequest) (*BitmapBitResponse, error)
+	BitmapCount(context.Context, *BitmapCountRequest) (*BitmapCountResponse, error)
+	BitmapPos(context.Context, *BitmapPosRequest) (*BitmapPosResponse, error)
+	BitmapOp(context.Context, *BitmapOpRequest) (*BitmapCountResponse, error)
+}
+
+// RegisterBitmapServer registers srv on s.
+func RegisterBitmapServer(s grpc.ServiceRegistrar, srv BitmapServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: bitmapService,
+		HandlerType: (*BitmapServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(bitmapService, "BitmapSetBit", BitmapServer.BitmapSetBit),
+			unary(bitmapService, "BitmapGetBit", BitmapServer.BitmapGetBit),
+			unary(bitmapService, "BitmapCount", BitmapServer.BitmapCount),
+			unary(bitmapService, "BitmapPos", BitmapServer.BitmapPos),
+			unary(bitmapService, "BitmapOp", BitmapServer.BitmapOp),
+		},
+	}, srv)
+}
+
+// BitmapClient is the client API for the bitmap service.
+type BitmapClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBitmapClient(cc grpc.ClientConnInterface) *BitmapClient {
+	return &BitmapClient{cc: cc}
+}
+
+func (c *BitmapClient) BitmapSetBit(ctx context.Context, in *BitmapSetBitRequest,
+	opts ...grpc.CallOption) (*BitmapBitResponse, error) {
+	out := new(BitmapBitResponse)
+	if err := invoke(ctx, c.cc, bitmapService, "BitmapSetBit", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *BitmapClient) BitmapGetBit(ctx context.Context, in *BitmapGetBitRequest,
+	opts ...grpc.CallOption) (*BitmapBitResponse, error) {
+	out := new(BitmapBitResponse)
+	if err := invoke(ctx, c.cc, bitmapService, "BitmapGetBit", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *BitmapClient) BitmapCount(ctx context.Context, in *BitmapCountRequest,
+	opts ...grpc.CallOption) (*BitmapCountResponse, error) {
+	out := new(BitmapCountResponse)
+	if err := invoke(ctx, c.cc, bitmapService, "BitmapCount", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *BitmapClient) BitmapPos(ctx context.Context, in *BitmapPosRequest,
+	opts ...grpc.CallOption) (*BitmapPosResponse, error) {
+	out := new(BitmapPosResponse)
+	if err := invoke(ctx, c.cc, bitmapService, "BitmapPos", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *BitmapClient) BitmapOp(ctx context.Context, in *BitmapOpRequest,
+	opts ...grpc.CallOption) (*BitmapCountResponse, error) {
+	out := new(BitmapCountResponse)
+	if err := invoke(ctx, c.cc, bitmapService, "BitmapOp", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
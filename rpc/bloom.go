@@ -0,0 +1,107 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/huseyinbabal/demory/ds/bloom"
+	"google.golang.org/grpc"
+)
+
+const bloomService = "demory.Bloom"
+
+type BloomCreateRequest struct {
+	Name string `json:"name"`
+	// Capacity is the expected number of items and ErrorRate the false positive rate at that capacity.
+	Capacity  uint64  `json:"capacity"`
+	ErrorRate float64 `json:"errorRate"`
+}
+
+type BloomItemsRequest struct {
+	Name  string   `json:"name"`
+	Items [][]byte `json:"items"`
+}
+
+type BloomAddResponse struct {
+	// Added tells for each item whether it was definitely not in the filter before.
+	Added []bool `json:"added"`
+}
+
+type BloomMightContainResponse struct {
+	// Found tells for each item whether it might have been added to the filter.
+	Found []bool `json:"found"`
+}
+
+type BloomInfoRequest struct {
+	Name string `json:"name"`
+}
+
+type BloomInfoResponse struct {
+	Info bloom.Info `json:"info"`
+}
+
+// BloomServer is the server API for the Bloom filter service.
+type BloomServer interface {
+	BloomCreate(context.Context, *BloomCreateRequest) (*Empty, error)
+	BloomAdd(context.Context, *BloomItemsRequest) (*BloomAddResponse, error)
+	BloomMightContain(context.Context, *BloomItemsRequest) (*BloomMightContainResponse, error)
+	BloomInfo(context.Context, *BloomInfoRequest) (*BloomInfoResponse, error)
+}
+
+// RegisterBloomServer registers srv on s.
+func RegisterBloomServer(s grpc.ServiceRegistrar, srv BloomServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: bloomService,
+		HandlerType: (*BloomServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(bloomService, "BloomCreate", BloomServer.BloomCreate),
+			unary(bloomService, "BloomAdd", BloomServer.BloomAdd),
+			unary(bloomService, "BloomMightContain", BloomServer.BloomMightContain),
+			unary(bloomService, "BloomInfo", BloomServer.BloomInfo),
+		},
+	}, srv)
+}
+
+// BloomClient is the client API for the Bloom filter service.
+type BloomClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBloomClient(cc grpc.ClientConnInterface) *BloomClient {
+	return &BloomClient{cc: cc}
+}
+
+func (c *BloomClient) BloomCreate(ctx context.Context, in *BloomCreateRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, bloomService, "BloomCreate", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *BloomClient) BloomAdd(ctx context.Context, in *BloomItemsRequest,
+	opts ...grpc.CallOption) (*BloomAddResponse, error) {
+	out := new(BloomAddResponse)
+	if err := invoke(ctx, c.cc, bloomService, "BloomAdd", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *BloomClient) BloomMightContain(ctx context.Context, in *BloomItemsRequest,
+	opts ...grpc.CallOption) (*BloomMightContainResponse, error) {
+	out := new(BloomMightContainResponse)
+	if err := invoke(ctx, c.cc, bloomService, "BloomMightContain", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *BloomClient) BloomInfo(ctx context.Context, in *BloomInfoRequest,
+	opts ...grpc.CallOption) (*BloomInfoResponse, error) {
+	out := new(BloomInfoResponse)
+	if err := invoke(ctx, c.cc, bloomService, "BloomInfo", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
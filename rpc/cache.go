@@ -0,0 +1,101 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+const cacheService = "demory.Cache"
+
+type CachePutWithTTLRequest struct {
+	Name  string `json:"name"`
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+	// TTL of the entry in milliseconds. The default TTL of the cache is used if it is zero.
+	TTL int64 `json:"ttl,omitempty"`
+}
+
+type CacheSetDefaultTTLRequest struct {
+	Name string `json:"name"`
+	// TTL in milliseconds of entries put without a TTL of their own. Zero disables the default.
+	TTL int64 `json:"ttl"`
+}
+
+type CacheStatsRequest struct {
+	// Name of the cache to describe. Statistics of all caches are returned if it is empty.
+	Name string `json:"name,omitempty"`
+}
+
+type CacheStats struct {
+	Name        string  `json:"name"`
+	Hits        uint64  `json:"hits"`
+	Misses      uint64  `json:"misses"`
+	HitRatio    float64 `json:"hitRatio"`
+	Puts        uint64  `json:"puts"`
+	Removals    uint64  `json:"removals"`
+	Evictions   uint64  `json:"evictions"`
+	Expirations uint64  `json:"expirations"`
+	Entries     int     `json:"entries"`
+	Bytes       int64   `json:"bytes"`
+}
+
+type CacheStatsResponse struct {
+	Caches []CacheStats `json:"caches"`
+}
+
+// CacheServer is the server API for the cache service.
+type CacheServer interface {
+	CachePutWithTTL(context.Context, *CachePutWithTTLRequest) (*Empty, error)
+	CacheSetDefaultTTL(context.Context, *CacheSetDefaultTTLRequest) (*Empty, error)
+	CacheStats(context.Context, *CacheStatsRequest) (*CacheStatsResponse, error)
+}
+
+// RegisterCacheServer registers srv on s.
+func RegisterCacheServer(s grpc.ServiceRegistrar, srv CacheServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: cacheService,
+		HandlerType: (*CacheServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(cacheService, "CachePutWithTTL", CacheServer.CachePutWithTTL),
+			unary(cacheService, "CacheSetDefaultTTL", CacheServer.CacheSetDefaultTTL),
+			unary(cacheService, "CacheStats", CacheServer.CacheStats),
+		},
+	}, srv)
+}
+
+// CacheClient is the client API for the cache service.
+type CacheClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCacheClient(cc grpc.ClientConnInterface) *CacheClient {
+	return &CacheClient{cc: cc}
+}
+
+func (c *CacheClient) CachePutWithTTL(ctx context.Context, in *CachePutWithTTLRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, cacheService, "CachePutWithTTL", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *CacheClient) CacheSetDefaultTTL(ctx context.Context, in *CacheSetDefaultTTLRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, cacheService, "CacheSetDefaultTTL", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *CacheClient) CacheStats(ctx context.Context, in *CacheStatsRequest,
+	opts ...grpc.CallOption) (*CacheStatsResponse, error) {
+	out := new(CacheStatsResponse)
+	if err := invoke(ctx, c.cc, cacheService, "CacheStats", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
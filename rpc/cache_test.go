@@ -0,0 +1,57 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type cacheServer struct{}
+
+func (cacheServer) CachePutWithTTL(ctx context.Context, req *CachePutWithTTLRequest) (*Empty, error) {
+	return &Empty{}, nil
+}
+
+func (cacheServer) CacheSetDefaultTTL(ctx context.Context, req *CacheSetDefaultTTLRequest) (*Empty, error) {
+	return &Empty{}, nil
+}
+
+func (cacheServer) CacheStats(ctx context.Context, req *CacheStatsRequest) (*CacheStatsResponse, error) {
+	return &CacheStatsResponse{Caches: []CacheStats{{Name: req.Name, Hits: 3}}}, nil
+}
+
+func dial(t *testing.T, register func(*grpc.Server)) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return listener.Dial()
+		}))
+	if err != nil {
+		t.Fatalf("dial failed %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func TestCacheStats(t *testing.T) {
+	conn := dial(t, func(s *grpc.Server) {
+		RegisterCacheServer(s, cacheServer{})
+	})
+
+	res, err := NewCacheClient(conn).CacheStats(context.Background(), &CacheStatsRequest{Name: "users"})
+	if err != nil {
+		t.Fatalf("cache stats failed %v", err)
+	}
+	if len(res.Caches) != 1 || res.Caches[0].Name != "users" || res.Caches[0].Hits != 3 {
+		t.Errorf("unexpected response %+v", res)
+	}
+}
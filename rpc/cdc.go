@@ -0,0 +1,48 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/huseyinbabal/demory/cdc"
+	"google.golang.org/grpc"
+)
+
+const cdcService = "demory.CDC"
+
+type ChangesRequest struct {
+	// AfterIndex resumes with the records after a raft log index, typically the index of the last record
+	// received. Only new records are sent if it is not set.
+	AfterIndex *uint64 `json:"afterIndex,omitempty"`
+	// Buffer is the number of records kept for the subscriber while it is busy. Defaults to 1024.
+	Buffer int `json:"buffer,omitempty"`
+}
+
+// CDCServer is the server API for the change data capture service.
+type CDCServer interface {
+	Changes(*ChangesRequest, ServerStream[cdc.Record]) error
+}
+
+// RegisterCDCServer registers srv on s.
+func RegisterCDCServer(s grpc.ServiceRegistrar, srv CDCServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: cdcService,
+		HandlerType: (*CDCServer)(nil),
+		Streams: []grpc.StreamDesc{
+			streaming("Changes", CDCServer.Changes),
+		},
+	}, srv)
+}
+
+// CDCClient is the client API for the change data capture service.
+type CDCClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCDCClient(cc grpc.ClientConnInterface) *CDCClient {
+	return &CDCClient{cc: cc}
+}
+
+func (c *CDCClient) Changes(ctx context.Context, in *ChangesRequest,
+	opts ...grpc.CallOption) (*ClientStream[cdc.Record], error) {
+	return open[cdc.Record](ctx, c.cc, cdcService, "Changes", in, opts...)
+}
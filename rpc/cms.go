@@ -0,0 +1,107 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/huseyinbabal/demory/ds/cms"
+	"google.golang.org/grpc"
+)
+
+const cmsService = "demory.CountMinSketch"
+
+type CMSCreateRequest struct {
+	Name string `json:"name"`
+	// Width is the number of counters per row and Depth the number of rows. Estimates are off by at most
+	// 2/Width of the total count, with a probability of 1-(1/2)^Depth.
+	Width uint32 `json:"width"`
+	Depth uint32 `json:"depth"`
+}
+
+type CMSIncrementRequest struct {
+	Name       string          `json:"name"`
+	Increments []cms.Increment `json:"increments"`
+}
+
+type CMSQueryRequest struct {
+	Name  string   `json:"name"`
+	Items []string `json:"items"`
+}
+
+type CMSCountsResponse struct {
+	// Counts are the estimated counts of the items, in the order of the request.
+	Counts []uint64 `json:"counts"`
+}
+
+type CMSMergeRequest struct {
+	// Dest is incremented by the counts of the sources, multiplied by their weights which default to 1.
+	Dest    string   `json:"dest"`
+	Sources []string `json:"sources"`
+	Weights []uint64 `json:"weights,omitempty"`
+}
+
+// CountMinSketchServer is the server API for the count-min sketch service.
+type CountMinSketchServer interface {
+	CMSCreate(context.Context, *CMSCreateRequest) (*Empty, error)
+	CMSIncrement(context.Context, *CMSIncrementRequest) (*CMSCountsResponse, error)
+	CMSQuery(context.Context, *CMSQueryRequest) (*CMSCountsResponse, error)
+	CMSMerge(context.Context, *CMSMergeRequest) (*Empty, error)
+}
+
+// RegisterCountMinSketchServer registers srv on s.
+func RegisterCountMinSketchServer(s grpc.ServiceRegistrar, srv CountMinSketchServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: cmsService,
+		HandlerType: (*CountMinSketchServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(cmsService, "CMSCreate", CountMinSketchServer.CMSCreate),
+			unary(cmsService, "CMSIncrement", CountMinSketchServer.CMSIncrement),
+			unary(cmsService, "CMSQuery", CountMinSketchServer.CMSQuery),
+			unary(cmsService, "CMSMerge", CountMinSketchServer.CMSMerge),
+		},
+	}, srv)
+}
+
+// CountMinSketchClient is the client API for the count-min sketch service.
+type CountMinSketchClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCountMinSketchClient(cc grpc.ClientConnInterface) *CountMinSketchClient {
+	return &CountMinSketchClient{cc: cc}
+}
+
+func (c *CountMinSketchClient) CMSCreate(ctx context.Context, in *CMSCreateRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, cmsService, "CMSCreate", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *CountMinSketchClient) CMSIncrement(ctx context.Context, in *CMSIncrementRequest,
+	opts ...grpc.CallOption) (*CMSCountsResponse, error) {
+	out := new(CMSCountsResponse)
+	if err := invoke(ctx, c.cc, cmsService, "CMSIncrement", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *CountMinSketchClient) CMSQuery(ctx context.Context, in *CMSQueryRequest,
+	opts ...grpc.CallOption) (*CMSCountsResponse, error) {
+	out := new(CMSCountsResponse)
+	if err := invoke(ctx, c.cc, cmsService, "CMSQuery", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *CountMinSketchClient) CMSMerge(ctx context.Context, in *CMSMergeRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, cmsService, "CMSMerge", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,29 @@
+package rpc
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// Codec is the name of the codec the services of this package are served with. Clients select it
+// with grpc.CallContentSubtype(Codec), which the clients of this package do by default.
+const Codec = "json"
+
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return Codec
+}
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
@@ -0,0 +1,201 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/huseyinbabal/demory/ds/document"
+	"google.golang.org/grpc"
+)
+
+const documentService = "demory.Document"
+
+type DocumentGetRequest struct {
+	Name string `json:"name"`
+	// Path is a dotted JSON path such as "address.city" or "$.tags[0]". The empty path and "$" refer to the
+	// whole document, in this request and all others.
+	Path string `json:"path"`
+}
+
+type DocumentGetResponse struct {
+	Value json.RawMessage `json:"value,omitempty"`
+	Found bool            `json:"found"`
+}
+
+type DocumentSetRequest struct {
+	Name  string          `json:"name"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+	// Condition is empty to always set the value, "if-absent" or "if-exists".
+	Condition document.Condition `json:"condition,omitempty"`
+}
+
+type DocumentSetResponse struct {
+	Set bool `json:"set"`
+}
+
+type DocumentDeleteRequest struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type DocumentDeleteResponse struct {
+	Deleted bool `json:"deleted"`
+}
+
+type DocumentIncrementRequest struct {
+	Name  string      `json:"name"`
+	Path  string      `json:"path"`
+	Delta json.Number `json:"delta"`
+}
+
+type DocumentIncrementResponse struct {
+	Value json.Number `json:"value"`
+}
+
+type DocumentArrayAppendRequest struct {
+	Name   string            `json:"name"`
+	Path   string            `json:"path"`
+	Values []json.RawMessage `json:"values"`
+}
+
+type DocumentArrayInsertRequest struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Index is the position the values are inserted at. Negative indexes count from the end of the array.
+	Index  int               `json:"index"`
+	Values []json.RawMessage `json:"values"`
+}
+
+type DocumentArrayResponse struct {
+	Length int `json:"length"`
+}
+
+type DocumentArrayPopRequest struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Index is the position of the popped element, the last one if it is not set.
+	Index *int `json:"index,omitempty"`
+}
+
+type DocumentArrayPopResponse struct {
+	Value json.RawMessage `json:"value,omitempty"`
+	Found bool            `json:"found"`
+}
+
+type DocumentTypeResponse struct {
+	// Type is object, array, string, integer, number, boolean or null.
+	Type  string `json:"type,omitempty"`
+	Found bool   `json:"found"`
+}
+
+// DocumentServer is the server API for the document service.
+type DocumentServer interface {
+	DocumentGet(context.Context, *DocumentGetRequest) (*DocumentGetResponse, error)
+	DocumentSet(context.Context, *DocumentSetRequest) (*DocumentSetResponse, error)
+	DocumentDelete(context.Context, *DocumentDeleteRequest) (*DocumentDeleteResponse, error)
+	DocumentIncrement(context.Context, *DocumentIncrementRequest) (*DocumentIncrementResponse, error)
+	DocumentArrayAppend(context.Context, *DocumentArrayAppendRequest) (*DocumentArrayResponse, error)
+	DocumentArrayInsert(context.Context, *DocumentArrayInsertRequest) (*DocumentArrayResponse, error)
+	DocumentArrayPop(context.Context, *DocumentArrayPopRequest) (*DocumentArrayPopResponse, error)
+	DocumentType(context.Context, *DocumentGetRequest) (*DocumentTypeResponse, error)
+}
+
+// RegisterDocumentServer registers srv on s.
+func RegisterDocumentServer(s grpc.ServiceRegistrar, srv DocumentServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: documentService,
+		HandlerType: (*DocumentServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(documentService, "DocumentGet", DocumentServer.DocumentGet),
+			unary(documentService, "DocumentSet", DocumentServer.DocumentSet),
+			unary(documentService, "DocumentDelete", DocumentServer.DocumentDelete),
+			unary(documentService, "DocumentIncrement", DocumentServer.DocumentIncrement),
+			unary(documentService, "DocumentArrayAppend", DocumentServer.DocumentArrayAppend),
+			unary(documentService, "DocumentArrayInsert", DocumentServer.DocumentArrayInsert),
+			unary(documentService, "DocumentArrayPop", DocumentServer.DocumentArrayPop),
+			unary(documentService, "DocumentType", DocumentServer.DocumentType),
+		},
+	}, srv)
+}
+
+// DocumentClient is the client API for the document service.
+type DocumentClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDocumentClient(cc grpc.ClientConnInterface) *DocumentClient {
+	return &DocumentClient{cc: cc}
+}
+
+func (c *DocumentClient) DocumentGet(ctx context.Context, in *DocumentGetRequest,
+	opts ...grpc.CallOption) (*DocumentGetResponse, error) {
+	out := new(DocumentGetResponse)
+	if err := invoke(ctx, c.cc, documentService, "DocumentGet", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *DocumentClient) DocumentSet(ctx context.Context, in *DocumentSetRequest,
+	opts ...grpc.CallOption) (*DocumentSetResponse, error) {
+	out := new(DocumentSetResponse)
+	if err := invoke(ctx, c.cc, documentService, "DocumentSet", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *DocumentClient) DocumentDelete(ctx context.Context, in *DocumentDeleteRequest,
+	opts ...grpc.CallOption) (*DocumentDeleteResponse, error) {
+	out := new(DocumentDeleteResponse)
+	if err := invoke(ctx, c.cc, documentService, "DocumentDelete", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *DocumentClient) DocumentIncrement(ctx context.Context, in *DocumentIncrementRequest,
+	opts ...grpc.CallOption) (*DocumentIncrementResponse, error) {
+	out := new(DocumentIncrementResponse)
+	if err := invoke(ctx, c.cc, documentService, "DocumentIncrement", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *DocumentClient) DocumentArrayAppend(ctx context.Context, in *DocumentArrayAppendRequest,
+	opts ...grpc.CallOption) (*DocumentArrayResponse, error) {
+	out := new(DocumentArrayResponse)
+	if err := invoke(ctx, c.cc, documentService, "DocumentArrayAppend", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *DocumentClient) DocumentArrayInsert(ctx context.Context, in *DocumentArrayInsertRequest,
+	opts ...grpc.CallOption) (*DocumentArrayResponse, error) {
+	out := new(DocumentArrayResponse)
+	if err := invoke(ctx, c.cc, documentService, "DocumentArrayInsert", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *DocumentClient) DocumentArrayPop(ctx context.Context, in *DocumentArrayPopRequest,
+	opts ...grpc.CallOption) (*DocumentArrayPopResponse, error) {
+	out := new(DocumentArrayPopResponse)
+	if err := invoke(ctx, c.cc, documentService, "DocumentArrayPop", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *DocumentClient) DocumentType(ctx context.Context, in *DocumentGetRequest,
+	opts ...grpc.CallOption) (*DocumentTypeResponse, error) {
+	out := new(DocumentTypeResponse)
+	if err := invoke(ctx, c.cc, documentService, "DocumentType", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,147 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/huseyinbabal/demory/ds/election"
+	"google.golang.org/grpc"
+)
+
+const electionService = "demory.Election"
+
+type SessionGrantRequest struct {
+	// TTL in milliseconds after which the session expires unless it is kept alive, between 1s and 1h.
+	TTL int64 `json:"ttl"`
+}
+
+type SessionRequest struct {
+	ID uint64 `json:"id"`
+}
+
+type SessionResponse struct {
+	ID uint64 `json:"id"`
+	// Expires is when the session expires unless it is kept alive, in unix nanoseconds of the leader.
+	Expires int64 `json:"expires"`
+}
+
+type CampaignRequest struct {
+	Name    string `json:"name"`
+	Session uint64 `json:"session"`
+	// Value is published to observers while the session leads the election, e.g. the address of the instance.
+	Value []byte `json:"value,omitempty"`
+}
+
+type ResignRequest struct {
+	Name    string `json:"name"`
+	Session uint64 `json:"session"`
+	// Term of the leadership to resign from. If it is set, a leader with another term does not resign.
+	Term uint64 `json:"term,omitempty"`
+}
+
+type ElectionRequest struct {
+	Name string `json:"name"`
+}
+
+type LeaderResponse struct {
+	// Leader of the election, nil if there is none.
+	Leader *election.Leader `json:"leader"`
+}
+
+// ElectionServer is the server API for the election service.
+type ElectionServer interface {
+	GrantSession(context.Context, *SessionGrantRequest) (*SessionResponse, error)
+	KeepAliveSession(context.Context, *SessionRequest) (*SessionResponse, error)
+	RevokeSession(context.Context, *SessionRequest) (*Empty, error)
+	Campaign(context.Context, *CampaignRequest) (*LeaderResponse, error)
+	Resign(context.Context, *ResignRequest) (*Empty, error)
+	ElectionLeader(context.Context, *ElectionRequest) (*LeaderResponse, error)
+	ObserveElection(*ElectionRequest, ServerStream[LeaderResponse]) error
+}
+
+// RegisterElectionServer registers srv on s.
+func RegisterElectionServer(s grpc.ServiceRegistrar, srv ElectionServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: electionService,
+		HandlerType: (*ElectionServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(electionService, "GrantSession", ElectionServer.GrantSession),
+			unary(electionService, "KeepAliveSession", ElectionServer.KeepAliveSession),
+			unary(electionService, "RevokeSession", ElectionServer.RevokeSession),
+			unary(electionService, "Campaign", ElectionServer.Campaign),
+			unary(electionService, "Resign", ElectionServer.Resign),
+			unary(electionService, "ElectionLeader", ElectionServer.ElectionLeader),
+		},
+		Streams: []grpc.StreamDesc{
+			streaming("ObserveElection", ElectionServer.ObserveElection),
+		},
+	}, srv)
+}
+
+// ElectionClient is the client API for the election service.
+type ElectionClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewElectionClient(cc grpc.ClientConnInterface) *ElectionClient {
+	return &ElectionClient{cc: cc}
+}
+
+func (c *ElectionClient) GrantSession(ctx context.Context, in *SessionGrantRequest,
+	opts ...grpc.CallOption) (*SessionResponse, error) {
+	out := new(SessionResponse)
+	if err := invoke(ctx, c.cc, electionService, "GrantSession", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ElectionClient) KeepAliveSession(ctx context.Context, in *SessionRequest,
+	opts ...grpc.CallOption) (*SessionResponse, error) {
+	out := new(SessionResponse)
+	if err := invoke(ctx, c.cc, electionService, "KeepAliveSession", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ElectionClient) RevokeSession(ctx context.Context, in *SessionRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, electionService, "RevokeSession", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Campaign blocks until the session leads the election, or ctx is done.
+func (c *ElectionClient) Campaign(ctx context.Context, in *CampaignRequest,
+	opts ...grpc.CallOption) (*LeaderResponse, error) {
+	out := new(LeaderResponse)
+	if err := invoke(ctx, c.cc, electionService, "Campaign", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ElectionClient) Resign(ctx context.Context, in *ResignRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, electionService, "Resign", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ElectionClient) ElectionLeader(ctx context.Context, in *ElectionRequest,
+	opts ...grpc.CallOption) (*LeaderResponse, error) {
+	out := new(LeaderResponse)
+	if err := invoke(ctx, c.cc, electionService, "ElectionLeader", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ElectionClient) ObserveElection(ctx context.Context, in *ElectionRequest,
+	opts ...grpc.CallOption) (*ClientStream[LeaderResponse], error) {
+	return open[LeaderResponse](ctx, c.cc, electionService, "ObserveElection", in, opts...)
+}
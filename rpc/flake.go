@@ -0,0 +1,55 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+const flakeIDService = "demory.FlakeID"
+
+type FlakeIDRequest struct {
+	// Name of the generator. Ids are unique per generator.
+	Name string `json:"name"`
+	// Count is the number of ids to generate, 1 if it is not set.
+	Count int `json:"count,omitempty"`
+}
+
+type FlakeIDResponse struct {
+	// IDs are increasing for the node that generated them, and roughly time ordered across nodes.
+	IDs []int64 `json:"ids"`
+}
+
+// FlakeIDServer is the server API for the flake id service.
+type FlakeIDServer interface {
+	NewIDs(context.Context, *FlakeIDRequest) (*FlakeIDResponse, error)
+}
+
+// RegisterFlakeIDServer registers srv on s.
+func RegisterFlakeIDServer(s grpc.ServiceRegistrar, srv FlakeIDServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: flakeIDService,
+		HandlerType: (*FlakeIDServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(flakeIDService, "NewIDs", FlakeIDServer.NewIDs),
+		},
+	}, srv)
+}
+
+// FlakeIDClient is the client API for the flake id service.
+type FlakeIDClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFlakeIDClient(cc grpc.ClientConnInterface) *FlakeIDClient {
+	return &FlakeIDClient{cc: cc}
+}
+
+func (c *FlakeIDClient) NewIDs(ctx context.Context, in *FlakeIDRequest,
+	opts ...grpc.CallOption) (*FlakeIDResponse, error) {
+	out := new(FlakeIDResponse)
+	if err := invoke(ctx, c.cc, flakeIDService, "NewIDs", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,146 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/huseyinbabal/demory/ds/geo"
+	"google.golang.org/grpc"
+)
+
+const geoService = "demory.Geo"
+
+type GeoAddRequest struct {
+	Name    string       `json:"name"`
+	Members []geo.Member `json:"members"`
+}
+
+type GeoAddResponse struct {
+	// Added is the number of members that were not in the set before. Existing members are moved.
+	Added int `json:"added"`
+}
+
+type GeoRemoveRequest struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+type GeoRemoveResponse struct {
+	Removed int `json:"removed"`
+}
+
+type GeoPositionRequest struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+type GeoPositionResponse struct {
+	// Positions of the members in the order of the request, nil for members that are not in the set.
+	Positions []*geo.Point `json:"positions"`
+}
+
+type GeoDistanceRequest struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type GeoDistanceResponse struct {
+	// Distance in meters, nil if one of the members is not in the set.
+	Distance *float64 `json:"distance"`
+}
+
+type GeoSearchRequest struct {
+	Name string `json:"name"`
+	// Center of the search, or the position of Member if it is not set.
+	Center *geo.Point `json:"center,omitempty"`
+	Member string     `json:"member,omitempty"`
+	// Radius in meters searches a circle. Otherwise Width and Height in meters search a box.
+	Radius float64 `json:"radius,omitempty"`
+	Width  float64 `json:"width,omitempty"`
+	Height float64 `json:"height,omitempty"`
+	// Limit returns only the nearest members, if it is positive.
+	Limit int `json:"limit,omitempty"`
+}
+
+type GeoSearchResponse struct {
+	// Results are the members found, nearest first.
+	Results []geo.Result `json:"results"`
+}
+
+// GeoServer is the server API for the geo set service.
+type GeoServer interface {
+	GeoAdd(context.Context, *GeoAddRequest) (*GeoAddResponse, error)
+	GeoRemove(context.Context, *GeoRemoveRequest) (*GeoRemoveResponse, error)
+	GeoPosition(context.Context, *GeoPositionRequest) (*GeoPositionResponse, error)
+	GeoDistance(context.Context, *GeoDistanceRequest) (*GeoDistanceResponse, error)
+	GeoSearch(context.Context, *GeoSearchRequest) (*GeoSearchResponse, error)
+}
+
+// RegisterGeoServer registers srv on s.
+func RegisterGeoServer(s grpc.ServiceRegistrar, srv GeoServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: geoService,
+		HandlerType: (*GeoServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(geoService, "GeoAdd", GeoServer.GeoAdd),
+			unary(geoService, "GeoRemove", GeoServer.GeoRemove),
+			unary(geoService, "GeoPosition", GeoServer.GeoPosition),
+			unary(geoService, "GeoDistance", GeoServer.GeoDistance),
+			unary(geoService, "GeoSearch", GeoServer.GeoSearch),
+		},
+	}, srv)
+}
+
+// GeoClient is the client API for the geo set service.
+type GeoClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGeoClient(cc grpc.ClientConnInterface) *GeoClient {
+	return &GeoClient{cc: cc}
+}
+
+func (c *GeoClient) GeoAdd(ctx context.Context, in *GeoAddRequest,
+	opts ...grpc.CallOption) (*GeoAddResponse, error) {
+	out := new(GeoAddResponse)
+	if err := invoke(ctx, c.cc, geoService, "GeoAdd", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *GeoClient) GeoRemove(ctx context.Context, in *GeoRemoveRequest,
+	opts ...grpc.CallOption) (*GeoRemoveResponse, error) {
+	out := new(GeoRemoveResponse)
+	if err := invoke(ctx, c.cc, geoService, "GeoRemove", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *GeoClient) GeoPosition(ctx context.Context, in *GeoPositionRequest,
+	opts ...grpc.CallOption) (*GeoPositionResponse, error) {
+	out := new(GeoPositionResponse)
+	if err := invoke(ctx, c.cc, geoService, "GeoPosition", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *GeoClient) GeoDistance(ctx context.Context, in *GeoDistanceRequest,
+	opts ...grpc.CallOption) (*GeoDistanceResponse, error) {
+	out := new(GeoDistanceResponse)
+	if err := invoke(ctx, c.cc, geoService, "GeoDistance", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *GeoClient) GeoSearch(ctx context.Context, in *GeoSearchRequest,
+	opts ...grpc.CallOption) (*GeoSearchResponse, error) {
+	out := new(GeoSearchResponse)
+	if err := invoke(ctx, c.cc, geoService, "GeoSearch", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,90 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+const hllService = "demory.HyperLogLog"
+
+type HLLAddRequest struct {
+	Name     string   `json:"name"`
+	Elements [][]byte `json:"elements"`
+}
+
+type HLLAddResponse struct {
+	// Changed is false if all elements were seen before, so the estimate did not change.
+	Changed bool `json:"changed"`
+}
+
+type HLLCountRequest struct {
+	// Names of the sketches whose union is counted.
+	Names []string `json:"names"`
+}
+
+type HLLCountResponse struct {
+	Count uint64 `json:"count"`
+}
+
+type HLLMergeRequest struct {
+	// Dest is set to the union of itself and the sources.
+	Dest    string   `json:"dest"`
+	Sources []string `json:"sources"`
+}
+
+// HyperLogLogServer is the server API for the HyperLogLog service.
+type HyperLogLogServer interface {
+	HLLAdd(context.Context, *HLLAddRequest) (*HLLAddResponse, error)
+	HLLCount(context.Context, *HLLCountRequest) (*HLLCountResponse, error)
+	HLLMerge(context.Context, *HLLMergeRequest) (*Empty, error)
+}
+
+// RegisterHyperLogLogServer registers srv on s.
+func RegisterHyperLogLogServer(s grpc.ServiceRegistrar, srv HyperLogLogServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: hllService,
+		HandlerType: (*HyperLogLogServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(hllService, "HLLAdd", HyperLogLogServer.HLLAdd),
+			unary(hllService, "HLLCount", HyperLogLogServer.HLLCount),
+			unary(hllService, "HLLMerge", HyperLogLogServer.HLLMerge),
+		},
+	}, srv)
+}
+
+// HyperLogLogClient is the client API for the HyperLogLog service.
+type HyperLogLogClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHyperLogLogClient(cc grpc.ClientConnInterface) *HyperLogLogClient {
+	return &HyperLogLogClient{cc: cc}
+}
+
+func (c *HyperLogLogClient) HLLAdd(ctx context.Context, in *HLLAddRequest,
+	opts ...grpc.CallOption) (*HLLAddResponse, error) {
+	out := new(HLLAddResponse)
+	if err := invoke(ctx, c.cc, hllService, "HLLAdd", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *HyperLogLogClient) HLLCount(ctx context.Context, in *HLLCountRequest,
+	opts ...grpc.CallOption) (*HLLCountResponse, error) {
+	out := new(HLLCountResponse)
+	if err := invoke(ctx, c.cc, hllService, "HLLCount", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *HyperLogLogClient) HLLMerge(ctx context.Context, in *HLLMergeRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, hllService, "HLLMerge", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,184 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/huseyinbabal/demory/index"
+	"google.golang.org/grpc"
+)
+
+const (
+	indexService = "demory.Index"
+	adminService = "demory.Admin"
+)
+
+type IndexLookupRequest struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Value looks up entries whose indexed value equals it. Otherwise From and To bound a range lookup,
+	// which needs a sorted index. A missing bound leaves the range open.
+	Value         json.RawMessage `json:"value,omitempty"`
+	From          json.RawMessage `json:"from,omitempty"`
+	To            json.RawMessage `json:"to,omitempty"`
+	FromExclusive bool            `json:"fromExclusive,omitempty"`
+	ToExclusive   bool            `json:"toExclusive,omitempty"`
+	Limit         int             `json:"limit,omitempty"`
+}
+
+type IndexLookupResponse struct {
+	Entries []Entry `json:"entries"`
+}
+
+type CreateIndexRequest struct {
+	Name string     `json:"name"`
+	Path string     `json:"path"`
+	Kind index.Kind `json:"kind"`
+}
+
+type DropIndexRequest struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type ListIndexesRequest struct {
+	// Name of the map whose indexes are listed. Indexes of all maps are listed if it is empty.
+	Name string `json:"name,omitempty"`
+}
+
+type ListIndexesResponse struct {
+	Indexes []index.Definition `json:"indexes"`
+}
+
+type DestroyStructureRequest struct {
+	// Kind is the structure type, such as "streams" or "blooms".
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+type DestroyStructureResponse struct {
+	Destroyed bool `json:"destroyed"`
+}
+
+type StructureStatsRequest struct{}
+
+type StructureStats struct {
+	Kind  string `json:"kind"`
+	Bytes int64  `json:"bytes"`
+}
+
+type StructureStatsResponse struct {
+	Structures []StructureStats `json:"structures"`
+}
+
+// IndexServer is the server API for the index service.
+type IndexServer interface {
+	IndexLookup(context.Context, *IndexLookupRequest) (*IndexLookupResponse, error)
+}
+
+// RegisterIndexServer registers srv on s.
+func RegisterIndexServer(s grpc.ServiceRegistrar, srv IndexServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: indexService,
+		HandlerType: (*IndexServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(indexService, "IndexLookup", IndexServer.IndexLookup),
+		},
+	}, srv)
+}
+
+// IndexClient is the client API for the index service.
+type IndexClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIndexClient(cc grpc.ClientConnInterface) *IndexClient {
+	return &IndexClient{cc: cc}
+}
+
+func (c *IndexClient) IndexLookup(ctx context.Context, in *IndexLookupRequest,
+	opts ...grpc.CallOption) (*IndexLookupResponse, error) {
+	out := new(IndexLookupResponse)
+	if err := invoke(ctx, c.cc, indexService, "IndexLookup", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServer is the server API for the admin service.
+type AdminServer interface {
+	CreateIndex(context.Context, *CreateIndexRequest) (*Empty, error)
+	DropIndex(context.Context, *DropIndexRequest) (*Empty, error)
+	ListIndexes(context.Context, *ListIndexesRequest) (*ListIndexesResponse, error)
+	DestroyStructure(context.Context, *DestroyStructureRequest) (*DestroyStructureResponse, error)
+	StructureStats(context.Context, *StructureStatsRequest) (*StructureStatsResponse, error)
+}
+
+// RegisterAdminServer registers srv on s.
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: adminService,
+		HandlerType: (*AdminServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(adminService, "CreateIndex", AdminServer.CreateIndex),
+			unary(adminService, "DropIndex", AdminServer.DropIndex),
+			unary(adminService, "ListIndexes", AdminServer.ListIndexes),
+			unary(adminService, "DestroyStructure", AdminServer.DestroyStructure),
+			unary(adminService, "StructureStats", AdminServer.StructureStats),
+		},
+	}, srv)
+}
+
+// AdminClient is the client API for the admin service.
+type AdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminClient(cc grpc.ClientConnInterface) *AdminClient {
+	return &AdminClient{cc: cc}
+}
+
+func (c *AdminClient) CreateIndex(ctx context.Context, in *CreateIndexRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, adminService, "CreateIndex", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *AdminClient) DropIndex(ctx context.Context, in *DropIndexRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, adminService, "DropIndex", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *AdminClient) ListIndexes(ctx context.Context, in *ListIndexesRequest,
+	opts ...grpc.CallOption) (*ListIndexesResponse, error) {
+	out := new(ListIndexesResponse)
+	if err := invoke(ctx, c.cc, adminService, "ListIndexes", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *AdminClient) DestroyStructure(ctx context.Context, in *DestroyStructureRequest,
+	opts ...grpc.CallOption) (*DestroyStructureResponse, error) {
+	out := new(DestroyStructureResponse)
+	if err := invoke(ctx, c.cc, adminService, "DestroyStructure", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *AdminClient) StructureStats(ctx context.Context, in *StructureStatsRequest,
+	opts ...grpc.CallOption) (*StructureStatsResponse, error) {
+	out := new(StructureStatsResponse)
+	if err := invoke(ctx, c.cc, adminService, "StructureStats", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,102 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/huseyinbabal/demory/predicate"
+	"github.com/huseyinbabal/demory/query"
+	"google.golang.org/grpc"
+)
+
+const mapService = "demory.Map"
+
+type MapExecuteRequest struct {
+	Name string `json:"name"`
+	// Processor is the name of a registered entry processor, and Arguments are passed to it as is.
+	Processor string          `json:"processor"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	// Exactly one of Key, Keys and Predicate selects the entries to process.
+	Key       string               `json:"key,omitempty"`
+	Keys      []string             `json:"keys,omitempty"`
+	Predicate *predicate.Predicate `json:"predicate,omitempty"`
+}
+
+type EntryResult struct {
+	Key     string `json:"key"`
+	Value   []byte `json:"value,omitempty"`
+	Removed bool   `json:"removed,omitempty"`
+}
+
+type MapExecuteResponse struct {
+	Results []EntryResult `json:"results"`
+}
+
+type MapQueryRequest struct {
+	Name string `json:"name"`
+	// Where is a predicate in the query language, e.g. "age >= 18 AND city IN ('izmir')". It can be
+	// given as a parsed Predicate instead. All entries match if both are empty.
+	Where     string               `json:"where,omitempty"`
+	Predicate *predicate.Predicate `json:"predicate,omitempty"`
+	// Fields projects values to the given JSON paths.
+	Fields  []string      `json:"fields,omitempty"`
+	OrderBy []query.Order `json:"orderBy,omitempty"`
+	Limit   int           `json:"limit,omitempty"`
+	// PageToken continues a previous query from its NextPageToken.
+	PageToken string `json:"pageToken,omitempty"`
+}
+
+type Entry struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type MapQueryResponse struct {
+	Entries       []Entry `json:"entries"`
+	NextPageToken string  `json:"nextPageToken,omitempty"`
+}
+
+// MapServer is the server API for the map service.
+type MapServer interface {
+	MapExecute(context.Context, *MapExecuteRequest) (*MapExecuteResponse, error)
+	MapQuery(context.Context, *MapQueryRequest) (*MapQueryResponse, error)
+}
+
+// RegisterMapServer registers srv on s.
+func RegisterMapServer(s grpc.ServiceRegistrar, srv MapServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: mapService,
+		HandlerType: (*MapServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(mapService, "MapExecute", MapServer.MapExecute),
+			unary(mapService, "MapQuery", MapServer.MapQuery),
+		},
+	}, srv)
+}
+
+// MapClient is the client API for the map service.
+type MapClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMapClient(cc grpc.ClientConnInterface) *MapClient {
+	return &MapClient{cc: cc}
+}
+
+func (c *MapClient) MapExecute(ctx context.Context, in *MapExecuteRequest,
+	opts ...grpc.CallOption) (*MapExecuteResponse, error) {
+	out := new(MapExecuteResponse)
+	if err := invoke(ctx, c.cc, mapService, "MapExecute", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *MapClient) MapQuery(ctx context.Context, in *MapQueryRequest,
+	opts ...grpc.CallOption) (*MapQueryResponse, error) {
+	out := new(MapQueryResponse)
+	if err := invoke(ctx, c.cc, mapService, "MapQuery", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,148 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/huseyinbabal/demory/ds/multimap"
+	"google.golang.org/grpc"
+)
+
+const multiMapService = "demory.MultiMap"
+
+type MultiMapPutRequest struct {
+	Name   string   `json:"name"`
+	Key    string   `json:"key"`
+	Values [][]byte `json:"values"`
+	// Mode is "set", the default, to keep distinct values, or "list" to keep duplicates in order. It is
+	// fixed by the first put of a key.
+	Mode multimap.Mode `json:"mode,omitempty"`
+}
+
+type MultiMapPutResponse struct {
+	Added int `json:"added"`
+}
+
+type MultiMapKeyRequest struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+type MultiMapGetResponse struct {
+	Values [][]byte      `json:"values"`
+	Mode   multimap.Mode `json:"mode,omitempty"`
+}
+
+type MultiMapRemoveRequest struct {
+	Name  string `json:"name"`
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type MultiMapRemoveResponse struct {
+	Removed bool `json:"removed"`
+}
+
+type MultiMapRemoveAllResponse struct {
+	Removed int `json:"removed"`
+}
+
+type MultiMapCountResponse struct {
+	Count int `json:"count"`
+}
+
+type MultiMapKeysRequest struct {
+	Name string `json:"name"`
+}
+
+type MultiMapKeysResponse struct {
+	Keys []string `json:"keys"`
+}
+
+// MultiMapServer is the server API for the multimap service.
+type MultiMapServer interface {
+	MultiMapPut(context.Context, *MultiMapPutRequest) (*MultiMapPutResponse, error)
+	MultiMapGet(context.Context, *MultiMapKeyRequest) (*MultiMapGetResponse, error)
+	MultiMapRemove(context.Context, *MultiMapRemoveRequest) (*MultiMapRemoveResponse, error)
+	MultiMapRemoveAll(context.Context, *MultiMapKeyRequest) (*MultiMapRemoveAllResponse, error)
+	MultiMapCount(context.Context, *MultiMapKeyRequest) (*MultiMapCountResponse, error)
+	MultiMapKeys(context.Context, *MultiMapKeysRequest) (*MultiMapKeysResponse, error)
+}
+
+// RegisterMultiMapServer registers srv on s.
+func RegisterMultiMapServer(s grpc.ServiceRegistrar, srv MultiMapServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: multiMapService,
+		HandlerType: (*MultiMapServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(multiMapService, "MultiMapPut", MultiMapServer.MultiMapPut),
+			unary(multiMapService, "MultiMapGet", MultiMapServer.MultiMapGet),
+			unary(multiMapService, "MultiMapRemove", MultiMapServer.MultiMapRemove),
+			unary(multiMapService, "MultiMapRemoveAll", MultiMapServer.MultiMapRemoveAll),
+			unary(multiMapService, "MultiMapCount", MultiMapServer.MultiMapCount),
+			unary(multiMapService, "MultiMapKeys", MultiMapServer.MultiMapKeys),
+		},
+	}, srv)
+}
+
+// MultiMapClient is the client API for the multimap service.
+type MultiMapClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMultiMapClient(cc grpc.ClientConnInterface) *MultiMapClient {
+	return &MultiMapClient{cc: cc}
+}
+
+func (c *MultiMapClient) MultiMapPut(ctx context.Context, in *MultiMapPutRequest,
+	opts ...grpc.CallOption) (*MultiMapPutResponse, error) {
+	out := new(MultiMapPutResponse)
+	if err := invoke(ctx, c.cc, multiMapService, "MultiMapPut", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *MultiMapClient) MultiMapGet(ctx context.Context, in *MultiMapKeyRequest,
+	opts ...grpc.CallOption) (*MultiMapGetResponse, error) {
+	out := new(MultiMapGetResponse)
+	if err := invoke(ctx, c.cc, multiMapService, "MultiMapGet", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *MultiMapClient) MultiMapRemove(ctx context.Context, in *MultiMapRemoveRequest,
+	opts ...grpc.CallOption) (*MultiMapRemoveResponse, error) {
+	out := new(MultiMapRemoveResponse)
+	if err := invoke(ctx, c.cc, multiMapService, "MultiMapRemove", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *MultiMapClient) MultiMapRemoveAll(ctx context.Context, in *MultiMapKeyRequest,
+	opts ...grpc.CallOption) (*MultiMapRemoveAllResponse, error) {
+	out := new(MultiMapRemoveAllResponse)
+	if err := invoke(ctx, c.cc, multiMapService, "MultiMapRemoveAll", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *MultiMapClient) MultiMapCount(ctx context.Context, in *MultiMapKeyRequest,
+	opts ...grpc.CallOption) (*MultiMapCountResponse, error) {
+	out := new(MultiMapCountResponse)
+	if err := invoke(ctx, c.cc, multiMapService, "MultiMapCount", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *MultiMapClient) MultiMapKeys(ctx context.Context, in *MultiMapKeysRequest,
+	opts ...grpc.CallOption) (*MultiMapKeysResponse, error) {
+	out := new(MultiMapKeysResponse)
+	if err := invoke(ctx, c.cc, multiMapService, "MultiMapKeys", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,101 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/huseyinbabal/demory/ds/ratelimit"
+	"google.golang.org/grpc"
+)
+
+const rateLimiterService = "demory.RateLimiter"
+
+type RateLimiterDefineRequest struct {
+	Name       string               `json:"name"`
+	Definition ratelimit.Definition `json:"definition"`
+}
+
+type RateLimiterNameRequest struct {
+	Name string `json:"name"`
+}
+
+type RateLimiterGetResponse struct {
+	Definition ratelimit.Definition `json:"definition"`
+}
+
+type TryAcquireRequest struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	// Permits to acquire, 1 if it is not set.
+	Permits int64 `json:"permits,omitempty"`
+}
+
+type TryAcquireResponse struct {
+	ratelimit.Result
+}
+
+// RateLimiterServer is the server API for the rate limiter service.
+type RateLimiterServer interface {
+	DefineRateLimiter(context.Context, *RateLimiterDefineRequest) (*Empty, error)
+	DeleteRateLimiter(context.Context, *RateLimiterNameRequest) (*Empty, error)
+	GetRateLimiter(context.Context, *RateLimiterNameRequest) (*RateLimiterGetResponse, error)
+	TryAcquire(context.Context, *TryAcquireRequest) (*TryAcquireResponse, error)
+}
+
+// RegisterRateLimiterServer registers srv on s.
+func RegisterRateLimiterServer(s grpc.ServiceRegistrar, srv RateLimiterServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: rateLimiterService,
+		HandlerType: (*RateLimiterServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(rateLimiterService, "DefineRateLimiter", RateLimiterServer.DefineRateLimiter),
+			unary(rateLimiterService, "DeleteRateLimiter", RateLimiterServer.DeleteRateLimiter),
+			unary(rateLimiterService, "GetRateLimiter", RateLimiterServer.GetRateLimiter),
+			unary(rateLimiterService, "TryAcquire", RateLimiterServer.TryAcquire),
+		},
+	}, srv)
+}
+
+// RateLimiterClient is the client API for the rate limiter service.
+type RateLimiterClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRateLimiterClient(cc grpc.ClientConnInterface) *RateLimiterClient {
+	return &RateLimiterClient{cc: cc}
+}
+
+func (c *RateLimiterClient) DefineRateLimiter(ctx context.Context, in *RateLimiterDefineRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, rateLimiterService, "DefineRateLimiter", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *RateLimiterClient) DeleteRateLimiter(ctx context.Context, in *RateLimiterNameRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, rateLimiterService, "DeleteRateLimiter", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *RateLimiterClient) GetRateLimiter(ctx context.Context, in *RateLimiterNameRequest,
+	opts ...grpc.CallOption) (*RateLimiterGetResponse, error) {
+	out := new(RateLimiterGetResponse)
+	if err := invoke(ctx, c.cc, rateLimiterService, "GetRateLimiter", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *RateLimiterClient) TryAcquire(ctx context.Context, in *TryAcquireRequest,
+	opts ...grpc.CallOption) (*TryAcquireResponse, error) {
+	out := new(TryAcquireResponse)
+	if err := invoke(ctx, c.cc, rateLimiterService, "TryAcquire", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,138 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/huseyinbabal/demory/ds/ringbuffer"
+	"google.golang.org/grpc"
+)
+
+const ringBufferService = "demory.RingBuffer"
+
+type RingCreateRequest struct {
+	Name     string `json:"name"`
+	Capacity int    `json:"capacity"`
+	// Policy is "overwrite", the default, to drop the oldest items of a full buffer, or "fail" to reject adds.
+	Policy ringbuffer.Policy `json:"policy,omitempty"`
+	// TTL expires items after TTL milliseconds if it is positive.
+	TTL int64 `json:"ttl,omitempty"`
+}
+
+type RingRequest struct {
+	Name string `json:"name"`
+}
+
+type RingDestroyResponse struct {
+	Destroyed bool `json:"destroyed"`
+}
+
+type RingAddRequest struct {
+	Name   string   `json:"name"`
+	Values [][]byte `json:"values"`
+}
+
+type RingAddResponse struct {
+	// Sequence is the sequence of the first added item, the others follow it.
+	Sequence uint64 `json:"sequence"`
+}
+
+type RingReadRequest struct {
+	Name string `json:"name"`
+	// Sequence is the sequence of the first item to read.
+	Sequence uint64 `json:"sequence"`
+	Count    int    `json:"count,omitempty"`
+	// Block waits up to Block milliseconds for items if there are none yet.
+	Block int64 `json:"block,omitempty"`
+	// SkipStale reads from the oldest item instead of failing if Sequence is no longer in the buffer.
+	SkipStale bool `json:"skipStale,omitempty"`
+}
+
+type RingReadResponse struct {
+	Items []ringbuffer.Item `json:"items"`
+	// Next is the sequence to read from next.
+	Next uint64 `json:"next"`
+	// Lost is the number of items skipped because they were no longer in the buffer.
+	Lost uint64 `json:"lost,omitempty"`
+}
+
+type RingInfoResponse struct {
+	Info ringbuffer.Info `json:"info"`
+}
+
+// RingBufferServer is the server API for the ring buffer service.
+type RingBufferServer interface {
+	RingCreate(context.Context, *RingCreateRequest) (*Empty, error)
+	RingDestroy(context.Context, *RingRequest) (*RingDestroyResponse, error)
+	RingAdd(context.Context, *RingAddRequest) (*RingAddResponse, error)
+	RingRead(context.Context, *RingReadRequest) (*RingReadResponse, error)
+	RingInfo(context.Context, *RingRequest) (*RingInfoResponse, error)
+}
+
+// RegisterRingBufferServer registers srv on s.
+func RegisterRingBufferServer(s grpc.ServiceRegistrar, srv RingBufferServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: ringBufferService,
+		HandlerType: (*RingBufferServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(ringBufferService, "RingCreate", RingBufferServer.RingCreate),
+			unary(ringBufferService, "RingDestroy", RingBufferServer.RingDestroy),
+			unary(ringBufferService, "RingAdd", RingBufferServer.RingAdd),
+			unary(ringBufferService, "RingRead", RingBufferServer.RingRead),
+			unary(ringBufferService, "RingInfo", RingBufferServer.RingInfo),
+		},
+	}, srv)
+}
+
+// RingBufferClient is the client API for the ring buffer service.
+type RingBufferClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRingBufferClient(cc grpc.ClientConnInterface) *RingBufferClient {
+	return &RingBufferClient{cc: cc}
+}
+
+func (c *RingBufferClient) RingCreate(ctx context.Context, in *RingCreateRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, ringBufferService, "RingCreate", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *RingBufferClient) RingDestroy(ctx context.Context, in *RingRequest,
+	opts ...grpc.CallOption) (*RingDestroyResponse, error) {
+	out := new(RingDestroyResponse)
+	if err := invoke(ctx, c.cc, ringBufferService, "RingDestroy", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *RingBufferClient) RingAdd(ctx context.Context, in *RingAddRequest,
+	opts ...grpc.CallOption) (*RingAddResponse, error) {
+	out := new(RingAddResponse)
+	if err := invoke(ctx, c.cc, ringBufferService, "RingAdd", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *RingBufferClient) RingRead(ctx context.Context, in *RingReadRequest,
+	opts ...grpc.CallOption) (*RingReadResponse, error) {
+	out := new(RingReadResponse)
+	if err := invoke(ctx, c.cc, ringBufferService, "RingRead", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *RingBufferClient) RingInfo(ctx context.Context, in *RingRequest,
+	opts ...grpc.CallOption) (*RingInfoResponse, error) {
+	out := new(RingInfoResponse)
+	if err := invoke(ctx, c.cc, ringBufferService, "RingInfo", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
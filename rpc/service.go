@@ -0,0 +1,107 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// Empty is returned by methods without a result.
+type Empty struct{}
+
+// unary describes a unary method of service, dispatching calls to the typed server method call.
+func unary[S any, Req any, Resp any](service, method string,
+	call func(S, context.Context, *Req) (*Resp, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: method,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error,
+			interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(Req)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return call(srv.(S), ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{
+				Server:     srv,
+				FullMethod: "/" + service + "/" + method,
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return call(srv.(S), ctx, req.(*Req))
+			}
+			return interceptor(ctx, in, info, handler)
+		},
+	}
+}
+
+// invoke calls a unary method of service with the codec of this package.
+func invoke(ctx context.Context, cc grpc.ClientConnInterface, service, method string, in, out interface{},
+	opts ...grpc.CallOption) error {
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(Codec)}, opts...)
+	return cc.Invoke(ctx, "/"+service+"/"+method, in, out, opts...)
+}
+
+// ServerStream sends the responses of a server streaming method.
+type ServerStream[Resp any] interface {
+	Send(*Resp) error
+	Context() context.Context
+}
+
+type serverStream[Resp any] struct {
+	grpc.ServerStream
+}
+
+func (s serverStream[Resp]) Send(m *Resp) error {
+	return s.ServerStream.SendMsg(m)
+}
+
+// streaming describes a server streaming method, dispatching calls to the typed server method call.
+func streaming[S any, Req any, Resp any](method string,
+	call func(S, *Req, ServerStream[Resp]) error) grpc.StreamDesc {
+	return grpc.StreamDesc{
+		StreamName:    method,
+		ServerStreams: true,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			in := new(Req)
+			if err := stream.RecvMsg(in); err != nil {
+				return err
+			}
+			return call(srv.(S), in, serverStream[Resp]{stream})
+		},
+	}
+}
+
+// ClientStream receives the responses of a server streaming method.
+type ClientStream[Resp any] struct {
+	grpc.ClientStream
+}
+
+// Recv returns the next response. It returns io.EOF once the server ends the stream.
+func (s *ClientStream[Resp]) Recv() (*Resp, error) {
+	m := new(Resp)
+	if err := s.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// open calls a server streaming method of service with the codec of this package.
+func open[Resp any](ctx context.Context, cc grpc.ClientConnInterface, service, method string, in interface{},
+	opts ...grpc.CallOption) (*ClientStream[Resp], error) {
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(Codec)}, opts...)
+	desc := &grpc.StreamDesc{StreamName: method, ServerStreams: true}
+
+	stream, err := cc.NewStream(ctx, desc, "/"+service+"/"+method, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	return &ClientStream[Resp]{stream}, nil
+}
@@ -0,0 +1,234 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/huseyinbabal/demory/ds/stream"
+	"google.golang.org/grpc"
+)
+
+const streamService = "demory.Stream"
+
+type StreamAddRequest struct {
+	Name string `json:"name"`
+	// ID of the entry, generated from the time of the leader if it is not set.
+	ID    *stream.ID `json:"id,omitempty"`
+	Value []byte     `json:"value"`
+	// MaxLen trims the stream to its last MaxLen entries after the entry is added, if it is positive.
+	MaxLen int `json:"maxLen,omitempty"`
+}
+
+type StreamAddResponse struct {
+	ID stream.ID `json:"id"`
+}
+
+type StreamRangeRequest struct {
+	Name string `json:"name"`
+	// From and To are ids, both included. "-" and "+" stand for the first and the last entry.
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count,omitempty"`
+}
+
+type StreamReadRequest struct {
+	Name string `json:"name"`
+	// After returns the entries after an id, or only the entries added from now on if it is "$".
+	After string `json:"after"`
+	Count int    `json:"count,omitempty"`
+	// Block waits up to Block milliseconds for entries if there are none yet.
+	Block int64 `json:"block,omitempty"`
+}
+
+type StreamEntriesResponse struct {
+	Entries []stream.Entry `json:"entries"`
+}
+
+type StreamTrimRequest struct {
+	Name   string `json:"name"`
+	MaxLen int    `json:"maxLen"`
+}
+
+type StreamTrimResponse struct {
+	Removed int `json:"removed"`
+}
+
+type StreamGroupRequest struct {
+	Name  string `json:"name"`
+	Group string `json:"group"`
+	// Start is the id after which a new group delivers entries, or "$" for the entries added from now on.
+	Start string `json:"start,omitempty"`
+}
+
+type StreamReadGroupRequest struct {
+	Name     string `json:"name"`
+	Group    string `json:"group"`
+	Consumer string `json:"consumer"`
+	Count    int    `json:"count,omitempty"`
+	// Block waits up to Block milliseconds for entries if there are none yet.
+	Block int64 `json:"block,omitempty"`
+}
+
+type StreamAckRequest struct {
+	Name  string      `json:"name"`
+	Group string      `json:"group"`
+	IDs   []stream.ID `json:"ids"`
+}
+
+type StreamAckResponse struct {
+	Acked int `json:"acked"`
+}
+
+type StreamClaimRequest struct {
+	Name     string `json:"name"`
+	Group    string `json:"group"`
+	Consumer string `json:"consumer"`
+	// MinIdle is how long in milliseconds entries must be pending to be claimed.
+	MinIdle int64 `json:"minIdle"`
+	// IDs to claim. If empty, up to Count idle entries are claimed, oldest first.
+	IDs   []stream.ID `json:"ids,omitempty"`
+	Count int         `json:"count,omitempty"`
+}
+
+type StreamPendingRequest struct {
+	Name  string `json:"name"`
+	Group string `json:"group"`
+	// Consumer limits the result to the entries pending for one consumer.
+	Consumer string `json:"consumer,omitempty"`
+}
+
+type StreamPendingResponse struct {
+	Pending []stream.Pending `json:"pending"`
+}
+
+// StreamServer is the server API for the stream service.
+type StreamServer interface {
+	StreamAdd(context.Context, *StreamAddRequest) (*StreamAddResponse, error)
+	StreamRange(context.Context, *StreamRangeRequest) (*StreamEntriesResponse, error)
+	StreamRead(context.Context, *StreamReadRequest) (*StreamEntriesResponse, error)
+	StreamTrim(context.Context, *StreamTrimRequest) (*StreamTrimResponse, error)
+	StreamCreateGroup(context.Context, *StreamGroupRequest) (*Empty, error)
+	StreamDestroyGroup(context.Context, *StreamGroupRequest) (*Empty, error)
+	StreamReadGroup(context.Context, *StreamReadGroupRequest) (*StreamEntriesResponse, error)
+	StreamAck(context.Context, *StreamAckRequest) (*StreamAckResponse, error)
+	StreamClaim(context.Context, *StreamClaimRequest) (*StreamEntriesResponse, error)
+	StreamPending(context.Context, *StreamPendingRequest) (*StreamPendingResponse, error)
+}
+
+// RegisterStreamServer registers srv on s.
+func RegisterStreamServer(s grpc.ServiceRegistrar, srv StreamServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: streamService,
+		HandlerType: (*StreamServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(streamService, "StreamAdd", StreamServer.StreamAdd),
+			unary(streamService, "StreamRange", StreamServer.StreamRange),
+			unary(streamService, "StreamRead", StreamServer.StreamRead),
+			unary(streamService, "StreamTrim", StreamServer.StreamTrim),
+			unary(streamService, "StreamCreateGroup", StreamServer.StreamCreateGroup),
+			unary(streamService, "StreamDestroyGroup", StreamServer.StreamDestroyGroup),
+			unary(streamService, "StreamReadGroup", StreamServer.StreamReadGroup),
+			unary(streamService, "StreamAck", StreamServer.StreamAck),
+			unary(streamService, "StreamClaim", StreamServer.StreamClaim),
+			unary(streamService, "StreamPending", StreamServer.StreamPending),
+		},
+	}, srv)
+}
+
+// StreamClient is the client API for the stream service.
+type StreamClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStreamClient(cc grpc.ClientConnInterface) *StreamClient {
+	return &StreamClient{cc: cc}
+}
+
+func (c *StreamClient) StreamAdd(ctx context.Context, in *StreamAddRequest,
+	opts ...grpc.CallOption) (*StreamAddResponse, error) {
+	out := new(StreamAddResponse)
+	if err := invoke(ctx, c.cc, streamService, "StreamAdd", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *StreamClient) StreamRange(ctx context.Context, in *StreamRangeRequest,
+	opts ...grpc.CallOption) (*StreamEntriesResponse, error) {
+	out := new(StreamEntriesResponse)
+	if err := invoke(ctx, c.cc, streamService, "StreamRange", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *StreamClient) StreamRead(ctx context.Context, in *StreamReadRequest,
+	opts ...grpc.CallOption) (*StreamEntriesResponse, error) {
+	out := new(StreamEntriesResponse)
+	if err := invoke(ctx, c.cc, streamService, "StreamRead", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *StreamClient) StreamTrim(ctx context.Context, in *StreamTrimRequest,
+	opts ...grpc.CallOption) (*StreamTrimResponse, error) {
+	out := new(StreamTrimResponse)
+	if err := invoke(ctx, c.cc, streamService, "StreamTrim", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *StreamClient) StreamCreateGroup(ctx context.Context, in *StreamGroupRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, streamService, "StreamCreateGroup", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *StreamClient) StreamDestroyGroup(ctx context.Context, in *StreamGroupRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, streamService, "StreamDestroyGroup", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *StreamClient) StreamReadGroup(ctx context.Context, in *StreamReadGroupRequest,
+	opts ...grpc.CallOption) (*StreamEntriesResponse, error) {
+	out := new(StreamEntriesResponse)
+	if err := invoke(ctx, c.cc, streamService, "StreamReadGroup", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *StreamClient) StreamAck(ctx context.Context, in *StreamAckRequest,
+	opts ...grpc.CallOption) (*StreamAckResponse, error) {
+	out := new(StreamAckResponse)
+	if err := invoke(ctx, c.cc, streamService, "StreamAck", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *StreamClient) StreamClaim(ctx context.Context, in *StreamClaimRequest,
+	opts ...grpc.CallOption) (*StreamEntriesResponse, error) {
+	out := new(StreamEntriesResponse)
+	if err := invoke(ctx, c.cc, streamService, "StreamClaim", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *StreamClient) StreamPending(ctx context.Context, in *StreamPendingRequest,
+	opts ...grpc.CallOption) (*StreamPendingResponse, error) {
+	out := new(StreamPendingResponse)
+	if err := invoke(ctx, c.cc, streamService, "StreamPending", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,74 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/huseyinbabal/demory/pubsub"
+	"google.golang.org/grpc"
+)
+
+const topicService = "demory.Topic"
+
+type PublishRequest struct {
+	Topic   string `json:"topic"`
+	Payload []byte `json:"payload"`
+}
+
+type PublishResponse struct {
+	// Index is the raft log index the message was published at.
+	Index uint64 `json:"index"`
+}
+
+type SubscribeRequest struct {
+	// Pattern selects topics by their dot separated tokens, where * matches one token and a trailing >
+	// matches the rest, e.g. "orders.*.created" or "orders.>".
+	Pattern string `json:"pattern"`
+	// Buffer is the number of messages kept for the subscriber while it is busy. Defaults to 256.
+	Buffer int `json:"buffer,omitempty"`
+	// Policy is "drop" to skip messages that do not fit into the buffer, or "disconnect" to end the
+	// subscription instead. Defaults to drop.
+	Policy pubsub.Policy `json:"policy,omitempty"`
+}
+
+// TopicServer is the server API for the topic service.
+type TopicServer interface {
+	Publish(context.Context, *PublishRequest) (*PublishResponse, error)
+	Subscribe(*SubscribeRequest, ServerStream[pubsub.Message]) error
+}
+
+// RegisterTopicServer registers srv on s.
+func RegisterTopicServer(s grpc.ServiceRegistrar, srv TopicServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: topicService,
+		HandlerType: (*TopicServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(topicService, "Publish", TopicServer.Publish),
+		},
+		Streams: []grpc.StreamDesc{
+			streaming("Subscribe", TopicServer.Subscribe),
+		},
+	}, srv)
+}
+
+// TopicClient is the client API for the topic service.
+type TopicClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTopicClient(cc grpc.ClientConnInterface) *TopicClient {
+	return &TopicClient{cc: cc}
+}
+
+func (c *TopicClient) Publish(ctx context.Context, in *PublishRequest,
+	opts ...grpc.CallOption) (*PublishResponse, error) {
+	out := new(PublishResponse)
+	if err := invoke(ctx, c.cc, topicService, "Publish", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TopicClient) Subscribe(ctx context.Context, in *SubscribeRequest,
+	opts ...grpc.CallOption) (*ClientStream[pubsub.Message], error) {
+	return open[pubsub.Message](ctx, c.cc, topicService, "Subscribe", in, opts...)
+}
@@ -0,0 +1,54 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/huseyinbabal/demory/pubsub"
+	"google.golang.org/grpc"
+)
+
+type topicServer struct{}
+
+func (topicServer) Publish(ctx context.Context, req *PublishRequest) (*PublishResponse, error) {
+	return &PublishResponse{Index: 1}, nil
+}
+
+func (topicServer) Subscribe(req *SubscribeRequest, stream ServerStream[pubsub.Message]) error {
+	for i := uint64(1); i <= 3; i++ {
+		if err := stream.Send(&pubsub.Message{Topic: req.Pattern, Index: i}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestSubscribe(t *testing.T) {
+	conn := dial(t, func(s *grpc.Server) {
+		RegisterTopicServer(s, topicServer{})
+	})
+
+	stream, err := NewTopicClient(conn).Subscribe(context.Background(), &SubscribeRequest{Pattern: "orders"})
+	if err != nil {
+		t.Fatalf("subscribe failed %v", err)
+	}
+
+	var indexes []uint64
+	for {
+		m, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("receive failed %v", err)
+		}
+		if m.Topic != "orders" {
+			t.Errorf("unexpected topic %s", m.Topic)
+		}
+		indexes = append(indexes, m.Index)
+	}
+	if len(indexes) != 3 || indexes[2] != 3 {
+		t.Errorf("unexpected messages %v", indexes)
+	}
+}
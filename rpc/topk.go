@@ -0,0 +1,116 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/huseyinbabal/demory/ds/cms"
+	"github.com/huseyinbabal/demory/ds/topk"
+	"google.golang.org/grpc"
+)
+
+const topKService = "demory.TopK"
+
+type TopKCreateRequest struct {
+	Name string `json:"name"`
+	K    uint32 `json:"k"`
+	// Width and Depth size the count-min sketch estimating the counts. They default to 2048 and 5.
+	Width uint32 `json:"width,omitempty"`
+	Depth uint32 `json:"depth,omitempty"`
+}
+
+type TopKAddRequest struct {
+	Name       string          `json:"name"`
+	Increments []cms.Increment `json:"increments"`
+}
+
+type TopKAddResponse struct {
+	// Expelled are the items dropped from the top k to make room for each increment, or empty strings.
+	Expelled []string `json:"expelled"`
+}
+
+type TopKListRequest struct {
+	Name string `json:"name"`
+}
+
+type TopKListResponse struct {
+	// Items are the tracked items, most frequent first.
+	Items []topk.Item `json:"items"`
+}
+
+type TopKQueryRequest struct {
+	Name  string   `json:"name"`
+	Items []string `json:"items"`
+}
+
+type TopKQueryResponse struct {
+	// Found tells for each item whether it is in the top k, Counts are their estimated counts.
+	Found  []bool   `json:"found"`
+	Counts []uint64 `json:"counts"`
+}
+
+// TopKServer is the server API for the top-k service.
+type TopKServer interface {
+	TopKCreate(context.Context, *TopKCreateRequest) (*Empty, error)
+	TopKAdd(context.Context, *TopKAddRequest) (*TopKAddResponse, error)
+	TopKList(context.Context, *TopKListRequest) (*TopKListResponse, error)
+	TopKQuery(context.Context, *TopKQueryRequest) (*TopKQueryResponse, error)
+}
+
+// RegisterTopKServer registers srv on s.
+func RegisterTopKServer(s grpc.ServiceRegistrar, srv TopKServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: topKService,
+		HandlerType: (*TopKServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(topKService, "TopKCreate", TopKServer.TopKCreate),
+			unary(topKService, "TopKAdd", TopKServer.TopKAdd),
+			unary(topKService, "TopKList", TopKServer.TopKList),
+			unary(topKService, "TopKQuery", TopKServer.TopKQuery),
+		},
+	}, srv)
+}
+
+// TopKClient is the client API for the top-k service.
+type TopKClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTopKClient(cc grpc.ClientConnInterface) *TopKClient {
+	return &TopKClient{cc: cc}
+}
+
+func (c *TopKClient) TopKCreate(ctx context.Context, in *TopKCreateRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, topKService, "TopKCreate", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TopKClient) TopKAdd(ctx context.Context, in *TopKAddRequest,
+	opts ...grpc.CallOption) (*TopKAddResponse, error) {
+	out := new(TopKAddResponse)
+	if err := invoke(ctx, c.cc, topKService, "TopKAdd", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TopKClient) TopKList(ctx context.Context, in *TopKListRequest,
+	opts ...grpc.CallOption) (*TopKListResponse, error) {
+	out := new(TopKListResponse)
+	if err := invoke(ctx, c.cc, topKService, "TopKList", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TopKClient) TopKQuery(ctx context.Context, in *TopKQueryRequest,
+	opts ...grpc.CallOption) (*TopKQueryResponse, error) {
+	out := new(TopKQueryResponse)
+	if err := invoke(ctx, c.cc, topKService, "TopKQuery", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
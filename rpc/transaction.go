@@ -0,0 +1,194 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+)
+
+const transactionService = "demory.Transaction"
+
+// OperationType is the kind of an operation of a transaction.
+type OperationType string
+
+const (
+	// OperationGet reads the entry at key.
+	OperationGet OperationType = "get"
+	// OperationPut writes Value at key.
+	OperationPut OperationType = "put"
+	// OperationRemove removes the entry at key.
+	OperationRemove OperationType = "remove"
+	// OperationExecute runs an entry processor on the entry at key of a map.
+	OperationExecute OperationType = "execute"
+)
+
+// Precondition must hold for a transaction to commit. All set fields are checked against the state
+// before the transaction.
+type Precondition struct {
+	// Exists requires the entry to exist if true, and to be absent if false.
+	Exists *bool `json:"exists,omitempty"`
+	// Version requires the entry of a map to have the given version. Absent entries have version zero.
+	Version *uint64 `json:"version,omitempty"`
+	// Value requires the entry to hold the given value.
+	Value []byte `json:"value,omitempty"`
+	// UnchangedSince requires the entry not to be written or removed after the given raft log index. Removals
+	// are tracked per map or cache, so a removal of any other entry of it after the index fails it too.
+	UnchangedSince *uint64 `json:"unchangedSince,omitempty"`
+}
+
+type Operation struct {
+	Type OperationType `json:"type"`
+	// Structure is "map" or "cache".
+	Structure string `json:"structure"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+	Value     []byte `json:"value,omitempty"`
+	// TTL of a cache entry in milliseconds. The default TTL of the cache is used if it is zero.
+	TTL       int64           `json:"ttl,omitempty"`
+	Processor string          `json:"processor,omitempty"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	If        *Precondition   `json:"if,omitempty"`
+}
+
+type TransactionRequest struct {
+	Operations []Operation `json:"operations"`
+}
+
+// OperationResult is the state of an entry after its operation, or before the transaction if it did not commit.
+type OperationResult struct {
+	Value  []byte `json:"value,omitempty"`
+	Exists bool   `json:"exists"`
+	// Version of a map entry.
+	Version            uint64 `json:"version,omitempty"`
+	PreconditionFailed bool   `json:"preconditionFailed,omitempty"`
+}
+
+type TransactionResponse struct {
+	// Committed is false if a precondition failed, in which case no operation was applied.
+	Committed bool              `json:"committed"`
+	Results   []OperationResult `json:"results"`
+}
+
+type BeginResponse struct {
+	ID string `json:"id"`
+	// StartIndex is the raft log index the reads of the transaction are validated against.
+	StartIndex uint64 `json:"startIndex"`
+	// Deadline is when the transaction expires, in unix milliseconds.
+	Deadline int64 `json:"deadline"`
+}
+
+type ReadRequest struct {
+	ID        string `json:"id"`
+	Structure string `json:"structure"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+}
+
+type ReadResponse struct {
+	Value  []byte `json:"value,omitempty"`
+	Exists bool   `json:"exists"`
+}
+
+type WriteRequest struct {
+	ID string `json:"id"`
+	// Operations are put, remove or execute operations, buffered until the transaction commits.
+	Operations []Operation `json:"operations"`
+}
+
+type CommitRequest struct {
+	ID string `json:"id"`
+}
+
+type RollbackRequest struct {
+	ID string `json:"id"`
+}
+
+// TransactionServer is the server API for the transaction service.
+type TransactionServer interface {
+	Execute(context.Context, *TransactionRequest) (*TransactionResponse, error)
+	Begin(context.Context, *Empty) (*BeginResponse, error)
+	Read(context.Context, *ReadRequest) (*ReadResponse, error)
+	Write(context.Context, *WriteRequest) (*Empty, error)
+	Commit(context.Context, *CommitRequest) (*TransactionResponse, error)
+	Rollback(context.Context, *RollbackRequest) (*Empty, error)
+}
+
+// RegisterTransactionServer registers srv on s.
+func RegisterTransactionServer(s grpc.ServiceRegistrar, srv TransactionServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: transactionService,
+		HandlerType: (*TransactionServer)(nil),
+		Methods: []grpc.MethodDesc{
+			unary(transactionService, "Execute", TransactionServer.Execute),
+			unary(transactionService, "Begin", TransactionServer.Begin),
+			unary(transactionService, "Read", TransactionServer.Read),
+			unary(transactionService, "Write", TransactionServer.Write),
+			unary(transactionService, "Commit", TransactionServer.Commit),
+			unary(transactionService, "Rollback", TransactionServer.Rollback),
+		},
+	}, srv)
+}
+
+// TransactionClient is the client API for the transaction service.
+type TransactionClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTransactionClient(cc grpc.ClientConnInterface) *TransactionClient {
+	return &TransactionClient{cc: cc}
+}
+
+func (c *TransactionClient) Execute(ctx context.Context, in *TransactionRequest,
+	opts ...grpc.CallOption) (*TransactionResponse, error) {
+	out := new(TransactionResponse)
+	if err := invoke(ctx, c.cc, transactionService, "Execute", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TransactionClient) Begin(ctx context.Context, in *Empty,
+	opts ...grpc.CallOption) (*BeginResponse, error) {
+	out := new(BeginResponse)
+	if err := invoke(ctx, c.cc, transactionService, "Begin", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TransactionClient) Read(ctx context.Context, in *ReadRequest,
+	opts ...grpc.CallOption) (*ReadResponse, error) {
+	out := new(ReadResponse)
+	if err := invoke(ctx, c.cc, transactionService, "Read", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TransactionClient) Write(ctx context.Context, in *WriteRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, transactionService, "Write", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TransactionClient) Commit(ctx context.Context, in *CommitRequest,
+	opts ...grpc.CallOption) (*TransactionResponse, error) {
+	out := new(TransactionResponse)
+	if err := invoke(ctx, c.cc, transactionService, "Commit", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TransactionClient) Rollback(ctx context.Context, in *RollbackRequest,
+	opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := invoke(ctx, c.cc, transactionService, "Rollback", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,55 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/huseyinbabal/demory/watch"
+	"google.golang.org/grpc"
+)
+
+const watchService = "demory.Watch"
+
+type WatchRequest struct {
+	// Structure is "map" or "cache".
+	Structure string `json:"structure"`
+	Name      string `json:"name"`
+	// Key watches a single key and Prefix the keys starting with it. The whole map or cache is watched
+	// if neither is set.
+	Key    string `json:"key,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	// AfterIndex resumes a watch with the events after a raft log index, typically the index of the last
+	// event received. Only new events are sent if it is not set.
+	AfterIndex *uint64 `json:"afterIndex,omitempty"`
+	// Buffer is the number of events kept for the watcher while it is busy. Defaults to 256.
+	Buffer int `json:"buffer,omitempty"`
+}
+
+// WatchServer is the server API for the watch service.
+type WatchServer interface {
+	Watch(*WatchRequest, ServerStream[watch.Event]) error
+}
+
+// RegisterWatchServer registers srv on s.
+func RegisterWatchServer(s grpc.ServiceRegistrar, srv WatchServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: watchService,
+		HandlerType: (*WatchServer)(nil),
+		Streams: []grpc.StreamDesc{
+			streaming("Watch", WatchServer.Watch),
+		},
+	}, srv)
+}
+
+// WatchClient is the client API for the watch service.
+type WatchClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWatchClient(cc grpc.ClientConnInterface) *WatchClient {
+	return &WatchClient{cc: cc}
+}
+
+func (c *WatchClient) Watch(ctx context.Context, in *WatchRequest,
+	opts ...grpc.CallOption) (*ClientStream[watch.Event], error) {
+	return open[watch.Event](ctx, c.cc, watchService, "Watch", in, opts...)
+}
@@ -0,0 +1,106 @@
+package demory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/huseyinbabal/demory/ds/cache"
+	"github.com/huseyinbabal/demory/ds/hashmap"
+	"github.com/huseyinbabal/demory/index"
+	"github.com/huseyinbabal/demory/mapstore"
+)
+
+// coreKinds are the snapshot keys of the state kept by the node itself, which structure types must not use.
+// Maps and caches are not structure types: transactions, entry processors, indexes, the map store, evictions
+// and change capture read and write them together within single commands, across both of them. The sets and
+// lists of the RESP keyspace are kept with them, as RESP batches write them within the same commands.
+var coreKinds = []string{"maps", "caches", "indexes", "mapstore", "resp"}
+
+// state is the replicated state of a node as it is written to raft snapshots. Indexes are saved by
+// definition only and rebuilt from the maps on restore, map entries pending in write-behind mode are saved
+// under "mapstore", and the sets and lists of the RESP keyspace under "resp". Every structure type is saved
+// under its kind.
+type state map[string]json.RawMessage
+
+// snapshot writes the replicated state of the node to w.
+func (d *Demory) snapshot(w io.Writer) error {
+	snapshot := make(state, len(d.structures.Structures())+len(coreKinds))
+	for kind, v := range map[string]interface{}{
+		"maps":     d.hashMap,
+		"caches":   d.cache,
+		"indexes":  d.indexes.Definitions(),
+		"mapstore": d.pending,
+		"resp":     d.resp,
+	} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		snapshot[kind] = data
+	}
+	for _, s := range d.structures.Structures() {
+		data, err := s.Snapshot()
+		if err != nil {
+			return fmt.Errorf("snapshot of %s: %w", s.Kind(), err)
+		}
+		snapshot[s.Kind()] = data
+	}
+
+	return json.NewEncoder(w).Encode(snapshot)
+}
+
+// restore replaces the replicated state of the node with a snapshot read from r. Structure types missing from
+// the snapshot are restored empty, and state of structure types unknown to this node is ignored.
+func (d *Demory) restore(r io.Reader) error {
+	var restored state
+	if err := json.NewDecoder(r).Decode(&restored); err != nil {
+		return err
+	}
+
+	maps, caches, pending, respState := hashmap.New(), cache.New(), mapstore.NewPending(), newRespState()
+	var definitions []index.Definition
+	for kind, v := range map[string]interface{}{
+		"maps":     maps,
+		"caches":   caches,
+		"indexes":  &definitions,
+		"mapstore": pending,
+		"resp":     respState,
+	} {
+		if data, ok := restored[kind]; ok {
+			if err := json.Unmarshal(data, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	indexes := index.NewIndexes()
+	for _, definition := range definitions {
+		err := indexes.Create(definition, func(fn func(key string, value []byte) bool) {
+			maps.Range(definition.Map, fn)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	swaps := make([]func(), 0, len(d.structures.Structures()))
+	for _, s := range d.structures.Structures() {
+		swap, err := s.Restore(restored[s.Kind()])
+		if err != nil {
+			return fmt.Errorf("restore of %s: %w", s.Kind(), err)
+		}
+		swaps = append(swaps, swap)
+	}
+
+	// Every part of the snapshot is decoded, so the state is replaced as a whole.
+	d.hashMap, d.cache, d.indexes, d.pending, d.resp = maps, caches, indexes, pending, respState
+	for _, swap := range swaps {
+		swap()
+	}
+	d.watches.Restored()
+	d.cdc.Restored()
+	d.listen()
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package demory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	proto "github.com/huseyinbabal/demory-proto/golang/demory"
+	"github.com/huseyinbabal/demory/node"
+	"github.com/huseyinbabal/demory/rpc"
+)
+
+func TestRestoreFailure(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	ctx := context.Background()
+	s, _ := d.structures.Kind("bitmaps")
+	bitmaps := s.(*bitmapStructure)
+
+	d.MapPut(ctx, &proto.MapPutRequest{Name: "users", Key: "a", Value: []byte("1")})
+	if _, err := bitmaps.BitmapSetBit(ctx, &rpc.BitmapSetBitRequest{Name: "flags", Offset: 7, Value: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The sections decoded before the broken one must not be restored either.
+	var b bytes.Buffer
+	if err := d.snapshot(&b); err != nil {
+		t.Fatal(err)
+	}
+	var snapshot state
+	if err := json.Unmarshal(b.Bytes(), &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	snapshot["maps"] = json.RawMessage(`{}`)
+	snapshot["bitmaps"] = json.RawMessage(`{}`)
+	snapshot["rings"] = json.RawMessage(`"broken"`)
+	data, _ := json.Marshal(snapshot)
+
+	if err := d.restore(bytes.NewReader(data)); err == nil {
+		t.Fatal("expected the restore to fail")
+	}
+	if value := d.hashMap.Get("users", "a"); string(value) != "1" {
+		t.Errorf("expected the maps to be kept, got %q", value)
+	}
+	if bit, _ := bitmaps.BitmapGetBit(ctx, &rpc.BitmapGetBitRequest{Name: "flags", Offset: 7}); !bit.Value {
+		t.Error("expected the bitmaps to be kept")
+	}
+}
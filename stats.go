@@ -0,0 +1,90 @@
+package demory
+
+import (
+	"context"
+
+	"github.com/huseyinbabal/demory/ds/cache"
+	"github.com/huseyinbabal/demory/metrics"
+	"github.com/huseyinbabal/demory/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CacheStats returns the statistics of a cache, or of all caches if no name is given.
+// Statistics are local to the node serving the request.
+func (d *Demory) CacheStats(ctx context.Context, req *rpc.CacheStatsRequest) (*rpc.CacheStatsResponse, error) {
+	var caches []rpc.CacheStats
+	d.fsm.Read(func() {
+		caches = d.cacheStats(req.Name)
+	})
+
+	if req.Name != "" && len(caches) == 0 {
+		return nil, status.Errorf(codes.NotFound, "cache %s not found", req.Name)
+	}
+
+	return &rpc.CacheStatsResponse{Caches: caches}, nil
+}
+
+func (d *Demory) cacheStats(name string) []rpc.CacheStats {
+	names := d.cache.Names()
+	if name != "" {
+		names = []string{name}
+	}
+
+	caches := make([]rpc.CacheStats, 0, len(names))
+	for _, n := range names {
+		stats, ok := d.cache.Stats(n)
+		if !ok {
+			continue
+		}
+		caches = append(caches, toCacheStats(n, stats))
+	}
+
+	return caches
+}
+
+func toCacheStats(name string, stats cache.Stats) rpc.CacheStats {
+	return rpc.CacheStats{
+		Name:        name,
+		Hits:        stats.Hits,
+		Misses:      stats.Misses,
+		HitRatio:    stats.HitRatio(),
+		Puts:        stats.Puts,
+		Removals:    stats.Removals,
+		Evictions:   stats.Evictions,
+		Expirations: stats.Expirations,
+		Entries:     stats.Entries,
+		Bytes:       stats.Bytes,
+	}
+}
+
+// collectCacheMetrics reports cache statistics labelled by cache name.
+func (d *Demory) collectCacheMetrics() []metrics.Family {
+	var caches []rpc.CacheStats
+	d.fsm.Read(func() {
+		caches = d.cacheStats("")
+	})
+
+	families := []metrics.Family{
+		{Name: "demory_cache_hits_total", Help: "Cache reads that found a value.", Type: metrics.Counter},
+		{Name: "demory_cache_misses_total", Help: "Cache reads that found no value.", Type: metrics.Counter},
+		{Name: "demory_cache_hit_ratio", Help: "Share of cache reads that found a value.", Type: metrics.Gauge},
+		{Name: "demory_cache_puts_total", Help: "Values written to the cache.", Type: metrics.Counter},
+		{Name: "demory_cache_removals_total", Help: "Entries removed from the cache.", Type: metrics.Counter},
+		{Name: "demory_cache_evictions_total", Help: "Entries evicted from the cache.", Type: metrics.Counter},
+		{Name: "demory_cache_expirations_total", Help: "Entries expired from the cache.", Type: metrics.Counter},
+		{Name: "demory_cache_entries", Help: "Entries held by the cache.", Type: metrics.Gauge},
+		{Name: "demory_cache_bytes", Help: "Bytes held by the keys and values of the cache.", Type: metrics.Gauge},
+	}
+
+	for _, c := range caches {
+		labels := map[string]string{"cache": c.Name}
+		values := []float64{float64(c.Hits), float64(c.Misses), c.HitRatio, float64(c.Puts), float64(c.Removals),
+			float64(c.Evictions), float64(c.Expirations), float64(c.Entries), float64(c.Bytes)}
+		for i, value := range values {
+			families[i].Samples = append(families[i].Samples, metrics.Sample{Labels: labels, Value: value})
+		}
+	}
+
+	return families
+}
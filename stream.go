@@ -0,0 +1,329 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/huseyinbabal/demory/ds/stream"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/structure"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ structure.Waker = &stream.Streams{}
+
+func init() {
+	structure.Register(newStreamStructure)
+}
+
+// streamStructure serves and applies streams.
+type streamStructure struct {
+	host    structure.Host
+	streams *stream.Streams
+}
+
+func newStreamStructure(host structure.Host) structure.Structure {
+	return &streamStructure{host: host, streams: stream.New()}
+}
+
+func (s *streamStructure) Kind() string {
+	return "streams"
+}
+
+func (s *streamStructure) Commands() []fsm.CommandType {
+	return []fsm.CommandType{
+		fsm.StreamAdd, fsm.StreamTrim, fsm.StreamCreateGroup, fsm.StreamDestroyGroup, fsm.StreamReadGroup,
+		fsm.StreamAck, fsm.StreamClaim,
+	}
+}
+
+func (s *streamStructure) Register(server grpc.ServiceRegistrar) {
+	rpc.RegisterStreamServer(server, s)
+}
+
+func (s *streamStructure) Snapshot() ([]byte, error) {
+	return json.Marshal(s.streams)
+}
+
+func (s *streamStructure) Restore(data []byte) (func(), error) {
+	streams := stream.New()
+	if err := structure.Unmarshal(data, streams); err != nil {
+		return nil, err
+	}
+	return func() {
+		previous := s.streams
+		s.streams = streams
+		previous.Wake()
+	}, nil
+}
+
+func (s *streamStructure) Stats() structure.Stats {
+	return structure.Stats{Bytes: s.streams.Bytes()}
+}
+
+func (s *streamStructure) Destroy(name string) bool {
+	return s.streams.Destroy(name)
+}
+
+// streamArgs are the arguments of replicated stream commands.
+type streamArgs struct {
+	ID       *stream.ID  `json:"id,omitempty"`
+	MaxLen   int         `json:"maxLen,omitempty"`
+	Group    string      `json:"group,omitempty"`
+	Start    string      `json:"start,omitempty"`
+	Consumer string      `json:"consumer,omitempty"`
+	Count    int         `json:"count,omitempty"`
+	MinIdle  int64       `json:"minIdle,omitempty"`
+	IDs      []stream.ID `json:"ids,omitempty"`
+}
+
+// StreamAdd appends an entry to a stream.
+func (s *streamStructure) StreamAdd(ctx context.Context, req *rpc.StreamAddRequest) (*rpc.StreamAddResponse, error) {
+	request := fsm.ApplyRequest{Type: fsm.StreamAdd, Name: req.Name, Value: req.Value}
+	data, err := s.host.Propose(request, streamArgs{ID: req.ID, MaxLen: req.MaxLen})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.StreamAddResponse{ID: data.(stream.ID)}, nil
+}
+
+// StreamRange returns the entries of a stream between two ids.
+func (s *streamStructure) StreamRange(ctx context.Context,
+	req *rpc.StreamRangeRequest) (*rpc.StreamEntriesResponse, error) {
+	from, to := stream.ID{}, stream.ID{Ms: ^uint64(0), Seq: ^uint64(0)}
+	var err error
+	if req.From != "-" {
+		if from, err = stream.ParseID(req.From); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if req.To != "+" {
+		if to, err = stream.ParseID(req.To); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	response := &rpc.StreamEntriesResponse{}
+	s.host.Read(func() {
+		response.Entries = s.streams.Range(req.Name, from, to, req.Count)
+	})
+
+	return response, nil
+}
+
+// StreamRead returns the entries of a stream after an id, waiting for new entries if asked to.
+func (s *streamStructure) StreamRead(ctx context.Context,
+	req *rpc.StreamReadRequest) (*rpc.StreamEntriesResponse, error) {
+	var after stream.ID
+	if req.After == "$" {
+		s.host.Read(func() {
+			after = s.streams.Last(req.Name)
+		})
+	} else {
+		var err error
+		if after, err = stream.ParseID(req.After); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	response := &rpc.StreamEntriesResponse{}
+	err := structure.Block(ctx, s.host, s.signal(req.Name), req.Block, func() (bool, error) {
+		s.host.Read(func() {
+			response.Entries = s.streams.After(req.Name, after, req.Count)
+		})
+		return len(response.Entries) > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// StreamTrim removes the oldest entries of a stream until at most a number of them is left.
+func (s *streamStructure) StreamTrim(ctx context.Context, req *rpc.StreamTrimRequest) (*rpc.StreamTrimResponse, error) {
+	if req.MaxLen < 0 {
+		return nil, status.Error(codes.InvalidArgument, "max length must not be negative")
+	}
+
+	data, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.StreamTrim, Name: req.Name}, streamArgs{MaxLen: req.MaxLen})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.StreamTrimResponse{Removed: data.(int)}, nil
+}
+
+// StreamCreateGroup creates a consumer group of a stream.
+func (s *streamStructure) StreamCreateGroup(ctx context.Context, req *rpc.StreamGroupRequest) (*rpc.Empty, error) {
+	start := req.Start
+	if start == "" {
+		start = "$"
+	}
+
+	request := fsm.ApplyRequest{Type: fsm.StreamCreateGroup, Name: req.Name}
+	_, err := s.host.Propose(request, streamArgs{Group: req.Group, Start: start})
+	return new(rpc.Empty), err
+}
+
+// StreamDestroyGroup removes a consumer group of a stream with its pending entries.
+func (s *streamStructure) StreamDestroyGroup(ctx context.Context, req *rpc.StreamGroupRequest) (*rpc.Empty, error) {
+	request := fsm.ApplyRequest{Type: fsm.StreamDestroyGroup, Name: req.Name}
+	_, err := s.host.Propose(request, streamArgs{Group: req.Group})
+	return new(rpc.Empty), err
+}
+
+// StreamReadGroup delivers the entries a consumer group has not delivered yet to one of its consumers,
+// waiting for new entries if asked to. Delivered entries stay pending until they are acknowledged.
+func (s *streamStructure) StreamReadGroup(ctx context.Context,
+	req *rpc.StreamReadGroupRequest) (*rpc.StreamEntriesResponse, error) {
+	if req.Consumer == "" {
+		return nil, status.Error(codes.InvalidArgument, "consumer must be set")
+	}
+
+	response := &rpc.StreamEntriesResponse{}
+	err := structure.Block(ctx, s.host, s.signal(req.Name), req.Block, func() (bool, error) {
+		// Delivering entries changes the group, so it goes through the raft log, but only once there is
+		// something to deliver.
+		var undelivered bool
+		var err error
+		s.host.Read(func() {
+			undelivered, err = s.streams.Undelivered(req.Name, req.Group)
+		})
+		if err != nil {
+			return false, streamError(err)
+		}
+		if !undelivered {
+			return false, nil
+		}
+
+		request := fsm.ApplyRequest{Type: fsm.StreamReadGroup, Name: req.Name}
+		data, err := s.host.Propose(request, streamArgs{Group: req.Group, Consumer: req.Consumer, Count: req.Count})
+		if err != nil {
+			return false, err
+		}
+		response.Entries = data.([]stream.Entry)
+		return len(response.Entries) > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// StreamAck acknowledges pending entries of a consumer group.
+func (s *streamStructure) StreamAck(ctx context.Context, req *rpc.StreamAckRequest) (*rpc.StreamAckResponse, error) {
+	request := fsm.ApplyRequest{Type: fsm.StreamAck, Name: req.Name}
+	data, err := s.host.Propose(request, streamArgs{Group: req.Group, IDs: req.IDs})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.StreamAckResponse{Acked: data.(int)}, nil
+}
+
+// StreamClaim transfers entries pending for too long to another consumer of the group.
+func (s *streamStructure) StreamClaim(ctx context.Context,
+	req *rpc.StreamClaimRequest) (*rpc.StreamEntriesResponse, error) {
+	if req.Consumer == "" {
+		return nil, status.Error(codes.InvalidArgument, "consumer must be set")
+	}
+
+	request := fsm.ApplyRequest{Type: fsm.StreamClaim, Name: req.Name}
+	data, err := s.host.Propose(request, streamArgs{
+		Group:    req.Group,
+		Consumer: req.Consumer,
+		MinIdle:  req.MinIdle,
+		IDs:      req.IDs,
+		Count:    req.Count,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.StreamEntriesResponse{Entries: data.([]stream.Entry)}, nil
+}
+
+// StreamPending returns the pending entries of a consumer group.
+func (s *streamStructure) StreamPending(ctx context.Context,
+	req *rpc.StreamPendingRequest) (*rpc.StreamPendingResponse, error) {
+	response := &rpc.StreamPendingResponse{}
+	var err error
+	s.host.Read(func() {
+		response.Pending, err = s.streams.Pending(req.Name, req.Group, req.Consumer)
+	})
+	if err != nil {
+		return nil, streamError(err)
+	}
+
+	return response, nil
+}
+
+// signal returns the signal of structure.Block for entries added to a stream.
+func (s *streamStructure) signal(name string) func() <-chan struct{} {
+	return func() <-chan struct{} {
+		return s.streams.Signal(name)
+	}
+}
+
+// Apply applies a replicated stream command.
+func (s *streamStructure) Apply(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args streamArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+	now := request.Time / int64(time.Millisecond)
+
+	var data interface{}
+	var err error
+	switch request.Type {
+	case fsm.StreamAdd:
+		data, err = s.streams.Add(request.Name, args.ID, request.Value, args.MaxLen, now)
+	case fsm.StreamTrim:
+		data = s.streams.Trim(request.Name, args.MaxLen)
+	case fsm.StreamCreateGroup:
+		err = s.streams.CreateGroup(request.Name, args.Group, args.Start)
+	case fsm.StreamDestroyGroup:
+		if !s.streams.DestroyGroup(request.Name, args.Group) {
+			err = stream.ErrNoGroup
+		}
+	case fsm.StreamReadGroup:
+		data, err = s.streams.ReadGroup(request.Name, args.Group, args.Consumer, args.Count, now)
+	case fsm.StreamAck:
+		data, err = s.streams.Ack(request.Name, args.Group, args.IDs)
+	case fsm.StreamClaim:
+		data, err = s.streams.Claim(request.Name, args.Group, args.Consumer, args.MinIdle, args.IDs, args.Count, now)
+	}
+	if err != nil {
+		return fsm.ApplyResponse{Error: streamError(err)}
+	}
+
+	return fsm.ApplyResponse{Data: data}
+}
+
+// streamError converts errors of the stream package to gRPC statuses.
+func streamError(err error) error {
+	switch {
+	case errors.Is(err, stream.ErrNoStream), errors.Is(err, stream.ErrNoGroup):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, stream.ErrGroupExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
+
+// Growth returns the number of bytes of an added entry.
+func (s *streamStructure) Growth(request fsm.ApplyRequest) int64 {
+	if request.Type != fsm.StreamAdd {
+		return 0
+	}
+	return s.streams.EntrySize(request.Value)
+}
@@ -0,0 +1,184 @@
+// Package structure lets data structure types plug into a node. A structure type applies its own replicated
+// commands, keeps its own state in snapshots and serves its own gRPC service, so adding one does not need
+// changes to the node itself. Every node of a cluster must register the same structure types before it
+// starts applying commands.
+package structure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/huseyinbabal/demory/fsm"
+	"google.golang.org/grpc"
+)
+
+// Host is the node a structure type runs on.
+type Host interface {
+	// Propose replicates a command with its arguments through the raft log, stamped with the time of the
+	// leader, and returns the data of the applied command.
+	Propose(request fsm.ApplyRequest, args interface{}) (interface{}, error)
+	// Read runs fn while no command is being applied, so that reads see a consistent state.
+	Read(fn func())
+	// Leader reports whether the node is the raft leader.
+	Leader() bool
+	// Server returns the raft server id of the node.
+	Server() string
+	// Servers returns the raft server ids of the members of the cluster, as known by the node.
+	Servers() ([]string, error)
+}
+
+// Stats describes the state a structure type holds on a node.
+type Stats struct {
+	// Bytes is the number of bytes counted against maxmemory.
+	Bytes int64 `json:"bytes"`
+}
+
+// Structure is a replicated data structure type, holding all its named instances on a node. Apply, Snapshot,
+// Restore, Stats and Destroy are called by the node with commands excluded, either from the FSM or within
+// Host.Read, so they need no locking of their own.
+type Structure interface {
+	// Kind names the structure type. It keys the state of the type in snapshots, so it must never change.
+	Kind() string
+	// Commands are the command types applied by the structure type.
+	Commands() []fsm.CommandType
+	// Apply applies a replicated command of one of the types returned by Commands. It must be
+	// deterministic, depending only on the request and the replicated state.
+	Apply(request fsm.ApplyRequest) fsm.ApplyResponse
+	// Growth returns about the number of bytes applying a command would add, for maxmemory to be enforced.
+	Growth(request fsm.ApplyRequest) int64
+	// Snapshot encodes the state of the structure type.
+	Snapshot() ([]byte, error)
+	// Restore decodes a snapshot of the structure type, or an empty state if data is nil, and returns a
+	// function replacing the state with it. The node only calls the functions once every part of the
+	// snapshot is decoded, so a snapshot failing to decode leaves all state as it was.
+	Restore(data []byte) (func(), error)
+	// Stats describes the state of the structure type.
+	Stats() Stats
+	// Destroy removes an instance with its state, and reports whether it existed.
+	Destroy(name string) bool
+	// Register registers the gRPC service of the structure type on s.
+	Register(s grpc.ServiceRegistrar)
+}
+
+// Waker is implemented by the state of structure types that clients wait on with Block. Wake is called on
+// the state a snapshot replaces, and closes every channel it signalled: waiters look at the restored state
+// once woken.
+type Waker interface {
+	Wake()
+}
+
+// Starter is implemented by structure types running background work, such as expiry sweeps. Start is called
+// once, when the node starts serving requests.
+type Starter interface {
+	Start()
+}
+
+// Factory creates a structure type bound to the node it runs on.
+type Factory func(host Host) Structure
+
+var (
+	mutex     sync.Mutex
+	factories []Factory
+)
+
+// Register makes a structure type available to the nodes created afterwards.
+func Register(factory Factory) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	factories = append(factories, factory)
+}
+
+// Registry holds the structure types of a node and finds the one applying a command.
+type Registry struct {
+	structures []Structure
+	kinds      map[string]Structure
+	commands   map[fsm.CommandType]Structure
+}
+
+// New creates the registered structure types for host. It returns an error if two of them share a kind or a
+// command type.
+func New(host Host) (*Registry, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return newRegistry(host, factories)
+}
+
+func newRegistry(host Host, factories []Factory) (*Registry, error) {
+	r := &Registry{kinds: make(map[string]Structure), commands: make(map[fsm.CommandType]Structure)}
+	for _, factory := range factories {
+		s := factory(host)
+		if _, ok := r.kinds[s.Kind()]; ok {
+			return nil, fmt.Errorf("structure %s is already registered", s.Kind())
+		}
+		for _, command := range s.Commands() {
+			if other, ok := r.commands[command]; ok {
+				return nil, fmt.Errorf("command %s of structure %s is already applied by %s", command, s.Kind(),
+					other.Kind())
+			}
+			r.commands[command] = s
+		}
+		r.kinds[s.Kind()] = s
+		r.structures = append(r.structures, s)
+	}
+
+	return r, nil
+}
+
+// Applying returns the structure type applying a command type.
+func (r *Registry) Applying(command fsm.CommandType) (Structure, bool) {
+	s, ok := r.commands[command]
+	return s, ok
+}
+
+// Kind returns the structure type of a kind.
+func (r *Registry) Kind(kind string) (Structure, bool) {
+	s, ok := r.kinds[kind]
+	return s, ok
+}
+
+// Structures returns all structure types, in the order they were registered.
+func (r *Registry) Structures() []Structure {
+	return r.structures
+}
+
+// Block calls read until it returns true, waiting for a change in between, for up to block milliseconds.
+// signal returns a channel closed on the next change, and is called within host.Read before every read, so
+// that no change after the read is missed.
+func Block(ctx context.Context, host Host, signal func() <-chan struct{}, block int64,
+	read func() (bool, error)) error {
+	timeout := time.NewTimer(time.Duration(block) * time.Millisecond)
+	defer timeout.Stop()
+
+	for {
+		var changed <-chan struct{}
+		host.Read(func() {
+			changed = signal()
+		})
+
+		found, err := read()
+		if err != nil || found || block <= 0 {
+			return err
+		}
+
+		select {
+		case <-changed:
+		case <-timeout.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Unmarshal decodes the snapshot of a structure type into v, leaving v as it is if data is nil.
+func Unmarshal(data []byte, v interface{}) error {
+	if data == nil {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
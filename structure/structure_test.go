@@ -0,0 +1,144 @@
+package structure
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/demory/fsm"
+	"google.golang.org/grpc"
+)
+
+type testHost struct {
+	mutex sync.Mutex
+}
+
+func (h *testHost) Propose(request fsm.ApplyRequest, args interface{}) (interface{}, error) {
+	return nil, nil
+}
+
+func (h *testHost) Read(fn func()) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	fn()
+}
+
+func (h *testHost) Leader() bool {
+	return true
+}
+
+func (h *testHost) Server() string {
+	return "test"
+}
+
+func (h *testHost) Servers() ([]string, error) {
+	return []string{"test"}, nil
+}
+
+type testStructure struct {
+	kind     string
+	commands []fsm.CommandType
+}
+
+func (s *testStructure) Kind() string                                     { return s.kind }
+func (s *testStructure) Commands() []fsm.CommandType                      { return s.commands }
+func (s *testStructure) Apply(request fsm.ApplyRequest) fsm.ApplyResponse { return fsm.ApplyResponse{} }
+func (s *testStructure) Growth(request fsm.ApplyRequest) int64            { return 0 }
+func (s *testStructure) Snapshot() ([]byte, error)                        { return nil, nil }
+func (s *testStructure) Restore(data []byte) (func(), error)              { return func() {}, nil }
+func (s *testStructure) Stats() Stats                                     { return Stats{} }
+func (s *testStructure) Destroy(name string) bool                         { return false }
+func (s *testStructure) Register(server grpc.ServiceRegistrar)            {}
+
+func factory(kind string, commands ...fsm.CommandType) Factory {
+	return func(host Host) Structure {
+		return &testStructure{kind: kind, commands: commands}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r, err := newRegistry(&testHost{}, []Factory{
+		factory("first", fsm.FirstCustomCommand),
+		factory("second", fsm.FirstCustomCommand+1, fsm.FirstCustomCommand+2),
+	})
+	if err != nil {
+		t.Fatalf("expected a registry, got %v", err)
+	}
+
+	if s, ok := r.Applying(fsm.FirstCustomCommand + 2); !ok || s.Kind() != "second" {
+		t.Errorf("expected second to apply the command, got %v %v", s, ok)
+	}
+	if _, ok := r.Applying(fsm.FirstCustomCommand + 3); ok {
+		t.Errorf("expected no structure to apply an unknown command")
+	}
+	if s, ok := r.Kind("first"); !ok || s.Kind() != "first" {
+		t.Errorf("expected to find first, got %v %v", s, ok)
+	}
+	if structures := r.Structures(); len(structures) != 2 || structures[0].Kind() != "first" {
+		t.Errorf("expected structures in registration order, got %v", structures)
+	}
+}
+
+func TestRegistryConflicts(t *testing.T) {
+	if _, err := newRegistry(&testHost{}, []Factory{factory("same"), factory("same")}); err == nil {
+		t.Errorf("expected an error for a duplicate kind")
+	}
+
+	_, err := newRegistry(&testHost{}, []Factory{
+		factory("first", fsm.FirstCustomCommand),
+		factory("second", fsm.FirstCustomCommand),
+	})
+	if err == nil {
+		t.Errorf("expected an error for a duplicate command")
+	}
+}
+
+func TestBlock(t *testing.T) {
+	host := &testHost{}
+	var mutex sync.Mutex
+	signal := make(chan struct{})
+	found := false
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		host.Read(func() {
+			mutex.Lock()
+			found = true
+			mutex.Unlock()
+			close(signal)
+		})
+	}()
+
+	reads := 0
+	err := Block(context.Background(), host, func() <-chan struct{} { return signal }, 1000, func() (bool, error) {
+		reads++
+		mutex.Lock()
+		defer mutex.Unlock()
+		return found, nil
+	})
+	if err != nil || !found || reads != 2 {
+		t.Errorf("expected to read again once signalled, got %v after %d reads", err, reads)
+	}
+}
+
+func TestBlockTimeout(t *testing.T) {
+	never := make(chan struct{})
+	start := time.Now()
+	err := Block(context.Background(), &testHost{}, func() <-chan struct{} { return never }, 20, func() (bool, error) {
+		return false, nil
+	})
+	if err != nil || time.Since(start) < 20*time.Millisecond {
+		t.Errorf("expected to give up after the block time, got %v", err)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	v := map[string]int{"kept": 1}
+	if err := Unmarshal(nil, &v); err != nil || v["kept"] != 1 {
+		t.Errorf("expected nil data to leave the value as it is, got %v %v", v, err)
+	}
+	if err := Unmarshal([]byte(`{"read":2}`), &v); err != nil || v["read"] != 2 {
+		t.Errorf("expected data to be decoded, got %v %v", v, err)
+	}
+}
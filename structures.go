@@ -0,0 +1,114 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hashicorp/raft"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/metrics"
+	"github.com/huseyinbabal/demory/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// host is the node as seen by its structure types.
+type host struct {
+	d *Demory
+}
+
+func (h host) Propose(request fsm.ApplyRequest, args interface{}) (interface{}, error) {
+	return h.d.propose(request, args)
+}
+
+func (h host) Read(fn func()) {
+	h.d.fsm.Read(fn)
+}
+
+func (h host) Leader() bool {
+	return h.d.fsm.Raft.State() == raft.Leader
+}
+
+func (h host) Server() string {
+	return h.d.config.NodeID
+}
+
+func (h host) Servers() ([]string, error) {
+	future := h.d.fsm.Raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return nil, err
+	}
+
+	servers := make([]string, 0, len(future.Configuration().Servers))
+	for _, server := range future.Configuration().Servers {
+		servers = append(servers, string(server.ID))
+	}
+	return servers, nil
+}
+
+// structureArgs are the arguments of replicated StructureDestroy commands.
+type structureArgs struct {
+	Kind string `json:"kind"`
+}
+
+// DestroyStructure removes an instance of a structure type with all its state.
+func (d *Demory) DestroyStructure(ctx context.Context,
+	req *rpc.DestroyStructureRequest) (*rpc.DestroyStructureResponse, error) {
+	if _, ok := d.structures.Kind(req.Kind); !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown structure %q", req.Kind)
+	}
+
+	data, err := d.propose(fsm.ApplyRequest{Type: fsm.StructureDestroy, Name: req.Name}, structureArgs{Kind: req.Kind})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.DestroyStructureResponse{Destroyed: data.(bool)}, nil
+}
+
+// StructureStats describes the state held by every structure type of the node.
+func (d *Demory) StructureStats(ctx context.Context,
+	req *rpc.StructureStatsRequest) (*rpc.StructureStatsResponse, error) {
+	response := &rpc.StructureStatsResponse{Structures: []rpc.StructureStats{}}
+	d.fsm.Read(func() {
+		for _, s := range d.structures.Structures() {
+			response.Structures = append(response.Structures, rpc.StructureStats{Kind: s.Kind(), Bytes: s.Stats().Bytes})
+		}
+	})
+
+	return response, nil
+}
+
+// destroyStructure applies a StructureDestroy command.
+func (d *Demory) destroyStructure(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args structureArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	s, ok := d.structures.Kind(args.Kind)
+	if !ok {
+		return fsm.ApplyResponse{Error: status.Errorf(codes.InvalidArgument, "unknown structure %q", args.Kind)}
+	}
+
+	return fsm.ApplyResponse{Data: s.Destroy(request.Name)}
+}
+
+// collectStructureMetrics reports the bytes held by every structure type.
+func (d *Demory) collectStructureMetrics() []metrics.Family {
+	family := metrics.Family{
+		Name: "demory_structure_bytes",
+		Help: "Bytes held by a structure type and counted against maxmemory.",
+		Type: metrics.Gauge,
+	}
+	d.fsm.Read(func() {
+		for _, s := range d.structures.Structures() {
+			family.Samples = append(family.Samples, metrics.Sample{
+				Labels: map[string]string{"structure": s.Kind()},
+				Value:  float64(s.Stats().Bytes),
+			})
+		}
+	})
+
+	return []metrics.Family{family}
+}
@@ -0,0 +1,61 @@
+package demory
+
+import (
+	"context"
+
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/pubsub"
+	"github.com/huseyinbabal/demory/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Publish publishes a message to a topic through the raft log. Every node delivers it to its own
+// subscribers once it is applied there.
+func (d *Demory) Publish(ctx context.Context, req *rpc.PublishRequest) (*rpc.PublishResponse, error) {
+	if err := pubsub.ValidateTopic(req.Topic); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v %q", err, req.Topic)
+	}
+
+	data, err := d.propose(fsm.ApplyRequest{Type: fsm.TopicPublish, Name: req.Topic, Value: req.Payload}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.PublishResponse{Index: data.(uint64)}, nil
+}
+
+// Subscribe streams the messages published to topics matching a pattern, as they are applied on this node.
+func (d *Demory) Subscribe(req *rpc.SubscribeRequest, stream rpc.ServerStream[pubsub.Message]) error {
+	subscription, err := d.broker.Subscribe(req.Pattern, req.Buffer, req.Policy)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	defer subscription.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-subscription.Done():
+			return status.Error(codes.ResourceExhausted, subscription.Err().Error())
+		case m := <-subscription.Messages():
+			if err := stream.Send(&m); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// publish applies a TopicPublish command and returns its index. Delivery never blocks, so slow subscribers
+// cannot hold up the FSM.
+func (d *Demory) publish(request fsm.ApplyRequest) fsm.ApplyResponse {
+	d.broker.Publish(pubsub.Message{
+		Topic:   request.Name,
+		Payload: request.Value,
+		Index:   request.Index,
+		Time:    request.Time,
+	})
+
+	return fsm.ApplyResponse{Data: request.Index}
+}
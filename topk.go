@@ -0,0 +1,163 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/huseyinbabal/demory/ds/cms"
+	"github.com/huseyinbabal/demory/ds/topk"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/structure"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	structure.Register(newTopKStructure)
+}
+
+// topKStructure serves and applies top-k lists.
+type topKStructure struct {
+	host  structure.Host
+	lists *topk.Lists
+}
+
+func newTopKStructure(host structure.Host) structure.Structure {
+	return &topKStructure{host: host, lists: topk.New()}
+}
+
+func (s *topKStructure) Kind() string {
+	return "topks"
+}
+
+func (s *topKStructure) Commands() []fsm.CommandType {
+	return []fsm.CommandType{fsm.TopKCreate, fsm.TopKAdd}
+}
+
+func (s *topKStructure) Register(server grpc.ServiceRegistrar) {
+	rpc.RegisterTopKServer(server, s)
+}
+
+func (s *topKStructure) Snapshot() ([]byte, error) {
+	return json.Marshal(s.lists)
+}
+
+func (s *topKStructure) Restore(data []byte) (func(), error) {
+	lists := topk.New()
+	if err := structure.Unmarshal(data, lists); err != nil {
+		return nil, err
+	}
+	return func() { s.lists = lists }, nil
+}
+
+func (s *topKStructure) Stats() structure.Stats {
+	return structure.Stats{Bytes: s.lists.Bytes()}
+}
+
+func (s *topKStructure) Destroy(name string) bool {
+	return s.lists.Destroy(name)
+}
+
+// topKArgs are the arguments of replicated top-k commands.
+type topKArgs struct {
+	K          uint32          `json:"k,omitempty"`
+	Width      uint32          `json:"width,omitempty"`
+	Depth      uint32          `json:"depth,omitempty"`
+	Increments []cms.Increment `json:"increments,omitempty"`
+}
+
+// TopKCreate creates a list tracking the k most frequent items.
+func (s *topKStructure) TopKCreate(ctx context.Context, req *rpc.TopKCreateRequest) (*rpc.Empty, error) {
+	if _, _, err := topk.Dimensions(req.K, req.Width, req.Depth); err != nil {
+		return nil, topKError(err)
+	}
+
+	request := fsm.ApplyRequest{Type: fsm.TopKCreate, Name: req.Name}
+	_, err := s.host.Propose(request, topKArgs{K: req.K, Width: req.Width, Depth: req.Depth})
+	return new(rpc.Empty), err
+}
+
+// TopKAdd counts items in a top-k list and returns the items expelled from the top k.
+func (s *topKStructure) TopKAdd(ctx context.Context, req *rpc.TopKAddRequest) (*rpc.TopKAddResponse, error) {
+	data, err := s.host.Propose(fsm.ApplyRequest{Type: fsm.TopKAdd, Name: req.Name}, topKArgs{Increments: req.Increments})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.TopKAddResponse{Expelled: data.([]string)}, nil
+}
+
+// TopKList returns the items tracked by a top-k list, most frequent first.
+func (s *topKStructure) TopKList(ctx context.Context, req *rpc.TopKListRequest) (*rpc.TopKListResponse, error) {
+	response := &rpc.TopKListResponse{}
+	var err error
+	s.host.Read(func() {
+		response.Items, err = s.lists.List(req.Name)
+	})
+	if err != nil {
+		return nil, topKError(err)
+	}
+
+	return response, nil
+}
+
+// TopKQuery tells whether items are in the top k of a list, with their estimated counts.
+func (s *topKStructure) TopKQuery(ctx context.Context, req *rpc.TopKQueryRequest) (*rpc.TopKQueryResponse, error) {
+	response := &rpc.TopKQueryResponse{}
+	var err error
+	s.host.Read(func() {
+		if response.Found, err = s.lists.Query(req.Name, req.Items); err == nil {
+			response.Counts, err = s.lists.Count(req.Name, req.Items)
+		}
+	})
+	if err != nil {
+		return nil, topKError(err)
+	}
+
+	return response, nil
+}
+
+func (s *topKStructure) Apply(request fsm.ApplyRequest) fsm.ApplyResponse {
+	var args topKArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+
+	switch request.Type {
+	case fsm.TopKCreate:
+		if err := s.lists.Create(request.Name, args.K, args.Width, args.Depth); err != nil {
+			return fsm.ApplyResponse{Error: topKError(err)}
+		}
+		return fsm.ApplyResponse{}
+	default:
+		expelled, err := s.lists.Add(request.Name, args.Increments)
+		if err != nil {
+			return fsm.ApplyResponse{Error: topKError(err)}
+		}
+		return fsm.ApplyResponse{Data: expelled}
+	}
+}
+
+// Growth returns the number of bytes a top-k command allocates.
+func (s *topKStructure) Growth(request fsm.ApplyRequest) int64 {
+	var args topKArgs
+	if request.Type != fsm.TopKCreate || s.lists.Exists(request.Name) || json.Unmarshal(request.Args, &args) != nil {
+		return 0
+	}
+	return topk.Size(args.K, args.Width, args.Depth)
+}
+
+// topKError converts errors of the topk package to gRPC statuses.
+func topKError(err error) error {
+	switch {
+	case errors.Is(err, topk.ErrNoList):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, topk.ErrExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
@@ -0,0 +1,308 @@
+package demory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/mapstore"
+	"github.com/huseyinbabal/demory/processor"
+	"github.com/huseyinbabal/demory/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// transactionArgs are the arguments of a replicated Transaction command.
+type transactionArgs struct {
+	Operations []rpc.Operation `json:"operations"`
+}
+
+// stagedKey identifies an entry written by a transaction.
+type stagedKey struct {
+	structure fsm.Structure
+	name      string
+	key       string
+}
+
+// staged is the state of an entry within a transaction, before it is written.
+type staged struct {
+	value   []byte
+	exists  bool
+	version uint64
+	ttl     time.Duration
+	written bool
+}
+
+// Execute applies a list of operations on maps and caches atomically, as one raft log entry. Either all of
+// them are applied, or none if a precondition fails or an operation cannot be applied.
+func (d *Demory) Execute(ctx context.Context, req *rpc.TransactionRequest) (*rpc.TransactionResponse, error) {
+	if err := validateOperations(req.Operations); err != nil {
+		return nil, err
+	}
+
+	response, err := d.applyTransaction(req.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Committed {
+		if err := d.persistTransaction(req.Operations, response.Results); err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// validateOperations rejects operations that could never be applied.
+func validateOperations(operations []rpc.Operation) error {
+	if len(operations) == 0 {
+		return status.Error(codes.InvalidArgument, "a transaction needs operations")
+	}
+
+	for i, op := range operations {
+		structure := fsm.Structure(op.Structure)
+		if structure != fsm.StructureMap && structure != fsm.StructureCache {
+			return status.Errorf(codes.InvalidArgument, "operation %d: unknown structure %q", i, op.Structure)
+		}
+		switch op.Type {
+		case rpc.OperationGet, rpc.OperationPut, rpc.OperationRemove:
+		case rpc.OperationExecute:
+			if structure != fsm.StructureMap {
+				return status.Errorf(codes.InvalidArgument, "operation %d: processors only run on maps", i)
+			}
+			if _, ok := processor.Get(op.Processor); !ok {
+				return status.Errorf(codes.InvalidArgument, "operation %d: unknown processor %s", i, op.Processor)
+			}
+		default:
+			return status.Errorf(codes.InvalidArgument, "operation %d: unknown type %q", i, op.Type)
+		}
+		if op.TTL < 0 {
+			return status.Errorf(codes.InvalidArgument, "operation %d: ttl must not be negative", i)
+		}
+		if op.If != nil && op.If.Version != nil && structure != fsm.StructureMap {
+			return status.Errorf(codes.InvalidArgument, "operation %d: only map entries have versions", i)
+		}
+	}
+
+	return nil
+}
+
+func (d *Demory) applyTransaction(operations []rpc.Operation) (*rpc.TransactionResponse, error) {
+	var names []string
+	for _, op := range operations {
+		if fsm.Structure(op.Structure) == fsm.StructureMap && op.Type != rpc.OperationGet {
+			names = append(names, op.Name)
+		}
+	}
+
+	request := fsm.ApplyRequest{Type: fsm.Transaction, WriteBehind: d.writesBehind(names...)}
+	data, err := d.propose(request, transactionArgs{Operations: operations})
+	if err != nil {
+		return nil, err
+	}
+
+	return data.(*rpc.TransactionResponse), nil
+}
+
+// persistTransaction passes the map entries written by a committed transaction to the map store.
+func (d *Demory) persistTransaction(operations []rpc.Operation, results []rpc.OperationResult) error {
+	for i, op := range operations {
+		if fsm.Structure(op.Structure) != fsm.StructureMap || op.Type == rpc.OperationGet {
+			continue
+		}
+
+		result := results[i]
+		err := d.persist(op.Name, func(p *mapstore.Persister) error {
+			if result.Exists {
+				return p.Put(op.Name, op.Key, result.Value)
+			}
+			return p.Delete(op.Name, op.Key)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transaction applies a Transaction command. Preconditions are checked and every operation is staged
+// before anything is written, so a transaction either applies completely or not at all.
+func (d *Demory) transaction(request fsm.ApplyRequest) fsm.ApplyResponse {
+	st, err := d.stage(request)
+	if err != nil {
+		return fsm.ApplyResponse{Error: err}
+	}
+	if !st.response.Committed {
+		return fsm.ApplyResponse{Data: st.response}
+	}
+
+	now := time.Unix(0, request.Time)
+	for _, k := range st.order {
+		entry := st.entries[k]
+		switch {
+		case k.structure == fsm.StructureMap && entry.exists:
+			d.hashMap.Put(k.name, k.key, entry.value)
+		case k.structure == fsm.StructureMap:
+			d.hashMap.Remove(k.name, k.key)
+		case entry.exists:
+			d.cache.Put(k.name, k.key, entry.value, entry.ttl, now)
+		default:
+			d.cache.Remove(k.name, k.key)
+		}
+	}
+
+	return fsm.ApplyResponse{Data: st.response}
+}
+
+// stagedTransaction is a Transaction command staged without being written: its response, and the entries it
+// writes in the order of their first write.
+type stagedTransaction struct {
+	response *rpc.TransactionResponse
+	entries  map[stagedKey]*staged
+	order    []stagedKey
+}
+
+// stage checks the preconditions of a Transaction command and stages its operations. Nothing is staged if a
+// precondition fails.
+func (d *Demory) stage(request fsm.ApplyRequest) (*stagedTransaction, error) {
+	var args transactionArgs
+	if err := json.Unmarshal(request.Args, &args); err != nil {
+		return nil, err
+	}
+	now := time.Unix(0, request.Time)
+
+	response := &rpc.TransactionResponse{Committed: true, Results: make([]rpc.OperationResult, len(args.Operations))}
+	for i, op := range args.Operations {
+		value, exists := d.current(op, now)
+		response.Results[i] = rpc.OperationResult{Value: value, Exists: exists, Version: d.version(op)}
+		if !d.holds(op.If, op, value, exists) {
+			response.Results[i].PreconditionFailed = true
+			response.Committed = false
+		}
+	}
+	st := &stagedTransaction{response: response, entries: make(map[stagedKey]*staged)}
+	if !response.Committed {
+		return st, nil
+	}
+
+	for i, op := range args.Operations {
+		k := stagedKey{structure: fsm.Structure(op.Structure), name: op.Name, key: op.Key}
+		entry, ok := st.entries[k]
+		if !ok {
+			value, exists := d.current(op, now)
+			entry = &staged{value: value, exists: exists, version: d.version(op)}
+		}
+
+		switch op.Type {
+		case rpc.OperationPut:
+			entry = &staged{value: op.Value, exists: true, ttl: time.Duration(op.TTL) * time.Millisecond}
+		case rpc.OperationRemove:
+			entry = &staged{}
+		case rpc.OperationExecute:
+			process, found := processor.Get(op.Processor)
+			if !found {
+				return nil, status.Errorf(codes.InvalidArgument, "unknown processor %s", op.Processor)
+			}
+			processed, err := process(processor.Entry{Key: op.Key, Value: entry.value, Exists: entry.exists}, op.Arguments)
+			if err != nil {
+				return nil, status.Errorf(codes.FailedPrecondition, "operation %d: %v", i, err)
+			}
+			entry = &staged{value: processed.Value, exists: processed.Exists}
+		}
+		if op.Type != rpc.OperationGet {
+			if entry.exists && k.structure == fsm.StructureMap {
+				entry.version = request.Index
+			}
+			if !ok || !st.entries[k].written {
+				st.order = append(st.order, k)
+			}
+			entry.written = true
+		}
+		st.entries[k] = entry
+
+		response.Results[i] = rpc.OperationResult{Value: entry.value, Exists: entry.exists, Version: entry.version}
+	}
+
+	return st, nil
+}
+
+// transactionGrowth returns the number of bytes the node grows by once the entries of a Transaction command
+// are written, or zero if it fails or does not commit.
+func (d *Demory) transactionGrowth(request fsm.ApplyRequest) int64 {
+	st, err := d.stage(request)
+	if err != nil || !st.response.Committed {
+		return 0
+	}
+
+	var growth int64
+	for _, k := range st.order {
+		growth += d.stagedGrowth(k, st.entries[k])
+	}
+	return growth
+}
+
+// current returns the value of the entry an operation works on, as it is before the transaction.
+func (d *Demory) current(op rpc.Operation, now time.Time) ([]byte, bool) {
+	if fsm.Structure(op.Structure) == fsm.StructureCache {
+		return d.cache.Peek(op.Name, op.Key, now)
+	}
+
+	_, exists := d.hashMap.EntrySize(op.Name, op.Key)
+	return d.hashMap.Get(op.Name, op.Key), exists
+}
+
+// version returns the version of the map entry an operation works on, or zero for caches.
+func (d *Demory) version(op rpc.Operation) uint64 {
+	if fsm.Structure(op.Structure) != fsm.StructureMap {
+		return 0
+	}
+	return d.hashMap.Version(op.Name, op.Key)
+}
+
+// changed returns the raft log index of the last write or removal that may have changed the entry an
+// operation works on.
+func (d *Demory) changed(op rpc.Operation) uint64 {
+	if fsm.Structure(op.Structure) == fsm.StructureCache {
+		return d.cache.Changed(op.Name, op.Key)
+	}
+	return d.hashMap.Changed(op.Name, op.Key)
+}
+
+// holds reports whether precondition p holds for the entry an operation works on.
+func (d *Demory) holds(p *rpc.Precondition, op rpc.Operation, value []byte, exists bool) bool {
+	if p == nil {
+		return true
+	}
+	if p.Exists != nil && *p.Exists != exists {
+		return false
+	}
+	if p.Version != nil && *p.Version != d.version(op) {
+		return false
+	}
+	if p.Value != nil && (!exists || !bytes.Equal(p.Value, value)) {
+		return false
+	}
+	if p.UnchangedSince != nil && d.changed(op) > *p.UnchangedSince {
+		return false
+	}
+	return true
+}
+
+// stagedGrowth returns the number of bytes the node grows by when a staged entry is written.
+func (d *Demory) stagedGrowth(k stagedKey, entry *staged) int64 {
+	var current int64
+	if k.structure == fsm.StructureMap {
+		current, _ = d.hashMap.EntrySize(k.name, k.key)
+	} else {
+		current, _ = d.cache.EntrySize(k.name, k.key)
+	}
+
+	if !entry.exists {
+		return -current
+	}
+	return int64(len(k.key)+len(entry.value)) - current
+}
@@ -0,0 +1,180 @@
+package demory
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/demory/node"
+	"github.com/huseyinbabal/demory/processor"
+	"github.com/huseyinbabal/demory/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTransactionPreconditions(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	ctx := context.Background()
+
+	put := rpc.Operation{Type: rpc.OperationPut, Structure: "map", Name: "users", Key: "a", Value: []byte("1")}
+	response, err := d.Execute(ctx, &rpc.TransactionRequest{Operations: []rpc.Operation{put}})
+	if err != nil || !response.Committed {
+		t.Fatalf("expected the put to commit, got %v %v", response, err)
+	}
+	version := response.Results[0].Version
+
+	absent, stale := false, version-1
+	response, err = d.Execute(ctx, &rpc.TransactionRequest{Operations: []rpc.Operation{
+		{Type: rpc.OperationPut, Structure: "map", Name: "users", Key: "b", Value: []byte("2")},
+		{Type: rpc.OperationGet, Structure: "map", Name: "users", Key: "a", If: &rpc.Precondition{Exists: &absent}},
+		{Type: rpc.OperationGet, Structure: "map", Name: "users", Key: "a", If: &rpc.Precondition{Version: &stale}},
+		{Type: rpc.OperationGet, Structure: "map", Name: "users", Key: "a", If: &rpc.Precondition{Value: []byte("1")}},
+	}})
+	if err != nil || response.Committed {
+		t.Fatalf("expected the transaction to be rejected, got %v %v", response, err)
+	}
+	failed := []bool{false, true, true, false}
+	for i, result := range response.Results {
+		if result.PreconditionFailed != failed[i] {
+			t.Errorf("expected precondition %d failed to be %v, got %v", i, failed[i], result.PreconditionFailed)
+		}
+	}
+	d.fsm.Read(func() {
+		if _, ok := d.hashMap.EntrySize("users", "b"); ok {
+			t.Error("expected nothing to be written by a rejected transaction")
+		}
+	})
+
+	response, _ = d.Execute(ctx, &rpc.TransactionRequest{Operations: []rpc.Operation{
+		{Type: rpc.OperationRemove, Structure: "map", Name: "users", Key: "a", If: &rpc.Precondition{Version: &version}},
+	}})
+	if !response.Committed {
+		t.Error("expected the remove to commit on the current version")
+	}
+}
+
+func TestTransactionFailingProcessor(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	ctx := context.Background()
+
+	_, err := d.Execute(ctx, &rpc.TransactionRequest{Operations: []rpc.Operation{
+		{Type: rpc.OperationPut, Structure: "map", Name: "users", Key: "a", Value: []byte("1")},
+		{Type: rpc.OperationPut, Structure: "cache", Name: "sessions", Key: "b", Value: []byte("2")},
+		{Type: rpc.OperationPut, Structure: "map", Name: "users", Key: "c", Value: []byte("text")},
+		{
+			Type:      rpc.OperationExecute,
+			Structure: "map",
+			Name:      "users",
+			Key:       "c",
+			Processor: processor.Increment,
+			Arguments: json.RawMessage(`{"delta": 1}`),
+		},
+	}})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected the processor to fail the transaction, got %v", err)
+	}
+
+	d.fsm.Read(func() {
+		if d.hashMap.Bytes() != 0 || d.cache.Bytes() != 0 {
+			t.Errorf("expected nothing to be written, got %d and %d bytes", d.hashMap.Bytes(), d.cache.Bytes())
+		}
+	})
+}
+
+func TestTransactionRepeatedKeys(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	ctx := context.Background()
+
+	increment := rpc.Operation{
+		Type:      rpc.OperationExecute,
+		Structure: "map",
+		Name:      "counters",
+		Key:       "a",
+		Processor: processor.Increment,
+		Arguments: json.RawMessage(`{"delta": 2}`),
+	}
+	response, err := d.Execute(ctx, &rpc.TransactionRequest{Operations: []rpc.Operation{
+		{Type: rpc.OperationPut, Structure: "map", Name: "counters", Key: "a", Value: []byte("1")},
+		increment,
+		{Type: rpc.OperationGet, Structure: "map", Name: "counters", Key: "a"},
+		increment,
+		{Type: rpc.OperationRemove, Structure: "map", Name: "counters", Key: "b"},
+		{Type: rpc.OperationPut, Structure: "map", Name: "counters", Key: "b", Value: []byte("x")},
+	}})
+	if err != nil || !response.Committed {
+		t.Fatalf("expected the transaction to commit, got %v %v", response, err)
+	}
+
+	if got := string(response.Results[2].Value); got != "3" {
+		t.Errorf("expected operations to see earlier writes of the batch, got %s", got)
+	}
+	d.fsm.Read(func() {
+		if got := string(d.hashMap.Get("counters", "a")); got != "5" {
+			t.Errorf("expected the last write of a to win, got %s", got)
+		}
+		if got := string(d.hashMap.Get("counters", "b")); got != "x" {
+			t.Errorf("expected the last write of b to win, got %s", got)
+		}
+		if d.hashMap.Bytes() != 4 {
+			t.Errorf("expected 4 bytes to be accounted, got %d", d.hashMap.Bytes())
+		}
+	})
+}
+
+func TestTransactionOutOfMemory(t *testing.T) {
+	d := newTestNode(t, node.Config{MaxMemory: 8})
+	ctx := context.Background()
+
+	_, err := d.Execute(ctx, &rpc.TransactionRequest{Operations: []rpc.Operation{
+		{Type: rpc.OperationPut, Structure: "map", Name: "users", Key: "a", Value: []byte("1234")},
+		{Type: rpc.OperationPut, Structure: "cache", Name: "sessions", Key: "b", Value: []byte("1234")},
+	}})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected the transaction to be rejected, got %v", err)
+	}
+	d.fsm.Read(func() {
+		if d.usedMemory() != 0 {
+			t.Errorf("expected nothing to be written, got %d bytes", d.usedMemory())
+		}
+	})
+
+	// Removing an entry makes room within the same transaction.
+	d.Execute(ctx, &rpc.TransactionRequest{Operations: []rpc.Operation{
+		{Type: rpc.OperationPut, Structure: "map", Name: "users", Key: "a", Value: []byte("1234")},
+	}})
+	response, err := d.Execute(ctx, &rpc.TransactionRequest{Operations: []rpc.Operation{
+		{Type: rpc.OperationRemove, Structure: "map", Name: "users", Key: "a"},
+		{Type: rpc.OperationPut, Structure: "cache", Name: "sessions", Key: "b", Value: []byte("1234")},
+	}})
+	if err != nil || !response.Committed {
+		t.Errorf("expected the transaction to fit, got %v %v", response, err)
+	}
+}
+
+func TestTransactionCacheTTL(t *testing.T) {
+	d := newTestNode(t, node.Config{})
+	ctx := context.Background()
+	d.CacheSetDefaultTTL(ctx, &rpc.CacheSetDefaultTTLRequest{Name: "sessions", TTL: 60000})
+
+	_, err := d.Execute(ctx, &rpc.TransactionRequest{Operations: []rpc.Operation{
+		{Type: rpc.OperationPut, Structure: "cache", Name: "sessions", Key: "a", Value: []byte("1"), TTL: 1000},
+		{Type: rpc.OperationPut, Structure: "cache", Name: "sessions", Key: "b", Value: []byte("2")},
+	}})
+	if err != nil {
+		t.Fatalf("failed to execute %v", err)
+	}
+
+	now := time.Now()
+	d.fsm.Read(func() {
+		if ttl, ok := d.cache.TTL("sessions", "a", now); !ok || ttl <= 0 || ttl > time.Second {
+			t.Errorf("expected a to expire within a second, got %v", ttl)
+		}
+		if ttl, ok := d.cache.TTL("sessions", "b", now); !ok || ttl <= time.Second || ttl > time.Minute {
+			t.Errorf("expected b to expire after the default TTL, got %v", ttl)
+		}
+		if _, ok := d.cache.Peek("sessions", "a", now.Add(2*time.Second)); ok {
+			t.Error("expected a to be expired after its TTL")
+		}
+	})
+}
@@ -0,0 +1,220 @@
+// Package txn keeps the state of interactive transactions between their begin and their commit. Transactions
+// are optimistic: they only record what they read and buffer what they write, and are validated when they
+// commit.
+package txn
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/huseyinbabal/demory/rpc"
+)
+
+const (
+	DefaultLimit         = 1024
+	DefaultTimeout       = 30 * time.Second
+	DefaultMaxOperations = 1000
+)
+
+var (
+	ErrTooManyTransactions = errors.New("too many open transactions")
+	ErrTooManyOperations   = errors.New("too many operations in transaction")
+	ErrUnknownTransaction  = errors.New("unknown or expired transaction")
+	ErrConflict            = errors.New("transaction conflicts with a concurrent write")
+)
+
+// Config bounds the state kept for transactions.
+type Config struct {
+	// Limit is the maximum number of open transactions.
+	Limit int
+	// Timeout is how long a transaction can stay open after it begins.
+	Timeout time.Duration
+	// MaxOperations is the maximum number of reads and writes of a transaction.
+	MaxOperations int
+}
+
+// Key identifies an entry of a map or a cache.
+type Key struct {
+	Structure string
+	Name      string
+	Key       string
+}
+
+// Read is the state of an entry when a transaction first read it.
+type Read struct {
+	Key
+	Value  []byte
+	Exists bool
+	// Version is the raft log index of the last write or removal that may have changed the entry.
+	Version uint64
+}
+
+// Transaction is an open transaction.
+type Transaction struct {
+	ID string
+	// Start is the last raft log index applied on the node when the transaction began.
+	Start    uint64
+	Began    time.Time
+	Deadline time.Time
+	Reads    []Read
+	Writes   []rpc.Operation
+	read     map[Key]bool
+}
+
+// Recorded reports whether the entry at k was already read.
+func (t *Transaction) Recorded(k Key) bool {
+	return t.read[k]
+}
+
+// Written returns the writes buffered for the entry at k, in order.
+func (t *Transaction) Written(k Key) []rpc.Operation {
+	var writes []rpc.Operation
+	for _, op := range t.Writes {
+		if (Key{Structure: op.Structure, Name: op.Name, Key: op.Key}) == k {
+			writes = append(writes, op)
+		}
+	}
+	return writes
+}
+
+func (t *Transaction) operations() int {
+	return len(t.Reads) + len(t.Writes)
+}
+
+// Manager holds the open transactions of a node.
+type Manager struct {
+	config       Config
+	mutex        sync.Mutex
+	transactions map[string]*Transaction
+}
+
+// New creates a Manager.
+func New(config Config) *Manager {
+	if config.Limit <= 0 {
+		config.Limit = DefaultLimit
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.MaxOperations <= 0 {
+		config.MaxOperations = DefaultMaxOperations
+	}
+
+	return &Manager{config: config, transactions: make(map[string]*Transaction)}
+}
+
+// Begin opens a transaction starting at raft log index start. Expired transactions are dropped first.
+func (m *Manager) Begin(start uint64, now time.Time) (*Transaction, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for id, t := range m.transactions {
+		if !now.Before(t.Deadline) {
+			delete(m.transactions, id)
+		}
+	}
+	if len(m.transactions) >= m.config.Limit {
+		return nil, ErrTooManyTransactions
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	t := &Transaction{
+		ID:       hex.EncodeToString(id),
+		Start:    start,
+		Began:    now,
+		Deadline: now.Add(m.config.Timeout),
+		read:     make(map[Key]bool),
+	}
+	m.transactions[t.ID] = t
+
+	return t, nil
+}
+
+// Do runs fn on the open transaction id. Transactions are not safe for concurrent use, so calls on the
+// same transaction are serialized.
+func (m *Manager) Do(id string, now time.Time, fn func(t *Transaction) error) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	t, err := m.get(id, now)
+	if err != nil {
+		return err
+	}
+	return fn(t)
+}
+
+// Record adds a read to the read set of transaction id. It returns ErrConflict if the entry changed after the
+// transaction began, or since it was first read.
+func (m *Manager) Record(id string, now time.Time, read Read) error {
+	return m.Do(id, now, func(t *Transaction) error {
+		if read.Version > t.Start {
+			return ErrConflict
+		}
+		if t.read[read.Key] {
+			for _, r := range t.Reads {
+				if r.Key == read.Key && (r.Version != read.Version || r.Exists != read.Exists) {
+					return ErrConflict
+				}
+			}
+			return nil
+		}
+		if t.operations() >= m.config.MaxOperations {
+			return ErrTooManyOperations
+		}
+		t.read[read.Key] = true
+		t.Reads = append(t.Reads, read)
+		return nil
+	})
+}
+
+// Write buffers operations in transaction id.
+func (m *Manager) Write(id string, now time.Time, operations []rpc.Operation) error {
+	return m.Do(id, now, func(t *Transaction) error {
+		if t.operations()+len(operations) > m.config.MaxOperations {
+			return ErrTooManyOperations
+		}
+		t.Writes = append(t.Writes, operations...)
+		return nil
+	})
+}
+
+// End closes transaction id and returns it.
+func (m *Manager) End(id string, now time.Time) (*Transaction, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	t, err := m.get(id, now)
+	if err != nil {
+		return nil, err
+	}
+	delete(m.transactions, id)
+
+	return t, nil
+}
+
+// Len returns the number of open transactions, including expired ones that were not dropped yet.
+func (m *Manager) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return len(m.transactions)
+}
+
+func (m *Manager) get(id string, now time.Time) (*Transaction, error) {
+	t, ok := m.transactions[id]
+	if !ok {
+		return nil, ErrUnknownTransaction
+	}
+	if !now.Before(t.Deadline) {
+		delete(m.transactions, id)
+		return nil, ErrUnknownTransaction
+	}
+	return t, nil
+}
@@ -0,0 +1,75 @@
+package txn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/demory/rpc"
+)
+
+var now = time.Unix(1700000000, 0)
+
+func TestManagerLimit(t *testing.T) {
+	m := New(Config{Limit: 2, Timeout: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		if _, err := m.Begin(1, now); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if _, err := m.Begin(1, now); err != ErrTooManyTransactions {
+		t.Errorf("expected %v, got %v", ErrTooManyTransactions, err)
+	}
+	if _, err := m.Begin(1, now.Add(time.Minute)); err != nil {
+		t.Errorf("expected expired transactions to make room, got %v", err)
+	}
+	if m.Len() != 1 {
+		t.Errorf("expected 1 open transaction, got %d", m.Len())
+	}
+}
+
+func TestManagerTimeout(t *testing.T) {
+	m := New(Config{Timeout: time.Second})
+	tx, _ := m.Begin(1, now)
+
+	if err := m.Write(tx.ID, now.Add(time.Second), nil); err != ErrUnknownTransaction {
+		t.Errorf("expected %v, got %v", ErrUnknownTransaction, err)
+	}
+	if _, err := m.End(tx.ID, now); err != ErrUnknownTransaction {
+		t.Errorf("expected expired transaction to be dropped, got %v", err)
+	}
+}
+
+func TestManagerOperations(t *testing.T) {
+	m := New(Config{MaxOperations: 2})
+	tx, _ := m.Begin(1, now)
+	k := Key{Structure: "map", Name: "users", Key: "a"}
+
+	if err := m.Record(tx.ID, now, Read{Key: k, Version: 1}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := m.Record(tx.ID, now, Read{Key: k, Version: 1}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := m.Record(tx.ID, now, Read{Key: k, Version: 2}); err != ErrConflict {
+		t.Errorf("expected %v, got %v", ErrConflict, err)
+	}
+	put := rpc.Operation{Type: rpc.OperationPut, Structure: "map", Name: "users", Key: "a", Value: []byte("1")}
+	if err := m.Write(tx.ID, now, []rpc.Operation{put, put}); err != ErrTooManyOperations {
+		t.Errorf("expected %v, got %v", ErrTooManyOperations, err)
+	}
+	if err := m.Write(tx.ID, now, []rpc.Operation{put}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	ended, err := m.End(tx.ID, now)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(ended.Reads) != 1 || ended.Reads[0].Version != 1 {
+		t.Errorf("expected only the first read to be recorded, got %+v", ended.Reads)
+	}
+	if len(ended.Written(k)) != 1 {
+		t.Errorf("expected one buffered write, got %+v", ended.Writes)
+	}
+}
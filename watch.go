@@ -0,0 +1,91 @@
+package demory
+
+import (
+	"errors"
+
+	"github.com/huseyinbabal/demory/cdc"
+	"github.com/huseyinbabal/demory/ds/cache"
+	"github.com/huseyinbabal/demory/ds/hashmap"
+	"github.com/huseyinbabal/demory/fsm"
+	"github.com/huseyinbabal/demory/rpc"
+	"github.com/huseyinbabal/demory/watch"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Watch streams the changes of a map or a cache as they are applied on this node. A watch resumed after an
+// index that is no longer kept fails with OUT_OF_RANGE, and a watcher that does not keep up is ended with
+// RESOURCE_EXHAUSTED; both can resume from the index of the last event they received.
+func (d *Demory) Watch(req *rpc.WatchRequest, stream rpc.ServerStream[watch.Event]) error {
+	structure := fsm.Structure(req.Structure)
+	if structure != fsm.StructureMap && structure != fsm.StructureCache {
+		return status.Errorf(codes.InvalidArgument, "unknown structure %q", req.Structure)
+	}
+
+	filter := watch.Filter{Structure: req.Structure, Name: req.Name, Key: req.Key, Prefix: req.Prefix}
+	watcher, err := d.watches.Watch(filter, req.AfterIndex, req.Buffer)
+	switch {
+	case errors.Is(err, watch.ErrCompacted):
+		return status.Error(codes.OutOfRange, err.Error())
+	case err != nil:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	defer watcher.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-watcher.Done():
+			return status.Error(codes.ResourceExhausted, watcher.Err().Error())
+		case e := <-watcher.Events():
+			if err := stream.Send(&e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// listen subscribes to the changes of the maps and caches of the node, to keep indexes up to date, notify
+// watchers and capture changes.
+func (d *Demory) listen() {
+	d.hashMap.Listen(d.mapChanged)
+	d.cache.Listen(d.cacheChanged)
+}
+
+func (d *Demory) mapChanged(e hashmap.Event) {
+	d.indexes.Update(e.Name, e.Key, e.Value, e.Kind != hashmap.Put)
+	d.changes = append(d.changes, cdc.Record{
+		Type:      string(e.Kind),
+		Structure: string(fsm.StructureMap),
+		Name:      e.Name,
+		Key:       e.Key,
+		Old:       e.Old,
+		Value:     e.Value,
+	})
+	d.watches.Publish(watch.Event{
+		Type:      watch.Type(e.Kind),
+		Structure: string(fsm.StructureMap),
+		Name:      e.Name,
+		Key:       e.Key,
+		Value:     e.Value,
+	})
+}
+
+func (d *Demory) cacheChanged(e cache.Event) {
+	d.changes = append(d.changes, cdc.Record{
+		Type:      string(e.Kind),
+		Structure: string(fsm.StructureCache),
+		Name:      e.Name,
+		Key:       e.Key,
+		Old:       e.Old,
+		Value:     e.Value,
+	})
+	d.watches.Publish(watch.Event{
+		Type:      watch.Type(e.Kind),
+		Structure: string(fsm.StructureCache),
+		Name:      e.Name,
+		Key:       e.Key,
+		Value:     e.Value,
+	})
+}
@@ -0,0 +1,213 @@
+// Package watch notifies watchers of the changes of map and cache entries, as they are applied on a node.
+// Recent events are kept, so that watchers can resume after a reconnect without missing any.
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultHistory = 10000
+	DefaultBuffer  = 256
+	MaxBuffer      = 1 << 16
+)
+
+var (
+	ErrCompacted   = errors.New("index is compacted")
+	ErrSlowWatcher = errors.New("watcher did not keep up with events")
+	ErrInvalid     = errors.New("invalid watch")
+)
+
+// Type is the type of a change.
+type Type string
+
+const (
+	Put    Type = "put"
+	Remove Type = "remove"
+	Evict  Type = "evict"
+	Expire Type = "expire"
+)
+
+// Event is a change of an entry.
+type Event struct {
+	// Index is the raft log index of the command that caused the change. Commands can cause several changes.
+	Index     uint64 `json:"index"`
+	Type      Type   `json:"type"`
+	Structure string `json:"structure"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+	// Value is the value after a put.
+	Value []byte `json:"value,omitempty"`
+}
+
+// Filter selects the events of a map or a cache, optionally of a single key or of keys with a prefix.
+type Filter struct {
+	Structure string
+	Name      string
+	Key       string
+	Prefix    string
+}
+
+func (f Filter) matches(e Event) bool {
+	if f.Structure != e.Structure || f.Name != e.Name {
+		return false
+	}
+	if f.Key != "" && f.Key != e.Key {
+		return false
+	}
+	return strings.HasPrefix(e.Key, f.Prefix)
+}
+
+// Watcher receives the events matching its filter.
+type Watcher struct {
+	hub    *Hub
+	filter Filter
+	events chan Event
+	done   chan struct{}
+	err    error
+}
+
+// Events returns the channel events are delivered on.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Done is closed once the watcher is dropped because it was too slow, see Err.
+func (w *Watcher) Done() <-chan struct{} {
+	return w.done
+}
+
+// Err returns why the watcher was dropped.
+func (w *Watcher) Err() error {
+	w.hub.mutex.Lock()
+	defer w.hub.mutex.Unlock()
+
+	return w.err
+}
+
+// Close stops the watcher.
+func (w *Watcher) Close() {
+	w.hub.mutex.Lock()
+	defer w.hub.mutex.Unlock()
+
+	w.hub.remove(w, nil)
+}
+
+// Hub keeps the recent events of a node and delivers new ones to watchers without blocking.
+type Hub struct {
+	mutex    sync.Mutex
+	limit    int
+	history  []Event
+	index    uint64
+	floor    uint64
+	restored bool
+	watchers map[*Watcher]struct{}
+}
+
+// New creates a hub keeping up to history events.
+func New(history int) *Hub {
+	if history <= 0 {
+		history = DefaultHistory
+	}
+	return &Hub{limit: history, watchers: make(map[*Watcher]struct{})}
+}
+
+// Applied tells the hub that the command at index is being applied. Events published afterwards get index.
+func (h *Hub) Applied(index uint64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.index = index
+	if h.restored {
+		h.floor, h.restored = index-1, false
+	}
+}
+
+// Restored tells the hub that the state was replaced by a snapshot. The events before it are lost.
+func (h *Hub) Restored() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.history = nil
+	h.restored = true
+}
+
+// Publish records e with the index of the command being applied and delivers it to the matching watchers.
+func (h *Hub) Publish(e Event) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	e.Index = h.index
+	h.history = append(h.history, e)
+	if len(h.history) > h.limit {
+		if h.history[0].Index > h.floor {
+			h.floor = h.history[0].Index
+		}
+		h.history = h.history[1:]
+	}
+
+	for w := range h.watchers {
+		if !w.filter.matches(e) {
+			continue
+		}
+		select {
+		case w.events <- e:
+		default:
+			h.remove(w, ErrSlowWatcher)
+		}
+	}
+}
+
+// Watch starts delivering the events matching filter. If after is set, the recorded events after that index
+// are delivered first, unless some of them are no longer kept, in which case ErrCompacted is returned.
+func (h *Hub) Watch(filter Filter, after *uint64, buffer int) (*Watcher, error) {
+	if filter.Structure == "" || filter.Name == "" || (filter.Key != "" && filter.Prefix != "") {
+		return nil, ErrInvalid
+	}
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	if buffer > MaxBuffer {
+		buffer = MaxBuffer
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var backlog []Event
+	if after != nil {
+		if *after < h.floor {
+			return nil, fmt.Errorf("%w, the oldest index to resume after is %d", ErrCompacted, h.floor)
+		}
+		for _, e := range h.history {
+			if e.Index > *after && filter.matches(e) {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+
+	w := &Watcher{
+		hub:    h,
+		filter: filter,
+		events: make(chan Event, buffer+len(backlog)),
+		done:   make(chan struct{}),
+	}
+	for _, e := range backlog {
+		w.events <- e
+	}
+	h.watchers[w] = struct{}{}
+
+	return w, nil
+}
+
+func (h *Hub) remove(w *Watcher, err error) {
+	if _, ok := h.watchers[w]; !ok {
+		return
+	}
+	delete(h.watchers, w)
+	w.err = err
+	close(w.done)
+}
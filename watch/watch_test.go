@@ -0,0 +1,83 @@
+package watch
+
+import (
+	"errors"
+	"testing"
+)
+
+func index(i uint64) *uint64 {
+	return &i
+}
+
+func TestWatch(t *testing.T) {
+	h := New(10)
+	w, err := h.Watch(Filter{Structure: "map", Name: "users", Prefix: "a"}, nil, 10)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	h.Applied(1)
+	h.Publish(Event{Type: Put, Structure: "map", Name: "users", Key: "ab", Value: []byte("1")})
+	h.Publish(Event{Type: Put, Structure: "map", Name: "users", Key: "b"})
+	h.Publish(Event{Type: Put, Structure: "cache", Name: "users", Key: "ac"})
+	h.Applied(2)
+	h.Publish(Event{Type: Remove, Structure: "map", Name: "users", Key: "ab"})
+
+	if e := <-w.Events(); e.Index != 1 || e.Key != "ab" || e.Type != Put {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if e := <-w.Events(); e.Index != 2 || e.Type != Remove {
+		t.Errorf("unexpected event %+v", e)
+	}
+	select {
+	case e := <-w.Events():
+		t.Errorf("unexpected event %+v", e)
+	default:
+	}
+}
+
+func TestResume(t *testing.T) {
+	h := New(2)
+	for i := uint64(1); i <= 3; i++ {
+		h.Applied(i)
+		h.Publish(Event{Type: Put, Structure: "map", Name: "users", Key: "a"})
+	}
+
+	filter := Filter{Structure: "map", Name: "users", Key: "a"}
+	if _, err := h.Watch(filter, index(0), 0); !errors.Is(err, ErrCompacted) {
+		t.Errorf("expected %v, got %v", ErrCompacted, err)
+	}
+
+	w, err := h.Watch(filter, index(1), 0)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if e := <-w.Events(); e.Index != 2 {
+		t.Errorf("expected to resume at index 2, got %+v", e)
+	}
+	if e := <-w.Events(); e.Index != 3 {
+		t.Errorf("expected index 3, got %+v", e)
+	}
+
+	h.Restored()
+	h.Applied(10)
+	if _, err := h.Watch(filter, index(8), 0); !errors.Is(err, ErrCompacted) {
+		t.Errorf("expected events before a restore to be compacted, got %v", err)
+	}
+	if _, err := h.Watch(filter, index(9), 0); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSlowWatcher(t *testing.T) {
+	h := New(10)
+	w, _ := h.Watch(Filter{Structure: "map", Name: "users"}, nil, 1)
+
+	h.Publish(Event{Type: Put, Structure: "map", Name: "users", Key: "a"})
+	h.Publish(Event{Type: Put, Structure: "map", Name: "users", Key: "b"})
+
+	<-w.Done()
+	if w.Err() != ErrSlowWatcher {
+		t.Errorf("expected %v, got %v", ErrSlowWatcher, w.Err())
+	}
+}